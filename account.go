@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// contextSlotHeader reports the slot an account was read at
+const contextSlotHeader = "X-Context-Slot"
+
+// accountStateHeader says whether an account read at ?slot= is the state at
+// that slot ("historical") or a later one ("current")
+const accountStateHeader = "X-Account-State"
+
+// JSON-RPC codes returned by providers that do not accept a parameter
+const (
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// AccountInfo is the account state returned by getAccountInfo
+type AccountInfo struct {
+	Lamports   uint64          `json:"lamports"`
+	Owner      string          `json:"owner"`
+	Data       json.RawMessage `json:"data"`
+	Executable bool            `json:"executable"`
+	RentEpoch  uint64          `json:"rentEpoch"`
+	Space      uint64          `json:"space"`
+}
+
+// rawData decodes base64 account data as returned by getAccountInfo
+func (a *AccountInfo) rawData() ([]byte, error) {
+	var data []string
+	if err := json.Unmarshal(a.Data, &data); err != nil || len(data) != 2 {
+		return nil, fmt.Errorf("account data is not binary encoded")
+	}
+	if data[1] != "base64" {
+		return nil, fmt.Errorf("unsupported account data encoding: %s", data[1])
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(data[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode account data: %w", err)
+	}
+	return raw, nil
+}
+
+// accountEncodings are the encodings /account accepts for account data.
+// With jsonParsed, the node decodes accounts of well-known programs and
+// falls back to base64 for the rest.
+var accountEncodings = map[string]bool{
+	"base64":     true,
+	"base58":     true,
+	"jsonParsed": true,
+}
+
+// AccountOptions configures getAccountInfo and getProgramAccounts. An empty
+// Encoding asks for base64, which the account decoders expect.
+type AccountOptions struct {
+	Encoding string
+	// DataSlice returns only part of the account data
+	DataSlice *DataSlice
+}
+
+// DataSlice is a range of account data, by byte offset and length
+type DataSlice struct {
+	Offset uint64 `json:"offset"`
+	Length uint64 `json:"length"`
+}
+
+// accountConfig builds the getAccountInfo config object
+func accountConfig(opts AccountOptions) map[string]interface{} {
+	encoding := opts.Encoding
+	if encoding == "" {
+		encoding = "base64"
+	}
+	config := map[string]interface{}{"encoding": encoding}
+	if opts.DataSlice != nil {
+		config["dataSlice"] = opts.DataSlice
+	}
+	return config
+}
+
+// decodedAccount is the response for /account when a decoder is requested
+type decodedAccount struct {
+	Address  string      `json:"address"`
+	Owner    string      `json:"owner"`
+	Lamports uint64      `json:"lamports"`
+	Decoder  string      `json:"decoder"`
+	Type     string      `json:"type"`
+	Data     interface{} `json:"data"`
+}
+
+// getAccountInfo gets the state of an account, or nil if it does not exist
+func (c *rpcClient) getAccountInfo(ctx context.Context, address string, opts AccountOptions) (*AccountInfo, error) {
+	response, err := c.sendRequest(ctx, "getAccountInfo", []interface{}{address, accountConfig(opts)})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value *AccountInfo `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse account info: %w", err)
+	}
+
+	return result.Value, nil
+}
+
+// getAccountInfoAt gets the state of an account as seen by a node that has
+// processed at least slot, along with the slot it was read at. Standard
+// nodes only serve their current state, so the context slot may be past the
+// one asked for; archival providers that honour minContextSlot as a
+// historical bound return the state nearest to it.
+func (c *rpcClient) getAccountInfoAt(ctx context.Context, address string, slot uint64, opts AccountOptions) (*AccountInfo, uint64, error) {
+	config := accountConfig(opts)
+	config["minContextSlot"] = slot
+	response, err := c.sendRequest(ctx, "getAccountInfo", []interface{}{address, config})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value *AccountInfo `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse account info: %w", err)
+	}
+
+	return result.Value, result.Context.Slot, nil
+}
+
+// unsupportedQuery reports whether the upstream rejected a method or
+// parameter it does not implement
+func unsupportedQuery(err error) bool {
+	var rpcErr *upstreamError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return rpcErr.Code == rpcMethodNotFound || rpcErr.Code == rpcInvalidParams
+}
+
+// getMultipleAccounts gets the state of several accounts in one call, with
+// nil entries for accounts that do not exist
+func (c *rpcClient) getMultipleAccounts(ctx context.Context, addresses []string) ([]*AccountInfo, error) {
+	response, err := c.sendRequest(ctx, "getMultipleAccounts", []interface{}{
+		addresses,
+		map[string]interface{}{"encoding": "base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value []*AccountInfo `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse accounts: %w", err)
+	}
+	if len(result.Value) != len(addresses) {
+		return nil, fmt.Errorf("RPC returned %d accounts for %d addresses", len(result.Value), len(addresses))
+	}
+
+	return result.Value, nil
+}
+
+// ProgramAccount is an account owned by a program, as returned by
+// getProgramAccounts
+type ProgramAccount struct {
+	Pubkey  string      `json:"pubkey"`
+	Account AccountInfo `json:"account"`
+}
+
+// getProgramAccounts gets the accounts owned by a program that pass every
+// getProgramAccounts filter
+func (c *rpcClient) getProgramAccounts(ctx context.Context, program string, filters []interface{}, opts AccountOptions) ([]ProgramAccount, error) {
+	config := accountConfig(opts)
+	if len(filters) > 0 {
+		config["filters"] = filters
+	}
+	response, err := c.sendRequest(ctx, "getProgramAccounts", []interface{}{program, config})
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []ProgramAccount
+	if err := json.Unmarshal(response.Result, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse program accounts: %w", err)
+	}
+
+	return accounts, nil
+}
+
+// accountDecoder decodes account data into a named type and its fields
+type accountDecoder func(account *AccountInfo) (string, interface{}, error)
+
+func handleGetAccount(client SolanaRPCClient, idls *anchorRegistry) http.HandlerFunc {
+	decoders := map[string]accountDecoder{
+		"anchor": idls.decodeAccount,
+		"stake":  decodeStakeAccount,
+		"vote":   decodeVoteAccount,
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			http.Error(w, "address parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		decode := r.URL.Query().Get("decode")
+		decoder, ok := decoders[decode]
+		if decode != "" && !ok {
+			http.Error(w, "unsupported decode value", http.StatusBadRequest)
+			return
+		}
+
+		opts := AccountOptions{Encoding: r.URL.Query().Get("encoding")}
+		if opts.Encoding != "" && !accountEncodings[opts.Encoding] {
+			http.Error(w, fmt.Sprintf("unsupported encoding %q", opts.Encoding), http.StatusBadRequest)
+			return
+		}
+		if decoder != nil && opts.Encoding != "" && opts.Encoding != "base64" {
+			http.Error(w, "decode requires base64 encoding", http.StatusBadRequest)
+			return
+		}
+
+		var account *AccountInfo
+		var err error
+		if slotParam := r.URL.Query().Get("slot"); slotParam != "" {
+			slot, parseErr := strconv.ParseUint(slotParam, 10, 64)
+			if parseErr != nil {
+				http.Error(w, "invalid slot parameter", http.StatusBadRequest)
+				return
+			}
+
+			var contextSlot uint64
+			account, contextSlot, err = client.getAccountInfoAt(r.Context(), address, slot, opts)
+			if unsupportedQuery(err) {
+				http.Error(w, "the RPC provider does not support historical account queries", http.StatusNotImplemented)
+				return
+			}
+			if err == nil {
+				w.Header().Set(contextSlotHeader, strconv.FormatUint(contextSlot, 10))
+
+				// minContextSlot is only a lower bound: most nodes answer
+				// with their current state, which is not what was asked for
+				// unless the client accepts a newer one
+				state := "historical"
+				if contextSlot != slot {
+					state = "current"
+					if r.URL.Query().Get("allowNewer") != "true" {
+						http.Error(w, fmt.Sprintf("account state at slot %d is not available, the RPC provider returned slot %d; pass allowNewer=true to accept it", slot, contextSlot), http.StatusConflict)
+						return
+					}
+				}
+				w.Header().Set(accountStateHeader, state)
+			}
+		} else {
+			account, err = client.getAccountInfo(r.Context(), address, opts)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		if account == nil {
+			http.Error(w, "account not found", http.StatusNotFound)
+			return
+		}
+
+		var response interface{} = account
+		if decoder != nil {
+			typeName, fields, err := decoder(account)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			response = decodedAccount{
+				Address:  address,
+				Owner:    account.Owner,
+				Lamports: account.Lamports,
+				Decoder:  decode,
+				Type:     typeName,
+				Data:     fields,
+			}
+		}
+
+		jsonData, err := json.Marshal(response)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to encode account: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetAccountAtSlot(t *testing.T) {
+	account := &AccountInfo{Lamports: 1000, Owner: "11111111111111111111111111111111", Data: []byte(`["","base64"]`)}
+
+	tests := []struct {
+		name           string
+		query          string
+		mock           mockRPCClient
+		expectedStatus int
+		expectedSlot   string
+		expectedState  string
+	}{
+		{name: "Current State", query: "address=abc", mock: mockRPCClient{accountInfo: account, latestSlot: 500}, expectedStatus: http.StatusOK},
+		{name: "At Slot", query: "address=abc&slot=400", mock: mockRPCClient{accountInfo: account, latestSlot: 400}, expectedStatus: http.StatusOK, expectedSlot: "400", expectedState: "historical"},
+		{name: "Newer State Rejected", query: "address=abc&slot=400", mock: mockRPCClient{accountInfo: account, latestSlot: 500}, expectedStatus: http.StatusConflict, expectedSlot: "500"},
+		{name: "Newer State Allowed", query: "address=abc&slot=400&allowNewer=true", mock: mockRPCClient{accountInfo: account, latestSlot: 500}, expectedStatus: http.StatusOK, expectedSlot: "500", expectedState: "current"},
+		{name: "Invalid Slot", query: "address=abc&slot=latest", mock: mockRPCClient{accountInfo: account}, expectedStatus: http.StatusBadRequest},
+		{name: "Method Not Supported", query: "address=abc&slot=400", mock: mockRPCClient{accountErr: &upstreamError{Code: rpcMethodNotFound, Message: "Method not found", Status: http.StatusInternalServerError}}, expectedStatus: http.StatusNotImplemented},
+		{name: "Param Not Supported", query: "address=abc&slot=400", mock: mockRPCClient{accountErr: &upstreamError{Code: rpcInvalidParams, Message: "Invalid params: unknown field `minContextSlot`", Status: http.StatusInternalServerError}}, expectedStatus: http.StatusNotImplemented},
+		{name: "Slot Not Reached", query: "address=abc&slot=900", mock: mockRPCClient{accountErr: &upstreamError{Code: -32016, Message: "Minimum context slot has not been reached", Status: http.StatusServiceUnavailable}}, expectedStatus: http.StatusServiceUnavailable},
+		{name: "Not Found", query: "address=abc&slot=400", mock: mockRPCClient{latestSlot: 400}, expectedStatus: http.StatusNotFound, expectedSlot: "400", expectedState: "historical"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/account?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetAccount(&tt.mock, nil).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if got := rr.Header().Get(contextSlotHeader); got != tt.expectedSlot {
+				t.Errorf("Expected %s %q, got %q", contextSlotHeader, tt.expectedSlot, got)
+			}
+			if got := rr.Header().Get(accountStateHeader); got != tt.expectedState {
+				t.Errorf("Expected %s %q, got %q", accountStateHeader, tt.expectedState, got)
+			}
+		})
+	}
+}
+
+func TestHandleGetAccountEncoding(t *testing.T) {
+	account := &AccountInfo{Lamports: 1000, Owner: "11111111111111111111111111111111", Data: []byte(`["3Bxs4h24hBtQy9rw","base58"]`)}
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedStatus   int
+		expectedEncoding string
+	}{
+		{name: "Default", query: "address=abc", expectedStatus: http.StatusOK},
+		{name: "Base58", query: "address=abc&encoding=base58", expectedStatus: http.StatusOK, expectedEncoding: "base58"},
+		{name: "JSON Parsed At Slot", query: "address=abc&slot=400&encoding=jsonParsed", expectedStatus: http.StatusOK, expectedEncoding: "jsonParsed"},
+		{name: "Unsupported Encoding", query: "address=abc&encoding=hex", expectedStatus: http.StatusBadRequest},
+		{name: "Decode Needs Base64", query: "address=abc&encoding=base58&decode=stake", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockRPCClient{accountInfo: account, latestSlot: 400}
+			req := httptest.NewRequest("GET", "/account?"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetAccount(mock, nil).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if mock.acctOptions.Encoding != tt.expectedEncoding {
+				t.Errorf("Expected encoding %q, got %q", tt.expectedEncoding, mock.acctOptions.Encoding)
+			}
+		})
+	}
+}
+
+func TestAccountConfig(t *testing.T) {
+	if got := accountConfig(AccountOptions{})["encoding"]; got != "base64" {
+		t.Errorf("Expected base64 by default, got %v", got)
+	}
+	if got := accountConfig(AccountOptions{Encoding: "jsonParsed"})["encoding"]; got != "jsonParsed" {
+		t.Errorf("Expected jsonParsed, got %v", got)
+	}
+}
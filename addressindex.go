@@ -0,0 +1,319 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Address index settings
+const (
+	addressPageSize      = 500
+	defaultActivityLimit = 20
+)
+
+// addressEntry is a transaction involving an address, as kept by the
+// address index and served by /address/activity
+type addressEntry struct {
+	Signature string          `json:"signature"`
+	Slot      uint64          `json:"slot"`
+	BlockTime *int64          `json:"block_time"`
+	Err       json.RawMessage `json:"err"`
+}
+
+// addressHead locates the newest entries of an address. Entries are kept
+// oldest first in pages of addressPageSize; Last counts those in the last
+// page, which may hold more that a failed write left behind.
+type addressHead struct {
+	Pages int    `json:"pages"`
+	Last  int    `json:"last"`
+	Slot  uint64 `json:"slot"`
+}
+
+// total is the number of entries indexed for the address
+func (h addressHead) total() int {
+	if h.Pages == 0 {
+		return 0
+	}
+	return (h.Pages-1)*addressPageSize + h.Last
+}
+
+// addressIndex maps addresses to the transactions involving them, built
+// from the blocks the indexer stores. Vote transactions are left out, as
+// they make up most of every block and nobody pages through them. Only the
+// indexer writes to it, one block at a time.
+type addressIndex struct {
+	store blockStore
+}
+
+func newAddressIndex(store blockStore) *addressIndex {
+	return &addressIndex{store: store}
+}
+
+// blockAddresses groups the non-vote transactions of a json or jsonParsed
+// encoded block by the addresses they involve, keeping block order
+func blockAddresses(slot uint64, raw json.RawMessage) (map[string][]addressEntry, []string, error) {
+	var block struct {
+		BlockTime    *int64 `json:"blockTime"`
+		Transactions []struct {
+			Transaction struct {
+				Signatures []string `json:"signatures"`
+				Message    struct {
+					AccountKeys []json.RawMessage `json:"accountKeys"`
+				} `json:"message"`
+			} `json:"transaction"`
+			Meta *struct {
+				Err             json.RawMessage `json:"err"`
+				LoadedAddresses *struct {
+					Writable []string `json:"writable"`
+					Readonly []string `json:"readonly"`
+				} `json:"loadedAddresses"`
+			} `json:"meta"`
+		} `json:"transactions"`
+	}
+	if err := json.Unmarshal(raw, &block); err != nil {
+		return nil, nil, fmt.Errorf("address index needs json or jsonParsed encoded blocks: %w", err)
+	}
+
+	entries := make(map[string][]addressEntry)
+	var order []string
+	for _, tx := range block.Transactions {
+		if len(tx.Transaction.Signatures) == 0 {
+			continue
+		}
+		// json encoding lists keys as strings, jsonParsed as objects
+		var keys []string
+		for _, raw := range tx.Transaction.Message.AccountKeys {
+			var key string
+			if json.Unmarshal(raw, &key) != nil {
+				var parsed struct {
+					Pubkey string `json:"pubkey"`
+				}
+				if err := json.Unmarshal(raw, &parsed); err != nil {
+					return nil, nil, fmt.Errorf("failed to parse account key: %w", err)
+				}
+				key = parsed.Pubkey
+			}
+			keys = append(keys, key)
+		}
+
+		entry := addressEntry{Signature: tx.Transaction.Signatures[0], Slot: slot, BlockTime: block.BlockTime, Err: json.RawMessage("null")}
+		if tx.Meta != nil {
+			if len(tx.Meta.Err) > 0 && !bytes.Equal(tx.Meta.Err, []byte("null")) {
+				entry.Err = tx.Meta.Err
+			}
+			if loaded := tx.Meta.LoadedAddresses; loaded != nil {
+				keys = append(append(keys, loaded.Writable...), loaded.Readonly...)
+			}
+		}
+
+		vote := false
+		for _, key := range keys {
+			if key == voteProgramID {
+				vote = true
+				break
+			}
+		}
+		if vote {
+			continue
+		}
+		seen := make(map[string]bool, len(keys))
+		for _, key := range keys {
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if _, ok := entries[key]; !ok {
+				order = append(order, key)
+			}
+			entries[key] = append(entries[key], entry)
+		}
+	}
+	return entries, order, nil
+}
+
+// add indexes the transactions of the block at slot. Addresses already
+// indexed at or past slot are skipped, so a block retried after a failed
+// write is not indexed twice.
+func (x *addressIndex) add(ctx context.Context, slot uint64, block json.RawMessage) error {
+	entries, order, err := blockAddresses(slot, block)
+	if err != nil {
+		return err
+	}
+	for _, address := range order {
+		if err := x.append(ctx, address, slot, entries[address]); err != nil {
+			return err
+		}
+	}
+	metrics.addCounter("solana_client_address_index_entries_total", "Address to transaction entries added to the address index.", float64(len(order)))
+	return nil
+}
+
+// append adds entries from slot to the pages of address, writing the pages
+// before the head so that a failure leaves the head at the old entries
+func (x *addressIndex) append(ctx context.Context, address string, slot uint64, entries []addressEntry) error {
+	head, err := x.head(ctx, address)
+	if err != nil {
+		return err
+	}
+	if head.Pages > 0 && head.Slot >= slot {
+		return nil
+	}
+
+	var page []addressEntry
+	if head.Pages > 0 && head.Last < addressPageSize {
+		if page, err = x.page(ctx, address, head.Pages-1, head.Last); err != nil {
+			return err
+		}
+		head.Pages--
+	}
+	for len(entries) > 0 {
+		n := addressPageSize - len(page)
+		if n > len(entries) {
+			n = len(entries)
+		}
+		page = append(page, entries[:n]...)
+		entries = entries[n:]
+
+		data, _ := json.Marshal(page)
+		if err := x.store.put(ctx, storeKindAddressPage, addressPageKey(address, head.Pages), data); err != nil {
+			return err
+		}
+		head.Pages++
+		head.Last = len(page)
+		page = nil
+	}
+
+	head.Slot = slot
+	data, _ := json.Marshal(head)
+	return x.store.put(ctx, storeKindAddressHead, address, data)
+}
+
+func addressPageKey(address string, page int) string {
+	return address + "/" + strconv.Itoa(page)
+}
+
+// head returns the head of address, zero when it has no entries
+func (x *addressIndex) head(ctx context.Context, address string) (addressHead, error) {
+	var head addressHead
+	data, ok, err := x.store.get(ctx, storeKindAddressHead, address)
+	if err != nil || !ok {
+		return head, err
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return head, fmt.Errorf("failed to parse address index head: %w", err)
+	}
+	return head, nil
+}
+
+// page reads the first n entries of a page of address
+func (x *addressIndex) page(ctx context.Context, address string, page, n int) ([]addressEntry, error) {
+	data, ok, err := x.store.get(ctx, storeKindAddressPage, addressPageKey(address, page))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("address index page %d of %s is missing", page, address)
+	}
+	var entries []addressEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse address index page: %w", err)
+	}
+	if len(entries) < n {
+		return nil, fmt.Errorf("address index page %d of %s is short", page, address)
+	}
+	return entries[:n], nil
+}
+
+// activity returns up to limit entries of address, newest first, from
+// before the entry numbered before counting from the oldest. A negative
+// before starts at the newest entry. It also returns the number of the
+// oldest entry returned, which pages on, and the head read.
+func (x *addressIndex) activity(ctx context.Context, address string, before, limit int) ([]addressEntry, int, addressHead, error) {
+	head, err := x.head(ctx, address)
+	if err != nil {
+		return nil, 0, head, err
+	}
+	end := head.total()
+	if before >= 0 && before < end {
+		end = before
+	}
+
+	entries := make([]addressEntry, 0, limit)
+	for end > 0 && len(entries) < limit {
+		page := (end - 1) / addressPageSize
+		stored, err := x.page(ctx, address, page, end-page*addressPageSize)
+		if err != nil {
+			return nil, 0, head, err
+		}
+		for i := len(stored) - 1; i >= 0 && len(entries) < limit; i-- {
+			entries = append(entries, stored[i])
+			end--
+		}
+	}
+	return entries, end, head, nil
+}
+
+// addressActivityResponse is the response of /address/activity. NextCursor
+// is passed as ?cursor= to fetch the following page.
+type addressActivityResponse struct {
+	Address      string         `json:"address"`
+	IndexedSlot  uint64         `json:"indexed_slot"`
+	Transactions []addressEntry `json:"transactions"`
+	NextCursor   string         `json:"next_cursor,omitempty"`
+}
+
+// handleGetAddressActivity pages through the transactions of ?address= in
+// the local address index, newest first, ?limit= at a time. Unlike
+// /transactions it makes no upstream calls, but only covers the slots the
+// indexer has stored, up to indexed_slot for the address.
+func handleGetAddressActivity(index *addressIndex) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			http.Error(w, "address parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := decodePubkey(address); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		limit := defaultActivityLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxSignaturesPerPage {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxSignaturesPerPage), http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		before := -1
+		if value := r.URL.Query().Get("cursor"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				http.Error(w, "invalid cursor", http.StatusBadRequest)
+				return
+			}
+			before = parsed
+		}
+
+		entries, next, head, err := index.activity(r.Context(), address, before, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := addressActivityResponse{Address: address, IndexedSlot: head.Slot, Transactions: entries}
+		if next > 0 && len(entries) == limit {
+			response.NextCursor = strconv.Itoa(next)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBlockAddresses(t *testing.T) {
+	block := json.RawMessage(`{"blockTime":1700000000,"transactions":[
+		{"transaction":{"signatures":["sig1"],"message":{"accountKeys":["payer","program","payer"]}},"meta":{"err":null}},
+		{"transaction":{"signatures":["vote"],"message":{"accountKeys":["validator","Vote111111111111111111111111111111111111111"]}},"meta":{"err":null}},
+		{"transaction":{"signatures":["sig2"],"message":{"accountKeys":[{"pubkey":"payer","signer":true},{"pubkey":"program","signer":false}]}},
+		 "meta":{"err":{"InstructionError":[0,"Custom"]},"loadedAddresses":{"writable":["table"],"readonly":[]}}}
+	]}`)
+
+	entries, order, err := blockAddresses(7, block)
+	if err != nil {
+		t.Fatalf("blockAddresses returned error: %v", err)
+	}
+	if strings.Join(order, ",") != "payer,program,table" {
+		t.Errorf("Expected addresses payer,program,table, got %v", order)
+	}
+
+	tests := []struct {
+		name               string
+		address            string
+		expectedSignatures string
+	}{
+		{name: "Listed Twice Counts Once", address: "payer", expectedSignatures: "sig1,sig2"},
+		{name: "Parsed Keys", address: "program", expectedSignatures: "sig1,sig2"},
+		{name: "Loaded Address", address: "table", expectedSignatures: "sig2"},
+		{name: "Vote Transaction Skipped", address: "validator", expectedSignatures: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var signatures []string
+			for _, entry := range entries[tt.address] {
+				signatures = append(signatures, entry.Signature)
+				if entry.Slot != 7 || entry.BlockTime == nil || *entry.BlockTime != 1700000000 {
+					t.Errorf("unexpected entry %+v", entry)
+				}
+			}
+			if strings.Join(signatures, ",") != tt.expectedSignatures {
+				t.Errorf("Expected signatures %q, got %v", tt.expectedSignatures, signatures)
+			}
+		})
+	}
+	if string(entries["table"][0].Err) != `{"InstructionError":[0,"Custom"]}` {
+		t.Errorf("Expected the transaction error kept, got %s", entries["table"][0].Err)
+	}
+
+	if _, _, err := blockAddresses(7, json.RawMessage(`{"transactions":[["AQID","base64"]]}`)); err == nil {
+		t.Error("Expected error for base64 encoded transactions")
+	}
+}
+
+// indexActivity fills index with count entries for address, one slot each,
+// signed sig0 onwards
+func indexActivity(t *testing.T, index *addressIndex, address string, count int) {
+	for slot := 0; slot < count; slot += 100 {
+		var entries []addressEntry
+		for i := slot; i < slot+100 && i < count; i++ {
+			entries = append(entries, addressEntry{Signature: fmt.Sprintf("sig%d", i), Slot: uint64(slot + 1), Err: json.RawMessage("null")})
+		}
+		if err := index.append(context.Background(), address, uint64(slot+1), entries); err != nil {
+			t.Fatalf("append returned error: %v", err)
+		}
+	}
+}
+
+func TestAddressIndex(t *testing.T) {
+	store, err := newFileBlockStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFileBlockStore returned error: %v", err)
+	}
+	index := newAddressIndex(store)
+	ctx := context.Background()
+	indexActivity(t, index, "payer", 1150)
+
+	// A retried slot is not indexed again
+	if err := index.append(ctx, "payer", 1101, []addressEntry{{Signature: "again"}}); err != nil {
+		t.Fatalf("append returned error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		before        int
+		limit         int
+		expectedFirst string
+		expectedLast  string
+		expectedCount int
+		expectedNext  int
+	}{
+		{name: "Newest", before: -1, limit: 3, expectedFirst: "sig1149", expectedLast: "sig1147", expectedCount: 3, expectedNext: 1147},
+		{name: "Across Pages", before: 1002, limit: 4, expectedFirst: "sig1001", expectedLast: "sig998", expectedCount: 4, expectedNext: 998},
+		{name: "Oldest", before: 2, limit: 5, expectedFirst: "sig1", expectedLast: "sig0", expectedCount: 2, expectedNext: 0},
+		{name: "Cursor Past End", before: 5000, limit: 1, expectedFirst: "sig1149", expectedLast: "sig1149", expectedCount: 1, expectedNext: 1149},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, next, head, err := index.activity(ctx, "payer", tt.before, tt.limit)
+			if err != nil {
+				t.Fatalf("activity returned error: %v", err)
+			}
+			if len(entries) != tt.expectedCount {
+				t.Fatalf("Expected %d entries, got %d", tt.expectedCount, len(entries))
+			}
+			if entries[0].Signature != tt.expectedFirst || entries[len(entries)-1].Signature != tt.expectedLast {
+				t.Errorf("Expected %s to %s, got %s to %s", tt.expectedFirst, tt.expectedLast, entries[0].Signature, entries[len(entries)-1].Signature)
+			}
+			if next != tt.expectedNext {
+				t.Errorf("Expected next %d, got %d", tt.expectedNext, next)
+			}
+			if head.Slot != 1101 || head.total() != 1150 {
+				t.Errorf("Expected 1150 entries up to slot 1101, got %+v", head)
+			}
+		})
+	}
+}
+
+func TestHandleGetAddressActivity(t *testing.T) {
+	const address = "11111111111111111111111111111111"
+	store, err := newFileBlockStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFileBlockStore returned error: %v", err)
+	}
+	index := newAddressIndex(store)
+	indexActivity(t, index, address, 5)
+
+	tests := []struct {
+		name           string
+		queryParam     string
+		expectedStatus int
+		expectedCount  int
+		expectedCursor string
+	}{
+		{name: "Default Limit", queryParam: "?address=" + address, expectedStatus: http.StatusOK, expectedCount: 5},
+		{name: "Paged", queryParam: "?address=" + address + "&limit=2", expectedStatus: http.StatusOK, expectedCount: 2, expectedCursor: "3"},
+		{name: "Next Page", queryParam: "?address=" + address + "&limit=2&cursor=3", expectedStatus: http.StatusOK, expectedCount: 2, expectedCursor: "1"},
+		{name: "Last Page", queryParam: "?address=" + address + "&limit=2&cursor=1", expectedStatus: http.StatusOK, expectedCount: 1},
+		{name: "Unindexed Address", queryParam: "?address=Vote111111111111111111111111111111111111111", expectedStatus: http.StatusOK, expectedCount: 0},
+		{name: "Missing Address", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Address", queryParam: "?address=nope", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Limit", queryParam: "?address=" + address + "&limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Cursor", queryParam: "?address=" + address + "&cursor=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handleGetAddressActivity(index)(rr, httptest.NewRequest("GET", "/address/activity"+tt.queryParam, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+			var response addressActivityResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(response.Transactions) != tt.expectedCount {
+				t.Errorf("Expected %d transactions, got %d", tt.expectedCount, len(response.Transactions))
+			}
+			if response.NextCursor != tt.expectedCursor {
+				t.Errorf("Expected next cursor %q, got %q", tt.expectedCursor, response.NextCursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,438 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// anchorIDL is the subset of an Anchor IDL needed to decode accounts. Both the
+// legacy layout (account types inline, discriminator derived from the name)
+// and the 0.30 layout (explicit discriminators, types listed separately) are
+// supported.
+type anchorIDL struct {
+	Address  string `json:"address"`
+	Metadata struct {
+		Address string `json:"address"`
+	} `json:"metadata"`
+	Accounts []struct {
+		Name          string          `json:"name"`
+		Discriminator []int           `json:"discriminator"`
+		Type          *anchorTypeBody `json:"type"`
+	} `json:"accounts"`
+	Types []struct {
+		Name string         `json:"name"`
+		Type anchorTypeBody `json:"type"`
+	} `json:"types"`
+}
+
+// anchorTypeBody describes a struct or enum. Struct fields are either named
+// ({"name","type"} objects) or positional (bare types).
+type anchorTypeBody struct {
+	Kind     string          `json:"kind"`
+	Fields   json.RawMessage `json:"fields"`
+	Variants []struct {
+		Name   string          `json:"name"`
+		Fields json.RawMessage `json:"fields"`
+	} `json:"variants"`
+}
+
+type anchorField struct {
+	Name string          `json:"name"`
+	Type json.RawMessage `json:"type"`
+}
+
+// anchorAccount is an account type with its resolved discriminator
+type anchorAccount struct {
+	name          string
+	discriminator [8]byte
+	body          anchorTypeBody
+}
+
+// anchorProgram holds the decodable account types of one program
+type anchorProgram struct {
+	accounts []anchorAccount
+	types    map[string]anchorTypeBody
+}
+
+// anchorRegistry maps program ids to their IDLs
+type anchorRegistry struct {
+	programs map[string]*anchorProgram
+}
+
+var errBorshEOF = errors.New("unexpected end of account data")
+
+// loadAnchorIDLs loads every *.json IDL in dir. The program id is taken from
+// the IDL itself, falling back to the file name for IDLs that omit it.
+func loadAnchorIDLs(dir string) (*anchorRegistry, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list IDLs: %w", err)
+	}
+
+	registry := &anchorRegistry{programs: make(map[string]*anchorProgram)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read IDL %s: %w", path, err)
+		}
+		fallback := strings.TrimSuffix(filepath.Base(path), ".json")
+		if err := registry.add(data, fallback); err != nil {
+			return nil, fmt.Errorf("failed to load IDL %s: %w", path, err)
+		}
+	}
+	return registry, nil
+}
+
+// add parses an IDL and registers it under its program id
+func (reg *anchorRegistry) add(data []byte, fallbackAddress string) error {
+	var idl anchorIDL
+	if err := json.Unmarshal(data, &idl); err != nil {
+		return fmt.Errorf("failed to parse IDL: %w", err)
+	}
+
+	address := idl.Address
+	if address == "" {
+		address = idl.Metadata.Address
+	}
+	if address == "" {
+		address = fallbackAddress
+	}
+
+	program := &anchorProgram{types: make(map[string]anchorTypeBody)}
+	for _, def := range idl.Types {
+		program.types[def.Name] = def.Type
+	}
+
+	for _, def := range idl.Accounts {
+		account := anchorAccount{name: def.Name}
+
+		if len(def.Discriminator) > 0 {
+			if len(def.Discriminator) != 8 {
+				return fmt.Errorf("account %s has a %d-byte discriminator", def.Name, len(def.Discriminator))
+			}
+			for i, b := range def.Discriminator {
+				account.discriminator[i] = byte(b)
+			}
+		} else {
+			sum := sha256.Sum256([]byte("account:" + def.Name))
+			copy(account.discriminator[:], sum[:8])
+		}
+
+		if def.Type != nil {
+			account.body = *def.Type
+		} else if body, ok := program.types[def.Name]; ok {
+			account.body = body
+		} else {
+			return fmt.Errorf("account %s has no type definition", def.Name)
+		}
+
+		program.accounts = append(program.accounts, account)
+	}
+
+	reg.programs[address] = program
+	return nil
+}
+
+// decodeAccount decodes account data using the IDL of the owning program,
+// returning the matched account type name and its fields
+func (reg *anchorRegistry) decodeAccount(account *AccountInfo) (string, interface{}, error) {
+	if reg == nil || len(reg.programs) == 0 {
+		return "", nil, fmt.Errorf("no Anchor IDLs configured")
+	}
+
+	program, ok := reg.programs[account.Owner]
+	if !ok {
+		return "", nil, fmt.Errorf("no Anchor IDL configured for program %s", account.Owner)
+	}
+
+	data, err := account.rawData()
+	if err != nil {
+		return "", nil, err
+	}
+	if len(data) < 8 {
+		return "", nil, fmt.Errorf("account data too short for an Anchor discriminator")
+	}
+
+	for _, def := range program.accounts {
+		if string(def.discriminator[:]) != string(data[:8]) {
+			continue
+		}
+
+		r := &borshReader{data: data[8:]}
+		fields, err := program.decodeBody(r, def.body)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decode %s: %w", def.name, err)
+		}
+		return def.name, fields, nil
+	}
+
+	return "", nil, fmt.Errorf("account discriminator %s does not match any account type in the IDL for program %s",
+		hex.EncodeToString(data[:8]), account.Owner)
+}
+
+// borshReader reads Borsh-encoded values with bounds checking
+type borshReader struct {
+	data []byte
+	off  int
+}
+
+func (r *borshReader) read(n int) ([]byte, error) {
+	if n < 0 || len(r.data)-r.off < n {
+		return nil, errBorshEOF
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *borshReader) remaining() int {
+	return len(r.data) - r.off
+}
+
+func (p *anchorProgram) decodeBody(r *borshReader, body anchorTypeBody) (interface{}, error) {
+	switch body.Kind {
+	case "struct":
+		return p.decodeFields(r, body.Fields)
+	case "enum":
+		tag, err := r.read(1)
+		if err != nil {
+			return nil, err
+		}
+		if int(tag[0]) >= len(body.Variants) {
+			return nil, fmt.Errorf("enum variant %d out of range", tag[0])
+		}
+		variant := body.Variants[tag[0]]
+		if len(variant.Fields) == 0 || string(variant.Fields) == "null" {
+			return variant.Name, nil
+		}
+		fields, err := p.decodeFields(r, variant.Fields)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{variant.Name: fields}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type kind %q", body.Kind)
+	}
+}
+
+// decodeFields decodes named fields into a map and positional fields into a slice
+func (p *anchorProgram) decodeFields(r *borshReader, raw json.RawMessage) (interface{}, error) {
+	var items []json.RawMessage
+	if len(raw) > 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, fmt.Errorf("invalid fields definition: %w", err)
+		}
+	}
+
+	named := make(map[string]interface{}, len(items))
+	var positional []interface{}
+	for _, item := range items {
+		var field anchorField
+		if json.Unmarshal(item, &field) == nil && field.Name != "" && len(field.Type) > 0 {
+			value, err := p.decodeType(r, field.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", field.Name, err)
+			}
+			named[field.Name] = value
+			continue
+		}
+
+		value, err := p.decodeType(r, item)
+		if err != nil {
+			return nil, err
+		}
+		positional = append(positional, value)
+	}
+
+	if positional != nil {
+		return positional, nil
+	}
+	return named, nil
+}
+
+func (p *anchorProgram) decodeType(r *borshReader, typ json.RawMessage) (interface{}, error) {
+	var primitive string
+	if err := json.Unmarshal(typ, &primitive); err == nil {
+		return decodePrimitive(r, primitive)
+	}
+
+	var compound map[string]json.RawMessage
+	if err := json.Unmarshal(typ, &compound); err != nil || len(compound) != 1 {
+		return nil, fmt.Errorf("invalid type %s", typ)
+	}
+
+	for kind, inner := range compound {
+		switch kind {
+		case "vec":
+			b, err := r.read(4)
+			if err != nil {
+				return nil, err
+			}
+			n := int(binary.LittleEndian.Uint32(b))
+			if n > r.remaining() {
+				return nil, errBorshEOF
+			}
+			return p.decodeSequence(r, inner, n)
+		case "array":
+			var spec []json.RawMessage
+			var n int
+			if err := json.Unmarshal(inner, &spec); err != nil || len(spec) != 2 || json.Unmarshal(spec[1], &n) != nil || n < 0 {
+				return nil, fmt.Errorf("invalid array type %s", inner)
+			}
+			return p.decodeSequence(r, spec[0], n)
+		case "option", "coption":
+			tagSize := 1
+			if kind == "coption" {
+				tagSize = 4
+			}
+			tag, err := r.read(tagSize)
+			if err != nil {
+				return nil, err
+			}
+			if tag[0] == 0 {
+				return nil, nil
+			}
+			return p.decodeType(r, inner)
+		case "defined":
+			name := strings.Trim(string(inner), `"`)
+			var ref struct {
+				Name string `json:"name"`
+			}
+			if json.Unmarshal(inner, &ref) == nil && ref.Name != "" {
+				name = ref.Name
+			}
+			body, ok := p.types[name]
+			if !ok {
+				return nil, fmt.Errorf("undefined type %s", name)
+			}
+			return p.decodeBody(r, body)
+		}
+		return nil, fmt.Errorf("unsupported type %s", kind)
+	}
+	return nil, nil
+}
+
+func (p *anchorProgram) decodeSequence(r *borshReader, elem json.RawMessage, n int) (interface{}, error) {
+	// Byte arrays are far more readable as a single hex string
+	if string(elem) == `"u8"` {
+		b, err := r.read(n)
+		if err != nil {
+			return nil, err
+		}
+		return hex.EncodeToString(b), nil
+	}
+
+	// The length comes from the account data or the IDL, so it is not
+	// trusted to size the allocation up front
+	capacity := n
+	if capacity > r.remaining() {
+		capacity = r.remaining()
+	}
+	values := make([]interface{}, 0, capacity)
+	for i := 0; i < n; i++ {
+		value, err := p.decodeType(r, elem)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// nonFinite returns the string form ("NaN", "+Inf" or "-Inf") of a float
+// that has no JSON number representation
+func nonFinite(f float64) (string, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	}
+	return "", false
+}
+
+// decodePrimitive decodes a Borsh primitive. 128-bit integers are returned as
+// decimal strings since they do not fit a JSON number, as are NaN and
+// infinite floats.
+func decodePrimitive(r *borshReader, name string) (interface{}, error) {
+	sizes := map[string]int{
+		"bool": 1, "u8": 1, "i8": 1, "u16": 2, "i16": 2, "u32": 4, "i32": 4, "f32": 4,
+		"u64": 8, "i64": 8, "f64": 8, "u128": 16, "i128": 16, "publicKey": 32, "pubkey": 32,
+	}
+
+	if name == "string" || name == "bytes" {
+		b, err := r.read(4)
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.read(int(binary.LittleEndian.Uint32(b)))
+		if err != nil {
+			return nil, err
+		}
+		if name == "bytes" {
+			return hex.EncodeToString(value), nil
+		}
+		return string(value), nil
+	}
+
+	size, ok := sizes[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported primitive type %s", name)
+	}
+	b, err := r.read(size)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "bool":
+		return b[0] != 0, nil
+	case "u8":
+		return b[0], nil
+	case "i8":
+		return int8(b[0]), nil
+	case "u16":
+		return binary.LittleEndian.Uint16(b), nil
+	case "i16":
+		return int16(binary.LittleEndian.Uint16(b)), nil
+	case "u32":
+		return binary.LittleEndian.Uint32(b), nil
+	case "i32":
+		return int32(binary.LittleEndian.Uint32(b)), nil
+	case "f32":
+		f := math.Float32frombits(binary.LittleEndian.Uint32(b))
+		if text, ok := nonFinite(float64(f)); ok {
+			return text, nil
+		}
+		return f, nil
+	case "u64":
+		return binary.LittleEndian.Uint64(b), nil
+	case "i64":
+		return int64(binary.LittleEndian.Uint64(b)), nil
+	case "f64":
+		f := math.Float64frombits(binary.LittleEndian.Uint64(b))
+		if text, ok := nonFinite(f); ok {
+			return text, nil
+		}
+		return f, nil
+	case "u128", "i128":
+		be := make([]byte, 16)
+		for i := range b {
+			be[15-i] = b[i]
+		}
+		n := new(big.Int).SetBytes(be)
+		if name == "i128" && be[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), 128))
+		}
+		return n.String(), nil
+	default:
+		return base58Encode(b), nil
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testProgramID = "Counter111111111111111111111111111111111111"
+
+const legacyCounterIDL = `{
+	"version": "0.1.0",
+	"name": "counter",
+	"accounts": [{
+		"name": "Counter",
+		"type": {"kind": "struct", "fields": [
+			{"name": "authority", "type": "publicKey"},
+			{"name": "count", "type": "u64"},
+			{"name": "label", "type": "string"},
+			{"name": "tags", "type": {"vec": "u16"}},
+			{"name": "delegate", "type": {"option": "publicKey"}},
+			{"name": "state", "type": {"defined": "State"}}
+		]}
+	}],
+	"types": [{
+		"name": "State",
+		"type": {"kind": "enum", "variants": [{"name": "Idle"}, {"name": "Running", "fields": [{"name": "since", "type": "i64"}]}]}
+	}]
+}`
+
+// encodeCounter builds Borsh data for the Counter account in legacyCounterIDL
+func encodeCounter(discriminator []byte) []byte {
+	data := append([]byte{}, discriminator...)
+	data = append(data, make([]byte, 32)...)
+	data = binary.LittleEndian.AppendUint64(data, 7)
+	data = binary.LittleEndian.AppendUint32(data, 5)
+	data = append(data, "hello"...)
+	data = binary.LittleEndian.AppendUint32(data, 2)
+	data = binary.LittleEndian.AppendUint16(data, 10)
+	data = binary.LittleEndian.AppendUint16(data, 20)
+	data = append(data, 0)
+	data = append(data, 1)
+	data = binary.LittleEndian.AppendUint64(data, 1700000000)
+	return data
+}
+
+func testAccount(owner string, data []byte) *AccountInfo {
+	encoded, _ := json.Marshal([]string{base64.StdEncoding.EncodeToString(data), "base64"})
+	return &AccountInfo{Lamports: 1000, Owner: owner, Data: encoded}
+}
+
+func TestAnchorDecodeLegacyIDL(t *testing.T) {
+	registry := &anchorRegistry{programs: make(map[string]*anchorProgram)}
+	if err := registry.add([]byte(legacyCounterIDL), testProgramID); err != nil {
+		t.Fatalf("add returned error: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte("account:Counter"))
+	typeName, fields, err := registry.decodeAccount(testAccount(testProgramID, encodeCounter(sum[:8])))
+	if err != nil {
+		t.Fatalf("decodeAccount returned error: %v", err)
+	}
+
+	if typeName != "Counter" {
+		t.Errorf("Expected type Counter, got %s", typeName)
+	}
+
+	got, _ := json.Marshal(fields)
+	want := `{"authority":"11111111111111111111111111111111","count":7,"delegate":null,"label":"hello","state":{"Running":{"since":1700000000}},"tags":[10,20]}`
+	if string(got) != want {
+		t.Errorf("unexpected decoded fields:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestAnchorDecodeExplicitDiscriminator(t *testing.T) {
+	idl := `{
+		"address": "` + testProgramID + `",
+		"accounts": [{"name": "Vault", "discriminator": [1, 2, 3, 4, 5, 6, 7, 8]}],
+		"types": [{"name": "Vault", "type": {"kind": "struct", "fields": [
+			{"name": "balance", "type": "u128"},
+			{"name": "seed", "type": {"array": ["u8", 4]}}
+		]}}]
+	}`
+
+	registry := &anchorRegistry{programs: make(map[string]*anchorProgram)}
+	if err := registry.add([]byte(idl), ""); err != nil {
+		t.Fatalf("add returned error: %v", err)
+	}
+
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	data = binary.LittleEndian.AppendUint64(data, 0)
+	data = binary.LittleEndian.AppendUint64(data, 1) // 2^64
+	data = append(data, 0xde, 0xad, 0xbe, 0xef)
+
+	_, fields, err := registry.decodeAccount(testAccount(testProgramID, data))
+	if err != nil {
+		t.Fatalf("decodeAccount returned error: %v", err)
+	}
+
+	got, _ := json.Marshal(fields)
+	if want := `{"balance":"18446744073709551616","seed":"deadbeef"}`; string(got) != want {
+		t.Errorf("unexpected decoded fields: got %s want %s", got, want)
+	}
+}
+
+func TestAnchorDecodeErrors(t *testing.T) {
+	registry := &anchorRegistry{programs: make(map[string]*anchorProgram)}
+	registry.add([]byte(legacyCounterIDL), testProgramID)
+	sum := sha256.Sum256([]byte("account:Counter"))
+
+	tests := []struct {
+		name    string
+		account *AccountInfo
+		wantErr string
+	}{
+		{name: "Unknown Program", account: testAccount("Other1111", encodeCounter(sum[:8])), wantErr: "no Anchor IDL configured"},
+		{name: "Discriminator Mismatch", account: testAccount(testProgramID, encodeCounter(make([]byte, 8))), wantErr: "does not match any account type"},
+		{name: "Truncated Data", account: testAccount(testProgramID, encodeCounter(sum[:8])[:20]), wantErr: "unexpected end of account data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := registry.decodeAccount(tt.account)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestHandleGetAccountAnchor(t *testing.T) {
+	registry := &anchorRegistry{programs: make(map[string]*anchorProgram)}
+	registry.add([]byte(legacyCounterIDL), testProgramID)
+	sum := sha256.Sum256([]byte("account:Counter"))
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+	}{
+		{name: "Raw Account", mockClient: mockRPCClient{accountInfo: testAccount(testProgramID, encodeCounter(sum[:8]))}, queryParam: "?address=abc", expectedStatus: http.StatusOK},
+		{name: "Decoded Account", mockClient: mockRPCClient{accountInfo: testAccount(testProgramID, encodeCounter(sum[:8]))}, queryParam: "?address=abc&decode=anchor", expectedStatus: http.StatusOK},
+		{name: "Discriminator Mismatch", mockClient: mockRPCClient{accountInfo: testAccount(testProgramID, make([]byte, 16))}, queryParam: "?address=abc&decode=anchor", expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Missing Address", mockClient: mockRPCClient{}, queryParam: "", expectedStatus: http.StatusBadRequest},
+		{name: "Account Not Found", mockClient: mockRPCClient{}, queryParam: "?address=abc", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/account"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetAccount(&tt.mockClient, registry).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAnchorDecodeUntrustedData(t *testing.T) {
+	tests := []struct {
+		name    string
+		field   string
+		data    []byte
+		want    string
+		wantErr string
+	}{
+		{name: "NaN", field: `"f64"`, data: binary.LittleEndian.AppendUint64(nil, 0x7ff8000000000001), want: `{"value":"NaN"}`},
+		{name: "Infinity", field: `"f32"`, data: binary.LittleEndian.AppendUint32(nil, 0xff800000), want: `{"value":"-Inf"}`},
+		{name: "Finite Float", field: `"f32"`, data: binary.LittleEndian.AppendUint32(nil, 0x3fc00000), want: `{"value":1.5}`},
+		{name: "Oversized Vec", field: `{"vec": "u64"}`, data: binary.LittleEndian.AppendUint32(nil, 0xffffffff), wantErr: "unexpected end of account data"},
+		{name: "Oversized Array", field: `{"array": ["u64", 1000000000000]}`, data: make([]byte, 8), wantErr: "unexpected end of account data"},
+		{name: "Negative Array", field: `{"array": ["u64", -1]}`, data: make([]byte, 8), wantErr: "invalid array type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idl := `{
+				"address": "` + testProgramID + `",
+				"accounts": [{"name": "Sample", "discriminator": [1, 2, 3, 4, 5, 6, 7, 8]}],
+				"types": [{"name": "Sample", "type": {"kind": "struct", "fields": [{"name": "value", "type": ` + tt.field + `}]}}]
+			}`
+			registry := &anchorRegistry{programs: make(map[string]*anchorProgram)}
+			if err := registry.add([]byte(idl), ""); err != nil {
+				t.Fatalf("add returned error: %v", err)
+			}
+
+			data := append([]byte{1, 2, 3, 4, 5, 6, 7, 8}, tt.data...)
+			_, fields, err := registry.decodeAccount(testAccount(testProgramID, data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeAccount returned error: %v", err)
+			}
+
+			got, err := json.Marshal(fields)
+			if err != nil {
+				t.Fatalf("decoded fields do not encode: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("unexpected decoded fields: got %s want %s", got, tt.want)
+			}
+		})
+	}
+}
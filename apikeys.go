@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// API key settings
+const (
+	apiKeyHeader       = "X-API-Key"
+	defaultAPIKeyBurst = 20
+
+	// envAPIAdminToken is read for -api-admin-token, keeping the token out of
+	// the process list
+	envAPIAdminToken = "SOLANA_CLIENT_API_ADMIN_TOKEN"
+)
+
+// errUnknownAPIKey is returned for keys that were never issued or have been
+// revoked
+var errUnknownAPIKey = errors.New("unknown or revoked API key")
+
+// apiKey is an issued key. Only the SHA-256 of its secret is kept, so the
+// keys file can be read without exposing the keys. Rate and DailyQuota
+// limit the key's requests; zero leaves them unlimited.
+type apiKey struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	SecretSHA256 string     `json:"secret_sha256"`
+	Rate         float64    `json:"rate,omitempty"`
+	Burst        int        `json:"burst,omitempty"`
+	DailyQuota   uint64     `json:"daily_quota,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// apiKeyUsage counts the requests admitted for a key. Counts are kept in
+// memory, so a restart resets the day's quota.
+type apiKeyUsage struct {
+	Day   string `json:"day"`
+	Today uint64 `json:"today"`
+	Total uint64 `json:"total"`
+}
+
+// apiKeyRing holds the keys of a keys file, writing the file back when keys
+// are issued or revoked. Keys are presented as "<id>.<secret>".
+type apiKeyRing struct {
+	mu       sync.Mutex
+	path     string
+	now      func() time.Time
+	keys     map[string]*apiKey
+	limiters map[string]*keyedLimiter
+	usage    map[string]*apiKeyUsage
+}
+
+// loadAPIKeys reads a JSON array of keys from path. A missing file is an
+// empty ring, created once the first key is issued.
+func loadAPIKeys(path string) (*apiKeyRing, error) {
+	ring := &apiKeyRing{path: path, now: time.Now, keys: make(map[string]*apiKey), limiters: make(map[string]*keyedLimiter), usage: make(map[string]*apiKeyUsage)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ring, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	var keys []*apiKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys: %w", err)
+	}
+	for i, key := range keys {
+		if key.ID == "" || strings.Contains(key.ID, ".") || len(key.SecretSHA256) != 2*sha256.Size {
+			return nil, fmt.Errorf("API key %d needs an id without dots and a hex secret_sha256", i)
+		}
+		if _, ok := ring.keys[key.ID]; ok {
+			return nil, fmt.Errorf("API key id %s is used twice", key.ID)
+		}
+		ring.add(key)
+	}
+	return ring, nil
+}
+
+// add registers a key with its rate limiter. The caller holds mu or owns
+// the ring.
+func (k *apiKeyRing) add(key *apiKey) {
+	k.keys[key.ID] = key
+	burst := key.Burst
+	if burst == 0 {
+		burst = defaultAPIKeyBurst
+	}
+	if limiter := newKeyedLimiter(key.Rate, burst); limiter != nil {
+		limiter.now = k.now
+		k.limiters[key.ID] = limiter
+	}
+	k.usage[key.ID] = &apiKeyUsage{}
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticate returns the live key a token belongs to
+func (k *apiKeyRing) authenticate(token string) (*apiKey, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errUnknownAPIKey
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[id]
+	if !ok || key.RevokedAt != nil || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretSHA256)) != 1 {
+		return nil, errUnknownAPIKey
+	}
+	return key, nil
+}
+
+// admit counts a request against the key's rate and daily quota. Refused
+// requests return the wait until the key may retry and why it was refused.
+// remaining is what is left of the daily quota, -1 without one.
+func (k *apiKeyRing) admit(key *apiKey) (ok bool, wait time.Duration, reason string, remaining int64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now().UTC()
+	usage := k.usage[key.ID]
+	if day := now.Format("2006-01-02"); usage.Day != day {
+		usage.Day, usage.Today = day, 0
+	}
+	remaining = -1
+	if key.DailyQuota > 0 {
+		if usage.Today >= key.DailyQuota {
+			midnight := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+			return false, midnight.Sub(now), "API key daily quota exceeded", 0
+		}
+		remaining = int64(key.DailyQuota - usage.Today - 1)
+	}
+	if limiter := k.limiters[key.ID]; limiter != nil {
+		if allowed, wait := limiter.allow(globalKey); !allowed {
+			return false, wait, "API key rate limit exceeded", remaining
+		}
+	}
+	usage.Today++
+	usage.Total++
+	return true, 0, "", remaining
+}
+
+// issue creates a key, returning it along with the token to present, which
+// is not kept and cannot be shown again
+func (k *apiKeyRing) issue(name string, rate float64, burst int, quota uint64) (*apiKey, string, error) {
+	id, secret := make([]byte, 8), make([]byte, 24)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	key := &apiKey{ID: hex.EncodeToString(id), Name: name, SecretSHA256: hashSecret(base58Encode(secret)), Rate: rate, Burst: burst, DailyQuota: quota}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key.CreatedAt = k.now().UTC()
+	k.add(key)
+	if err := k.save(); err != nil {
+		delete(k.keys, key.ID)
+		return nil, "", err
+	}
+	return key, key.ID + "." + base58Encode(secret), nil
+}
+
+// revoke disables a key. Revoked keys stay in the file as a record.
+func (k *apiKeyRing) revoke(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	key, ok := k.keys[id]
+	if !ok {
+		return errUnknownAPIKey
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	now := k.now().UTC()
+	key.RevokedAt = &now
+	if err := k.save(); err != nil {
+		key.RevokedAt = nil
+		return err
+	}
+	return nil
+}
+
+// save writes the keys file through a temporary file, so that a failed
+// write leaves the previous keys in place. The caller holds mu.
+func (k *apiKeyRing) save() error {
+	keys := make([]*apiKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	data, _ := json.MarshalIndent(keys, "", "  ")
+
+	tmp, err := os.CreateTemp(filepath.Dir(k.path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to save API keys: %w", err)
+	}
+	_, err = tmp.Write(append(data, '\n'))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), k.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to save API keys: %w", err)
+	}
+	return nil
+}
+
+// apiKeyStatus is a key as listed by the admin API, without its hash
+type apiKeyStatus struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Rate       float64     `json:"rate,omitempty"`
+	Burst      int         `json:"burst,omitempty"`
+	DailyQuota uint64      `json:"daily_quota,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty"`
+	Usage      apiKeyUsage `json:"usage"`
+}
+
+func (k *apiKeyRing) status(key *apiKey) apiKeyStatus {
+	return apiKeyStatus{ID: key.ID, Name: key.Name, Rate: key.Rate, Burst: key.Burst, DailyQuota: key.DailyQuota, CreatedAt: key.CreatedAt, RevokedAt: key.RevokedAt, Usage: *k.usage[key.ID]}
+}
+
+// list returns the keys, oldest first
+func (k *apiKeyRing) list() []apiKeyStatus {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	keys := make([]apiKeyStatus, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, k.status(key))
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+type apiKeyContextKey struct{}
+
+// apiKeyFromContext returns the id of the key a request was made with
+func apiKeyFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(apiKeyContextKey{}).(string)
+	return id
+}
+
+// requestAPIKey returns the key of a request, from the X-API-Key header or
+// a bearer token
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return key
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	return ""
+}
+
+// withAPIKeys requires a live key on every request outside the exempt
+// paths, answering 401 without one and 429 once the key is over its rate
+// or daily quota. Requests are counted by key.
+func withAPIKeys(next http.Handler, keys *apiKeyRing, exempt ...string) http.Handler {
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := requestAPIKey(r)
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "API key required", http.StatusUnauthorized)
+			return
+		}
+		key, err := keys.authenticate(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		ok, wait, reason, remaining := keys.admit(key)
+		if remaining >= 0 {
+			w.Header().Set("X-Quota-Remaining", strconv.FormatInt(remaining, 10))
+		}
+		metrics.addCounter("solana_client_api_key_requests_total", "HTTP requests by API key and whether they were admitted.", 1,
+			"key", key.ID, "admitted", strconv.FormatBool(ok))
+		if !ok {
+			retryAfter(w, wait, reason)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), apiKeyContextKey{}, key.ID)))
+	})
+}
+
+// issueAPIKeyRequest is the body of a POST to /admin/keys
+type issueAPIKeyRequest struct {
+	Name       string  `json:"name"`
+	Rate       float64 `json:"rate"`
+	Burst      int     `json:"burst"`
+	DailyQuota uint64  `json:"daily_quota"`
+}
+
+// issueAPIKeyResponse carries the only copy of a new key's token
+type issueAPIKeyResponse struct {
+	Key   apiKeyStatus `json:"key"`
+	Token string       `json:"token"`
+}
+
+// handleAPIKeys is the admin API of the keys: GET lists them with their
+// usage, POST issues one and DELETE revokes the key ?id=. Callers present
+// the admin token as a bearer token.
+func handleAPIKeys(keys *apiKeyRing, adminToken string) http.HandlerFunc {
+	authorized := func(r *http.Request) bool {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		return ok && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+	}
+	writeJSON := func(w http.ResponseWriter, status int, value interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		jsonData, _ := json.Marshal(value)
+		w.Write(jsonData)
+	}
+
+	handler := byMethod(map[string]http.HandlerFunc{
+		http.MethodGet: func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, keys.list())
+		},
+		http.MethodPost: func(w http.ResponseWriter, r *http.Request) {
+			var req issueAPIKeyRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4<<10)).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Name == "" || req.Rate < 0 || req.Burst < 0 {
+				http.Error(w, "name is required and rate and burst must not be negative", http.StatusBadRequest)
+				return
+			}
+			key, token, err := keys.issue(req.Name, req.Rate, req.Burst, req.DailyQuota)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			logFields("API key issued", "key", key.ID, "name", key.Name)
+			keys.mu.Lock()
+			status := keys.status(key)
+			keys.mu.Unlock()
+			writeJSON(w, http.StatusCreated, issueAPIKeyResponse{Key: status, Token: token})
+		},
+		http.MethodDelete: func(w http.ResponseWriter, r *http.Request) {
+			id := r.URL.Query().Get("id")
+			if id == "" {
+				http.Error(w, "id parameter is required", http.StatusBadRequest)
+				return
+			}
+			err := keys.revoke(id)
+			if errors.Is(err, errUnknownAPIKey) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			logFields("API key revoked", "key", id)
+			w.WriteHeader(http.StatusNoContent)
+		},
+	})
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "admin token required", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
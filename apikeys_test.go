@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadAPIKeys(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		expectedErr string
+		expected    int
+	}{
+		{name: "Missing File", expected: 0},
+		{name: "Keys", content: `[{"id":"a","name":"team a","secret_sha256":"` + hashSecret("s") + `","rate":5}]`, expected: 1},
+		{name: "Invalid JSON", content: `{`, expectedErr: "failed to parse API keys"},
+		{name: "Missing Hash", content: `[{"id":"a","name":"team a"}]`, expectedErr: "secret_sha256"},
+		{name: "Dotted Id", content: `[{"id":"a.b","secret_sha256":"` + hashSecret("s") + `"}]`, expectedErr: "without dots"},
+		{name: "Duplicate Id", content: `[{"id":"a","secret_sha256":"` + hashSecret("s") + `"},{"id":"a","secret_sha256":"` + hashSecret("t") + `"}]`, expectedErr: "used twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "keys.json")
+			if tt.content != "" {
+				if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ring, err := loadAPIKeys(path)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(ring.list()) != tt.expected {
+				t.Errorf("Expected %d keys, got %d", tt.expected, len(ring.list()))
+			}
+		})
+	}
+}
+
+func TestWithAPIKeys(t *testing.T) {
+	now := time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)
+	ring, _ := loadAPIKeys(filepath.Join(t.TempDir(), "keys.json"))
+	ring.now = func() time.Time { return now }
+	ring.add(&apiKey{ID: "open", SecretSHA256: hashSecret("s1")})
+	ring.add(&apiKey{ID: "quota", SecretSHA256: hashSecret("s2"), DailyQuota: 1})
+	ring.add(&apiKey{ID: "rate", SecretSHA256: hashSecret("s3"), Rate: 1, Burst: 1})
+	revokedAt := now
+	ring.add(&apiKey{ID: "revoked", SecretSHA256: hashSecret("s4"), RevokedAt: &revokedAt})
+
+	var seen string
+	handler := withAPIKeys(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = apiKeyFromContext(r.Context())
+	}), ring, "/healthz")
+
+	tests := []struct {
+		name           string
+		path           string
+		header         string
+		value          string
+		expectedStatus int
+		expectedKey    string
+		expectedQuota  string
+	}{
+		{name: "Exempt Path", path: "/healthz", expectedStatus: http.StatusOK},
+		{name: "Missing Key", expectedStatus: http.StatusUnauthorized},
+		{name: "Header Key", header: apiKeyHeader, value: "open.s1", expectedStatus: http.StatusOK, expectedKey: "open"},
+		{name: "Bearer Key", header: "Authorization", value: "Bearer open.s1", expectedStatus: http.StatusOK, expectedKey: "open"},
+		{name: "Wrong Secret", header: apiKeyHeader, value: "open.s2", expectedStatus: http.StatusUnauthorized},
+		{name: "Malformed", header: apiKeyHeader, value: "open", expectedStatus: http.StatusUnauthorized},
+		{name: "Revoked", header: apiKeyHeader, value: "revoked.s4", expectedStatus: http.StatusUnauthorized},
+		{name: "Within Quota", header: apiKeyHeader, value: "quota.s2", expectedStatus: http.StatusOK, expectedKey: "quota", expectedQuota: "0"},
+		{name: "Over Quota", header: apiKeyHeader, value: "quota.s2", expectedStatus: http.StatusTooManyRequests, expectedQuota: "0"},
+		{name: "Within Rate", header: apiKeyHeader, value: "rate.s3", expectedStatus: http.StatusOK, expectedKey: "rate"},
+		{name: "Over Rate", header: apiKeyHeader, value: "rate.s3", expectedStatus: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = ""
+			path := tt.path
+			if path == "" {
+				path = "/v1/latest-block"
+			}
+			req := httptest.NewRequest("GET", path, nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if seen != tt.expectedKey {
+				t.Errorf("Expected key %q in the context, got %q", tt.expectedKey, seen)
+			}
+			if quota := rr.Header().Get("X-Quota-Remaining"); quota != tt.expectedQuota {
+				t.Errorf("Expected quota remaining %q, got %q", tt.expectedQuota, quota)
+			}
+		})
+	}
+
+	// The quota resets at midnight UTC, which the refusal points to
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/v1/latest-block", nil)
+	req.Header.Set(apiKeyHeader, "quota.s2")
+	handler.ServeHTTP(rr, req)
+	if rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("Expected a retry after 60 seconds, got %q", rr.Header().Get("Retry-After"))
+	}
+	now = now.Add(time.Minute)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected the quota to reset the next day, got %v", rr.Code)
+	}
+}
+
+func TestHandleAPIKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	ring, _ := loadAPIKeys(path)
+	handler := handleAPIKeys(ring, "admin")
+	do := func(method, target, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+
+	if rr := do("GET", "/admin/keys", "", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without the admin token, got %v", rr.Code)
+	}
+	if rr := do("GET", "/admin/keys", "wrong", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong admin token, got %v", rr.Code)
+	}
+	if rr := do("POST", "/admin/keys", "admin", `{"rate":1}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a name, got %v", rr.Code)
+	}
+
+	rr := do("POST", "/admin/keys", "admin", `{"name":"team a","rate":2,"daily_quota":100}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %v (%s)", rr.Code, rr.Body.String())
+	}
+	var issued issueAPIKeyResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &issued); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if key, err := ring.authenticate(issued.Token); err != nil || key.Name != "team a" || key.DailyQuota != 100 {
+		t.Fatalf("Expected the issued token to authenticate, got %+v, %v", key, err)
+	}
+
+	// The keys file keeps the hash but not the token, and reloads
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), strings.SplitN(issued.Token, ".", 2)[1]) {
+		t.Error("Expected the keys file not to contain the secret")
+	}
+	reloaded, err := loadAPIKeys(path)
+	if err != nil {
+		t.Fatalf("Failed to reload keys: %v", err)
+	}
+	if _, err := reloaded.authenticate(issued.Token); err != nil {
+		t.Errorf("Expected the reloaded key to authenticate, got %v", err)
+	}
+
+	rr = do("GET", "/admin/keys", "admin", "")
+	var listed []apiKeyStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil || len(listed) != 1 || listed[0].ID != issued.Key.ID {
+		t.Fatalf("Expected the issued key to be listed, got %s", rr.Body.String())
+	}
+	if strings.Contains(rr.Body.String(), "secret_sha256") {
+		t.Error("Expected the listing not to contain key hashes")
+	}
+
+	if rr := do("DELETE", "/admin/keys?id=unknown", "admin", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking an unknown key, got %v", rr.Code)
+	}
+	if rr := do("DELETE", "/admin/keys?id="+issued.Key.ID, "admin", ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 revoking the key, got %v", rr.Code)
+	}
+	if _, err := ring.authenticate(issued.Token); err == nil {
+		t.Error("Expected the revoked key to be refused")
+	}
+	if reloaded, _ := loadAPIKeys(path); reloaded.list()[0].RevokedAt == nil {
+		t.Error("Expected the revocation to be saved")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// balanceResponse is the response of /balance
+type balanceResponse struct {
+	Address  string  `json:"address"`
+	Lamports uint64  `json:"lamports"`
+	SOL      float64 `json:"sol"`
+	Slot     uint64  `json:"slot"`
+}
+
+// getBalance gets the lamports held by an account, along with the slot it
+// was read at. Accounts that do not exist hold nothing.
+func (c *rpcClient) getBalance(ctx context.Context, address string) (uint64, uint64, error) {
+	response, err := c.sendRequest(ctx, "getBalance", []interface{}{address})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var result struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value uint64 `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse balance: %w", err)
+	}
+
+	return result.Value, result.Context.Slot, nil
+}
+
+func handleGetBalance(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			http.Error(w, "address parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := decodePubkey(address); err != nil {
+			http.Error(w, "address must be a base58 public key", http.StatusBadRequest)
+			return
+		}
+
+		lamports, slot, err := client.getBalance(r.Context(), address)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := balanceResponse{
+			Address:  address,
+			Lamports: lamports,
+			SOL:      float64(lamports) / lamportsPerSOL,
+			Slot:     slot,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetBalance(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		mock           mockRPCClient
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Funded",
+			query:          "?address=" + stakeProgramID,
+			mock:           mockRPCClient{accountInfo: &AccountInfo{Lamports: 1_500_000_000}, latestSlot: 42},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"address":"` + stakeProgramID + `","lamports":1500000000,"sol":1.5,"slot":42}`,
+		},
+		{
+			name:           "Missing Account",
+			query:          "?address=" + stakeProgramID,
+			mock:           mockRPCClient{latestSlot: 42},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"address":"` + stakeProgramID + `","lamports":0,"sol":0,"slot":42}`,
+		},
+		{name: "Missing Address", query: "", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Address", query: "?address=0OIl", expectedStatus: http.StatusBadRequest},
+		{name: "Short Address", query: "?address=abc", expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Error", query: "?address=" + stakeProgramID, mock: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/balance"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetBalance(&tt.mock).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
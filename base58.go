@@ -0,0 +1,70 @@
+package main
+
+import (
+	"errors"
+	"math/big"
+)
+
+// base58Alphabet is the Bitcoin alphabet used by Solana for keys and signatures
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var errInvalidBase58 = errors.New("invalid base58 string")
+
+var base58Index = func() [256]int {
+	var index [256]int
+	for i := range index {
+		index[i] = -1
+	}
+	for i := 0; i < len(base58Alphabet); i++ {
+		index[base58Alphabet[i]] = i
+	}
+	return index
+}()
+
+// base58Encode encodes b, preserving leading zero bytes as '1'
+func base58Encode(b []byte) string {
+	n := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58Decode decodes s, preserving leading '1' characters as zero bytes
+func base58Decode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errInvalidBase58
+	}
+
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		digit := base58Index[s[i]]
+		if digit < 0 {
+			return nil, errInvalidBase58
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(digit)))
+	}
+
+	var zeros int
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
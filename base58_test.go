@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBase58RoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		decoded []byte
+		encoded string
+	}{
+		{name: "System Program", decoded: make([]byte, 32), encoded: "11111111111111111111111111111111"},
+		{name: "Leading Zero", decoded: []byte{0, 0, 1}, encoded: "112"},
+		{name: "Hello World", decoded: []byte("Hello World!"), encoded: "2NEpo7TZRRrLZSi2U"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base58Encode(tt.decoded); got != tt.encoded {
+				t.Errorf("base58Encode returned %q, want %q", got, tt.encoded)
+			}
+
+			got, err := base58Decode(tt.encoded)
+			if err != nil {
+				t.Fatalf("base58Decode returned error: %v", err)
+			}
+			if !bytes.Equal(got, tt.decoded) {
+				t.Errorf("base58Decode returned %v, want %v", got, tt.decoded)
+			}
+		})
+	}
+}
+
+func TestBase58DecodeInvalid(t *testing.T) {
+	for _, input := range []string{"", "0OIl", "abc!"} {
+		if _, err := base58Decode(input); err == nil {
+			t.Errorf("Expected error decoding %q", input)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// rpcCall is a single call within a batch request
+type rpcCall struct {
+	Method string
+	Params []interface{}
+}
+
+// sendBatch sends calls as JSON-RPC batches and returns their responses in
+// call order. Calls are split into sub-batches of at most maxBatchSize, and a
+// sub-batch the provider rejects as too large (HTTP 413) is halved until it is
+// accepted. Per-call RPC errors are left on the individual responses.
+func (c *rpcClient) sendBatch(ctx context.Context, calls []rpcCall) ([]*RPCResponse, error) {
+	requests := make([]RPCRequest, len(calls))
+	for i, call := range calls {
+		params, err := c.withCommitment(ctx, call.Method, call.Params)
+		if err != nil {
+			return nil, err
+		}
+		requests[i] = RPCRequest{
+			Jsonrpc: "2.0",
+			Method:  call.Method,
+			Params:  params,
+			ID:      int(atomic.AddUint64(c.lastID, 1)),
+		}
+	}
+
+	size := c.maxBatchSize
+	if size <= 0 {
+		size = len(requests)
+	}
+
+	responses := make([]*RPCResponse, 0, len(requests))
+	for start := 0; start < len(requests); start += size {
+		end := start + size
+		if end > len(requests) {
+			end = len(requests)
+		}
+
+		chunk, err := c.sendBatchChunk(ctx, requests[start:end])
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, chunk...)
+	}
+
+	return responses, nil
+}
+
+// sendBatchChunk sends one sub-batch, splitting it further on HTTP 413
+func (c *rpcClient) sendBatchChunk(ctx context.Context, requests []RPCRequest) ([]*RPCResponse, error) {
+	jsonData, err := json.Marshal(requests)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
+	}
+
+	start := time.Now()
+	body, err := c.postWithRetry(ctx, jsonData, len(requests))
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusRequestEntityTooLarge && len(requests) > 1 {
+		half := len(requests) / 2
+		first, err := c.sendBatchChunk(ctx, requests[:half])
+		if err != nil {
+			return nil, err
+		}
+		second, err := c.sendBatchChunk(ctx, requests[half:])
+		if err != nil {
+			return nil, err
+		}
+		return append(first, second...), nil
+	}
+	if err != nil {
+		c.observeBatch(requests, time.Since(start), nil, err)
+		return nil, err
+	}
+
+	var batch []RPCResponse
+	if err := json.Unmarshal(body, &batch); err != nil {
+		// Providers reject a whole batch with a single error object
+		var single RPCResponse
+		if json.Unmarshal(body, &single) == nil && single.Error != nil {
+			err := c.rpcError(single.Error)
+			c.observeBatch(requests, time.Since(start), nil, err)
+			return nil, err
+		}
+		err = fmt.Errorf("failed to unmarshal batch response: %w", err)
+		c.observeBatch(requests, time.Since(start), nil, err)
+		return nil, err
+	}
+
+	byID := make(map[int]*RPCResponse, len(batch))
+	for i := range batch {
+		byID[batch[i].ID] = &batch[i]
+	}
+
+	// Responses may arrive in any order, so correlate them by id
+	responses := make([]*RPCResponse, len(requests))
+	for i, req := range requests {
+		response, ok := byID[req.ID]
+		if c.trace != nil {
+			if ok {
+				c.trace(req.ID, response.ID)
+			} else {
+				c.trace(req.ID, 0)
+			}
+		}
+		if !ok {
+			err := fmt.Errorf("RPC batch response missing id %d", req.ID)
+			c.observeBatch(requests, time.Since(start), nil, err)
+			return nil, err
+		}
+		responses[i] = response
+	}
+
+	c.observeBatch(requests, time.Since(start), responses, nil)
+	return responses, nil
+}
+
+// observeBatch records every call of a batch, with the batch's latency and
+// either the error of the whole batch or the call's own RPC error
+func (c *rpcClient) observeBatch(requests []RPCRequest, elapsed time.Duration, responses []*RPCResponse, err error) {
+	for i, req := range requests {
+		callErr := err
+		if responses != nil && responses[i].Error != nil {
+			callErr = c.rpcError(responses[i].Error)
+		}
+		observeRPC(req.Method, elapsed, callErr)
+	}
+}
+
+// batchResults splits batch responses into their results and per-call
+// errors
+func (c *rpcClient) batchResults(responses []*RPCResponse) ([]json.RawMessage, []error) {
+	results := make([]json.RawMessage, len(responses))
+	errs := make([]error, len(responses))
+	for i, response := range responses {
+		if response.Error != nil {
+			errs[i] = c.rpcError(response.Error)
+			continue
+		}
+		results[i] = response.Result
+	}
+	return results, errs
+}
+
+// getBlocks gets the blocks at slots in as few batch requests as the batch
+// size allows. Each block's own error, such as a skipped slot, is returned
+// alongside it; the error result means the batch as a whole failed.
+func (c *rpcClient) getBlocks(ctx context.Context, slots []uint64, opts BlockOptions) ([]json.RawMessage, []error, error) {
+	calls := make([]rpcCall, len(slots))
+	for i, slot := range slots {
+		calls[i] = rpcCall{Method: "getBlock", Params: blockParams(slot, opts)}
+	}
+
+	responses, err := c.sendBatch(ctx, calls)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocks, errs := c.batchResults(responses)
+	return blocks, errs, nil
+}
+
+// getTransactions gets transactions by signature in as few batch requests as
+// the batch size allows, with nil for signatures the node does not know.
+// Errors are reported as for getBlocks.
+func (c *rpcClient) getTransactions(ctx context.Context, signatures []string, opts TransactionOptions) ([]json.RawMessage, []error, error) {
+	calls := make([]rpcCall, len(signatures))
+	for i, signature := range signatures {
+		calls[i] = rpcCall{Method: "getTransaction", Params: transactionParams(signature, opts)}
+	}
+
+	responses, err := c.sendBatch(ctx, calls)
+	if err != nil {
+		return nil, nil, err
+	}
+	transactions, errs := c.batchResults(responses)
+	for i, transaction := range transactions {
+		if bytes.Equal(transaction, []byte("null")) {
+			transactions[i] = nil
+		}
+	}
+	return transactions, errs, nil
+}
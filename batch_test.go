@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// batchServer answers batches with each call's first param, in reverse order,
+// rejecting batches larger than limit with HTTP 413
+func batchServer(t *testing.T, limit int, sizes *[]int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []RPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("Failed to decode batch: %v", err)
+			return
+		}
+		*sizes = append(*sizes, len(batch))
+
+		if len(batch) > limit {
+			http.Error(w, "request entity too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		responses := make([]RPCResponse, len(batch))
+		for i, req := range batch {
+			result, _ := json.Marshal(req.Params[0])
+			responses[len(batch)-1-i] = RPCResponse{Jsonrpc: "2.0", Result: result, ID: req.ID}
+		}
+		json.NewEncoder(w).Encode(responses)
+	}))
+}
+
+func testCalls(n int) []rpcCall {
+	calls := make([]rpcCall, n)
+	for i := range calls {
+		calls[i] = rpcCall{Method: "getBlockTime", Params: []interface{}{i}}
+	}
+	return calls
+}
+
+func checkBatchResults(t *testing.T, responses []*RPCResponse, n int) {
+	t.Helper()
+	if len(responses) != n {
+		t.Fatalf("Expected %d responses, got %d", n, len(responses))
+	}
+	for i, response := range responses {
+		if string(response.Result) != fmt.Sprint(i) {
+			t.Fatalf("response %d out of order: got %s", i, response.Result)
+		}
+	}
+}
+
+func TestSendBatchSplitsOversizedBatch(t *testing.T) {
+	var sizes []int
+	server := batchServer(t, 100, &sizes)
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+
+	responses, err := client.sendBatch(context.Background(), testCalls(250))
+	if err != nil {
+		t.Fatalf("sendBatch returned error: %v", err)
+	}
+
+	if fmt.Sprint(sizes) != "[100 100 50]" {
+		t.Errorf("Expected upstream batches of [100 100 50], got %v", sizes)
+	}
+	checkBatchResults(t, responses, 250)
+}
+
+func TestSendBatchHalvesOn413(t *testing.T) {
+	var sizes []int
+	server := batchServer(t, 40, &sizes)
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+
+	responses, err := client.sendBatch(context.Background(), testCalls(100))
+	if err != nil {
+		t.Fatalf("sendBatch returned error: %v", err)
+	}
+
+	if fmt.Sprint(sizes) != "[100 50 25 25 50 25 25]" {
+		t.Errorf("unexpected upstream batch sizes: %v", sizes)
+	}
+	checkBatchResults(t, responses, 100)
+}
+
+func TestSendBatchMissingResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"jsonrpc":"2.0","result":1,"id":1}]`))
+	}))
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+
+	if _, err := client.sendBatch(context.Background(), testCalls(2)); err == nil {
+		t.Error("Expected error for a batch response missing an id")
+	}
+}
+
+func TestRecordThenReplayBatch(t *testing.T) {
+	var sizes []int
+	server := batchServer(t, 100, &sizes)
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder, err := newRecordingTransport(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("newRecordingTransport returned error: %v", err)
+	}
+	live := newRPCClient(server.URL)
+	live.client.Transport = recorder
+
+	if _, err := live.sendBatch(context.Background(), testCalls(5)); err != nil {
+		t.Fatalf("sendBatch returned error: %v", err)
+	}
+	recorder.Close()
+
+	replayer, err := newReplayTransport(dir)
+	if err != nil {
+		t.Fatalf("newReplayTransport returned error: %v", err)
+	}
+	replayed := newRPCClient(server.URL)
+	replayed.client.Transport = replayer
+
+	// Advance the id sequence so replayed ids differ from the recorded ones
+	*replayed.lastID = 1000
+
+	responses, err := replayed.sendBatch(context.Background(), testCalls(5))
+	if err != nil {
+		t.Fatalf("replayed sendBatch returned error: %v", err)
+	}
+	checkBatchResults(t, responses, 5)
+}
+
+func TestGetBlocksReportsPerSlotErrors(t *testing.T) {
+	var sizes []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []RPCRequest
+		json.NewDecoder(r.Body).Decode(&batch)
+		sizes = append(sizes, len(batch))
+
+		responses := make([]RPCResponse, len(batch))
+		for i, req := range batch {
+			responses[i] = RPCResponse{Jsonrpc: "2.0", ID: req.ID}
+			if slot := req.Params[0].(float64); slot == 11 {
+				responses[i].Error = &RPCError{Code: rpcSlotSkipped, Message: "Slot 11 was skipped"}
+				continue
+			}
+			responses[i].Result = json.RawMessage(fmt.Sprintf(`{"parentSlot":%v}`, req.Params[0].(float64)-1))
+		}
+		json.NewEncoder(w).Encode(responses)
+	}))
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+	blocks, errs, err := client.getBlocks(context.Background(), []uint64{10, 11, 12}, BlockOptions{})
+	if err != nil {
+		t.Fatalf("getBlocks returned error: %v", err)
+	}
+
+	if len(sizes) != 1 || sizes[0] != 3 {
+		t.Errorf("Expected a single batch of 3 calls, got %v", sizes)
+	}
+	if string(blocks[0]) != `{"parentSlot":9}` || string(blocks[2]) != `{"parentSlot":11}` {
+		t.Errorf("Unexpected blocks %s, %s", blocks[0], blocks[2])
+	}
+	if errs[0] != nil || errs[2] != nil || !slotSkipped(errs[1]) {
+		t.Errorf("Expected only slot 11 to be skipped, got %v", errs)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+)
+
+// Bitcoin Core JSON-RPC error codes
+const (
+	bitcoinInvalidAddressOrKey = -5
+	bitcoinInvalidParameter    = -8
+)
+
+// envBitcoinRPCPassword is read for -btc-rpc-password, keeping the password
+// out of the process list
+const envBitcoinRPCPassword = "SOLANA_CLIENT_BTC_RPC_PASSWORD"
+
+// bitcoinChain is a ChainClient for a Bitcoin Core node. Core answers
+// JSON-RPC 2.0 requests, with errors in the body of a 200 response, from
+// version 28; older nodes fail every error with HTTP 500.
+type bitcoinChain struct {
+	client *rpcClient
+}
+
+// withBasicAuth authenticates upstream requests with the node's rpcuser and
+// rpcpassword, or the contents of its .cookie file split at the colon
+func withBasicAuth(user, password string) RequestInterceptor {
+	return func(req *http.Request) error {
+		req.SetBasicAuth(user, password)
+		return nil
+	}
+}
+
+// bitcoinErrorCode reports whether err is the Bitcoin Core error code
+func bitcoinErrorCode(err error, code int) bool {
+	var rpcErr *upstreamError
+	return errors.As(err, &rpcErr) && rpcErr.Code == code
+}
+
+func (c bitcoinChain) name() string {
+	return "bitcoin"
+}
+
+func (c bitcoinChain) latestBlock(ctx context.Context) (uint64, error) {
+	response, err := c.client.sendRequest(ctx, "getblockcount", nil)
+	if err != nil {
+		return 0, err
+	}
+	var height uint64
+	if err := json.Unmarshal(response.Result, &height); err != nil {
+		return 0, fmt.Errorf("failed to parse block count: %w", err)
+	}
+	return height, nil
+}
+
+// block gets the block at a height with the ids of its transactions
+func (c bitcoinChain) block(ctx context.Context, number uint64) (json.RawMessage, error) {
+	response, err := c.client.sendRequest(ctx, "getblockhash", []interface{}{number})
+	if bitcoinErrorCode(err, bitcoinInvalidParameter) {
+		return nil, errBlockNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var hash string
+	if err := json.Unmarshal(response.Result, &hash); err != nil {
+		return nil, fmt.Errorf("failed to parse block hash: %w", err)
+	}
+
+	response, err = c.client.sendRequest(ctx, "getblock", []interface{}{hash, 1})
+	if err != nil {
+		return nil, err
+	}
+	return response.Result, nil
+}
+
+// transaction gets a decoded transaction. Outside the mempool the node only
+// finds transactions it indexes, which needs txindex=1 for all but its own.
+func (c bitcoinChain) transaction(ctx context.Context, id string) (json.RawMessage, error) {
+	response, err := c.client.sendRequest(ctx, "getrawtransaction", []interface{}{id, true})
+	if bitcoinErrorCode(err, bitcoinInvalidAddressOrKey) {
+		return nil, errTransactionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response.Result, nil
+}
+
+// balance is not offered, as Core only tracks the balances of its wallets
+func (c bitcoinChain) balance(ctx context.Context, address string) (*big.Int, error) {
+	return nil, errChainUnsupported
+}
+
+// bech32Charset holds the characters of the data part of a bech32 address
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// validateAddress accepts base58check addresses and well formed bech32 ones
+// of mainnet, testnet and regtest. Bech32 checksums are left to the node.
+func (c bitcoinChain) validateAddress(address string) error {
+	lower := strings.ToLower(address)
+	for _, hrp := range []string{"bc1", "tb1", "bcrt1"} {
+		if !strings.HasPrefix(lower, hrp) {
+			continue
+		}
+		data := lower[len(hrp):]
+		if (address != lower && address != strings.ToUpper(address)) || len(address) > 90 || len(data) < 6+8 {
+			return fmt.Errorf("invalid bech32 address: %s", address)
+		}
+		for _, r := range data {
+			if !strings.ContainsRune(bech32Charset, r) {
+				return fmt.Errorf("invalid bech32 address: %s", address)
+			}
+		}
+		return nil
+	}
+
+	decoded, err := base58Decode(address)
+	if err != nil || len(decoded) != 25 {
+		return fmt.Errorf("invalid address: %s", address)
+	}
+	first := sha256.Sum256(decoded[:21])
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], decoded[21:]) {
+		return fmt.Errorf("invalid address checksum: %s", address)
+	}
+	return nil
+}
+
+func (c bitcoinChain) unit() (string, int) {
+	return "satoshis", 8
+}
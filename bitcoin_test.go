@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newBitcoinServer is a Bitcoin Core node at height 100 that knows one
+// transaction and requires the rpcuser "user" with rpcpassword "pass"
+func newBitcoinServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "user" || password != "pass" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		result, rpcErr := "null", "null"
+		switch req.Method {
+		case "getblockcount":
+			result = "100"
+		case "getblockhash":
+			if req.Params[0] == float64(100) {
+				result = `"00ab"`
+			} else {
+				rpcErr = `{"code":-8,"message":"Block height out of range"}`
+			}
+		case "getblock":
+			result = fmt.Sprintf(`{"hash":%q,"height":100,"tx":["aa"]}`, req.Params[0])
+		case "getrawtransaction":
+			if req.Params[0] == "aa" {
+				result = `{"txid":"aa","vout":[]}`
+			} else {
+				rpcErr = `{"code":-5,"message":"No such mempool or blockchain transaction"}`
+			}
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%s,"error":%s,"id":%d}`, result, rpcErr, req.ID)
+	}))
+}
+
+func TestBitcoinChain(t *testing.T) {
+	server := newBitcoinServer()
+	defer server.Close()
+	client := newRPCClient(server.URL)
+	client.addInterceptor(withBasicAuth("user", "pass"))
+	btc := bitcoinChain{client: client}
+	unauthorized := bitcoinChain{client: newRPCClient(server.URL)}
+	unauthorized.client.maxAttempts = 1
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		queryParam     string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Latest Block", handler: handleChainLatestBlock(btc), expectedStatus: http.StatusOK, expectedBody: `{"chain":"bitcoin","block":100}`},
+		{name: "Block", handler: handleChainBlock(btc), queryParam: "?number=100", expectedStatus: http.StatusOK, expectedBody: `{"hash":"00ab","height":100,"tx":["aa"]}`},
+		{name: "Block Not Found", handler: handleChainBlock(btc), queryParam: "?number=101", expectedStatus: http.StatusNotFound},
+		{name: "Transaction", handler: handleChainTransaction(btc), queryParam: "?id=aa", expectedStatus: http.StatusOK, expectedBody: `{"txid":"aa","vout":[]}`},
+		{name: "Transaction Not Found", handler: handleChainTransaction(btc), queryParam: "?id=bb", expectedStatus: http.StatusNotFound},
+		{name: "Balance Unsupported", handler: handleChainBalance(btc), queryParam: "?address=1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", expectedStatus: http.StatusNotImplemented},
+		{name: "Invalid Address", handler: handleChainBalance(btc), queryParam: "?address=1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", expectedStatus: http.StatusBadRequest},
+		{name: "Missing Credentials", handler: handleChainLatestBlock(unauthorized), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.handler(rr, httptest.NewRequest("GET", "/v1/bitcoin/route"+tt.queryParam, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestBitcoinValidateAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		valid   bool
+	}{
+		{address: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", valid: true},
+		{address: "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", valid: true},
+		{address: "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", valid: true},
+		{address: "BC1QAR0SRRR7XFKVY5L643LYDNW9RE59GTZZWF5MDQ", valid: true},
+		{address: "bcrt1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", valid: true},
+		{address: "bc1Qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq"},
+		{address: "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdb"},
+		{address: "bc1q"},
+		{address: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb"},
+		{address: "11111111111111111111111111111111"},
+		{address: "0x00000000219ab540356cBB839Cbe05303d7705Fa"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := bitcoinChain{}.validateAddress(tt.address)
+			if (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got error %v", tt.valid, err)
+			}
+		})
+	}
+}
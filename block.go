@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Block is a block in the stable shape served by /block-details?format=parsed
+type Block struct {
+	Slot              uint64               `json:"slot"`
+	Blockhash         string               `json:"blockhash"`
+	PreviousBlockhash string               `json:"previous_blockhash"`
+	ParentSlot        uint64               `json:"parent_slot"`
+	BlockTime         *int64               `json:"block_time"`
+	BlockHeight       *uint64              `json:"block_height"`
+	Transactions      []TransactionSummary `json:"transactions,omitempty"`
+	Signatures        []string             `json:"signatures,omitempty"`
+	Rewards           []Reward             `json:"rewards"`
+}
+
+// TransactionSummary is the outcome of a transaction in a block
+type TransactionSummary struct {
+	Signature string          `json:"signature"`
+	Fee       uint64          `json:"fee"`
+	Status    string          `json:"status"`
+	Err       json.RawMessage `json:"err"`
+}
+
+// Reward is a reward credited to an account by a block
+type Reward struct {
+	Pubkey      string `json:"pubkey"`
+	Lamports    int64  `json:"lamports"`
+	PostBalance uint64 `json:"post_balance"`
+	RewardType  string `json:"reward_type,omitempty"`
+	Commission  *uint8 `json:"commission,omitempty"`
+}
+
+// parseBlock reads a json encoded getBlock result. Blocks fetched with
+// transactionDetails=signatures list only the signatures of their
+// transactions, and blocks fetched without transaction details or rewards
+// have neither field, which leaves the corresponding lists empty.
+func parseBlock(slot uint64, raw json.RawMessage) (*Block, error) {
+	var block struct {
+		Blockhash         string   `json:"blockhash"`
+		PreviousBlockhash string   `json:"previousBlockhash"`
+		ParentSlot        uint64   `json:"parentSlot"`
+		BlockTime         *int64   `json:"blockTime"`
+		BlockHeight       *uint64  `json:"blockHeight"`
+		Signatures        []string `json:"signatures"`
+		Transactions      []struct {
+			Transaction struct {
+				Signatures []string `json:"signatures"`
+			} `json:"transaction"`
+			Meta *struct {
+				Fee uint64          `json:"fee"`
+				Err json.RawMessage `json:"err"`
+			} `json:"meta"`
+		} `json:"transactions"`
+		Rewards []struct {
+			Pubkey      string `json:"pubkey"`
+			Lamports    int64  `json:"lamports"`
+			PostBalance uint64 `json:"postBalance"`
+			RewardType  string `json:"rewardType"`
+			Commission  *uint8 `json:"commission"`
+		} `json:"rewards"`
+	}
+	if err := json.Unmarshal(raw, &block); err != nil {
+		return nil, fmt.Errorf("failed to parse block: %w", err)
+	}
+
+	parsed := &Block{
+		Slot:              slot,
+		Blockhash:         block.Blockhash,
+		PreviousBlockhash: block.PreviousBlockhash,
+		ParentSlot:        block.ParentSlot,
+		BlockTime:         block.BlockTime,
+		BlockHeight:       block.BlockHeight,
+		Signatures:        block.Signatures,
+		Rewards:           make([]Reward, 0, len(block.Rewards)),
+	}
+	for _, tx := range block.Transactions {
+		summary := TransactionSummary{Status: transactionSucceeded, Err: json.RawMessage("null")}
+		if len(tx.Transaction.Signatures) > 0 {
+			summary.Signature = tx.Transaction.Signatures[0]
+		}
+		if tx.Meta != nil {
+			summary.Fee = tx.Meta.Fee
+			if len(tx.Meta.Err) > 0 && !bytes.Equal(tx.Meta.Err, []byte("null")) {
+				summary.Status, summary.Err = transactionFailed, tx.Meta.Err
+			}
+		}
+		parsed.Transactions = append(parsed.Transactions, summary)
+	}
+	for _, r := range block.Rewards {
+		parsed.Rewards = append(parsed.Rewards, Reward{
+			Pubkey:      r.Pubkey,
+			Lamports:    r.Lamports,
+			PostBalance: r.PostBalance,
+			RewardType:  r.RewardType,
+			Commission:  r.Commission,
+		})
+	}
+	return parsed, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetBlockDetailsParsed(t *testing.T) {
+	full := `{"blockhash":"hash2","previousBlockhash":"hash1","parentSlot":41,"blockTime":1700000000,"blockHeight":40,` +
+		`"transactions":[{"transaction":{"signatures":["sig1"]},"meta":{"fee":5000,"err":null}},{"transaction":{"signatures":["sig2"]},"meta":{"fee":5000,"err":{"InstructionError":[0,"Custom"]}}}],` +
+		`"rewards":[{"pubkey":"leader","lamports":2500,"postBalance":1000000,"rewardType":"Fee","commission":null}]}`
+	headerOnly := `{"blockhash":"hash2","previousBlockhash":"hash1","parentSlot":41,"blockTime":null,"blockHeight":null}`
+
+	tests := []struct {
+		name           string
+		block          string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Full",
+			block:          full,
+			query:          "?block=42&format=parsed",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"slot":42,"blockhash":"hash2","previous_blockhash":"hash1","parent_slot":41,"block_time":1700000000,"block_height":40,` +
+				`"transactions":[{"signature":"sig1","fee":5000,"status":"success","err":null},{"signature":"sig2","fee":5000,"status":"failed","err":{"InstructionError":[0,"Custom"]}}],` +
+				`"rewards":[{"pubkey":"leader","lamports":2500,"post_balance":1000000,"reward_type":"Fee"}]}`,
+		},
+		{
+			name:           "Without Transactions Or Rewards",
+			block:          headerOnly,
+			query:          "?block=42&format=parsed",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"slot":42,"blockhash":"hash2","previous_blockhash":"hash1","parent_slot":41,"block_time":null,"block_height":null,"rewards":[]}`,
+		},
+		{name: "Raw", block: headerOnly, query: "?block=42&format=raw", expectedStatus: http.StatusOK, expectedBody: headerOnly},
+		{name: "Parsed Needs JSON", block: full, query: "?block=42&format=parsed&encoding=base64", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown Format", block: full, query: "?block=42&format=typed", expectedStatus: http.StatusBadRequest},
+		{name: "Malformed Block", block: `[]`, query: "?block=42&format=parsed", expectedStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockRPCClient{blockDetails: json.RawMessage(tt.block)}
+			req := httptest.NewRequest("GET", "/block-details"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetBlockDetails(mock, 0, "base64").ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
+			}
+			if tt.query == "?block=42&format=parsed" && mock.blockOptions.Encoding != "json" {
+				t.Errorf("Expected json encoding for the parsed format, got %q", mock.blockOptions.Encoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// defaultBlockCacheBytes bounds the memory held by cached blocks
+const defaultBlockCacheBytes = 64 << 20
+
+// blockCacheKey identifies a block fetched with a given set of options, as
+// they change what the node returns for the same slot
+type blockCacheKey struct {
+	slot               uint64
+	maxVersion         int
+	legacy             bool
+	encoding           string
+	transactionDetails string
+}
+
+func newBlockCacheKey(slot uint64, opts BlockOptions) blockCacheKey {
+	key := blockCacheKey{slot: slot, legacy: opts.MaxSupportedTransactionVersion == nil, encoding: opts.Encoding, transactionDetails: opts.TransactionDetails}
+	if !key.legacy {
+		key.maxVersion = *opts.MaxSupportedTransactionVersion
+	}
+	return key
+}
+
+type blockCacheEntry struct {
+	key   blockCacheKey
+	block json.RawMessage
+}
+
+// blockCache keeps finalized blocks in memory, evicting the least recently
+// used once their size passes maxBytes. Finalized blocks never change, so
+// entries need no TTL.
+type blockCache struct {
+	maxBytes int64
+
+	mu        sync.Mutex
+	entries   map[blockCacheKey]*list.Element
+	order     *list.List
+	bytes     int64
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func newBlockCache(maxBytes int64) *blockCache {
+	return &blockCache{maxBytes: maxBytes, entries: make(map[blockCacheKey]*list.Element), order: list.New()}
+}
+
+func (c *blockCache) get(key blockCacheKey) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		metrics.addCounter("solana_client_block_cache_requests_total", "Finalized block lookups by cache result.", 1, "result", "miss")
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(element)
+	metrics.addCounter("solana_client_block_cache_requests_total", "Finalized block lookups by cache result.", 1, "result", "hit")
+	return element.Value.(*blockCacheEntry).block, true
+}
+
+// put stores a block, unless it is missing or alone would not fit in the
+// cache
+func (c *blockCache) put(key blockCacheKey, block json.RawMessage) {
+	size := int64(len(block))
+	if size == 0 || string(block) == "null" || size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&blockCacheEntry{key: key, block: block})
+	c.bytes += size
+	for c.bytes > c.maxBytes {
+		oldest := c.order.Back()
+		entry := oldest.Value.(*blockCacheEntry)
+		c.order.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.bytes -= int64(len(entry.block))
+		c.evictions++
+	}
+	metrics.setGauge("solana_client_block_cache_bytes", "Size of the blocks held by the block cache.", float64(c.bytes))
+}
+
+// blockCacheStats is the block cache part of /cache/stats
+type blockCacheStats struct {
+	Enabled   bool   `json:"enabled"`
+	Entries   int    `json:"entries"`
+	Bytes     int64  `json:"bytes"`
+	MaxBytes  int64  `json:"max_bytes"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+func (c *blockCache) stats() blockCacheStats {
+	if c == nil {
+		return blockCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return blockCacheStats{Enabled: true, Entries: len(c.entries), Bytes: c.bytes, MaxBytes: c.maxBytes, Hits: c.hits, Misses: c.misses, Evictions: c.evictions}
+}
+
+// wrap returns a client whose finalized blocks are served through the
+// cache. commitment is the one configured for upstream calls. A nil cache
+// returns client unchanged.
+func (c *blockCache) wrap(client SolanaRPCClient, commitment string) SolanaRPCClient {
+	if c == nil {
+		return client
+	}
+	return &blockCachingClient{SolanaRPCClient: client, cache: c, commitment: commitment}
+}
+
+// blockCachingClient serves getBlockDetails and getBlocks from a shared
+// blockCache when the blocks are fetched at finalized commitment
+type blockCachingClient struct {
+	SolanaRPCClient
+	cache      *blockCache
+	commitment string
+}
+
+// finalizedRead reports whether a read made for ctx is at finalized
+// commitment, taking the commitment from the call, the request or the
+// configuration in turn and falling back on the node's default of finalized
+func finalizedRead(ctx context.Context, commitment, configured string) bool {
+	if commitment == "" {
+		commitment = requestCommitment(ctx)
+	}
+	if commitment == "" {
+		commitment = configured
+	}
+	return commitment == "" || commitment == commitmentFinalized
+}
+
+func (c *blockCachingClient) getBlockDetails(ctx context.Context, slot uint64, opts BlockOptions) (json.RawMessage, error) {
+	if !finalizedRead(ctx, opts.Commitment, c.commitment) {
+		return c.SolanaRPCClient.getBlockDetails(ctx, slot, opts)
+	}
+	key := newBlockCacheKey(slot, opts)
+	if block, ok := c.cache.get(key); ok {
+		// Only finalized blocks are cached
+		noteCommitment(ctx, commitmentFinalized)
+		return block, nil
+	}
+	block, err := c.SolanaRPCClient.getBlockDetails(ctx, slot, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.put(key, block)
+	return block, nil
+}
+
+// getBlocks fetches only the blocks missing from the cache upstream
+func (c *blockCachingClient) getBlocks(ctx context.Context, slots []uint64, opts BlockOptions) ([]json.RawMessage, []error, error) {
+	if !finalizedRead(ctx, opts.Commitment, c.commitment) {
+		return c.SolanaRPCClient.getBlocks(ctx, slots, opts)
+	}
+
+	blocks := make([]json.RawMessage, len(slots))
+	errs := make([]error, len(slots))
+	var missing []uint64
+	var indexes []int
+	for i, slot := range slots {
+		if block, ok := c.cache.get(newBlockCacheKey(slot, opts)); ok {
+			noteCommitment(ctx, commitmentFinalized)
+			blocks[i] = block
+			continue
+		}
+		missing = append(missing, slot)
+		indexes = append(indexes, i)
+	}
+	if len(missing) == 0 {
+		return blocks, errs, nil
+	}
+
+	fetched, fetchErrs, err := c.SolanaRPCClient.getBlocks(ctx, missing, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for j, i := range indexes {
+		blocks[i], errs[i] = fetched[j], fetchErrs[j]
+		if fetchErrs[j] == nil {
+			c.cache.put(newBlockCacheKey(missing[j], opts), fetched[j])
+		}
+	}
+	return blocks, errs, nil
+}
+
+// cacheStatsResponse is returned by /cache/stats
+type cacheStatsResponse struct {
+	Blocks     blockCacheStats      `json:"blocks"`
+	LatestSlot latestSlotCacheStats `json:"latest_slot"`
+}
+
+func handleCacheStats(blocks *blockCache, slots *latestSlotCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		jsonData, _ := json.Marshal(cacheStatsResponse{Blocks: blocks.stats(), LatestSlot: slots.stats()})
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// countingBlockClient counts the blocks fetched upstream
+type countingBlockClient struct {
+	mockRPCClient
+	fetched int
+}
+
+func (c *countingBlockClient) getBlockDetails(ctx context.Context, slot uint64, opts BlockOptions) (json.RawMessage, error) {
+	c.fetched++
+	return c.mockRPCClient.getBlockDetails(ctx, slot, opts)
+}
+
+func (c *countingBlockClient) getBlocks(ctx context.Context, slots []uint64, opts BlockOptions) ([]json.RawMessage, []error, error) {
+	blocks := make([]json.RawMessage, len(slots))
+	errs := make([]error, len(slots))
+	for i, slot := range slots {
+		blocks[i], errs[i] = c.getBlockDetails(ctx, slot, opts)
+	}
+	return blocks, errs, nil
+}
+
+func TestBlockCache(t *testing.T) {
+	upstream := &countingBlockClient{mockRPCClient: mockRPCClient{blocks: map[uint64]json.RawMessage{
+		1: json.RawMessage(`{"blockhash":"a"}`),
+		2: json.RawMessage(`{"blockhash":"b"}`),
+		3: json.RawMessage(`{"blockhash":"c"}`),
+	}}}
+	cache := newBlockCache(34)
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		client          SolanaRPCClient
+		ctx             context.Context
+		slot            uint64
+		opts            BlockOptions
+		expectedFetched int
+	}{
+		{name: "Miss", client: cache.wrap(upstream, ""), ctx: ctx, slot: 1, expectedFetched: 1},
+		{name: "Hit", client: cache.wrap(upstream, ""), ctx: ctx, slot: 1, expectedFetched: 0},
+		{name: "Options Are Part Of The Key", client: cache.wrap(upstream, ""), ctx: ctx, slot: 1, opts: BlockOptions{TransactionDetails: "none"}, expectedFetched: 1},
+		{name: "Confirmed Commitment Bypasses", client: cache.wrap(upstream, ""), ctx: ctx, slot: 1, opts: BlockOptions{Commitment: "confirmed"}, expectedFetched: 1},
+		{name: "Configured Commitment Bypasses", client: cache.wrap(upstream, "confirmed"), ctx: ctx, slot: 1, expectedFetched: 1},
+		{name: "Requested Commitment Bypasses", client: cache.wrap(upstream, ""), ctx: context.WithValue(ctx, requestCommitmentKey{}, "processed"), slot: 1, expectedFetched: 1},
+		{name: "Explicit Finalized Hits", client: cache.wrap(upstream, "finalized"), ctx: ctx, slot: 1, expectedFetched: 0},
+		{name: "Skipped Slot Not Cached", client: cache.wrap(upstream, ""), ctx: ctx, slot: 9, expectedFetched: 1},
+		{name: "Skipped Slot Fetched Again", client: cache.wrap(upstream, ""), ctx: ctx, slot: 9, expectedFetched: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream.fetched = 0
+			tt.client.getBlockDetails(tt.ctx, tt.slot, tt.opts)
+			if upstream.fetched != tt.expectedFetched {
+				t.Errorf("Expected %d upstream fetches, got %d", tt.expectedFetched, upstream.fetched)
+			}
+		})
+	}
+
+	// Each block is 17 bytes, so slot 2 evicts the least recently used
+	client := cache.wrap(upstream, "")
+	client.getBlockDetails(ctx, 1, BlockOptions{})
+	client.getBlockDetails(ctx, 2, BlockOptions{})
+	stats := cache.stats()
+	if stats.Entries != 2 || stats.Bytes != 34 || stats.Evictions != 1 {
+		t.Errorf("Expected 2 entries of 34 bytes after 1 eviction, got %+v", stats)
+	}
+
+	upstream.fetched = 0
+	blocks, errs, err := client.getBlocks(ctx, []uint64{1, 2, 3}, BlockOptions{})
+	if err != nil {
+		t.Fatalf("getBlocks returned error: %v", err)
+	}
+	if upstream.fetched != 1 {
+		t.Errorf("Expected only the uncached block fetched, got %d fetches", upstream.fetched)
+	}
+	for i, expected := range []string{`{"blockhash":"a"}`, `{"blockhash":"b"}`, `{"blockhash":"c"}`} {
+		if errs[i] != nil || string(blocks[i]) != expected {
+			t.Errorf("Expected block %s at %d, got %s (%v)", expected, i, blocks[i], errs[i])
+		}
+	}
+}
+
+func TestHandleCacheStats(t *testing.T) {
+	slots := newLatestSlotCache(defaultSlotCacheTTL, defaultSlotLagTolerance)
+	slots.wrap(&mockRPCClient{latestSlot: 42}).getLatestSlot(context.Background())
+
+	tests := []struct {
+		name            string
+		blocks          *blockCache
+		expectedEnabled bool
+	}{
+		{name: "Enabled", blocks: newBlockCache(defaultBlockCacheBytes), expectedEnabled: true},
+		{name: "Disabled", blocks: nil, expectedEnabled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handleCacheStats(tt.blocks, slots)(rr, httptest.NewRequest("GET", "/cache/stats", nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d", http.StatusOK, rr.Code)
+			}
+			var response cacheStatsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Blocks.Enabled != tt.expectedEnabled {
+				t.Errorf("Expected enabled %v, got %v", tt.expectedEnabled, response.Blocks.Enabled)
+			}
+			if response.LatestSlot.Slot != 42 || response.LatestSlot.Misses != 1 {
+				t.Errorf("Expected latest slot 42 after 1 miss, got %+v", response.LatestSlot)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// LatestBlockhash is the result of getLatestBlockhash
+type LatestBlockhash struct {
+	Slot                 uint64
+	Blockhash            string
+	LastValidBlockHeight uint64
+}
+
+// blockhashAndFeeResponse is the response of /blockhash-and-fee
+type blockhashAndFeeResponse struct {
+	Blockhash            string `json:"blockhash"`
+	LastValidBlockHeight uint64 `json:"last_valid_block_height"`
+	ContextSlot          uint64 `json:"context_slot"`
+	FeeLamports          uint64 `json:"fee_lamports"`
+	FeeMessage           string `json:"fee_message"`
+}
+
+// blockhashResponse is the response of /blockhash
+type blockhashResponse struct {
+	Blockhash            string `json:"blockhash"`
+	LastValidBlockHeight uint64 `json:"last_valid_block_height"`
+	ContextSlot          uint64 `json:"context_slot"`
+}
+
+// blockhashValidResponse is the response of /blockhash/valid
+type blockhashValidResponse struct {
+	Blockhash   string `json:"blockhash"`
+	Valid       bool   `json:"valid"`
+	ContextSlot uint64 `json:"context_slot"`
+}
+
+// feeForMessageResponse is the response of /fee-for-message
+type feeForMessageResponse struct {
+	FeeLamports uint64  `json:"fee_lamports"`
+	FeeSOL      float64 `json:"fee_sol"`
+}
+
+// getLatestBlockhash gets the latest blockhash and the last block height
+// at which a transaction using it is accepted
+func (c *rpcClient) getLatestBlockhash(ctx context.Context) (*LatestBlockhash, error) {
+	response, err := c.sendRequest(ctx, "getLatestBlockhash", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value struct {
+			Blockhash            string `json:"blockhash"`
+			LastValidBlockHeight uint64 `json:"lastValidBlockHeight"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse latest blockhash: %w", err)
+	}
+
+	return &LatestBlockhash{
+		Slot:                 result.Context.Slot,
+		Blockhash:            result.Value.Blockhash,
+		LastValidBlockHeight: result.Value.LastValidBlockHeight,
+	}, nil
+}
+
+// isBlockhashValid reports whether a blockhash is still accepted for new
+// transactions, with the slot the node evaluated it at
+func (c *rpcClient) isBlockhashValid(ctx context.Context, blockhash string) (bool, uint64, error) {
+	response, err := c.sendRequest(ctx, "isBlockhashValid", []interface{}{blockhash})
+	if err != nil {
+		return false, 0, err
+	}
+
+	var result struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value bool `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return false, 0, fmt.Errorf("failed to parse blockhash validity: %w", err)
+	}
+
+	return result.Value, result.Context.Slot, nil
+}
+
+// getFeeForMessage gets the fee the network charges for a base64 encoded
+// message, or nil if its blockhash has expired
+func (c *rpcClient) getFeeForMessage(ctx context.Context, message string) (*uint64, error) {
+	response, err := c.sendRequest(ctx, "getFeeForMessage", []interface{}{message})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value *uint64 `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse fee: %w", err)
+	}
+
+	return result.Value, nil
+}
+
+// feeSampleMessage builds a legacy message with a single signer and no
+// instructions, whose fee is the fee per signature
+func feeSampleMessage(blockhash string) (string, error) {
+	hash, err := decodePubkey(blockhash)
+	if err != nil {
+		return "", fmt.Errorf("invalid blockhash: %s", blockhash)
+	}
+
+	// Header: 1 required signature, no read-only accounts; then the fee
+	// payer as the only account, the blockhash and an empty instruction list
+	message := []byte{1, 0, 0, 1}
+	message = append(message, bytes.Repeat([]byte{1}, 32)...)
+	message = append(message, hash...)
+	message = append(message, 0)
+	return base64.StdEncoding.EncodeToString(message), nil
+}
+
+func handleGetBlockhashAndFee(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		message := r.URL.Query().Get("message")
+		if message != "" {
+			if _, err := base64.StdEncoding.DecodeString(message); err != nil {
+				http.Error(w, "message must be base64 encoded", http.StatusBadRequest)
+				return
+			}
+		}
+
+		latest, err := client.getLatestBlockhash(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := blockhashAndFeeResponse{
+			Blockhash:            latest.Blockhash,
+			LastValidBlockHeight: latest.LastValidBlockHeight,
+			ContextSlot:          latest.Slot,
+			FeeMessage:           "supplied",
+		}
+		if message == "" {
+			if message, err = feeSampleMessage(latest.Blockhash); err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+			response.FeeMessage = "single signature"
+		}
+
+		fee, err := client.getFeeForMessage(r.Context(), message)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		if fee == nil {
+			http.Error(w, "message blockhash is no longer valid", http.StatusUnprocessableEntity)
+			return
+		}
+		response.FeeLamports = *fee
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+func handleGetBlockhash(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		latest, err := client.getLatestBlockhash(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := blockhashResponse{
+			Blockhash:            latest.Blockhash,
+			LastValidBlockHeight: latest.LastValidBlockHeight,
+			ContextSlot:          latest.Slot,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+func handleGetBlockhashValid(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		blockhash := r.URL.Query().Get("blockhash")
+		if blockhash == "" {
+			http.Error(w, "blockhash parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := decodePubkey(blockhash); err != nil {
+			http.Error(w, fmt.Sprintf("invalid blockhash: %s", blockhash), http.StatusBadRequest)
+			return
+		}
+
+		valid, slot, err := client.isBlockhashValid(r.Context(), blockhash)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(blockhashValidResponse{Blockhash: blockhash, Valid: valid, ContextSlot: slot})
+		w.Write(jsonData)
+	}
+}
+
+func handleGetFeeForMessage(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		message := r.URL.Query().Get("message")
+		if message == "" {
+			http.Error(w, "message parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := base64.StdEncoding.DecodeString(message); err != nil {
+			http.Error(w, "message must be base64 encoded", http.StatusBadRequest)
+			return
+		}
+
+		fee, err := client.getFeeForMessage(r.Context(), message)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		if fee == nil {
+			http.Error(w, "message blockhash is no longer valid", http.StatusUnprocessableEntity)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(feeForMessageResponse{FeeLamports: *fee, FeeSOL: float64(*fee) / lamportsPerSOL})
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetBlockhashAndFee(t *testing.T) {
+	blockhash := base58Encode(bytes.Repeat([]byte{7}, 32))
+	latest := &LatestBlockhash{Slot: 1000, Blockhash: blockhash, LastValidBlockHeight: 900}
+	sample, _ := feeSampleMessage(blockhash)
+	supplied := base64.StdEncoding.EncodeToString([]byte("two signature message"))
+
+	tests := []struct {
+		name            string
+		mockClient      mockRPCClient
+		queryParam      string
+		expectedStatus  int
+		expectedFee     uint64
+		expectedMessage string
+	}{
+		{
+			name:            "Default Message",
+			mockClient:      mockRPCClient{blockhash: latest, messageFees: map[string]uint64{sample: 5000}},
+			expectedStatus:  http.StatusOK,
+			expectedFee:     5000,
+			expectedMessage: "single signature",
+		},
+		{
+			name:            "Supplied Message",
+			mockClient:      mockRPCClient{blockhash: latest, messageFees: map[string]uint64{supplied: 10000}},
+			queryParam:      "?message=" + supplied,
+			expectedStatus:  http.StatusOK,
+			expectedFee:     10000,
+			expectedMessage: "supplied",
+		},
+		{name: "Expired Blockhash", mockClient: mockRPCClient{blockhash: latest}, queryParam: "?message=" + supplied, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Invalid Message", mockClient: mockRPCClient{blockhash: latest}, queryParam: "?message=not*base64", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/blockhash-and-fee"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetBlockhashAndFee(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response blockhashAndFeeResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.Blockhash != blockhash || response.LastValidBlockHeight != 900 || response.ContextSlot != 1000 {
+				t.Errorf("unexpected blockhash: %+v", response)
+			}
+			if response.FeeLamports != tt.expectedFee || response.FeeMessage != tt.expectedMessage {
+				t.Errorf("Expected fee %d for %s message, got %d for %s", tt.expectedFee, tt.expectedMessage, response.FeeLamports, response.FeeMessage)
+			}
+		})
+	}
+}
+
+func TestFeeSampleMessage(t *testing.T) {
+	blockhash := bytes.Repeat([]byte{7}, 32)
+	encoded, err := feeSampleMessage(base58Encode(blockhash))
+	if err != nil {
+		t.Fatalf("feeSampleMessage returned error: %v", err)
+	}
+
+	message, _ := base64.StdEncoding.DecodeString(encoded)
+	if len(message) != 3+1+32+32+1 {
+		t.Fatalf("Expected a 69 byte message, got %d", len(message))
+	}
+	if message[0] != 1 || !bytes.Equal(message[36:68], blockhash) || message[68] != 0 {
+		t.Errorf("unexpected message layout: %x", message)
+	}
+}
+
+func TestHandleGetBlockhash(t *testing.T) {
+	latest := &LatestBlockhash{Slot: 1000, Blockhash: base58Encode(bytes.Repeat([]byte{7}, 32)), LastValidBlockHeight: 900}
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		expectedStatus int
+	}{
+		{name: "Latest Blockhash", mockClient: mockRPCClient{blockhash: latest}, expectedStatus: http.StatusOK},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/blockhash", nil)
+			rr := httptest.NewRecorder()
+			handleGetBlockhash(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response blockhashResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			expected := blockhashResponse{Blockhash: latest.Blockhash, LastValidBlockHeight: 900, ContextSlot: 1000}
+			if response != expected {
+				t.Errorf("Expected %+v, got %+v", expected, response)
+			}
+		})
+	}
+}
+
+func TestHandleGetBlockhashValid(t *testing.T) {
+	valid := base58Encode(bytes.Repeat([]byte{7}, 32))
+	expired := base58Encode(bytes.Repeat([]byte{8}, 32))
+	hashes := map[string]bool{valid: true}
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+		expectedValid  bool
+	}{
+		{name: "Valid", mockClient: mockRPCClient{latestSlot: 1000, validHashes: hashes}, queryParam: "?blockhash=" + valid, expectedStatus: http.StatusOK, expectedValid: true},
+		{name: "Expired", mockClient: mockRPCClient{latestSlot: 1000, validHashes: hashes}, queryParam: "?blockhash=" + expired, expectedStatus: http.StatusOK},
+		{name: "Missing Blockhash", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Blockhash", queryParam: "?blockhash=not-base58", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, queryParam: "?blockhash=" + valid, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/blockhash/valid"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetBlockhashValid(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response blockhashValidResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.Valid != tt.expectedValid || response.ContextSlot != 1000 {
+				t.Errorf("Expected valid %v at slot 1000, got %+v", tt.expectedValid, response)
+			}
+		})
+	}
+}
+
+func TestHandleGetFeeForMessage(t *testing.T) {
+	message := base64.StdEncoding.EncodeToString([]byte("two signature message"))
+	fees := map[string]uint64{message: 10000}
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+	}{
+		{name: "Fee", mockClient: mockRPCClient{messageFees: fees}, queryParam: "?message=" + message, expectedStatus: http.StatusOK},
+		{name: "Expired Blockhash", mockClient: mockRPCClient{}, queryParam: "?message=" + message, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Missing Message", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Message", queryParam: "?message=not*base64", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, queryParam: "?message=" + message, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/fee-for-message"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetFeeForMessage(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response feeForMessageResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.FeeLamports != 10000 || response.FeeSOL != 0.00001 {
+				t.Errorf("Expected a fee of 10000 lamports, got %+v", response)
+			}
+		})
+	}
+}
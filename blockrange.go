@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Block range settings
+const (
+	maxBlockRange     = 1000
+	maxBlockSummaries = 100
+)
+
+// blockRangeResponse is the response of /blocks
+type blockRangeResponse struct {
+	Start  uint64   `json:"start"`
+	End    uint64   `json:"end"`
+	Slots  []uint64 `json:"slots"`
+	Blocks []*Block `json:"blocks,omitempty"`
+}
+
+// getConfirmedBlocks lists the slots between start and end, inclusive, that
+// hold a confirmed block
+func (c *rpcClient) getConfirmedBlocks(ctx context.Context, start, end uint64) ([]uint64, error) {
+	response, err := c.sendRequest(ctx, "getBlocks", []interface{}{start, end})
+	if err != nil {
+		return nil, err
+	}
+
+	var slots []uint64
+	if err := json.Unmarshal(response.Result, &slots); err != nil {
+		return nil, fmt.Errorf("failed to parse blocks: %w", err)
+	}
+	return slots, nil
+}
+
+// blockSummaries fetches the headers, transaction signatures and rewards of
+// the blocks at slots
+func blockSummaries(ctx context.Context, client SolanaRPCClient, pool *workerPool, slots []uint64) ([]*Block, error) {
+	blocks, errs, err := fetchBlocks(ctx, client, pool, slots, BlockOptions{MaxSupportedTransactionVersion: new(int), TransactionDetails: "signatures"})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*Block, len(slots))
+	for i, slot := range slots {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		block, err := parseBlock(slot, blocks[i])
+		if err != nil {
+			return nil, err
+		}
+		summaries[i] = block
+	}
+	return summaries, nil
+}
+
+// handleGetBlockRange lists the confirmed blocks between ?start= and ?end=,
+// with their summaries when ?summaries=true
+func handleGetBlockRange(client SolanaRPCClient, pool *workerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, err := strconv.ParseUint(r.URL.Query().Get("start"), 10, 64)
+		if err != nil {
+			http.Error(w, "start must be a slot number", http.StatusBadRequest)
+			return
+		}
+		end, err := strconv.ParseUint(r.URL.Query().Get("end"), 10, 64)
+		if err != nil {
+			http.Error(w, "end must be a slot number", http.StatusBadRequest)
+			return
+		}
+		if end < start || end-start >= maxBlockRange {
+			http.Error(w, fmt.Sprintf("end must be at or after start and within %d slots of it", maxBlockRange), http.StatusBadRequest)
+			return
+		}
+		summaries := r.URL.Query().Get("summaries") == "true"
+
+		slots, err := client.getConfirmedBlocks(r.Context(), start, end)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := blockRangeResponse{Start: start, End: end, Slots: slots}
+		if response.Slots == nil {
+			response.Slots = []uint64{}
+		}
+		if summaries {
+			if len(slots) > maxBlockSummaries {
+				http.Error(w, fmt.Sprintf("summaries are limited to %d blocks, the range holds %d", maxBlockSummaries, len(slots)), http.StatusBadRequest)
+				return
+			}
+			pool.setPriorityHeader(w, len(slots))
+
+			response.Blocks, err = blockSummaries(r.Context(), client, pool, slots)
+			if errors.Is(err, errOverloaded) {
+				shed(w, priorityBulk)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
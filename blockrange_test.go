@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetBlockRange(t *testing.T) {
+	pool := newWorkerPool(2, 8)
+	defer pool.stop()
+
+	blocks := map[uint64]json.RawMessage{
+		10: json.RawMessage(`{"blockhash":"h10","previousBlockhash":"h9","parentSlot":9,"blockTime":100,"blockHeight":8,"signatures":["s1","s2"],"rewards":[]}`),
+		12: json.RawMessage(`{"blockhash":"h12","previousBlockhash":"h10","parentSlot":10,"blockTime":101,"blockHeight":9,"signatures":[],"rewards":[]}`),
+	}
+
+	tests := []struct {
+		name           string
+		query          string
+		mock           mockRPCClient
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Slots", query: "?start=10&end=12", mock: mockRPCClient{blocks: blocks}, expectedStatus: http.StatusOK, expectedBody: `{"start":10,"end":12,"slots":[10,12]}`},
+		{name: "Empty Range", query: "?start=13&end=20", mock: mockRPCClient{blocks: blocks}, expectedStatus: http.StatusOK, expectedBody: `{"start":13,"end":20,"slots":[]}`},
+		{
+			name:           "Summaries",
+			query:          "?start=10&end=12&summaries=true",
+			mock:           mockRPCClient{blocks: blocks},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"start":10,"end":12,"slots":[10,12],"blocks":[` +
+				`{"slot":10,"blockhash":"h10","previous_blockhash":"h9","parent_slot":9,"block_time":100,"block_height":8,"signatures":["s1","s2"],"rewards":[]},` +
+				`{"slot":12,"blockhash":"h12","previous_blockhash":"h10","parent_slot":10,"block_time":101,"block_height":9,"rewards":[]}]}`,
+		},
+		{name: "Missing Start", query: "?end=12", expectedStatus: http.StatusBadRequest},
+		{name: "End Before Start", query: "?start=12&end=10", expectedStatus: http.StatusBadRequest},
+		{name: "Range Too Wide", query: "?start=0&end=1000", expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Error", query: "?start=10&end=12", mock: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/blocks"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetBlockRange(&tt.mock, pool).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
+			}
+			if tt.name == "Summaries" && tt.mock.blockOptions.TransactionDetails != "signatures" {
+				t.Errorf("Expected summaries to fetch signatures only, got %q", tt.mock.blockOptions.TransactionDetails)
+			}
+		})
+	}
+}
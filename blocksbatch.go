@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxBatchBlocks bounds the blocks of a /blocks-batch request, as full
+// blocks can each run to megabytes
+const maxBatchBlocks = 50
+
+// batchBlock is a block of a /blocks-batch response, or the error that kept
+// it from being fetched
+type batchBlock struct {
+	Slot  uint64          `json:"slot"`
+	Block json.RawMessage `json:"block,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// blocksBatchResponse is the response of /blocks-batch
+type blocksBatchResponse struct {
+	Blocks []batchBlock `json:"blocks"`
+}
+
+// parseSlotList parses a comma separated list of slots
+func parseSlotList(value string) ([]uint64, error) {
+	if value == "" {
+		return nil, fmt.Errorf("slots parameter is required")
+	}
+	parts := strings.Split(value, ",")
+	slots := make([]uint64, len(parts))
+	for i, part := range parts {
+		slot, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slot %q", part)
+		}
+		slots[i] = slot
+	}
+	return slots, nil
+}
+
+// fetchBlocks fetches the blocks at slots as JSON-RPC batches from a single
+// pool task, queued at the priority of the whole request
+func fetchBlocks(ctx context.Context, client SolanaRPCClient, pool *workerPool, slots []uint64, opts BlockOptions) ([]json.RawMessage, []error, error) {
+	var blocks []json.RawMessage
+	var errs []error
+	var fetchErr error
+	if err := pool.runAt(ctx, pool.priorityFor(len(slots)), []func(){func() {
+		blocks, errs, fetchErr = client.getBlocks(ctx, slots, opts)
+	}}); err != nil {
+		return nil, nil, err
+	}
+	return blocks, errs, fetchErr
+}
+
+// handleGetBlocksBatch serves the blocks at ?slots=, a comma separated list,
+// in one upstream round trip where the batch size allows. A block that
+// cannot be fetched, such as a skipped slot, carries its error rather than
+// failing the others.
+func handleGetBlocksBatch(client SolanaRPCClient, pool *workerPool, defaultEncoding string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slots, err := parseSlotList(r.URL.Query().Get("slots"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(slots) > maxBatchBlocks {
+			http.Error(w, fmt.Sprintf("at most %d slots may be requested at once", maxBatchBlocks), http.StatusBadRequest)
+			return
+		}
+
+		maxTxVersion, err := parseMaxTxVersion(r.URL.Query().Get("maxTxVersion"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		encoding, err := requestEncoding(r, defaultEncoding)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		details := r.URL.Query().Get("transactionDetails")
+		if details != "" && !blockTransactionDetails[details] {
+			http.Error(w, "transactionDetails must be none, signatures, accounts or full", http.StatusBadRequest)
+			return
+		}
+		pool.setPriorityHeader(w, len(slots))
+
+		opts := BlockOptions{MaxSupportedTransactionVersion: maxTxVersion, Encoding: encoding, TransactionDetails: details}
+		blocks, errs, err := fetchBlocks(r.Context(), client, pool, slots, opts)
+		if errors.Is(err, errOverloaded) {
+			shed(w, priorityBulk)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := blocksBatchResponse{Blocks: make([]batchBlock, len(slots))}
+		for i, slot := range slots {
+			response.Blocks[i] = batchBlock{Slot: slot, Block: blocks[i]}
+			if errs[i] != nil {
+				response.Blocks[i].Error = errs[i].Error()
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
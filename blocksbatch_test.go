@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleGetBlocksBatch(t *testing.T) {
+	pool := newWorkerPool(2, 8)
+	defer pool.stop()
+
+	blocks := map[uint64]json.RawMessage{
+		10: json.RawMessage(`{"blockhash":"h10"}`),
+		12: json.RawMessage(`{"blockhash":"h12"}`),
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		mock            mockRPCClient
+		expectedStatus  int
+		expectedBody    string
+		expectedDetails string
+	}{
+		{
+			name:           "Blocks In Order",
+			query:          "?slots=12,10",
+			mock:           mockRPCClient{blocks: blocks},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"blocks":[{"slot":12,"block":{"blockhash":"h12"}},{"slot":10,"block":{"blockhash":"h10"}}]}`,
+		},
+		{
+			name:           "Skipped Slot Reported Inline",
+			query:          "?slots=10,11",
+			mock:           mockRPCClient{blocks: blocks},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"blocks":[{"slot":10,"block":{"blockhash":"h10"}},{"slot":11,"error":"RPC error: -32007 - Slot 11 was skipped"}]}`,
+		},
+		{name: "Transaction Details", query: "?slots=10&transactionDetails=none", mock: mockRPCClient{blocks: blocks}, expectedStatus: http.StatusOK, expectedDetails: "none"},
+		{name: "Missing Slots", query: "", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Slot", query: "?slots=10,abc", expectedStatus: http.StatusBadRequest},
+		{name: "Too Many Slots", query: "?slots=" + strings.Repeat("1,", maxBatchBlocks) + "1", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Transaction Details", query: "?slots=10&transactionDetails=some", expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Error", query: "?slots=10", mock: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/blocks-batch"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetBlocksBatch(&tt.mock, pool, "").ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
+			}
+			if tt.expectedDetails != "" && tt.mock.blockOptions.TransactionDetails != tt.expectedDetails {
+				t.Errorf("Expected transactionDetails %q, got %q", tt.expectedDetails, tt.mock.blockOptions.TransactionDetails)
+			}
+		})
+	}
+}
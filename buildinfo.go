@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// Build metadata, injected at build time via:
+//
+//	go build -ldflags "-X main.version=v1.2.3 -X main.commit=abc123 -X main.buildTime=2024-01-01T00:00:00Z"
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildTime = "unknown"
+)
+
+// startTime records when the process started, for reporting uptime
+var startTime = time.Now()
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version       string `json:"version"`
+	Commit        string `json:"commit"`
+	BuildTime     string `json:"build_time"`
+	GoVersion     string `json:"go_version"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// currentBuildInfo collects the build metadata and the current uptime
+func currentBuildInfo() BuildInfo {
+	return BuildInfo{
+		Version:       version,
+		Commit:        commit,
+		BuildTime:     buildTime,
+		GoVersion:     runtime.Version(),
+		UptimeSeconds: int64(time.Since(startTime).Seconds()),
+	}
+}
+
+func handleBuildInfo(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	jsonData, _ := json.Marshal(currentBuildInfo())
+	w.Write(jsonData)
+}
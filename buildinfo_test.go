@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestHandleBuildInfo(t *testing.T) {
+	req := httptest.NewRequest("GET", "/buildinfo", nil)
+	rr := httptest.NewRecorder()
+	handleBuildInfo(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var info BuildInfo
+	if err := json.Unmarshal(rr.Body.Bytes(), &info); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if info.Version != version || info.Commit != commit || info.BuildTime != buildTime {
+		t.Errorf("unexpected build metadata: got %+v", info)
+	}
+
+	if info.GoVersion != runtime.Version() {
+		t.Errorf("Expected go version %s, got %s", runtime.Version(), info.GoVersion)
+	}
+
+	if info.UptimeSeconds < 0 {
+		t.Errorf("Expected non-negative uptime, got %d", info.UptimeSeconds)
+	}
+}
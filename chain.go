@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by a ChainClient for what the node does not have or the
+// chain does not offer
+var (
+	errBlockNotFound       = errors.New("block not found")
+	errTransactionNotFound = errors.New("transaction not found")
+	errChainUnsupported    = errors.New("not supported on this chain")
+)
+
+// ChainClient is the chain neutral view of a node served under
+// /v1/<chain>/. Blocks are numbered by height on Ethereum and Bitcoin and by
+// slot on Solana, and balances are in the chain's smallest unit.
+type ChainClient interface {
+	name() string
+	latestBlock(ctx context.Context) (uint64, error)
+	block(ctx context.Context, number uint64) (json.RawMessage, error)
+	transaction(ctx context.Context, id string) (json.RawMessage, error)
+	balance(ctx context.Context, address string) (*big.Int, error)
+	validateAddress(address string) error
+	// unit names the smallest unit of the native currency and the decimals
+	// it has
+	unit() (string, int)
+}
+
+// solanaChain adapts a SolanaRPCClient to ChainClient
+type solanaChain struct {
+	client SolanaRPCClient
+}
+
+func (c solanaChain) name() string {
+	return "solana"
+}
+
+func (c solanaChain) latestBlock(ctx context.Context) (uint64, error) {
+	return c.client.getLatestSlot(ctx)
+}
+
+func (c solanaChain) block(ctx context.Context, number uint64) (json.RawMessage, error) {
+	block, err := c.client.getBlockDetails(ctx, number, BlockOptions{MaxSupportedTransactionVersion: new(int)})
+	if slotSkipped(err) || (err == nil && (len(block) == 0 || string(block) == "null")) {
+		return nil, errBlockNotFound
+	}
+	return block, err
+}
+
+func (c solanaChain) transaction(ctx context.Context, id string) (json.RawMessage, error) {
+	transaction, err := c.client.getTransaction(ctx, id, TransactionOptions{Encoding: "json", MaxSupportedTransactionVersion: new(int)})
+	if err == nil && (len(transaction) == 0 || string(transaction) == "null") {
+		return nil, errTransactionNotFound
+	}
+	return transaction, err
+}
+
+func (c solanaChain) balance(ctx context.Context, address string) (*big.Int, error) {
+	lamports, _, err := c.client.getBalance(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetUint64(lamports), nil
+}
+
+func (c solanaChain) validateAddress(address string) error {
+	_, err := decodePubkey(address)
+	return err
+}
+
+func (c solanaChain) unit() (string, int) {
+	return "lamports", 9
+}
+
+// ethereumChain is a ChainClient for an Ethereum JSON-RPC node. It shares
+// the Solana client's transport, so retries, rate limits and metrics apply
+// to it too.
+type ethereumChain struct {
+	client *rpcClient
+}
+
+func (c ethereumChain) name() string {
+	return "ethereum"
+}
+
+// parseQuantity reads a hex encoded JSON-RPC quantity such as "0x1b4"
+func parseQuantity(raw json.RawMessage) (*big.Int, error) {
+	var quantity string
+	if err := json.Unmarshal(raw, &quantity); err != nil {
+		return nil, fmt.Errorf("failed to parse quantity: %w", err)
+	}
+	value, ok := new(big.Int).SetString(strings.TrimPrefix(quantity, "0x"), 16)
+	if !ok || !strings.HasPrefix(quantity, "0x") {
+		return nil, fmt.Errorf("invalid quantity %q", quantity)
+	}
+	return value, nil
+}
+
+func (c ethereumChain) latestBlock(ctx context.Context) (uint64, error) {
+	response, err := c.client.sendRequest(ctx, "eth_blockNumber", nil)
+	if err != nil {
+		return 0, err
+	}
+	number, err := parseQuantity(response.Result)
+	if err != nil {
+		return 0, err
+	}
+	if !number.IsUint64() {
+		return 0, fmt.Errorf("block number %s out of range", number)
+	}
+	return number.Uint64(), nil
+}
+
+// block gets a block with the hashes of its transactions
+func (c ethereumChain) block(ctx context.Context, number uint64) (json.RawMessage, error) {
+	response, err := c.client.sendRequest(ctx, "eth_getBlockByNumber", []interface{}{"0x" + strconv.FormatUint(number, 16), false})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Result) == 0 || string(response.Result) == "null" {
+		return nil, errBlockNotFound
+	}
+	return response.Result, nil
+}
+
+func (c ethereumChain) transaction(ctx context.Context, id string) (json.RawMessage, error) {
+	response, err := c.client.sendRequest(ctx, "eth_getTransactionByHash", []interface{}{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Result) == 0 || string(response.Result) == "null" {
+		return nil, errTransactionNotFound
+	}
+	return response.Result, nil
+}
+
+func (c ethereumChain) balance(ctx context.Context, address string) (*big.Int, error) {
+	response, err := c.client.sendRequest(ctx, "eth_getBalance", []interface{}{address, "latest"})
+	if err != nil {
+		return nil, err
+	}
+	return parseQuantity(response.Result)
+}
+
+func (c ethereumChain) validateAddress(address string) error {
+	digits := strings.TrimPrefix(address, "0x")
+	if _, err := hex.DecodeString(digits); err != nil || len(digits) != 40 || digits == address {
+		return fmt.Errorf("address must be 0x followed by 40 hex digits")
+	}
+	return nil
+}
+
+func (c ethereumChain) unit() (string, int) {
+	return "wei", 18
+}
+
+// chainRoutes lists the /v1/<chain>/ routes of a chain. serve builds each
+// handler on the chain's client, so that the Solana routes can go through
+// the shared caches.
+func chainRoutes(chain string, serve func(build func(ChainClient) http.HandlerFunc) http.HandlerFunc) []apiRoute {
+	prefix := apiVersion + "/" + chain
+	return []apiRoute{
+		{Path: prefix + "/latest-block", Description: "Number of the newest " + chain + " block", handler: serve(handleChainLatestBlock), responses: returning(chainBlockResponse{})},
+		{Path: prefix + "/block", Description: "The " + chain + " block numbered ?number=", handler: serve(handleChainBlock)},
+		{Path: prefix + "/transaction", Description: "The " + chain + " transaction with ?id=", handler: serve(handleChainTransaction)},
+		{Path: prefix + "/balance", Description: "Native balance of ?address= on " + chain + " in its smallest unit", handler: serve(handleChainBalance), responses: returning(chainBalanceResponse{})},
+	}
+}
+
+// chainStatus returns the HTTP status for an error from a ChainClient
+func chainStatus(err error) int {
+	switch {
+	case errors.Is(err, errBlockNotFound), errors.Is(err, errTransactionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errChainUnsupported):
+		return http.StatusNotImplemented
+	}
+	return upstreamStatus(err)
+}
+
+// chainBlockResponse is the response of /v1/<chain>/latest-block
+type chainBlockResponse struct {
+	Chain string `json:"chain"`
+	Block uint64 `json:"block"`
+}
+
+// chainBalanceResponse is the response of /v1/<chain>/balance. The balance
+// is a decimal string, as wei overflow JSON numbers.
+type chainBalanceResponse struct {
+	Chain    string `json:"chain"`
+	Address  string `json:"address"`
+	Balance  string `json:"balance"`
+	Unit     string `json:"unit"`
+	Decimals int    `json:"decimals"`
+}
+
+func handleChainLatestBlock(chain ChainClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		number, err := chain.latestBlock(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(chainBlockResponse{Chain: chain.name(), Block: number})
+		w.Write(jsonData)
+	}
+}
+
+func handleChainBlock(chain ChainClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		value := r.URL.Query().Get("number")
+		if value == "" {
+			http.Error(w, "number parameter is required", http.StatusBadRequest)
+			return
+		}
+		number, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid block number", http.StatusBadRequest)
+			return
+		}
+
+		block, err := chain.block(r.Context(), number)
+		if err != nil {
+			http.Error(w, err.Error(), chainStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(block)
+	}
+}
+
+func handleChainTransaction(chain ChainClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "id parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		transaction, err := chain.transaction(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), chainStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(transaction)
+	}
+}
+
+func handleChainBalance(chain ChainClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			http.Error(w, "address parameter is required", http.StatusBadRequest)
+			return
+		}
+		if err := chain.validateAddress(address); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		balance, err := chain.balance(r.Context(), address)
+		if err != nil {
+			http.Error(w, err.Error(), chainStatus(err))
+			return
+		}
+
+		unit, decimals := chain.unit()
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(chainBalanceResponse{Chain: chain.name(), Address: address, Balance: balance.String(), Unit: unit, Decimals: decimals})
+		w.Write(jsonData)
+	}
+}
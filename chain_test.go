@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newEthereumServer answers eth_blockNumber, eth_getBlockByNumber for block
+// 0x1b4 only, eth_getTransactionByHash for 0xdef only, and eth_getBalance
+// with more wei than fit in a uint64
+func newEthereumServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		result := "null"
+		switch req.Method {
+		case "eth_blockNumber":
+			result = `"0x1b4"`
+		case "eth_getBlockByNumber":
+			if req.Params[0] == "0x1b4" {
+				result = `{"number":"0x1b4","hash":"0xabc","transactions":[]}`
+			}
+		case "eth_getTransactionByHash":
+			if req.Params[0] == "0xdef" {
+				result = `{"hash":"0xdef","blockNumber":"0x1b4"}`
+			}
+		case "eth_getBalance":
+			result = `"0x3635c9adc5dea00000"`
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%s,"id":%d}`, result, req.ID)
+	}))
+}
+
+func TestChainRoutes(t *testing.T) {
+	server := newEthereumServer()
+	defer server.Close()
+	eth := ethereumChain{client: newRPCClient(server.URL)}
+	sol := solanaChain{client: &mockRPCClient{latestSlot: 42, accountInfo: &AccountInfo{Lamports: 1500000000}, blocks: map[uint64]json.RawMessage{42: json.RawMessage(`{"blockhash":"a"}`)}, transactions: map[string]json.RawMessage{"sig": json.RawMessage(`{"slot":42}`)}}}
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		queryParam     string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Ethereum Latest Block", handler: handleChainLatestBlock(eth), expectedStatus: http.StatusOK, expectedBody: `{"chain":"ethereum","block":436}`},
+		{name: "Ethereum Block", handler: handleChainBlock(eth), queryParam: "?number=436", expectedStatus: http.StatusOK, expectedBody: `{"number":"0x1b4","hash":"0xabc","transactions":[]}`},
+		{name: "Ethereum Block Not Found", handler: handleChainBlock(eth), queryParam: "?number=437", expectedStatus: http.StatusNotFound},
+		{name: "Ethereum Transaction", handler: handleChainTransaction(eth), queryParam: "?id=0xdef", expectedStatus: http.StatusOK, expectedBody: `{"hash":"0xdef","blockNumber":"0x1b4"}`},
+		{name: "Ethereum Transaction Not Found", handler: handleChainTransaction(eth), queryParam: "?id=0xabc", expectedStatus: http.StatusNotFound},
+		{name: "Ethereum Balance", handler: handleChainBalance(eth), queryParam: "?address=0x00000000219ab540356cBB839Cbe05303d7705Fa", expectedStatus: http.StatusOK,
+			expectedBody: `{"chain":"ethereum","address":"0x00000000219ab540356cBB839Cbe05303d7705Fa","balance":"1000000000000000000000","unit":"wei","decimals":18}`},
+		{name: "Ethereum Invalid Address", handler: handleChainBalance(eth), queryParam: "?address=00000000219ab540356cBB839Cbe05303d7705Fa", expectedStatus: http.StatusBadRequest},
+		{name: "Solana Latest Block", handler: handleChainLatestBlock(sol), expectedStatus: http.StatusOK, expectedBody: `{"chain":"solana","block":42}`},
+		{name: "Solana Block", handler: handleChainBlock(sol), queryParam: "?number=42", expectedStatus: http.StatusOK, expectedBody: `{"blockhash":"a"}`},
+		{name: "Solana Skipped Slot", handler: handleChainBlock(sol), queryParam: "?number=43", expectedStatus: http.StatusNotFound},
+		{name: "Solana Transaction", handler: handleChainTransaction(sol), queryParam: "?id=sig", expectedStatus: http.StatusOK, expectedBody: `{"slot":42}`},
+		{name: "Solana Transaction Not Found", handler: handleChainTransaction(sol), queryParam: "?id=other", expectedStatus: http.StatusNotFound},
+		{name: "Solana Balance", handler: handleChainBalance(sol), queryParam: "?address=11111111111111111111111111111111", expectedStatus: http.StatusOK,
+			expectedBody: `{"chain":"solana","address":"11111111111111111111111111111111","balance":"1500000000","unit":"lamports","decimals":9}`},
+		{name: "Solana Invalid Address", handler: handleChainBalance(sol), queryParam: "?address=0x00", expectedStatus: http.StatusBadRequest},
+		{name: "Missing Number", handler: handleChainBlock(sol), expectedStatus: http.StatusBadRequest},
+		{name: "Missing Id", handler: handleChainTransaction(sol), expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Number", handler: handleChainBlock(eth), queryParam: "?number=0x1b4", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.handler(rr, httptest.NewRequest("GET", "/v1/chain/route"+tt.queryParam, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+		})
+	}
+
+	routes := chainRoutes("ethereum", func(build func(ChainClient) http.HandlerFunc) http.HandlerFunc { return build(eth) })
+	for i, expected := range []string{"/v1/ethereum/latest-block", "/v1/ethereum/block", "/v1/ethereum/transaction", "/v1/ethereum/balance"} {
+		if routes[i].Path != expected {
+			t.Errorf("Expected route %s, got %s", expected, routes[i].Path)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client rate limit settings
+const (
+	defaultIPBurst     = 20
+	defaultGlobalBurst = 100
+
+	// Buckets are pruned once this many keys have been seen
+	maxLimiterBuckets = 10000
+
+	// globalKey is the single bucket of a limiter shared by all requests
+	globalKey = ""
+)
+
+// keyedLimiter gives every key, such as a tenant or a client address, its
+// own token bucket, holding up to burst requests and refilled at rate
+// requests per second, so that one key spending its quota does not eat into
+// the others'
+type keyedLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	now     func() time.Time
+	buckets map[string]*tokenBucket
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newKeyedLimiter returns a limiter, or nil when rate is not positive and
+// requests are not limited
+func newKeyedLimiter(rate float64, burst int) *keyedLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &keyedLimiter{rate: rate, burst: float64(burst), now: time.Now, buckets: make(map[string]*tokenBucket)}
+}
+
+// allow takes a request from the key's bucket. When the bucket is empty it
+// returns false with the time until the next request is available.
+func (l *keyedLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if len(l.buckets) >= maxLimiterBuckets {
+		l.prune(now)
+	}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// prune drops the buckets that have refilled, which behave like new ones
+func (l *keyedLimiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// retryAfter answers a rate limited request with 429 and a Retry-After of
+// wait rounded up to whole seconds
+func retryAfter(w http.ResponseWriter, wait time.Duration, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, message, http.StatusTooManyRequests)
+}
+
+// clientIP returns the address a request came from. With trustForwarded,
+// the last X-Forwarded-For entry is used, as the one appended by the proxy
+// in front of the service; earlier entries are set by the client and can be
+// forged.
+func clientIP(r *http.Request, trustForwarded bool) string {
+	if trustForwarded {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// withClientRateLimit answers 429 to requests over the rate of their client
+// address, or over the rate of all clients together, so that the proxy
+// cannot be used to exhaust the upstream quota. Either limiter may be nil.
+// The exempt paths are never limited.
+func withClientRateLimit(next http.Handler, perIP, global *keyedLimiter, trustForwarded bool, exempt ...string) http.Handler {
+	if perIP == nil && global == nil {
+		return next
+	}
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if perIP != nil {
+			if allowed, wait := perIP.allow(clientIP(r, trustForwarded)); !allowed {
+				metrics.addCounter("solana_client_rate_limited_requests_total", "Requests rejected by the client rate limits.", 1, "limit", "ip")
+				retryAfter(w, wait, "rate limit exceeded")
+				return
+			}
+		}
+		if global != nil {
+			if allowed, wait := global.allow(globalKey); !allowed {
+				metrics.addCounter("solana_client_rate_limited_requests_total", "Requests rejected by the client rate limits.", 1, "limit", "global")
+				retryAfter(w, wait, "rate limit exceeded")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithClientRateLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	perIP := newKeyedLimiter(1, 2)
+	perIP.now = func() time.Time { return now }
+	global := newKeyedLimiter(10, 4)
+	global.now = func() time.Time { return now }
+	handler := withClientRateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), perIP, global, true, "/readyz")
+
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		forwardedFor   string
+		path           string
+		advance        time.Duration
+		expectedStatus int
+		expectedRetry  string
+	}{
+		{name: "First Of Burst", remoteAddr: "10.0.0.1:1000", path: "/latest-block", expectedStatus: http.StatusOK},
+		{name: "Second Of Burst", remoteAddr: "10.0.0.1:1001", path: "/latest-block", expectedStatus: http.StatusOK},
+		{name: "Over Address Rate", remoteAddr: "10.0.0.1:1002", path: "/latest-block", expectedStatus: http.StatusTooManyRequests, expectedRetry: "1"},
+		{name: "Exempt Path", remoteAddr: "10.0.0.1:1003", path: "/readyz", expectedStatus: http.StatusOK},
+		{name: "Other Address", remoteAddr: "10.0.0.2:1000", path: "/latest-block", expectedStatus: http.StatusOK},
+		{name: "Forwarded Address", remoteAddr: "10.0.0.1:1004", forwardedFor: "10.0.0.1, 10.0.0.3", path: "/latest-block", expectedStatus: http.StatusOK},
+		{name: "Over Global Rate", remoteAddr: "10.0.0.4:1000", path: "/latest-block", expectedStatus: http.StatusTooManyRequests, expectedRetry: "1"},
+		{name: "Refilled", remoteAddr: "10.0.0.1:1005", path: "/latest-block", advance: time.Second, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if got := rr.Header().Get("Retry-After"); got != tt.expectedRetry {
+				t.Errorf("Expected Retry-After %q, got %q", tt.expectedRetry, got)
+			}
+		})
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		forwardedFor   string
+		trustForwarded bool
+		expected       string
+	}{
+		{name: "Remote Address", remoteAddr: "192.0.2.1:5000", expected: "192.0.2.1"},
+		{name: "IPv6 Remote Address", remoteAddr: "[2001:db8::1]:5000", expected: "2001:db8::1"},
+		{name: "Untrusted Forwarded For", remoteAddr: "192.0.2.1:5000", forwardedFor: "198.51.100.7", expected: "192.0.2.1"},
+		{name: "Trusted Forwarded For", remoteAddr: "192.0.2.1:5000", forwardedFor: "203.0.113.9, 198.51.100.7", trustForwarded: true, expected: "198.51.100.7"},
+		{name: "Trusted Without Header", remoteAddr: "192.0.2.1:5000", trustForwarded: true, expected: "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if got := clientIP(req, tt.trustForwarded); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
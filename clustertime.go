@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxBlockTimeLookback bounds how many slots /cluster-time walks back from
+// the latest slot looking for one with a block time
+const maxBlockTimeLookback = 64
+
+// clusterTimeResponse is the response of /cluster-time
+type clusterTimeResponse struct {
+	Slot          uint64 `json:"slot"`
+	BlockTime     int64  `json:"block_time"`
+	LatestSlot    uint64 `json:"latest_slot"`
+	SlotsBehind   uint64 `json:"slots_behind"`
+	EstimatedTime int64  `json:"estimated_time"`
+	ISO8601       string `json:"iso8601"`
+}
+
+// getBlockTime gets the estimated production time of a block as a Unix
+// timestamp, or nil if the cluster has no time for it yet
+func (c *rpcClient) getBlockTime(ctx context.Context, slot uint64) (*int64, error) {
+	response, err := c.sendRequest(ctx, "getBlockTime", []interface{}{slot})
+	if err != nil {
+		return nil, err
+	}
+
+	var blockTime *int64
+	if err := json.Unmarshal(response.Result, &blockTime); err != nil {
+		return nil, fmt.Errorf("failed to parse block time: %w", err)
+	}
+
+	return blockTime, nil
+}
+
+// blockTimeMissing reports whether the upstream answered that a slot has no
+// block to take a time from, either yet or ever
+func blockTimeMissing(err error) bool {
+	var rpcErr *upstreamError
+	if errors.As(err, &rpcErr) && rpcErr.Code == rpcBlockNotAvailable {
+		return true
+	}
+	return slotSkipped(err)
+}
+
+// latestBlockTime walks back from slot to the most recent slot with a block
+// time. The newest slots often have none yet, and skipped slots never do, so
+// both move on to the previous slot; any other error is returned.
+func latestBlockTime(ctx context.Context, client SolanaRPCClient, slot uint64) (uint64, int64, error) {
+	var lastErr error
+	for i := uint64(0); i < maxBlockTimeLookback && i <= slot; i++ {
+		blockTime, err := client.getBlockTime(ctx, slot-i)
+		if blockTimeMissing(err) {
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		if blockTime != nil {
+			return slot - i, *blockTime, nil
+		}
+	}
+	if lastErr != nil {
+		return 0, 0, fmt.Errorf("no block time within %d slots of %d: %w", maxBlockTimeLookback, slot, lastErr)
+	}
+	return 0, 0, fmt.Errorf("no block time within %d slots of %d", maxBlockTimeLookback, slot)
+}
+
+func handleGetClusterTime(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		latest, err := client.getLatestSlot(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		slot, blockTime, err := latestBlockTime(r.Context(), client, latest)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		// Block times have one second resolution, so the estimate only
+		// accounts for slots produced since then at the nominal slot time
+		response := clusterTimeResponse{
+			Slot:        slot,
+			BlockTime:   blockTime,
+			LatestSlot:  latest,
+			SlotsBehind: latest - slot,
+		}
+		estimated := time.Unix(blockTime, 0).Add(time.Duration(response.SlotsBehind) * fallbackSlotTime)
+		response.EstimatedTime = estimated.Unix()
+		response.ISO8601 = estimated.UTC().Format(time.RFC3339)
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetClusterTime(t *testing.T) {
+	tests := []struct {
+		name              string
+		mockClient        mockRPCClient
+		expectedStatus    int
+		expectedSlot      uint64
+		expectedEstimated int64
+	}{
+		{
+			name:              "Latest Slot Has Time",
+			mockClient:        mockRPCClient{latestSlot: 1000, blockTimes: map[uint64]int64{1000: 1700000000, 999: 1699999999}},
+			expectedStatus:    http.StatusOK,
+			expectedSlot:      1000,
+			expectedEstimated: 1700000000,
+		},
+		{
+			name:              "Walks Back To Slot With Time",
+			mockClient:        mockRPCClient{latestSlot: 1000, blockTimes: map[uint64]int64{995: 1700000000}},
+			expectedStatus:    http.StatusOK,
+			expectedSlot:      995,
+			expectedEstimated: 1700000002,
+		},
+		{
+			name: "Walks Past Skipped Slots",
+			mockClient: mockRPCClient{latestSlot: 1000, blockTimes: map[uint64]int64{998: 1700000000}, blockTimeErr: map[uint64]error{
+				1000: &upstreamError{Code: rpcBlockNotAvailable, Message: "Block not available for slot 1000", Status: http.StatusInternalServerError},
+				999:  &upstreamError{Code: rpcSlotSkipped, Message: "Slot 999 was skipped", Status: http.StatusInternalServerError},
+			}},
+			expectedStatus:    http.StatusOK,
+			expectedSlot:      998,
+			expectedEstimated: 1700000000,
+		},
+		{
+			name: "Stops On Other Errors",
+			mockClient: mockRPCClient{latestSlot: 1000, blockTimes: map[uint64]int64{998: 1700000000}, blockTimeErr: map[uint64]error{
+				1000: &upstreamError{Code: -32005, Message: "Node is behind", Status: http.StatusServiceUnavailable},
+			}},
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{name: "No Recent Block Time", mockClient: mockRPCClient{latestSlot: 1000, blockTimes: map[uint64]int64{900: 1700000000}}, expectedStatus: http.StatusInternalServerError},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/cluster-time", nil)
+			rr := httptest.NewRecorder()
+			handleGetClusterTime(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response clusterTimeResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.Slot != tt.expectedSlot || response.SlotsBehind != 1000-tt.expectedSlot {
+				t.Errorf("Expected slot %d, got %d (%d behind)", tt.expectedSlot, response.Slot, response.SlotsBehind)
+			}
+			if response.EstimatedTime != tt.expectedEstimated {
+				t.Errorf("Expected estimated time %d, got %d", tt.expectedEstimated, response.EstimatedTime)
+			}
+		})
+	}
+}
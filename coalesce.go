@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// defaultConfigValues are config fields that the RPC treats the same whether
+// they are set to these values or left out
+var defaultConfigValues = map[string]interface{}{
+	"commitment": "finalized",
+}
+
+// uncoalescedMethods have side effects, so identical calls must each reach
+// the upstream
+var uncoalescedMethods = map[string]bool{
+	"sendTransaction": true,
+	"requestAirdrop":  true,
+}
+
+// normalizeParams renders params as canonical JSON so that equivalent
+// parameter sets compare equal: object keys are sorted, and null fields and
+// fields set to their default value are dropped from config objects.
+func normalizeParams(params json.RawMessage) ([]byte, error) {
+	if len(bytes.TrimSpace(params)) == 0 {
+		return []byte("null"), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to parse params: %w", err)
+	}
+
+	// encoding/json writes map keys in sorted order
+	return json.Marshal(stripDefaults(value))
+}
+
+func stripDefaults(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if field == nil || defaultConfigValues[key] == field {
+				delete(v, key)
+				continue
+			}
+			v[key] = stripDefaults(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = stripDefaults(v[i])
+		}
+	}
+	return value
+}
+
+// requestKey identifies a method call by its normalized params, falling back
+// to the raw params if they cannot be parsed
+func requestKey(method string, params json.RawMessage) string {
+	normalized, err := normalizeParams(params)
+	if err != nil {
+		normalized = params
+	}
+	return method + ":" + string(normalized)
+}
+
+// inflightCall is an upstream call that later identical calls wait on
+type inflightCall struct {
+	done     chan struct{}
+	response *RPCResponse
+	err      error
+}
+
+// callGroup coalesces identical concurrent calls into one upstream request
+type callGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+func newCallGroup() *callGroup {
+	return &callGroup{calls: make(map[string]*inflightCall)}
+}
+
+// do runs fn unless a call with the same key is already in flight, in which
+// case it waits for and shares that call's result
+func (g *callGroup) do(key string, fn func() (*RPCResponse, error)) (*RPCResponse, error, bool) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		return call.response, call.err, true
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.response, call.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+	return call.response, call.err, false
+}
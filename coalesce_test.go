@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestKeyNormalization(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      string
+		wantEqual bool
+	}{
+		{name: "Config Field Order", a: `["abc",{"encoding":"base64","minContextSlot":5}]`, b: `["abc",{"minContextSlot":5,"encoding":"base64"}]`, wantEqual: true},
+		{name: "Default Commitment", a: `["abc",{"commitment":"finalized","encoding":"base64"}]`, b: `["abc",{"encoding":"base64"}]`, wantEqual: true},
+		{name: "Null Field", a: `["abc",{"encoding":"base64","dataSlice":null}]`, b: `["abc",{"encoding":"base64"}]`, wantEqual: true},
+		{name: "Whitespace", a: `[ "abc" , { "encoding" : "base64" } ]`, b: `["abc",{"encoding":"base64"}]`, wantEqual: true},
+		{name: "Large Numbers Kept Exact", a: `[18446744073709551615]`, b: `[18446744073709551614]`, wantEqual: false},
+		{name: "Different Commitment", a: `["abc",{"commitment":"confirmed"}]`, b: `["abc",{}]`, wantEqual: false},
+		{name: "Different Address", a: `["abc",{"encoding":"base64"}]`, b: `["abd",{"encoding":"base64"}]`, wantEqual: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := requestKey("getAccountInfo", json.RawMessage(tt.a))
+			b := requestKey("getAccountInfo", json.RawMessage(tt.b))
+			if (a == b) != tt.wantEqual {
+				t.Errorf("Expected keys equal=%v, got %q and %q", tt.wantEqual, a, b)
+			}
+			if recA, recB := recordingKey("getAccountInfo", json.RawMessage(tt.a)), recordingKey("getAccountInfo", json.RawMessage(tt.b)); (recA == recB) != tt.wantEqual {
+				t.Errorf("Expected recording keys equal=%v, got %q and %q", tt.wantEqual, recA, recB)
+			}
+		})
+	}
+}
+
+func TestSendRequestCoalescesIdenticalCalls(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		expectedHits int32
+	}{
+		{name: "Read Coalesced", method: "getBalance", expectedHits: 1},
+		{name: "Send Not Coalesced", method: "sendTransaction", expectedHits: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			release := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				var req RPCRequest
+				json.NewDecoder(r.Body).Decode(&req)
+				<-release
+				fmt.Fprintf(w, `{"jsonrpc":"2.0","result":"ok","id":%d}`, req.ID)
+			}))
+			defer server.Close()
+
+			client := newRPCClient(server.URL)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 3)
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := client.sendRequest(context.Background(), tt.method, []interface{}{"abc"})
+					errs <- err
+				}()
+			}
+
+			// Give every caller time to reach the upstream or join the
+			// call in flight before it completes
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+			close(errs)
+
+			for err := range errs {
+				if err != nil {
+					t.Errorf("sendRequest returned error: %v", err)
+				}
+			}
+			if got := atomic.LoadInt32(&hits); got != tt.expectedHits {
+				t.Errorf("Expected %d upstream requests, got %d", tt.expectedHits, got)
+			}
+		})
+	}
+}
+
+func TestSendRequestSurvivesCancelledLeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(100 * time.Millisecond):
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":"ok","id":%d}`, req.ID)
+	}))
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error, 1)
+	go func() {
+		_, err := client.sendRequest(ctx, "getBalance", []interface{}{"abc"})
+		leaderErr <- err
+	}()
+
+	// Join the leader's call, then cancel the leader while it is in flight
+	time.Sleep(20 * time.Millisecond)
+	followerErr := make(chan error, 1)
+	go func() {
+		_, err := client.sendRequest(context.Background(), "getBalance", []interface{}{"abc"})
+		followerErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-leaderErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the leader to be cancelled, got %v", err)
+	}
+	if err := <-followerErr; err != nil {
+		t.Errorf("Expected the follower to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// commitmentHeader reports the commitment a response was fetched at
+const commitmentHeader = "X-Commitment"
+
+const (
+	commitmentConfirmed = "confirmed"
+	commitmentFinalized = "finalized"
+)
+
+// chooseCommitment picks the commitment to fetch a slot at. Slots within
+// threshold of the tip may not be rooted yet, so they are read at confirmed
+// commitment; anything older is read at finalized.
+func chooseCommitment(slot, tip, threshold uint64) string {
+	if slot >= tip || tip-slot <= threshold {
+		return commitmentConfirmed
+	}
+	return commitmentFinalized
+}
+
+// commitmentMethods are the upstream methods whose trailing config object
+// accepts a commitment. The value reports whether processed is accepted,
+// which getBlock and getTransaction reject.
+var commitmentMethods = map[string]bool{
+	"getAccountInfo":                    true,
+	"getBalance":                        true,
+	"getBlock":                          false,
+	"getBlocks":                         false,
+	"getEpochInfo":                      true,
+	"getFeeForMessage":                  true,
+	"getLatestBlockhash":                true,
+	"getMinimumBalanceForRentExemption": true,
+	"getMultipleAccounts":               true,
+	"getProgramAccounts":                true,
+	"getSignaturesForAddress":           false,
+	"getSlot":                           true,
+	"getTokenAccountBalance":            true,
+	"getTokenAccountsByOwner":           true,
+	"getTransaction":                    false,
+	"getVoteAccounts":                   true,
+	"isBlockhashValid":                  true,
+	"simulateTransaction":               true,
+}
+
+// errProcessedUnsupported is returned for a request asking for processed
+// commitment from a method that rejects it
+var errProcessedUnsupported = errors.New("processed commitment is not supported")
+
+// withCommitment sets a commitment on a call that accepts one and does not
+// choose its own: the one the request asked for, or else the client's. A
+// configured commitment the method does not accept is passed over, while a
+// requested one fails the call with errProcessedUnsupported rather than be
+// silently ignored. The commitment the call ends up at, which is the node's
+// finalized default when none is set, is noted on ctx for the response
+// header. The config object is copied rather than modified, as callers may
+// share it.
+func (c *rpcClient) withCommitment(ctx context.Context, method string, params []interface{}) ([]interface{}, error) {
+	processed, ok := commitmentMethods[method]
+	if !ok {
+		return params, nil
+	}
+
+	n := len(params)
+	config, hasConfig := map[string]interface{}(nil), false
+	if n > 0 {
+		config, hasConfig = params[n-1].(map[string]interface{})
+	}
+	if chosen, set := config["commitment"]; set {
+		if commitment, ok := chosen.(string); ok {
+			noteCommitment(ctx, commitment)
+		}
+		return params, nil
+	}
+	requested := requestCommitment(ctx)
+	if requested == "processed" && !processed {
+		return nil, fmt.Errorf("%w by %s", errProcessedUnsupported, method)
+	}
+	for _, commitment := range []string{requested, c.commitment} {
+		if commitment == "" || (commitment == "processed" && !processed) {
+			continue
+		}
+
+		noteCommitment(ctx, commitment)
+		if hasConfig {
+			merged := make(map[string]interface{}, len(config)+1)
+			for k, v := range config {
+				merged[k] = v
+			}
+			merged["commitment"] = commitment
+			return append(params[:n-1:n-1], merged), nil
+		}
+		return append(params[:n:n], map[string]interface{}{"commitment": commitment}), nil
+	}
+	noteCommitment(ctx, commitmentFinalized)
+	return params, nil
+}
+
+type requestCommitmentKey struct{}
+
+// requestCommitment returns the commitment the request behind ctx asked for
+// with ?commitment=, if any
+func requestCommitment(ctx context.Context) string {
+	commitment, _ := ctx.Value(requestCommitmentKey{}).(string)
+	return commitment
+}
+
+// withCommitmentParam applies the ?commitment= of a request to the upstream
+// calls made for it, ahead of the configured commitment
+func withCommitmentParam(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		commitment := r.URL.Query().Get("commitment")
+		if commitment == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !commitmentLevels[commitment] {
+			http.Error(w, "commitment must be processed, confirmed or finalized", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestCommitmentKey{}, commitment)))
+	}
+}
+
+type commitmentNoteKey struct{}
+
+// commitmentNote collects the commitment the client applied to the upstream
+// calls made for a request
+type commitmentNote struct {
+	mu    sync.Mutex
+	value string
+}
+
+// noteCommitment records commitment on the request behind ctx, if any
+func noteCommitment(ctx context.Context, commitment string) {
+	if note, ok := ctx.Value(commitmentNoteKey{}).(*commitmentNote); ok {
+		note.mu.Lock()
+		note.value = commitment
+		note.mu.Unlock()
+	}
+}
+
+// capturingCommitment returns a context whose upstream calls note their
+// commitment apart from the request's, and a function reporting it, for
+// caches to store along with what they fetch. A cache hit then notes the
+// stored commitment, as no call is made that would.
+func capturingCommitment(ctx context.Context) (context.Context, func() string) {
+	note := &commitmentNote{}
+	return context.WithValue(ctx, commitmentNoteKey{}, note), func() string {
+		note.mu.Lock()
+		defer note.mu.Unlock()
+		return note.value
+	}
+}
+
+// commitmentWriter reports the noted commitment in the response headers
+// right before they are sent, unless the handler chose its own
+type commitmentWriter struct {
+	http.ResponseWriter
+	note        *commitmentNote
+	wroteHeader bool
+}
+
+func (w *commitmentWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.note.mu.Lock()
+		if w.note.value != "" && w.Header().Get(commitmentHeader) == "" {
+			w.Header().Set(commitmentHeader, w.note.value)
+		}
+		w.note.mu.Unlock()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *commitmentWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// withCommitmentHeader sets X-Commitment on responses whose upstream calls
+// accept a commitment, reporting the one they were made at
+func withCommitmentHeader(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		note := &commitmentNote{}
+		ctx := context.WithValue(r.Context(), commitmentNoteKey{}, note)
+		next.ServeHTTP(&commitmentWriter{ResponseWriter: w, note: note}, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChooseCommitment(t *testing.T) {
+	tests := []struct {
+		name      string
+		slot      uint64
+		tip       uint64
+		threshold uint64
+		expected  string
+	}{
+		{name: "At Tip", slot: 1000, tip: 1000, threshold: 64, expected: commitmentConfirmed},
+		{name: "Ahead Of Tip", slot: 1001, tip: 1000, threshold: 64, expected: commitmentConfirmed},
+		{name: "Within Threshold", slot: 950, tip: 1000, threshold: 64, expected: commitmentConfirmed},
+		{name: "At Threshold", slot: 936, tip: 1000, threshold: 64, expected: commitmentConfirmed},
+		{name: "Past Threshold", slot: 935, tip: 1000, threshold: 64, expected: commitmentFinalized},
+		{name: "Old Slot", slot: 10, tip: 1000, threshold: 64, expected: commitmentFinalized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chooseCommitment(tt.slot, tt.tip, tt.threshold); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHandleGetBlockDetailsAutoCommitment(t *testing.T) {
+	tests := []struct {
+		name                string
+		slot                string
+		autoCommitmentSlots uint64
+		expectedCommitment  string
+	}{
+		{name: "Disabled", slot: "990", autoCommitmentSlots: 0, expectedCommitment: ""},
+		{name: "Recent Slot", slot: "990", autoCommitmentSlots: 64, expectedCommitment: commitmentConfirmed},
+		{name: "Old Slot", slot: "100", autoCommitmentSlots: 64, expectedCommitment: commitmentFinalized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockRPCClient{latestSlot: 1000, blockDetails: []byte(`{"blockhash":"abc"}`)}
+			req := httptest.NewRequest("GET", "/block-details?block="+tt.slot, nil)
+			rr := httptest.NewRecorder()
+			handleGetBlockDetails(mock, tt.autoCommitmentSlots, "").ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, http.StatusOK, rr.Body.String())
+			}
+			if got := rr.Header().Get(commitmentHeader); got != tt.expectedCommitment {
+				t.Errorf("Expected %s header %q, got %q", commitmentHeader, tt.expectedCommitment, got)
+			}
+			if mock.blockOptions.Commitment != tt.expectedCommitment {
+				t.Errorf("Expected getBlock commitment %q, got %q", tt.expectedCommitment, mock.blockOptions.Commitment)
+			}
+		})
+	}
+}
+
+func TestWithCommitment(t *testing.T) {
+	shared := map[string]interface{}{"encoding": "base64"}
+
+	tests := []struct {
+		name        string
+		commitment  string
+		requested   string
+		method      string
+		params      []interface{}
+		expected    string
+		expectError bool
+	}{
+		{name: "Unset", commitment: "", method: "getSlot", params: nil, expected: `null`},
+		{name: "No Params", commitment: "confirmed", method: "getSlot", params: nil, expected: `[{"commitment":"confirmed"}]`},
+		{name: "Appended", commitment: "confirmed", method: "getBlock", params: []interface{}{42}, expected: `[42,{"commitment":"confirmed"}]`},
+		{name: "Merged", commitment: "confirmed", method: "getAccountInfo", params: []interface{}{"abc", shared}, expected: `["abc",{"commitment":"confirmed","encoding":"base64"}]`},
+		{name: "Call Chooses", commitment: "confirmed", method: "getBlock", params: []interface{}{42, map[string]interface{}{"commitment": "finalized"}}, expected: `[42,{"commitment":"finalized"}]`},
+		{name: "No Commitment Accepted", commitment: "confirmed", method: "getBlockTime", params: []interface{}{42}, expected: `[42]`},
+		{name: "Processed Rejected", commitment: "processed", method: "getTransaction", params: []interface{}{"sig", map[string]interface{}{}}, expected: `["sig",{}]`},
+		{name: "Processed Accepted", commitment: "processed", method: "getSlot", params: []interface{}{}, expected: `[{"commitment":"processed"}]`},
+		{name: "Requested", commitment: "finalized", requested: "confirmed", method: "getSlot", params: nil, expected: `[{"commitment":"confirmed"}]`},
+		{name: "Requested Without Default", requested: "processed", method: "getSlot", params: nil, expected: `[{"commitment":"processed"}]`},
+		{name: "Requested Not Accepted", commitment: "confirmed", requested: "processed", method: "getBlock", params: []interface{}{42}, expectError: true},
+		{name: "Requested But Call Chooses", requested: "processed", method: "getBlock", params: []interface{}{42, map[string]interface{}{"commitment": "finalized"}}, expected: `[42,{"commitment":"finalized"}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &rpcClient{commitment: tt.commitment}
+			ctx := context.Background()
+			if tt.requested != "" {
+				ctx = context.WithValue(ctx, requestCommitmentKey{}, tt.requested)
+			}
+			params, err := client.withCommitment(ctx, tt.method, tt.params)
+			if tt.expectError {
+				if !errors.Is(err, errProcessedUnsupported) {
+					t.Errorf("Expected errProcessedUnsupported, got %v", err)
+				}
+				return
+			}
+			got, _ := json.Marshal(params)
+			if string(got) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	if _, set := shared["commitment"]; set {
+		t.Error("withCommitment modified the caller's config")
+	}
+}
+
+func TestCommitmentHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":{"blockhash":"abc","lastValidBlockHeight":1},"id":%d}`, req.ID)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		commitment string
+		handler    func(client SolanaRPCClient) http.HandlerFunc
+		expected   string
+	}{
+		{name: "Configured", commitment: "confirmed", handler: func(client SolanaRPCClient) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				client.getLatestBlockhash(r.Context())
+				w.Write([]byte("ok"))
+			}
+		}, expected: "confirmed"},
+		{name: "Not Accepted", commitment: "processed", handler: func(client SolanaRPCClient) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				client.getTransaction(r.Context(), "sig", TransactionOptions{})
+				w.Write([]byte("ok"))
+			}
+		}, expected: commitmentFinalized},
+		{name: "Node Default", handler: func(client SolanaRPCClient) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				client.getLatestBlockhash(r.Context())
+				w.Write([]byte("ok"))
+			}
+		}, expected: commitmentFinalized},
+		{name: "Call Chooses", commitment: "confirmed", handler: func(client SolanaRPCClient) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				client.getBlockDetails(r.Context(), 1, BlockOptions{Commitment: commitmentFinalized})
+				w.Write([]byte("ok"))
+			}
+		}, expected: commitmentFinalized},
+		{name: "No Commitment", commitment: "confirmed", handler: func(client SolanaRPCClient) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			}
+		}, expected: ""},
+		{name: "Handler Chooses", commitment: "confirmed", handler: func(client SolanaRPCClient) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				client.getLatestBlockhash(r.Context())
+				w.Header().Set(commitmentHeader, commitmentFinalized)
+				w.Write([]byte("ok"))
+			}
+		}, expected: commitmentFinalized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newRPCClient(server.URL)
+			client.commitment = tt.commitment
+
+			rr := httptest.NewRecorder()
+			withCommitmentHeader(tt.handler(client)).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+			if got := rr.Header().Get(commitmentHeader); got != tt.expected {
+				t.Errorf("Expected %s header %q, got %q", commitmentHeader, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithCommitmentParam(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expected       string
+	}{
+		{name: "None", query: "", expectedStatus: http.StatusOK, expected: ""},
+		{name: "Confirmed", query: "?commitment=confirmed", expectedStatus: http.StatusOK, expected: "confirmed"},
+		{name: "Invalid", query: "?commitment=max", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := withCommitmentParam(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = requestCommitment(r.Context())
+			}))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/latest-block"+tt.query, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if seen != tt.expected {
+				t.Errorf("Expected requested commitment %q, got %q", tt.expected, seen)
+			}
+		})
+	}
+}
+
+func TestProcessedCommitmentRejected(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":{"blockhash":"abc"},"id":%d}`, req.ID)
+	}))
+	defer server.Close()
+	client := newRPCClient(server.URL)
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		target         string
+		expectedStatus int
+	}{
+		{name: "Block", handler: handleGetBlockDetails(client, 0, ""), target: "/block-details?block=1&commitment=processed", expectedStatus: http.StatusBadRequest},
+		{name: "Transaction", handler: handleGetTransaction(client, ""), target: "/transaction?signature=sig&commitment=processed", expectedStatus: http.StatusBadRequest},
+		{name: "Block Confirmed", handler: handleGetBlockDetails(client, 0, ""), target: "/block-details?block=1&commitment=confirmed", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&calls, 0)
+			rr := httptest.NewRecorder()
+			withCommitmentParam(tt.handler).ServeHTTP(rr, httptest.NewRequest("GET", tt.target, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if got := atomic.LoadInt32(&calls); tt.expectedStatus == http.StatusBadRequest && got != 0 {
+				t.Errorf("Expected no upstream calls, got %d", got)
+			}
+		})
+	}
+}
+
+func TestCommitmentHeaderOnCacheHits(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		result := `100`
+		if req.Method == "getBlock" {
+			result = `{"blockhash":"abc"}`
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%s,"id":%d}`, result, req.ID)
+	}))
+	defer server.Close()
+
+	upstream := newRPCClient(server.URL)
+	upstream.commitment = commitmentConfirmed
+	slotClient := newLatestSlotCache(time.Minute, 2).wrap(upstream)
+	blockClient := newBlockCache(1<<20).wrap(upstream, commitmentFinalized)
+
+	tests := []struct {
+		name     string
+		call     func(ctx context.Context)
+		expected string
+	}{
+		{name: "Latest Slot", call: func(ctx context.Context) { slotClient.getLatestSlot(ctx) }, expected: commitmentConfirmed},
+		{name: "Block", call: func(ctx context.Context) {
+			blockClient.getBlockDetails(ctx, 1, BlockOptions{Commitment: commitmentFinalized})
+		}, expected: commitmentFinalized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, phase := range []string{"miss", "hit"} {
+				before := atomic.LoadInt32(&calls)
+				rr := httptest.NewRecorder()
+				withCommitmentHeader(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					tt.call(r.Context())
+					w.Write([]byte("ok"))
+				})).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+				if fetched := atomic.LoadInt32(&calls) != before; fetched != (phase == "miss") {
+					t.Fatalf("Expected the %s to fetch %v, got %v", phase, phase == "miss", fetched)
+				}
+				if got := rr.Header().Get(commitmentHeader); got != tt.expected {
+					t.Errorf("Expected %s header %q on a cache %s, got %q", commitmentHeader, tt.expected, phase, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Size and compression settings
+const (
+	// defaultMaxUpstreamResponseBytes bounds the memory a single upstream
+	// response may take. Full blocks run to tens of megabytes.
+	defaultMaxUpstreamResponseBytes = 128 << 20
+	// gzipMinBytes is the smallest body worth compressing
+	gzipMinBytes = 1 << 10
+)
+
+// errResponseTooLarge is returned for upstream responses over the size limit
+var errResponseTooLarge = errors.New("upstream response too large")
+
+// readBody reads a response body of at most limit bytes, or any size when
+// limit is 0. The buffer is sized from the Content-Length up front, so that
+// large blocks are not copied over and over while it grows. Bodies are read
+// whole rather than streamed: results are passed on as raw JSON, shared
+// between coalesced requests and checked for errors before failover decides
+// whether to move on, so the limit is what bounds their memory.
+func readBody(r io.Reader, contentLength, limit int64) ([]byte, error) {
+	if limit > 0 && contentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes, over the limit of %d", errResponseTooLarge, contentLength, limit)
+	}
+	var buf bytes.Buffer
+	if contentLength > 0 {
+		buf.Grow(int(contentLength) + bytes.MinRead)
+	}
+	if limit > 0 {
+		// Read one byte past the limit to tell a body of exactly limit bytes
+		// from a longer one, as compressed bodies have no known length
+		r = io.LimitReader(r, limit+1)
+	}
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	if limit > 0 && int64(buf.Len()) > limit {
+		return nil, fmt.Errorf("%w: over the limit of %d bytes", errResponseTooLarge, limit)
+	}
+	return buf.Bytes(), nil
+}
+
+var gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(nil) }}
+
+// gzipBytes compresses data
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(gz)
+	gz.Reset(&buf)
+	gz.Write(data)
+	gz.Close()
+	return buf.Bytes()
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip, named
+// or through *, and not refused with q=0
+func acceptsGzip(header string) bool {
+	wildcardOK := false
+	for _, coding := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		accepted := true
+		for _, param := range strings.Split(params, ";") {
+			if key, value, ok := strings.Cut(param, "="); ok && strings.TrimSpace(key) == "q" {
+				q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				accepted = err == nil && q > 0
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gzip":
+			return accepted
+		case "*":
+			wildcardOK = accepted
+		}
+	}
+	return wildcardOK
+}
+
+// gzipResponseWriter compresses a response once its first write shows it
+// to be worth compressing. The status is held back until then, as the
+// decision changes the headers.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if !w.decided {
+		w.decide(len(p))
+	}
+	if w.gz != nil {
+		return w.gz.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide compresses the response when the first write is large enough and
+// the handler has not encoded the body itself
+func (w *gzipResponseWriter) decide(n int) {
+	w.decided = true
+	h := w.Header()
+	h.Add("Vary", "Accept-Encoding")
+	if n >= gzipMinBytes && h.Get("Content-Encoding") == "" && w.status != http.StatusNoContent && w.status != http.StatusNotModified {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.decide(0)
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// close sends a status held back for a response without a body, and ends
+// the compressed stream
+func (w *gzipResponseWriter) close() {
+	if !w.decided {
+		w.decide(0)
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+	}
+}
+
+// withGzip compresses responses for clients that accept gzip, and
+// decompresses request bodies sent with Content-Encoding: gzip. Handlers
+// still bound the decompressed size of the bodies they read. Streams are
+// left alone, as compression would hold back their events.
+func withGzip(next http.Handler, streams ...string) http.Handler {
+	streamPaths := make(map[string]bool, len(streams))
+	for _, path := range streams {
+		streamPaths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if streamPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch encoding := strings.ToLower(r.Header.Get("Content-Encoding")); encoding {
+		case "", "identity":
+		case "gzip":
+			body, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, "invalid gzip request body", http.StatusBadRequest)
+				return
+			}
+			defer body.Close()
+			r.Body = body
+			r.Header.Del("Content-Encoding")
+			r.ContentLength = -1
+		default:
+			http.Error(w, "unsupported Content-Encoding "+encoding, http.StatusUnsupportedMediaType)
+			return
+		}
+
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		limit         int64
+		expectedErr   error
+	}{
+		{name: "Unlimited", body: strings.Repeat("a", 100), contentLength: 100},
+		{name: "At Limit", body: strings.Repeat("a", 100), contentLength: 100, limit: 100},
+		{name: "Unknown Length At Limit", body: strings.Repeat("a", 100), contentLength: -1, limit: 100},
+		{name: "Declared Over Limit", body: strings.Repeat("a", 101), contentLength: 101, limit: 100, expectedErr: errResponseTooLarge},
+		{name: "Unknown Length Over Limit", body: strings.Repeat("a", 101), contentLength: -1, limit: 100, expectedErr: errResponseTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := readBody(strings.NewReader(tt.body), tt.contentLength, tt.limit)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if err == nil && string(body) != tt.body {
+				t.Errorf("Expected the body to be read whole, got %d bytes", len(body))
+			}
+		})
+	}
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected bool
+	}{
+		{header: "", expected: false},
+		{header: "gzip", expected: true},
+		{header: "deflate, GZIP;q=0.8", expected: true},
+		{header: "br, *", expected: true},
+		{header: "gzip;q=0", expected: false},
+		{header: "gzip; q=0.0, *", expected: false},
+		{header: "*;q=0, gzip", expected: true},
+		{header: "identity", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := acceptsGzip(tt.header); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithGzip(t *testing.T) {
+	large := strings.Repeat(`{"slot":1}`, gzipMinBytes)
+	handler := withGzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/echo":
+			w.Write(body)
+		case "/small":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("ok"))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(large))
+		}
+	}), "/sse")
+
+	tests := []struct {
+		name             string
+		path             string
+		acceptEncoding   string
+		body             []byte
+		contentEncoding  string
+		expectedStatus   int
+		expectedEncoding string
+		expectedBody     string
+	}{
+		{name: "Compressed", path: "/large", acceptEncoding: "gzip", expectedStatus: http.StatusAccepted, expectedEncoding: "gzip", expectedBody: large},
+		{name: "Not Accepted", path: "/large", expectedStatus: http.StatusAccepted, expectedBody: large},
+		{name: "Too Small", path: "/small", acceptEncoding: "gzip", expectedStatus: http.StatusCreated, expectedBody: "ok"},
+		{name: "No Body", path: "/empty", acceptEncoding: "gzip", expectedStatus: http.StatusNoContent},
+		{name: "Stream", path: "/sse", acceptEncoding: "gzip", expectedStatus: http.StatusAccepted, expectedBody: large},
+		{name: "Gzip Request", path: "/echo", body: gzipBytes([]byte("hello")), contentEncoding: "gzip", expectedStatus: http.StatusOK, expectedBody: "hello"},
+		{name: "Invalid Gzip Request", path: "/echo", body: []byte("hello"), contentEncoding: "gzip", expectedStatus: http.StatusBadRequest, expectedBody: "invalid gzip"},
+		{name: "Unsupported Encoding", path: "/echo", body: []byte("hello"), contentEncoding: "br", expectedStatus: http.StatusUnsupportedMediaType, expectedBody: "unsupported Content-Encoding"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader(tt.body))
+			if tt.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if got := rr.Header().Get("Content-Encoding"); got != tt.expectedEncoding {
+				t.Errorf("Expected Content-Encoding %q, got %q", tt.expectedEncoding, got)
+			}
+			body := rr.Body.Bytes()
+			if tt.expectedEncoding == "gzip" {
+				gz, err := gzip.NewReader(rr.Body)
+				if err != nil {
+					t.Fatalf("Expected a gzip body: %v", err)
+				}
+				body, _ = io.ReadAll(gz)
+			}
+			if !strings.Contains(string(body), tt.expectedBody) {
+				t.Errorf("Expected body containing %.20q, got %.20q", tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestUpstreamResponseLimit(t *testing.T) {
+	requests := 0
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"blockhash":"` + strings.Repeat("a", 2048) + `"}}`))
+	}))
+	defer node.Close()
+
+	client := newRPCClient(node.URL)
+	client.failover = newFailoverClient([]string{node.URL, node.URL})
+	client.maxResponseBytes = 1024
+
+	_, err := client.getLatestSlot(context.Background())
+	if !errors.Is(err, errResponseTooLarge) {
+		t.Fatalf("Expected %v, got %v", errResponseTooLarge, err)
+	}
+	if status := upstreamStatus(err); status != http.StatusBadGateway {
+		t.Errorf("Expected status %d, got %d", http.StatusBadGateway, status)
+	}
+	if requests != 1 {
+		t.Errorf("Expected no failover for an oversized response, got %d requests", requests)
+	}
+}
+
+func TestUpstreamGzipRequests(t *testing.T) {
+	var encodings []string
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":42}`))
+	}))
+	defer node.Close()
+
+	client := newRPCClient(node.URL)
+	client.gzipRequests = true
+	client.getLatestSlot(context.Background())
+	client.postTo(context.Background(), node.URL, bytes.Repeat([]byte(" "), gzipMinBytes))
+
+	if len(encodings) != 2 || encodings[0] != "" || encodings[1] != "gzip" {
+		t.Errorf("Expected only the large request to be compressed, got %q", encodings)
+	}
+}
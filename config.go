@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Environment variables read by newConfig
+const (
+	envRPCURL            = "SOLANA_CLIENT_RPC_URL"
+	envFallbackRPCURLs   = "SOLANA_CLIENT_FALLBACK_RPC_URLS"
+	envListenAddr        = "SOLANA_CLIENT_LISTEN"
+	envRPCTimeout        = "SOLANA_CLIENT_RPC_TIMEOUT"
+	envRequestTimeout    = "SOLANA_CLIENT_REQUEST_TIMEOUT"
+	envMaxRequestTimeout = "SOLANA_CLIENT_MAX_REQUEST_TIMEOUT"
+	envCommitment        = "SOLANA_CLIENT_COMMITMENT"
+)
+
+// commitmentLevels are the commitments accepted by Config.Commitment
+var commitmentLevels = map[string]bool{
+	"processed":         true,
+	commitmentConfirmed: true,
+	commitmentFinalized: true,
+}
+
+// Config is where the server connects and listens. Each setting starts from
+// its built-in default, is overridden by its environment variable, and then
+// by its flag.
+type Config struct {
+	RPCURL            string
+	ListenAddr        string
+	RPCTimeout        time.Duration
+	RequestTimeout    time.Duration
+	MaxRequestTimeout time.Duration
+	// FallbackRPCURLs is a comma-separated list of endpoints tried in order
+	// when RPCURL fails
+	FallbackRPCURLs string
+	// Commitment is applied to every upstream call that accepts one and
+	// does not set its own; empty leaves the node's default, finalized
+	Commitment string
+}
+
+// newConfig returns the defaults overridden by the environment
+func newConfig(getenv func(string) string) (*Config, error) {
+	c := &Config{
+		RPCURL:            solanaRPC,
+		ListenAddr:        httpServerAddr,
+		RPCTimeout:        httpTimeout,
+		RequestTimeout:    defaultRequestTimeout,
+		MaxRequestTimeout: maxRequestTimeout,
+	}
+
+	text := map[string]*string{
+		envRPCURL:          &c.RPCURL,
+		envFallbackRPCURLs: &c.FallbackRPCURLs,
+		envListenAddr:      &c.ListenAddr,
+		envCommitment:      &c.Commitment,
+	}
+	for name, field := range text {
+		if value := getenv(name); value != "" {
+			*field = value
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		envRPCTimeout:        &c.RPCTimeout,
+		envRequestTimeout:    &c.RequestTimeout,
+		envMaxRequestTimeout: &c.MaxRequestTimeout,
+	}
+	for name, field := range durations {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		*field = parsed
+	}
+
+	return c, nil
+}
+
+// bindFlags registers a flag for every setting, defaulting to its current
+// value
+func (c *Config) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.RPCURL, "rpc-url", c.RPCURL, "Solana JSON-RPC endpoint (env "+envRPCURL+")")
+	fs.StringVar(&c.FallbackRPCURLs, "fallback-rpc-urls", c.FallbackRPCURLs, "comma-separated Solana JSON-RPC endpoints to fail over to, in order (env "+envFallbackRPCURLs+")")
+	fs.StringVar(&c.ListenAddr, "listen", c.ListenAddr, "address the API listens on (env "+envListenAddr+")")
+	fs.DurationVar(&c.RPCTimeout, "rpc-timeout", c.RPCTimeout, "timeout of a single upstream HTTP request (env "+envRPCTimeout+")")
+	fs.DurationVar(&c.RequestTimeout, "request-timeout", c.RequestTimeout, "overall time allowed to serve a request, including retries (env "+envRequestTimeout+")")
+	fs.DurationVar(&c.MaxRequestTimeout, "max-request-timeout", c.MaxRequestTimeout, "upper bound for timeouts requested via the X-Request-Timeout header (env "+envMaxRequestTimeout+")")
+	fs.StringVar(&c.Commitment, "commitment", c.Commitment, "commitment for upstream calls that do not choose one: processed, confirmed or finalized (env "+envCommitment+")")
+}
+
+// endpoints returns the RPC URL followed by the fallback URLs
+func (c *Config) endpoints() []string {
+	endpoints := []string{c.RPCURL}
+	for _, fallback := range strings.Split(c.FallbackRPCURLs, ",") {
+		if fallback = strings.TrimSpace(fallback); fallback != "" {
+			endpoints = append(endpoints, fallback)
+		}
+	}
+	return endpoints
+}
+
+// validate checks the settings once flags are parsed
+func (c *Config) validate() error {
+	for _, rpcURL := range c.endpoints() {
+		endpoint, err := url.Parse(rpcURL)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("RPC URL must be an http or https URL, got %q", rpcURL)
+		}
+	}
+	if c.ListenAddr == "" {
+		return fmt.Errorf("listen address is required")
+	}
+	if c.RPCTimeout <= 0 || c.RequestTimeout <= 0 || c.MaxRequestTimeout <= 0 {
+		return fmt.Errorf("timeouts must be positive")
+	}
+	if c.Commitment != "" && !commitmentLevels[c.Commitment] {
+		return fmt.Errorf("unsupported commitment %q", c.Commitment)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigPrecedence(t *testing.T) {
+	env := map[string]string{
+		envRPCURL:         "https://api.devnet.solana.com",
+		envRPCTimeout:     "5s",
+		envCommitment:     "confirmed",
+		envRequestTimeout: "",
+	}
+	config, err := newConfig(func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("newConfig returned error: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	config.bindFlags(fs)
+	if err := fs.Parse([]string{"-listen", ":9000", "-rpc-timeout", "2s"}); err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	expected := Config{
+		RPCURL:            "https://api.devnet.solana.com",
+		ListenAddr:        ":9000",
+		RPCTimeout:        2 * time.Second,
+		RequestTimeout:    defaultRequestTimeout,
+		MaxRequestTimeout: maxRequestTimeout,
+		Commitment:        "confirmed",
+	}
+	if *config != expected {
+		t.Errorf("Expected %+v, got %+v", expected, *config)
+	}
+	if err := config.validate(); err != nil {
+		t.Errorf("validate returned error: %v", err)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "Invalid Duration", env: map[string]string{envRequestTimeout: "soon"}, wantErr: "invalid " + envRequestTimeout},
+		{name: "Invalid URL", env: map[string]string{envRPCURL: "api.devnet.solana.com"}, wantErr: "RPC URL"},
+		{name: "Negative Timeout", env: map[string]string{envRPCTimeout: "-1s"}, wantErr: "timeouts must be positive"},
+		{name: "Invalid Commitment", env: map[string]string{envCommitment: "max"}, wantErr: "unsupported commitment"},
+		{name: "Invalid Fallback URL", env: map[string]string{envFallbackRPCURLs: "https://api.devnet.solana.com, rpc.invalid"}, wantErr: "RPC URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := newConfig(func(name string) string { return tt.env[name] })
+			if err == nil {
+				err = config.validate()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
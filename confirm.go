@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// confirmPollInterval is how often confirmTransaction checks a signature,
+// about one slot
+var confirmPollInterval = 400 * time.Millisecond
+
+// errNotConfirmed is returned when a transaction does not reach the
+// requested commitment in time
+var errNotConfirmed = errors.New("transaction not confirmed in time")
+
+// commitmentRanks orders the commitments from least to most final
+var commitmentRanks = map[string]int{
+	"processed":         1,
+	commitmentConfirmed: 2,
+	commitmentFinalized: 3,
+}
+
+// reached reports whether the status is at least commitment
+func (s *SignatureStatus) reached(commitment string) bool {
+	return s != nil && commitmentRanks[s.ConfirmationStatus] >= commitmentRanks[commitment]
+}
+
+// failed reports whether the transaction was processed with an error
+func (s *SignatureStatus) failed() bool {
+	return s != nil && len(s.Err) > 0 && string(s.Err) != "null"
+}
+
+// confirmTransaction polls the status of signature until it reaches
+// commitment, fails, or timeout passes. The last status seen is returned
+// either way, nil if the node never saw the transaction, along with
+// errNotConfirmed on timeout.
+func confirmTransaction(ctx context.Context, client SolanaRPCClient, signature, commitment string, timeout time.Duration) (*SignatureStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(confirmPollInterval)
+	defer ticker.Stop()
+
+	var status *SignatureStatus
+	for {
+		statuses, err := client.getSignatureStatuses(ctx, []string{signature}, false)
+		if err != nil && ctx.Err() == nil {
+			return status, err
+		}
+		if err == nil {
+			status = statuses[0]
+		}
+		if status.reached(commitment) || status.failed() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, errNotConfirmed
+		case <-ticker.C:
+		}
+	}
+}
+
+// transactionStatusResponse is returned by /transaction/status
+type transactionStatusResponse struct {
+	Signature          string          `json:"signature"`
+	Commitment         string          `json:"commitment"`
+	ConfirmationStatus string          `json:"confirmation_status"`
+	Reached            bool            `json:"reached"`
+	Slot               uint64          `json:"slot,omitempty"`
+	Confirmations      *uint64         `json:"confirmations"`
+	Err                json.RawMessage `json:"err,omitempty"`
+}
+
+// handleGetTransactionStatus reports how far the transaction ?signature= has
+// been confirmed: its confirmation status and the number of blocks confirmed
+// on top of it, which is null once it is rooted. With ?wait=<duration> it
+// waits up to that long for the transaction to reach ?commitment=, confirmed
+// unless given, before answering.
+func handleGetTransactionStatus(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		signature := r.URL.Query().Get("signature")
+		if signature == "" {
+			http.Error(w, "signature parameter is required", http.StatusBadRequest)
+			return
+		}
+		commitment := requestCommitment(r.Context())
+		if commitment == "" {
+			commitment = commitmentConfirmed
+		}
+		var wait time.Duration
+		if value := r.URL.Query().Get("wait"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed < 0 {
+				http.Error(w, "invalid wait value", http.StatusBadRequest)
+				return
+			}
+			wait = parsed
+		}
+
+		var status *SignatureStatus
+		if wait > 0 {
+			var err error
+			status, err = confirmTransaction(r.Context(), client, signature, commitment, wait)
+			if err != nil && !errors.Is(err, errNotConfirmed) {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+		}
+		if status == nil {
+			// The recent status cache only covers the last few minutes, so
+			// look further back for older transactions
+			statuses, err := client.getSignatureStatuses(r.Context(), []string{signature}, true)
+			if err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+			status = statuses[0]
+		}
+
+		response := transactionStatusResponse{Signature: signature, Commitment: commitment, ConfirmationStatus: transactionPending}
+		if status != nil {
+			response.ConfirmationStatus = status.ConfirmationStatus
+			response.Reached = status.reached(commitment)
+			response.Slot = status.Slot
+			response.Confirmations = status.Confirmations
+			if status.failed() {
+				response.Err = status.Err
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// progressingStatusClient reports the next of its statuses on each poll,
+// staying at the last one
+type progressingStatusClient struct {
+	mockRPCClient
+	statuses []*SignatureStatus
+	polls    int
+}
+
+func (c *progressingStatusClient) getSignatureStatuses(ctx context.Context, signatures []string, searchHistory bool) ([]*SignatureStatus, error) {
+	if c.shouldFail {
+		return c.mockRPCClient.getSignatureStatuses(ctx, signatures, searchHistory)
+	}
+	status := c.statuses[len(c.statuses)-1]
+	if c.polls < len(c.statuses) {
+		status = c.statuses[c.polls]
+	}
+	c.polls++
+	return []*SignatureStatus{status}, nil
+}
+
+func TestConfirmTransaction(t *testing.T) {
+	defer func(interval time.Duration) { confirmPollInterval = interval }(confirmPollInterval)
+	confirmPollInterval = time.Millisecond
+
+	two := uint64(2)
+	processed := &SignatureStatus{Slot: 10, Confirmations: &two, ConfirmationStatus: "processed"}
+	confirmed := &SignatureStatus{Slot: 10, Confirmations: &two, ConfirmationStatus: commitmentConfirmed}
+	finalized := &SignatureStatus{Slot: 10, ConfirmationStatus: commitmentFinalized}
+	failed := &SignatureStatus{Slot: 10, Err: json.RawMessage(`{"InstructionError":[0,{"Custom":1}]}`), ConfirmationStatus: "processed"}
+
+	tests := []struct {
+		name           string
+		client         *progressingStatusClient
+		commitment     string
+		expectedStatus *SignatureStatus
+		expectedPolls  int
+		expectedErr    error
+	}{
+		{name: "Already Confirmed", client: &progressingStatusClient{statuses: []*SignatureStatus{confirmed}}, commitment: commitmentConfirmed, expectedStatus: confirmed, expectedPolls: 1},
+		{name: "Finalized Counts As Confirmed", client: &progressingStatusClient{statuses: []*SignatureStatus{finalized}}, commitment: commitmentConfirmed, expectedStatus: finalized, expectedPolls: 1},
+		{name: "Confirmed After Polling", client: &progressingStatusClient{statuses: []*SignatureStatus{nil, processed, confirmed}}, commitment: commitmentConfirmed, expectedStatus: confirmed, expectedPolls: 3},
+		{name: "Failed Transaction Stops", client: &progressingStatusClient{statuses: []*SignatureStatus{failed}}, commitment: commitmentFinalized, expectedStatus: failed, expectedPolls: 1},
+		{name: "Times Out", client: &progressingStatusClient{statuses: []*SignatureStatus{processed}}, commitment: commitmentFinalized, expectedStatus: processed, expectedErr: errNotConfirmed},
+		{name: "Never Seen", client: &progressingStatusClient{statuses: []*SignatureStatus{nil}}, commitment: commitmentConfirmed, expectedErr: errNotConfirmed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := confirmTransaction(context.Background(), tt.client, "sig", tt.commitment, 50*time.Millisecond)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if status != tt.expectedStatus {
+				t.Errorf("Expected status %+v, got %+v", tt.expectedStatus, status)
+			}
+			if tt.expectedPolls != 0 && tt.client.polls != tt.expectedPolls {
+				t.Errorf("Expected %d polls, got %d", tt.expectedPolls, tt.client.polls)
+			}
+		})
+	}
+
+	// Upstream errors end the wait
+	_, err := confirmTransaction(context.Background(), &progressingStatusClient{mockRPCClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}}, "sig", commitmentConfirmed, time.Second)
+	if err == nil || errors.Is(err, errNotConfirmed) {
+		t.Errorf("Expected the upstream error, got %v", err)
+	}
+}
+
+func TestHandleGetTransactionStatus(t *testing.T) {
+	defer func(interval time.Duration) { confirmPollInterval = interval }(confirmPollInterval)
+	confirmPollInterval = time.Millisecond
+
+	three := uint64(3)
+	processed := &SignatureStatus{Slot: 10, Confirmations: &three, ConfirmationStatus: "processed"}
+	confirmed := &SignatureStatus{Slot: 10, Confirmations: &three, ConfirmationStatus: commitmentConfirmed}
+
+	tests := []struct {
+		name                 string
+		client               SolanaRPCClient
+		queryParam           string
+		expectedStatus       int
+		expectedConfirmation string
+		expectedReached      bool
+	}{
+		{name: "Current Status", client: &mockRPCClient{sigStatuses: map[string]*SignatureStatus{"sig": processed}}, queryParam: "?signature=sig", expectedStatus: http.StatusOK, expectedConfirmation: "processed"},
+		{name: "Unknown Signature", client: &mockRPCClient{}, queryParam: "?signature=sig", expectedStatus: http.StatusOK, expectedConfirmation: transactionPending},
+		{name: "Waits For Commitment", client: &progressingStatusClient{statuses: []*SignatureStatus{nil, processed, confirmed}}, queryParam: "?signature=sig&wait=1s", expectedStatus: http.StatusOK, expectedConfirmation: commitmentConfirmed, expectedReached: true},
+		{name: "Wait Times Out", client: &progressingStatusClient{statuses: []*SignatureStatus{processed}}, queryParam: "?signature=sig&wait=20ms&commitment=finalized", expectedStatus: http.StatusOK, expectedConfirmation: "processed"},
+		{name: "Processed Commitment", client: &mockRPCClient{sigStatuses: map[string]*SignatureStatus{"sig": processed}}, queryParam: "?signature=sig&commitment=processed", expectedStatus: http.StatusOK, expectedConfirmation: "processed", expectedReached: true},
+		{name: "Missing Signature", client: &mockRPCClient{}, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Wait", client: &mockRPCClient{}, queryParam: "?signature=sig&wait=soon", expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Error", client: &mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, queryParam: "?signature=sig", expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/transaction/status"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			withCommitmentParam(handleGetTransactionStatus(tt.client)).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var response transactionStatusResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.ConfirmationStatus != tt.expectedConfirmation {
+				t.Errorf("Expected confirmation status %q, got %q", tt.expectedConfirmation, response.ConfirmationStatus)
+			}
+			if response.Reached != tt.expectedReached {
+				t.Errorf("Expected reached %v, got %v", tt.expectedReached, response.Reached)
+			}
+			if response.ConfirmationStatus != transactionPending && (response.Confirmations == nil || *response.Confirmations != 3) {
+				t.Errorf("Expected 3 confirmations, got %v", response.Confirmations)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS settings
+const (
+	defaultCORSMethods = "GET, POST"
+	defaultCORSHeaders = "Authorization, Content-Type, X-API-Key, " + tenantHeader + ", " + requestIDHeader
+	defaultCORSMaxAge  = 10 * time.Minute
+)
+
+// corsExposedHeaders are the response headers browsers let scripts read
+var corsExposedHeaders = strings.Join([]string{requestIDHeader, "X-Quota-Remaining", "Retry-After", "Deprecation", "Link"}, ", ")
+
+// corsPolicy lists the origins browsers may call the API from, and what
+// their preflight requests are allowed
+type corsPolicy struct {
+	anyOrigin bool
+	origins   map[string]bool
+	// wildcards hold the scheme and host suffix of origins such as
+	// https://*.example.com
+	wildcards [][2]string
+	methods   string
+	headers   string
+	maxAge    time.Duration
+}
+
+// newCORSPolicy parses comma-separated lists of origins, methods and
+// headers. An origin of * allows every origin, and a * in place of the
+// leftmost host label allows every subdomain.
+func newCORSPolicy(origins, methods, headers string, maxAge time.Duration) (*corsPolicy, error) {
+	policy := &corsPolicy{origins: make(map[string]bool), maxAge: maxAge}
+	for _, origin := range strings.Split(origins, ",") {
+		origin = strings.TrimSuffix(strings.TrimSpace(origin), "/")
+		switch {
+		case origin == "":
+		case origin == "*":
+			policy.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			scheme, host, ok := strings.Cut(origin, "://*.")
+			if !ok || strings.Contains(host, "*") {
+				return nil, fmt.Errorf("invalid CORS origin %q: only the leftmost host label may be *", origin)
+			}
+			policy.wildcards = append(policy.wildcards, [2]string{scheme + "://", "." + host})
+		case !strings.Contains(origin, "://"):
+			return nil, fmt.Errorf("invalid CORS origin %q: want scheme://host[:port]", origin)
+		default:
+			policy.origins[origin] = true
+		}
+	}
+	policy.methods = canonicalList(methods, strings.ToUpper)
+	policy.headers = canonicalList(headers, http.CanonicalHeaderKey)
+	return policy, nil
+}
+
+// canonicalList normalizes the items of a comma-separated list
+func canonicalList(list string, canonical func(string) string) string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, canonical(item))
+		}
+	}
+	return strings.Join(items, ", ")
+}
+
+// allows reports whether scripts from origin may call the API
+func (p *corsPolicy) allows(origin string) bool {
+	if p.anyOrigin || p.origins[origin] {
+		return true
+	}
+	for _, wildcard := range p.wildcards {
+		if rest, ok := strings.CutPrefix(origin, wildcard[0]); ok && strings.HasSuffix(rest, wildcard[1]) && len(rest) > len(wildcard[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// withCORS adds the CORS headers browsers need to let scripts from the
+// allowed origins read responses, and answers their preflight requests
+// before authentication, which preflights never carry. Requests from other
+// origins are served without the headers, so browsers withhold the
+// response.
+func withCORS(next http.Handler, policy *corsPolicy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		if !policy.allows(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", policy.methods)
+			if policy.headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", policy.headers)
+			}
+			if policy.maxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(policy.maxAge/time.Second)))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Access-Control-Expose-Headers", corsExposedHeaders)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewCORSPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     string
+		expectedErr string
+		allowed     []string
+		refused     []string
+	}{
+		{name: "Exact", origins: "https://app.example.com, http://localhost:3000/", allowed: []string{"https://app.example.com", "http://localhost:3000"}, refused: []string{"https://evil.example.com", "http://app.example.com"}},
+		{name: "Any", origins: "*", allowed: []string{"https://anything.example"}},
+		{name: "Subdomains", origins: "https://*.example.com", allowed: []string{"https://app.example.com", "https://a.b.example.com"}, refused: []string{"https://example.com", "http://app.example.com", "https://app.example.com.evil.net", "https://evilexample.com"}},
+		{name: "Inner Wildcard", origins: "https://app.*.com", expectedErr: "leftmost host label"},
+		{name: "Missing Scheme", origins: "app.example.com", expectedErr: "want scheme://host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := newCORSPolicy(tt.origins, defaultCORSMethods, defaultCORSHeaders, defaultCORSMaxAge)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, origin := range tt.allowed {
+				if !policy.allows(origin) {
+					t.Errorf("Expected %s to be allowed", origin)
+				}
+			}
+			for _, origin := range tt.refused {
+				if policy.allows(origin) {
+					t.Errorf("Expected %s to be refused", origin)
+				}
+			}
+		})
+	}
+}
+
+func TestWithCORS(t *testing.T) {
+	policy, err := newCORSPolicy("https://app.example.com", "get, post", "authorization, x-api-key", 5*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := withCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("served"))
+	}), policy)
+
+	tests := []struct {
+		name            string
+		method          string
+		origin          string
+		preflight       bool
+		expectedStatus  int
+		expectedHeaders map[string]string
+	}{
+		{name: "Same Origin", method: http.MethodGet, expectedStatus: http.StatusOK, expectedHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": ""}},
+		{name: "Allowed Origin", method: http.MethodGet, origin: "https://app.example.com", expectedStatus: http.StatusOK, expectedHeaders: map[string]string{
+			"Access-Control-Allow-Origin":   "https://app.example.com",
+			"Access-Control-Expose-Headers": corsExposedHeaders,
+			"Vary":                          "Origin",
+		}},
+		{name: "Refused Origin", method: http.MethodGet, origin: "https://evil.example.com", expectedStatus: http.StatusOK, expectedHeaders: map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"}},
+		{name: "Preflight", method: http.MethodOptions, origin: "https://app.example.com", preflight: true, expectedStatus: http.StatusNoContent, expectedHeaders: map[string]string{
+			"Access-Control-Allow-Origin":  "https://app.example.com",
+			"Access-Control-Allow-Methods": "GET, POST",
+			"Access-Control-Allow-Headers": "Authorization, X-Api-Key",
+			"Access-Control-Max-Age":       "300",
+		}},
+		{name: "Refused Preflight", method: http.MethodOptions, origin: "https://evil.example.com", preflight: true, expectedStatus: http.StatusOK, expectedHeaders: map[string]string{"Access-Control-Allow-Methods": ""}},
+		{name: "Plain OPTIONS", method: http.MethodOptions, origin: "https://app.example.com", expectedStatus: http.StatusOK, expectedHeaders: map[string]string{"Access-Control-Allow-Methods": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/v1/latest-slot", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			for header, expected := range tt.expectedHeaders {
+				if got := rr.Header().Get(header); got != expected {
+					t.Errorf("Expected %s %q, got %q", header, expected, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// rpcIDHeader carries the JSON-RPC ids exchanged with the upstream while
+// serving a request, formatted as "<request id>:<response id>" pairs
+const rpcIDHeader = "X-RPC-Id"
+
+// rpcIDWriter adds the collected RPC ids to the response headers right before
+// they are sent
+type rpcIDWriter struct {
+	http.ResponseWriter
+	mu          sync.Mutex
+	ids         []string
+	wroteHeader bool
+}
+
+func (w *rpcIDWriter) record(requestID, responseID int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ids = append(w.ids, fmt.Sprintf("%d:%d", requestID, responseID))
+}
+
+func (w *rpcIDWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.mu.Lock()
+		if len(w.ids) > 0 {
+			w.Header().Set(rpcIDHeader, strings.Join(w.ids, ", "))
+		}
+		w.mu.Unlock()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *rpcIDWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// withRPCIDHeader builds the handler per request around a traced copy of the
+// client so that the upstream ids of this request alone end up in the header
+func withRPCIDHeader(client *rpcClient, build func(SolanaRPCClient) http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rw := &rpcIDWriter{ResponseWriter: w}
+		build(client.withTrace(rw.record)).ServeHTTP(rw, r)
+	}
+}
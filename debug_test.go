@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithRPCIDHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":42,"id":%d}`, req.ID)
+	}))
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+	handler := withRPCIDHeader(client, handleGetLatestSlot)
+
+	for _, want := range []string{"1:1", "2:2"} {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/latest-block", nil))
+
+		if got := rr.Header().Get(rpcIDHeader); got != want {
+			t.Errorf("unexpected %s header: got %q want %q", rpcIDHeader, got, want)
+		}
+	}
+}
+
+func TestSendRequestIDMismatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","result":42,"id":7}`))
+	}))
+	defer server.Close()
+
+	var traced []int
+	client := newRPCClient(server.URL).withTrace(func(requestID, responseID int) {
+		traced = append(traced, requestID, responseID)
+	})
+
+	_, err := client.sendRequest(context.Background(), "getSlot", nil)
+	if err == nil || !strings.Contains(err.Error(), "id mismatch") {
+		t.Errorf("Expected id mismatch error, got %v", err)
+	}
+
+	if len(traced) != 2 || traced[0] != 1 || traced[1] != 7 {
+		t.Errorf("Expected trace of [1 7], got %v", traced)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Epoch boundary settings
+const (
+	defaultEpochBoundarySlots = 2160
+	performanceSampleLimit    = 30
+	fallbackSlotTime          = 400 * time.Millisecond
+)
+
+// EpochInfo is the result of getEpochInfo
+type EpochInfo struct {
+	AbsoluteSlot     uint64 `json:"absoluteSlot"`
+	BlockHeight      uint64 `json:"blockHeight"`
+	Epoch            uint64 `json:"epoch"`
+	SlotIndex        uint64 `json:"slotIndex"`
+	SlotsInEpoch     uint64 `json:"slotsInEpoch"`
+	TransactionCount uint64 `json:"transactionCount"`
+}
+
+// PerformanceSample is a single sample from getRecentPerformanceSamples.
+// NumNonVoteTransactions is missing from nodes older than 1.15.
+type PerformanceSample struct {
+	Slot                   uint64  `json:"slot"`
+	NumSlots               uint64  `json:"numSlots"`
+	NumTransactions        uint64  `json:"numTransactions"`
+	NumNonVoteTransactions *uint64 `json:"numNonVoteTransactions"`
+	SamplePeriodSecs       uint64  `json:"samplePeriodSecs"`
+}
+
+// epochBoundaryResponse is the response of /epoch-boundary
+type epochBoundaryResponse struct {
+	Epoch                      uint64 `json:"epoch"`
+	AbsoluteSlot               uint64 `json:"absolute_slot"`
+	SlotIndex                  uint64 `json:"slot_index"`
+	SlotsInEpoch               uint64 `json:"slots_in_epoch"`
+	SlotsRemaining             uint64 `json:"slots_remaining"`
+	ThresholdSlots             uint64 `json:"threshold_slots"`
+	NearBoundary               bool   `json:"near_boundary"`
+	AverageSlotTimeMs          int64  `json:"average_slot_time_ms"`
+	EstimatedSecondsToBoundary int64  `json:"estimated_seconds_to_boundary"`
+	EstimatedBoundaryTime      string `json:"estimated_boundary_time"`
+}
+
+// epochScheduleResponse is the cluster's epoch schedule as served by /epoch
+type epochScheduleResponse struct {
+	SlotsPerEpoch            uint64 `json:"slots_per_epoch"`
+	LeaderScheduleSlotOffset uint64 `json:"leader_schedule_slot_offset"`
+	Warmup                   bool   `json:"warmup"`
+	FirstNormalEpoch         uint64 `json:"first_normal_epoch"`
+	FirstNormalSlot          uint64 `json:"first_normal_slot"`
+}
+
+// epochResponse is the response of /epoch
+type epochResponse struct {
+	Epoch            uint64                `json:"epoch"`
+	AbsoluteSlot     uint64                `json:"absolute_slot"`
+	BlockHeight      uint64                `json:"block_height"`
+	SlotIndex        uint64                `json:"slot_index"`
+	SlotsInEpoch     uint64                `json:"slots_in_epoch"`
+	SlotsRemaining   uint64                `json:"slots_remaining"`
+	FirstSlot        uint64                `json:"first_slot"`
+	LastSlot         uint64                `json:"last_slot"`
+	ProgressPercent  float64               `json:"progress_percent"`
+	TransactionCount uint64                `json:"transaction_count"`
+	Schedule         epochScheduleResponse `json:"schedule"`
+}
+
+// getEpochInfo gets information about the current epoch
+func (c *rpcClient) getEpochInfo(ctx context.Context) (*EpochInfo, error) {
+	response, err := c.sendRequest(ctx, "getEpochInfo", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var info EpochInfo
+	if err := json.Unmarshal(response.Result, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse epoch info: %w", err)
+	}
+
+	return &info, nil
+}
+
+// getRecentPerformanceSamples gets up to limit recent performance samples,
+// each covering roughly a minute of slots
+func (c *rpcClient) getRecentPerformanceSamples(ctx context.Context, limit int) ([]PerformanceSample, error) {
+	response, err := c.sendRequest(ctx, "getRecentPerformanceSamples", []interface{}{limit})
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []PerformanceSample
+	if err := json.Unmarshal(response.Result, &samples); err != nil {
+		return nil, fmt.Errorf("failed to parse performance samples: %w", err)
+	}
+
+	return samples, nil
+}
+
+// averageSlotTime estimates the slot time from performance samples, falling
+// back to the nominal slot time when there are none
+func averageSlotTime(samples []PerformanceSample) time.Duration {
+	var slots, seconds uint64
+	for _, sample := range samples {
+		slots += sample.NumSlots
+		seconds += sample.SamplePeriodSecs
+	}
+	if slots == 0 {
+		return fallbackSlotTime
+	}
+	return time.Duration(seconds) * time.Second / time.Duration(slots)
+}
+
+func handleGetEpochBoundary(client SolanaRPCClient, thresholdSlots uint64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, err := client.getEpochInfo(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		samples, err := client.getRecentPerformanceSamples(r.Context(), performanceSampleLimit)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		var remaining uint64
+		if info.SlotsInEpoch > info.SlotIndex {
+			remaining = info.SlotsInEpoch - info.SlotIndex
+		}
+		slotTime := averageSlotTime(samples)
+		untilBoundary := time.Duration(remaining) * slotTime
+
+		response := epochBoundaryResponse{
+			Epoch:                      info.Epoch,
+			AbsoluteSlot:               info.AbsoluteSlot,
+			SlotIndex:                  info.SlotIndex,
+			SlotsInEpoch:               info.SlotsInEpoch,
+			SlotsRemaining:             remaining,
+			ThresholdSlots:             thresholdSlots,
+			NearBoundary:               remaining <= thresholdSlots,
+			AverageSlotTimeMs:          slotTime.Milliseconds(),
+			EstimatedSecondsToBoundary: int64(untilBoundary.Seconds()),
+			EstimatedBoundaryTime:      time.Now().Add(untilBoundary).UTC().Format(time.RFC3339),
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+func handleGetEpoch(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		info, err := client.getEpochInfo(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		schedule, err := client.getEpochSchedule(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		if info.SlotsInEpoch == 0 || info.SlotIndex > info.AbsoluteSlot {
+			http.Error(w, "inconsistent epoch info from the node", http.StatusInternalServerError)
+			return
+		}
+
+		firstSlot := info.AbsoluteSlot - info.SlotIndex
+		response := epochResponse{
+			Epoch:            info.Epoch,
+			AbsoluteSlot:     info.AbsoluteSlot,
+			BlockHeight:      info.BlockHeight,
+			SlotIndex:        info.SlotIndex,
+			SlotsInEpoch:     info.SlotsInEpoch,
+			FirstSlot:        firstSlot,
+			LastSlot:         firstSlot + info.SlotsInEpoch - 1,
+			ProgressPercent:  float64(info.SlotIndex) * 100 / float64(info.SlotsInEpoch),
+			TransactionCount: info.TransactionCount,
+			Schedule: epochScheduleResponse{
+				SlotsPerEpoch:            schedule.SlotsPerEpoch,
+				LeaderScheduleSlotOffset: schedule.LeaderScheduleSlotOffset,
+				Warmup:                   schedule.Warmup,
+				FirstNormalEpoch:         schedule.FirstNormalEpoch,
+				FirstNormalSlot:          schedule.FirstNormalSlot,
+			},
+		}
+		if info.SlotsInEpoch > info.SlotIndex {
+			response.SlotsRemaining = info.SlotsInEpoch - info.SlotIndex
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
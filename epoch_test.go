@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAverageSlotTime(t *testing.T) {
+	samples := []PerformanceSample{{NumSlots: 150, SamplePeriodSecs: 60}, {NumSlots: 100, SamplePeriodSecs: 60}}
+	if got := averageSlotTime(samples); got != 480*time.Millisecond {
+		t.Errorf("Expected 480ms slot time, got %v", got)
+	}
+
+	if got := averageSlotTime(nil); got != fallbackSlotTime {
+		t.Errorf("Expected fallback slot time without samples, got %v", got)
+	}
+}
+
+func TestHandleGetEpochBoundary(t *testing.T) {
+	samples := []PerformanceSample{{NumSlots: 150, SamplePeriodSecs: 60}}
+
+	tests := []struct {
+		name              string
+		mockClient        mockRPCClient
+		expectedStatus    int
+		expectedRemaining uint64
+		expectedNear      bool
+		expectedSeconds   int64
+	}{
+		{
+			name:              "Near Boundary",
+			mockClient:        mockRPCClient{epochInfo: &EpochInfo{Epoch: 500, SlotIndex: 431000, SlotsInEpoch: 432000}, perfSamples: samples},
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: 1000,
+			expectedNear:      true,
+			expectedSeconds:   400,
+		},
+		{
+			name:              "Mid Epoch",
+			mockClient:        mockRPCClient{epochInfo: &EpochInfo{Epoch: 500, SlotIndex: 1000, SlotsInEpoch: 432000}, perfSamples: samples},
+			expectedStatus:    http.StatusOK,
+			expectedRemaining: 431000,
+			expectedSeconds:   172400,
+		},
+		{
+			name:           "RPC Error",
+			mockClient:     mockRPCClient{shouldFail: true, errorMessage: "RPC connection failed"},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/epoch-boundary", nil)
+			rr := httptest.NewRecorder()
+			handleGetEpochBoundary(&tt.mockClient, 2160).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response epochBoundaryResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.SlotsRemaining != tt.expectedRemaining || response.NearBoundary != tt.expectedNear {
+				t.Errorf("unexpected boundary status: %+v", response)
+			}
+			if response.EstimatedSecondsToBoundary != tt.expectedSeconds {
+				t.Errorf("Expected %d seconds to boundary, got %d", tt.expectedSeconds, response.EstimatedSecondsToBoundary)
+			}
+		})
+	}
+}
+
+func TestHandleGetEpoch(t *testing.T) {
+	schedule := &EpochSchedule{SlotsPerEpoch: 432000, LeaderScheduleSlotOffset: 432000, Warmup: true, FirstNormalEpoch: 14, FirstNormalSlot: 524256}
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		expectedStatus int
+		expected       epochResponse
+	}{
+		{
+			name:           "Mid Epoch",
+			mockClient:     mockRPCClient{epochInfo: &EpochInfo{Epoch: 500, AbsoluteSlot: 216108000, BlockHeight: 200000000, SlotIndex: 108000, SlotsInEpoch: 432000, TransactionCount: 7}, schedule: schedule},
+			expectedStatus: http.StatusOK,
+			expected: epochResponse{
+				Epoch: 500, AbsoluteSlot: 216108000, BlockHeight: 200000000, SlotIndex: 108000, SlotsInEpoch: 432000,
+				SlotsRemaining: 324000, FirstSlot: 216000000, LastSlot: 216431999, ProgressPercent: 25, TransactionCount: 7,
+				Schedule: epochScheduleResponse{SlotsPerEpoch: 432000, LeaderScheduleSlotOffset: 432000, Warmup: true, FirstNormalEpoch: 14, FirstNormalSlot: 524256},
+			},
+		},
+		{
+			name:           "Inconsistent Info",
+			mockClient:     mockRPCClient{epochInfo: &EpochInfo{Epoch: 500}, schedule: schedule},
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{
+			name:           "RPC Error",
+			mockClient:     mockRPCClient{shouldFail: true, errorMessage: "RPC connection failed"},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/epoch", nil)
+			rr := httptest.NewRecorder()
+			handleGetEpoch(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response epochResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+)
+
+// errorMapping teaches the client how a provider signals a condition
+// through its JSON-RPC error objects. A mapping matches an error by code,
+// by a regular expression on its message, or both.
+type errorMapping struct {
+	Code    *int   `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	Status  int    `json:"status"`
+	Retry   bool   `json:"retry"`
+
+	pattern *regexp.Regexp
+}
+
+// upstreamError is a JSON-RPC error returned by the upstream, with the HTTP
+// status it should be answered with and whether it is worth retrying
+type upstreamError struct {
+	Code    int
+	Message string
+	Status  int
+	Retry   bool
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("RPC error: %d - %s", e.Code, e.Message)
+}
+
+// loadErrorMappings reads a JSON array of error mappings, such as
+//
+//	[{"code": -32429, "status": 429, "retry": true},
+//	 {"message": "(?i)invalid api key", "status": 502}]
+func loadErrorMappings(path string) ([]errorMapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read error mappings: %w", err)
+	}
+
+	var mappings []errorMapping
+	if err := json.Unmarshal(data, &mappings); err != nil {
+		return nil, fmt.Errorf("failed to parse error mappings: %w", err)
+	}
+
+	for i := range mappings {
+		m := &mappings[i]
+		if m.Code == nil && m.Message == "" {
+			return nil, fmt.Errorf("error mapping %d matches neither a code nor a message", i)
+		}
+		if m.Status == 0 {
+			m.Status = http.StatusInternalServerError
+		}
+		if m.Status < 400 || m.Status > 599 {
+			return nil, fmt.Errorf("error mapping %d has status %d, want 4xx or 5xx", i, m.Status)
+		}
+		if m.Message != "" {
+			if m.pattern, err = regexp.Compile(m.Message); err != nil {
+				return nil, fmt.Errorf("error mapping %d has an invalid message pattern: %w", i, err)
+			}
+		}
+	}
+	return mappings, nil
+}
+
+func (m *errorMapping) matches(e *RPCError) bool {
+	if m.Code != nil && *m.Code != e.Code {
+		return false
+	}
+	return m.pattern == nil || m.pattern.MatchString(e.Message)
+}
+
+// rpcNodeUnhealthy is the error a node returns while it is behind the cluster
+const rpcNodeUnhealthy = -32005
+
+// rpcError classifies an upstream error with the first matching mapping.
+// Unmapped errors are answered with 500 and not retried, except a node that
+// is behind, which is answered with 503 and retried as it may catch up.
+func (c *rpcClient) rpcError(e *RPCError) *upstreamError {
+	err := &upstreamError{Code: e.Code, Message: e.Message, Status: http.StatusInternalServerError}
+	if e.Code == rpcNodeUnhealthy {
+		err.Status, err.Retry = http.StatusServiceUnavailable, true
+	}
+	for i := range c.errorMappings {
+		if c.errorMappings[i].matches(e) {
+			err.Status, err.Retry = c.errorMappings[i].Status, c.errorMappings[i].Retry
+			break
+		}
+	}
+	return err
+}
+
+// upstreamStatus returns the HTTP status for an error from the client
+func upstreamStatus(err error) int {
+	var rpcErr *upstreamError
+	if errors.As(err, &rpcErr) {
+		return rpcErr.Status
+	}
+	// The request ran out of time or its client went away, was shed to stay
+	// within the upstream budget, or the upstream is known to be down
+	if isContextError(err) || errors.Is(err, errUpstreamBudget) || errors.Is(err, errCircuitOpen) {
+		return http.StatusServiceUnavailable
+	}
+	if errors.Is(err, errResponseTooLarge) {
+		return http.StatusBadGateway
+	}
+	if errors.Is(err, errProcessedUnsupported) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
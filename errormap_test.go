@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeErrorMappings(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "errors.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadErrorMappings(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "Valid", data: `[{"code":-32429,"status":429,"retry":true},{"message":"(?i)invalid api key","status":502}]`},
+		{name: "Default Status", data: `[{"code":-32000}]`},
+		{name: "Matches Nothing", data: `[{"status":429}]`, wantErr: true},
+		{name: "Invalid Status", data: `[{"code":-32000,"status":200}]`, wantErr: true},
+		{name: "Invalid Pattern", data: `[{"message":"(","status":429}]`, wantErr: true},
+		{name: "Invalid JSON", data: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadErrorMappings(writeErrorMappings(t, tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestErrorMappingsApplied(t *testing.T) {
+	mappings, err := loadErrorMappings(writeErrorMappings(t, `[
+		{"code":-32429,"status":429,"retry":true},
+		{"message":"(?i)invalid api key","status":502}
+	]`))
+	if err != nil {
+		t.Fatalf("loadErrorMappings returned error: %v", err)
+	}
+
+	tests := []struct {
+		name             string
+		errors           []string
+		expectedStatus   int
+		expectedAttempts int
+	}{
+		{name: "Throttled Then Served", errors: []string{`{"code":-32429,"message":"rate limited"}`}, expectedStatus: http.StatusOK, expectedAttempts: 2},
+		{name: "Throttled Throughout", errors: []string{`{"code":-32429,"message":"rate limited"}`, `{"code":-32429,"message":"rate limited"}`, `{"code":-32429,"message":"rate limited"}`}, expectedStatus: http.StatusTooManyRequests, expectedAttempts: 3},
+		{name: "Auth Failure", errors: []string{`{"code":-32600,"message":"Invalid API key"}`}, expectedStatus: http.StatusBadGateway, expectedAttempts: 1},
+		{name: "Unmapped", errors: []string{`{"code":-32000,"message":"node is behind"}`}, expectedStatus: http.StatusInternalServerError, expectedAttempts: 1},
+		{name: "Node Behind Then Served", errors: []string{`{"code":-32005,"message":"Node is behind by 42 slots"}`}, expectedStatus: http.StatusOK, expectedAttempts: 2},
+		{name: "Node Behind Throughout", errors: []string{`{"code":-32005,"message":"Node is unhealthy"}`, `{"code":-32005,"message":"Node is unhealthy"}`, `{"code":-32005,"message":"Node is unhealthy"}`}, expectedStatus: http.StatusServiceUnavailable, expectedAttempts: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Retries resend the request under its original id
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if attempts <= len(tt.errors) {
+					fmt.Fprintf(w, `{"jsonrpc":"2.0","error":%s,"id":1}`, tt.errors[attempts-1])
+					return
+				}
+				fmt.Fprint(w, `{"jsonrpc":"2.0","result":42,"id":1}`)
+			}))
+			defer server.Close()
+
+			client := newRPCClient(server.URL)
+			client.errorMappings = mappings
+			client.sleep = func(time.Duration) {}
+
+			req := httptest.NewRequest("GET", "/latest-block", nil)
+			rr := httptest.NewRecorder()
+			handleGetLatestSlot(client).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Failover settings
+const (
+	// An endpoint is skipped once this many requests in a row have failed
+	failoverFailureThreshold = 3
+	// and is tried again once failoverCooldown has passed
+	failoverCooldown = 30 * time.Second
+)
+
+// errCircuitOpen is returned without calling the upstream while every
+// endpoint's circuit breaker is open
+var errCircuitOpen = errors.New("upstream unavailable: circuit breaker open")
+
+// failoverClient picks the endpoints an upstream request is sent to from an
+// ordered list, the primary first. Each endpoint has a circuit breaker that
+// skips it after repeated failures; once its cooldown passes, a single
+// request is let through to find out whether it has recovered.
+type failoverClient struct {
+	endpoints []*failoverEndpoint
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	// failFast refuses requests while every breaker is open, rather than
+	// sending them to the endpoints anyway
+	failFast bool
+	// maxTries caps the endpoints a single request is sent to, so that
+	// requests during an outage fail within a predictable time. Endpoints
+	// skipped by their breaker do not count; 0 tries them all.
+	maxTries int
+
+	mu   sync.Mutex
+	down int
+}
+
+type failoverEndpoint struct {
+	url string
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func newFailoverClient(endpoints []string) *failoverClient {
+	f := &failoverClient{threshold: failoverFailureThreshold, cooldown: failoverCooldown, now: time.Now}
+	for _, url := range endpoints {
+		f.endpoints = append(f.endpoints, &failoverEndpoint{url: url})
+	}
+	return f
+}
+
+// candidates returns the endpoints to try, in order, and whether their
+// breakers are to be ignored. When every breaker is open there are none
+// with failFast, and otherwise they are all returned, to be sent to anyway.
+func (f *failoverClient) candidates() ([]*failoverEndpoint, bool) {
+	now := f.now()
+	candidates := make([]*failoverEndpoint, 0, len(f.endpoints))
+	for _, e := range f.endpoints {
+		if e.available(now) {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 && !f.failFast {
+		return f.endpoints, true
+	}
+	return candidates, false
+}
+
+// available reports whether the endpoint's breaker is closed, or has cooled
+// down with no probe in flight
+func (e *failoverEndpoint) available(now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.openUntil.IsZero() || (!now.Before(e.openUntil) && !e.probing)
+}
+
+// claim is called right before a request is sent to the endpoint. Once an
+// open breaker has cooled down, it lets a single request through as the
+// probe, and refuses the rest until the probe is recorded. Claiming only
+// the endpoints actually sent to leaves the others free to be probed by a
+// later request.
+func (f *failoverClient) claim(e *failoverEndpoint) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.openUntil.IsZero() {
+		return true
+	}
+	if f.now().Before(e.openUntil) || e.probing {
+		return false
+	}
+	e.probing = true
+	breakerTransition("half_open")
+	return true
+}
+
+// record updates the endpoint's breaker with the outcome of a request and
+// reports whether the request should be tried on the next endpoint
+func (f *failoverClient) record(e *failoverEndpoint, err error) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	probed := e.probing
+	e.probing = false
+
+	if err == nil {
+		if !e.openUntil.IsZero() {
+			e.openUntil = time.Time{}
+			f.publish(-1)
+			breakerTransition("closed")
+		}
+		e.failures = 0
+		return false
+	}
+	if !endpointFailed(err) {
+		return false
+	}
+
+	e.failures++
+	if e.failures >= f.threshold {
+		if e.openUntil.IsZero() {
+			f.publish(1)
+		}
+		if e.openUntil.IsZero() || probed {
+			breakerTransition("open")
+		}
+		e.openUntil = f.now().Add(f.cooldown)
+	}
+	return true
+}
+
+// endpointFailed reports whether err shows the endpoint itself to be
+// unhealthy, rather than the request being abandoned or rejected
+func endpointFailed(err error) bool {
+	// Other endpoints would return the same oversized response
+	if isContextError(err) || errors.Is(err, errResponseTooLarge) {
+		return false
+	}
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// publish adjusts the gauge of endpoints whose breaker is open
+func (f *failoverClient) publish(delta int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down += delta
+	metrics.setGauge("solana_client_upstream_endpoints_down", "Upstream endpoints skipped by their circuit breaker.", float64(f.down))
+	if delta > 0 {
+		metrics.addCounter("solana_client_upstream_breaker_opened_total", "Times an upstream endpoint was skipped after repeated failures.", 1)
+	}
+}
+
+// breakerTransition counts a circuit breaker moving to state: open after
+// repeated failures, half_open when a probe is let through, and closed once
+// one succeeds
+func breakerTransition(state string) {
+	metrics.addCounter("solana_client_upstream_breaker_transitions_total", "Upstream circuit breaker state changes by the state entered.", 1, "state", state)
+}
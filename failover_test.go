@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newSlotServer answers getSlot with slot, or with HTTP 503 while down
+func newSlotServer(slot int, down *int32, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if atomic.LoadInt32(down) != 0 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%d,"id":%d}`, slot, req.ID)
+	}))
+}
+
+func TestFailoverClient(t *testing.T) {
+	var primaryDown, primaryCalls, fallbackCalls, fallbackDown int32
+	primary := newSlotServer(1, &primaryDown, &primaryCalls)
+	defer primary.Close()
+	fallback := newSlotServer(2, &fallbackDown, &fallbackCalls)
+	defer fallback.Close()
+
+	now := time.Unix(0, 0)
+	client := newRPCClient(primary.URL)
+	client.maxAttempts = 1
+	client.failover = newFailoverClient([]string{primary.URL, fallback.URL})
+	client.failover.now = func() time.Time { return now }
+
+	tests := []struct {
+		name            string
+		primaryDown     bool
+		advance         time.Duration
+		expectedSlot    uint64
+		expectedPrimary int32
+	}{
+		{name: "Primary Serves", expectedSlot: 1, expectedPrimary: 1},
+		{name: "Fails Over", primaryDown: true, expectedSlot: 2, expectedPrimary: 1},
+		{name: "Fails Over Again", primaryDown: true, expectedSlot: 2, expectedPrimary: 1},
+		{name: "Breaker Opens", primaryDown: true, expectedSlot: 2, expectedPrimary: 1},
+		{name: "Primary Skipped", primaryDown: true, expectedSlot: 2, expectedPrimary: 0},
+		{name: "Primary Recovers After Cooldown", advance: failoverCooldown, expectedSlot: 1, expectedPrimary: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			if tt.primaryDown {
+				atomic.StoreInt32(&primaryDown, 1)
+			} else {
+				atomic.StoreInt32(&primaryDown, 0)
+			}
+			atomic.StoreInt32(&primaryCalls, 0)
+
+			slot, err := client.getLatestSlot(context.Background())
+			if err != nil {
+				t.Fatalf("getLatestSlot returned error: %v", err)
+			}
+			if slot != tt.expectedSlot {
+				t.Errorf("Expected slot %d, got %d", tt.expectedSlot, slot)
+			}
+			if got := atomic.LoadInt32(&primaryCalls); got != tt.expectedPrimary {
+				t.Errorf("Expected %d calls to the primary, got %d", tt.expectedPrimary, got)
+			}
+		})
+	}
+
+	// With every endpoint down, the error of the last one is returned
+	atomic.StoreInt32(&primaryDown, 1)
+	atomic.StoreInt32(&fallbackDown, 1)
+	if _, err := client.getLatestSlot(context.Background()); err == nil {
+		t.Error("Expected error with every endpoint down")
+	}
+}
+
+func TestCircuitBreakerFailFast(t *testing.T) {
+	var down, calls int32 = 1, 0
+	node := newSlotServer(7, &down, &calls)
+	defer node.Close()
+
+	now := time.Unix(0, 0)
+	client := newRPCClient(node.URL)
+	client.maxAttempts = 1
+	client.failover = newFailoverClient([]string{node.URL})
+	client.failover.failFast = true
+	client.failover.now = func() time.Time { return now }
+	transitions := func(state string) float64 {
+		return metrics.value("solana_client_upstream_breaker_transitions_total", "state", state)
+	}
+	opened, halfOpened, closed := transitions("open"), transitions("half_open"), transitions("closed")
+
+	for i := 0; i < failoverFailureThreshold; i++ {
+		if _, err := client.getLatestSlot(context.Background()); err == nil || errors.Is(err, errCircuitOpen) {
+			t.Fatalf("Expected the upstream error before the breaker opens, got %v", err)
+		}
+	}
+	if got := transitions("open") - opened; got != 1 {
+		t.Errorf("Expected the breaker to open once, got %v", got)
+	}
+
+	// While open, requests fail at once without reaching the node
+	atomic.StoreInt32(&calls, 0)
+	_, err := client.getLatestSlot(context.Background())
+	if !errors.Is(err, errCircuitOpen) || upstreamStatus(err) != http.StatusServiceUnavailable {
+		t.Fatalf("Expected %v with status 503, got %v", errCircuitOpen, err)
+	}
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Errorf("Expected no upstream calls while open, got %d", got)
+	}
+
+	// A failed probe opens the breaker again
+	now = now.Add(failoverCooldown)
+	if _, err := client.getLatestSlot(context.Background()); err == nil || errors.Is(err, errCircuitOpen) {
+		t.Fatalf("Expected the probe to reach the node, got %v", err)
+	}
+	if _, err := client.getLatestSlot(context.Background()); !errors.Is(err, errCircuitOpen) {
+		t.Errorf("Expected the breaker open after a failed probe, got %v", err)
+	}
+
+	// A successful probe closes it
+	atomic.StoreInt32(&down, 0)
+	now = now.Add(failoverCooldown)
+	if slot, err := client.getLatestSlot(context.Background()); err != nil || slot != 7 {
+		t.Fatalf("Expected slot 7 from the probe, got %d, %v", slot, err)
+	}
+	if slot, err := client.getLatestSlot(context.Background()); err != nil || slot != 7 {
+		t.Errorf("Expected the breaker closed, got %d, %v", slot, err)
+	}
+
+	if got := transitions("open") - opened; got != 2 {
+		t.Errorf("Expected 2 transitions to open, got %v", got)
+	}
+	if got := transitions("half_open") - halfOpened; got != 2 {
+		t.Errorf("Expected 2 transitions to half_open, got %v", got)
+	}
+	if got := transitions("closed") - closed; got != 1 {
+		t.Errorf("Expected 1 transition to closed, got %v", got)
+	}
+}
+
+func TestFailoverProbesOnlyEndpointsSentTo(t *testing.T) {
+	var primaryDown, primaryCalls, backupDown, backupCalls int32 = 1, 0, 1, 0
+	primary := newSlotServer(1, &primaryDown, &primaryCalls)
+	defer primary.Close()
+	backup := newSlotServer(2, &backupDown, &backupCalls)
+	defer backup.Close()
+
+	now := time.Unix(0, 0)
+	client := newRPCClient(primary.URL)
+	client.maxAttempts = 1
+	client.failover = newFailoverClient([]string{primary.URL, backup.URL})
+	client.failover.threshold = 1
+	client.failover.failFast = true
+	client.failover.now = func() time.Time { return now }
+
+	if _, err := client.getLatestSlot(context.Background()); err == nil {
+		t.Fatal("Expected an error with both endpoints down")
+	}
+
+	// Both recover; the primary's probe serves the request and the backup,
+	// recovered while the primary is healthy, is left unclaimed
+	atomic.StoreInt32(&primaryDown, 0)
+	atomic.StoreInt32(&backupDown, 0)
+	now = now.Add(failoverCooldown)
+	if slot, err := client.getLatestSlot(context.Background()); err != nil || slot != 1 {
+		t.Fatalf("Expected slot 1 from the primary's probe, got %d, %v", slot, err)
+	}
+
+	// The primary fails again, and the backup is probed in its place
+	atomic.StoreInt32(&primaryDown, 1)
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Hour)
+		if slot, err := client.getLatestSlot(context.Background()); err != nil || slot != 2 {
+			t.Fatalf("Expected slot 2 from the backup, got %d, %v", slot, err)
+		}
+	}
+	if got := atomic.LoadInt32(&backupCalls); got != 3 {
+		t.Errorf("Expected 3 calls to the backup, got %d", got)
+	}
+}
+
+func TestFailoverMaxTries(t *testing.T) {
+	var down, calls [3]int32
+	var urls []string
+	for i := range down {
+		down[i] = 1
+		node := newSlotServer(i, &down[i], &calls[i])
+		defer node.Close()
+		urls = append(urls, node.URL)
+	}
+
+	client := newRPCClient(urls[0])
+	client.maxAttempts = 1
+	client.failover = newFailoverClient(urls)
+	client.failover.maxTries = 2
+
+	if _, err := client.getLatestSlot(context.Background()); err == nil {
+		t.Fatal("Expected an error with every endpoint down")
+	}
+	if calls[0] != 1 || calls[1] != 1 || calls[2] != 0 {
+		t.Errorf("Expected only the first 2 endpoints tried, got calls %v", calls)
+	}
+
+	// Endpoints skipped by their breaker do not count against the cap
+	for i := 1; i < failoverFailureThreshold; i++ {
+		client.getLatestSlot(context.Background())
+	}
+	atomic.StoreInt32(&down[2], 0)
+	if slot, err := client.getLatestSlot(context.Background()); err != nil || slot != 2 {
+		t.Errorf("Expected slot 2 from the third endpoint, got %d, %v", slot, err)
+	}
+}
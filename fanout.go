@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// hubStream is a subscription offered by /ws and /sse, chosen with ?stream=
+type hubStream struct {
+	method string
+	event  string
+	params func(url.Values) ([]interface{}, error)
+	format func(json.RawMessage) (json.RawMessage, error)
+}
+
+var hubStreams = map[string]hubStream{
+	"slots": {method: "slotSubscribe", event: "slot", params: func(url.Values) ([]interface{}, error) {
+		return []interface{}{}, nil
+	}},
+	"blocks": {method: "blockSubscribe", event: "block", params: blockSubscribeParams, format: formatBlockNotification},
+}
+
+// subscribeHubStream joins the stream a request chooses. On failure the
+// request has been answered and ok is false.
+func subscribeHubStream(w http.ResponseWriter, r *http.Request, hub *subscriptionHub) (stream hubStream, sub *subscriber, leave func(), ok bool) {
+	stream, ok = hubStreams[r.URL.Query().Get("stream")]
+	if !ok {
+		http.Error(w, "stream must be slots or blocks", http.StatusBadRequest)
+		return stream, nil, nil, false
+	}
+	params, err := stream.params(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return stream, nil, nil, false
+	}
+	sub, leave, err = hub.subscribe(stream.method, params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return stream, nil, nil, false
+	}
+	return stream, sub, leave, true
+}
+
+// handleStreamSSE streams the slots or blocks chosen with ?stream= as
+// server-sent events, sharing one upstream subscription between clients
+func handleStreamSSE(hub *subscriptionHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stream, sub, leave, ok := subscribeHubStream(w, r, hub)
+		if !ok {
+			return
+		}
+		defer leave()
+
+		serveSSE(w, r, sub, stream.event, stream.format)
+	}
+}
+
+// handleStreamWS streams the slots or blocks chosen with ?stream= over a
+// WebSocket, sharing one upstream subscription between clients
+func handleStreamWS(hub *subscriptionHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stream, sub, leave, ok := subscribeHubStream(w, r, hub)
+		if !ok {
+			return
+		}
+		defer leave()
+
+		conn, err := upgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.close()
+
+		serveWS(conn, sub, stream.event, stream.format)
+	}
+}
+
+// wsEvent is a notification sent to WebSocket clients, named like the
+// events of the matching server-sent event stream
+type wsEvent struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// serveWS sends a subscriber's notifications over a WebSocket until the
+// client goes away or the subscription ends, when a final error event says
+// why. A non-nil format rewrites each notification; notifications it
+// rejects are skipped. A client that stops reading fails the write timeout
+// rather than holding the stream.
+func serveWS(conn *wsConn, sub *subscriber, event string, format func(json.RawMessage) (json.RawMessage, error)) {
+	conn.readTimeout, conn.writeTimeout = wsReadTimeout, wsWriteTimeout
+	stop := conn.keepAlive(wsPingInterval)
+	defer stop()
+
+	// Reading answers the client's pings and notices when it leaves
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, err := conn.readMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-gone:
+			return
+		case message, ok := <-sub.messages:
+			if !ok {
+				reason, _ := json.Marshal(sub.closeReason())
+				payload, _ := json.Marshal(wsEvent{Event: "error", Data: reason})
+				conn.writeText(payload)
+				return
+			}
+			if format != nil {
+				formatted, err := format(message)
+				if err != nil {
+					continue
+				}
+				message = formatted
+			}
+			payload, _ := json.Marshal(wsEvent{Event: event, Data: message})
+			if err := conn.writeText(payload); err != nil {
+				return
+			}
+		}
+	}
+}
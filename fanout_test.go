@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHandleStreamWSAndSSE(t *testing.T) {
+	upstream := newFakeSubscriptionServer(t)
+	defer upstream.Close()
+	hub := newSubscriptionHub(wsEndpoint(upstream.URL))
+	sse := httptest.NewServer(handleStreamSSE(hub))
+	defer sse.Close()
+	ws := httptest.NewServer(handleStreamWS(hub))
+	defer ws.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := openStream(t, ctx, sse.URL+"?stream=slots")
+	conn, err := dialWebSocket(wsEndpoint(ws.URL) + "?stream=slots")
+	if err != nil {
+		t.Fatalf("dialWebSocket returned error: %v", err)
+	}
+	defer conn.close()
+
+	// Both clients share the one upstream subscription
+	if subscribe := <-upstream.requests; subscribe.Method != "slotSubscribe" {
+		t.Errorf("Expected slotSubscribe, got %s", subscribe.Method)
+	}
+	waitFor(t, "both clients to subscribe", func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return len(hub.streams) == 1 && len(hub.streams[requestKey("slotSubscribe", json.RawMessage("[]"))].subscribers) == 2
+	})
+
+	upstream.notify <- `{"parent":41,"root":10,"slot":42}`
+	if event, data := readEvent(t, events); event != "slot" || data != `{"parent":41,"root":10,"slot":42}` {
+		t.Errorf("unexpected event %s: %s", event, data)
+	}
+	message, err := conn.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage returned error: %v", err)
+	}
+	if string(message) != `{"event":"slot","data":{"parent":41,"root":10,"slot":42}}` {
+		t.Errorf("unexpected message %s", message)
+	}
+	if n := atomic.LoadInt32(&upstream.dials); n != 1 {
+		t.Errorf("Expected 1 upstream connection, got %d", n)
+	}
+}
+
+func TestHandleStreamValidation(t *testing.T) {
+	hub := newSubscriptionHub("ws://127.0.0.1:0")
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		query          string
+		expectedStatus int
+	}{
+		{name: "SSE Missing Stream", handler: handleStreamSSE(hub), expectedStatus: http.StatusBadRequest},
+		{name: "SSE Unknown Stream", handler: handleStreamSSE(hub), query: "?stream=votes", expectedStatus: http.StatusBadRequest},
+		{name: "WS Invalid Details", handler: handleStreamWS(hub), query: "?stream=blocks&transactionDetails=some", expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Unreachable", handler: handleStreamWS(hub), query: "?stream=slots", expectedStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, httptest.NewRequest("GET", "/ws"+tt.query, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestSubscriptionHubEvictsSlowSubscribers(t *testing.T) {
+	upstream := newFakeSubscriptionServer(t)
+	defer upstream.Close()
+	hub := newSubscriptionHub(wsEndpoint(upstream.URL))
+	hub.bufferSize, hub.maxDrops = 1, 2
+
+	sub, leave, err := hub.subscribe("slotSubscribe", []interface{}{})
+	if err != nil {
+		t.Fatalf("subscribe returned error: %v", err)
+	}
+	defer leave()
+	<-upstream.requests
+
+	// The first notification fits, the next two each displace one
+	for _, n := range []string{"1", "2", "3"} {
+		upstream.notify <- n
+	}
+
+	// With its only subscriber evicted, the upstream subscription ends
+	waitFor(t, "the stream to close", func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return len(hub.streams) == 0
+	})
+
+	var got []string
+	for message := range sub.messages {
+		got = append(got, string(message))
+	}
+	if strings.Join(got, ",") != "3" || !sub.evicted {
+		t.Errorf("Expected eviction after the newest notification 3, got %v (evicted %v)", got, sub.evicted)
+	}
+	if sub.closeReason() != "client fell too far behind" {
+		t.Errorf("unexpected close reason %q", sub.closeReason())
+	}
+}
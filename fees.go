@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Prioritization fee estimation settings
+const (
+	maxFeeAccounts       = 128
+	defaultFeePercentile = 75
+	noFeeSamplesNote     = "no prioritization fee samples returned for the recent slots"
+	priorityFeeUnits     = "micro-lamports per compute unit"
+)
+
+// PrioritizationFee is a per-slot sample from getRecentPrioritizationFees
+type PrioritizationFee struct {
+	Slot              uint64 `json:"slot"`
+	PrioritizationFee uint64 `json:"prioritizationFee"`
+}
+
+// priorityFeeEstimate is the response of /priority-fee-estimate
+type priorityFeeEstimate struct {
+	Percentile  float64  `json:"percentile"`
+	PriorityFee uint64   `json:"priority_fee"`
+	Units       string   `json:"units"`
+	Samples     int      `json:"samples"`
+	Accounts    []string `json:"accounts,omitempty"`
+	Note        string   `json:"note,omitempty"`
+}
+
+// priorityFeeLevels is the response of /fees/priority, suggesting fees at
+// increasing chances of landing a transaction quickly
+type priorityFeeLevels struct {
+	Low       uint64   `json:"p50"`
+	Medium    uint64   `json:"p75"`
+	High      uint64   `json:"p90"`
+	Min       uint64   `json:"min"`
+	Max       uint64   `json:"max"`
+	Units     string   `json:"units"`
+	Samples   int      `json:"samples"`
+	FirstSlot uint64   `json:"first_slot,omitempty"`
+	LastSlot  uint64   `json:"last_slot,omitempty"`
+	Accounts  []string `json:"accounts,omitempty"`
+	Note      string   `json:"note,omitempty"`
+}
+
+// getRecentPrioritizationFees gets the fees paid in recent slots by
+// transactions that lock all of the given accounts as writable
+func (c *rpcClient) getRecentPrioritizationFees(ctx context.Context, accounts []string) ([]PrioritizationFee, error) {
+	var params []interface{}
+	if len(accounts) > 0 {
+		params = []interface{}{accounts}
+	}
+
+	response, err := c.sendRequest(ctx, "getRecentPrioritizationFees", params)
+	if err != nil {
+		return nil, err
+	}
+
+	var fees []PrioritizationFee
+	if err := json.Unmarshal(response.Result, &fees); err != nil {
+		return nil, fmt.Errorf("failed to parse prioritization fees: %w", err)
+	}
+
+	return fees, nil
+}
+
+// feePercentile returns the nearest-rank percentile of the sampled fees
+func feePercentile(fees []PrioritizationFee, percentile float64) uint64 {
+	if len(fees) == 0 {
+		return 0
+	}
+
+	values := make([]uint64, len(fees))
+	for i, fee := range fees {
+		values[i] = fee.PrioritizationFee
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+
+	rank := int(math.Ceil(percentile / 100 * float64(len(values))))
+	if rank < 1 {
+		rank = 1
+	}
+	return values[rank-1]
+}
+
+// parseAccountList splits a comma separated list of accounts
+func parseAccountList(value string) []string {
+	var accounts []string
+	for _, account := range strings.Split(value, ",") {
+		if account = strings.TrimSpace(account); account != "" {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts
+}
+
+func handleGetPriorityFeeEstimate(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accounts := parseAccountList(r.URL.Query().Get("accounts"))
+		if len(accounts) > maxFeeAccounts {
+			http.Error(w, fmt.Sprintf("at most %d accounts are allowed", maxFeeAccounts), http.StatusBadRequest)
+			return
+		}
+
+		percentile := float64(defaultFeePercentile)
+		if value := r.URL.Query().Get("percentile"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || parsed < 0 || parsed > 100 {
+				http.Error(w, "percentile must be between 0 and 100", http.StatusBadRequest)
+				return
+			}
+			percentile = parsed
+		}
+
+		fees, err := client.getRecentPrioritizationFees(r.Context(), accounts)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := priorityFeeEstimate{
+			Percentile:  percentile,
+			PriorityFee: feePercentile(fees, percentile),
+			Units:       priorityFeeUnits,
+			Samples:     len(fees),
+			Accounts:    accounts,
+		}
+		if len(fees) == 0 {
+			response.Note = noFeeSamplesNote
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+// summarizePriorityFees aggregates fee samples into suggested levels
+func summarizePriorityFees(fees []PrioritizationFee, accounts []string) priorityFeeLevels {
+	levels := priorityFeeLevels{
+		Low:      feePercentile(fees, 50),
+		Medium:   feePercentile(fees, 75),
+		High:     feePercentile(fees, 90),
+		Min:      feePercentile(fees, 0),
+		Max:      feePercentile(fees, 100),
+		Units:    priorityFeeUnits,
+		Samples:  len(fees),
+		Accounts: accounts,
+	}
+	if len(fees) == 0 {
+		levels.Note = noFeeSamplesNote
+		return levels
+	}
+	levels.FirstSlot, levels.LastSlot = fees[0].Slot, fees[0].Slot
+	for _, fee := range fees[1:] {
+		if fee.Slot < levels.FirstSlot {
+			levels.FirstSlot = fee.Slot
+		}
+		if fee.Slot > levels.LastSlot {
+			levels.LastSlot = fee.Slot
+		}
+	}
+	return levels
+}
+
+func handleGetPriorityFees(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accounts := parseAccountList(r.URL.Query().Get("accounts"))
+		if len(accounts) > maxFeeAccounts {
+			http.Error(w, fmt.Sprintf("at most %d accounts are allowed", maxFeeAccounts), http.StatusBadRequest)
+			return
+		}
+
+		fees, err := client.getRecentPrioritizationFees(r.Context(), accounts)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(summarizePriorityFees(fees, accounts))
+		w.Write(jsonData)
+	}
+}
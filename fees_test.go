@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFeePercentile(t *testing.T) {
+	var fees []PrioritizationFee
+	for i, fee := range []uint64{0, 500, 100, 10000, 2000, 0, 300, 700, 1500, 50} {
+		fees = append(fees, PrioritizationFee{Slot: uint64(i), PrioritizationFee: fee})
+	}
+
+	tests := []struct {
+		percentile float64
+		expected   uint64
+	}{
+		{percentile: 0, expected: 0},
+		{percentile: 50, expected: 300},
+		{percentile: 75, expected: 1500},
+		{percentile: 90, expected: 2000},
+		{percentile: 100, expected: 10000},
+	}
+
+	for _, tt := range tests {
+		if got := feePercentile(fees, tt.percentile); got != tt.expected {
+			t.Errorf("feePercentile(%v) = %d, want %d", tt.percentile, got, tt.expected)
+		}
+	}
+}
+
+func TestHandleGetPriorityFeeEstimate(t *testing.T) {
+	samples := []PrioritizationFee{{Slot: 1, PrioritizationFee: 100}, {Slot: 2, PrioritizationFee: 200}, {Slot: 3, PrioritizationFee: 300}, {Slot: 4, PrioritizationFee: 400}}
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+		expectedFee    uint64
+		expectNote     bool
+	}{
+		{name: "Default Percentile", mockClient: mockRPCClient{priorityFees: samples}, queryParam: "?accounts=a,b", expectedStatus: http.StatusOK, expectedFee: 300},
+		{name: "Explicit Percentile", mockClient: mockRPCClient{priorityFees: samples}, queryParam: "?percentile=50", expectedStatus: http.StatusOK, expectedFee: 200},
+		{name: "No Samples", mockClient: mockRPCClient{}, queryParam: "?percentile=90", expectedStatus: http.StatusOK, expectNote: true},
+		{name: "Percentile Out Of Range", mockClient: mockRPCClient{}, queryParam: "?percentile=101", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Percentile", mockClient: mockRPCClient{}, queryParam: "?percentile=high", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/priority-fee-estimate"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetPriorityFeeEstimate(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var estimate priorityFeeEstimate
+			if err := json.Unmarshal(rr.Body.Bytes(), &estimate); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if estimate.PriorityFee != tt.expectedFee {
+				t.Errorf("Expected priority fee %d, got %d", tt.expectedFee, estimate.PriorityFee)
+			}
+			if tt.expectNote != (estimate.Note != "") {
+				t.Errorf("unexpected note: %q", estimate.Note)
+			}
+		})
+	}
+}
+
+func TestHandleGetPriorityFees(t *testing.T) {
+	var samples []PrioritizationFee
+	for i, fee := range []uint64{0, 500, 100, 10000, 2000, 0, 300, 700, 1500, 50} {
+		samples = append(samples, PrioritizationFee{Slot: 1000 - uint64(i), PrioritizationFee: fee})
+	}
+	tooMany := strings.TrimSuffix(strings.Repeat("a,", maxFeeAccounts+1), ",")
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+		expected       priorityFeeLevels
+	}{
+		{
+			name:           "Samples",
+			mockClient:     mockRPCClient{priorityFees: samples},
+			queryParam:     "?accounts=a,b",
+			expectedStatus: http.StatusOK,
+			expected:       priorityFeeLevels{Low: 300, Medium: 1500, High: 2000, Max: 10000, Samples: 10, FirstSlot: 991, LastSlot: 1000},
+		},
+		{name: "No Samples", mockClient: mockRPCClient{}, expectedStatus: http.StatusOK, expected: priorityFeeLevels{Note: noFeeSamplesNote}},
+		{name: "Too Many Accounts", mockClient: mockRPCClient{}, queryParam: "?accounts=" + tooMany, expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC connection failed"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/fees/priority"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetPriorityFees(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var levels priorityFeeLevels
+			if err := json.Unmarshal(rr.Body.Bytes(), &levels); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			levels.Units, levels.Accounts = "", nil
+			if !reflect.DeepEqual(levels, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, levels)
+			}
+		})
+	}
+}
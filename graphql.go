@@ -0,0 +1,663 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// GraphQL limits. Every nested field can cost an upstream call, so queries
+// are bounded in depth and in the calls they make.
+const (
+	maxGraphQLBodyBytes = 64 << 10
+	maxGraphQLDepth     = 8
+	maxGraphQLFetches   = 50
+)
+
+// gqlField is a field selected by a query
+type gqlField struct {
+	Alias     string
+	Name      string
+	Arguments map[string]interface{}
+	Selection []*gqlField
+}
+
+// key is the name the field is returned under
+func (f *gqlField) key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// selects reports whether the field's selection includes name
+func (f *gqlField) selects(name string) bool {
+	for _, sub := range f.Selection {
+		if sub.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// gqlVariable is a $name in a query, replaced by its value on execution
+type gqlVariable string
+
+// gqlOperation is a query of a document with its variable defaults
+type gqlOperation struct {
+	Name      string
+	Defaults  map[string]interface{}
+	Selection []*gqlField
+}
+
+// gqlParser reads the query subset of GraphQL: operations with variables,
+// aliases and arguments. Fragments, directives and mutations are rejected.
+type gqlParser struct {
+	src string
+	pos int
+}
+
+// parseGraphQL parses a document and returns its operation named name, or
+// its only operation when name is empty
+func parseGraphQL(src, name string) (*gqlOperation, error) {
+	p := &gqlParser{src: src}
+	var operations []*gqlOperation
+	for p.skip(); p.pos < len(p.src); p.skip() {
+		operation, err := p.operation()
+		if err != nil {
+			return nil, err
+		}
+		operations = append(operations, operation)
+	}
+
+	switch {
+	case len(operations) == 0:
+		return nil, errors.New("query has no operation")
+	case name != "":
+		for _, operation := range operations {
+			if operation.Name == name {
+				return operation, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown operation %q", name)
+	case len(operations) > 1:
+		return nil, errors.New("operationName is required for documents with several operations")
+	}
+	return operations[0], nil
+}
+
+// skip moves past whitespace, commas and comments
+func (p *gqlParser) skip() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *gqlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("syntax error at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// peek reports whether the next token starts with punctuator c
+func (p *gqlParser) peek(c byte) bool {
+	p.skip()
+	return p.pos < len(p.src) && p.src[p.pos] == c
+}
+
+func (p *gqlParser) expect(c byte) error {
+	if !p.peek(c) {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+func isNameByte(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+func (p *gqlParser) name() (string, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.src) && isNameByte(p.src[p.pos], p.pos == start) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a name")
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *gqlParser) operation() (*gqlOperation, error) {
+	operation := &gqlOperation{Defaults: make(map[string]interface{})}
+	if !p.peek('{') {
+		kind, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "query":
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("%s operations are not supported", kind)
+		case "fragment":
+			return nil, errors.New("fragments are not supported")
+		default:
+			return nil, p.errorf("unexpected %q", kind)
+		}
+		if !p.peek('{') && !p.peek('(') {
+			if operation.Name, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek('(') {
+			if err := p.variableDefinitions(operation); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	selection, err := p.selectionSet(1)
+	if err != nil {
+		return nil, err
+	}
+	operation.Selection = selection
+	return operation, nil
+}
+
+// variableDefinitions reads ($name: Type = default, ...). Types are not
+// checked; values are validated by the fields that take them.
+func (p *gqlParser) variableDefinitions(operation *gqlOperation) error {
+	p.pos++
+	for !p.peek(')') {
+		if err := p.expect('$'); err != nil {
+			return err
+		}
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if p.peek('=') {
+			p.pos++
+			value, err := p.value(true)
+			if err != nil {
+				return err
+			}
+			operation.Defaults[name] = value
+		}
+	}
+	p.pos++
+	return nil
+}
+
+// typeRef reads a type such as [String!]!
+func (p *gqlParser) typeRef() error {
+	if p.peek('[') {
+		p.pos++
+		if err := p.typeRef(); err != nil {
+			return err
+		}
+		if err := p.expect(']'); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.peek('!') {
+		p.pos++
+	}
+	return nil
+}
+
+func (p *gqlParser) selectionSet(depth int) ([]*gqlField, error) {
+	if depth > maxGraphQLDepth {
+		return nil, fmt.Errorf("query is nested deeper than %d levels", maxGraphQLDepth)
+	}
+	if err := p.expect('{'); err != nil {
+		return nil, err
+	}
+	var fields []*gqlField
+	for !p.peek('}') {
+		if p.pos >= len(p.src) {
+			return nil, p.errorf("unterminated selection set")
+		}
+		if strings.HasPrefix(p.src[p.pos:], "...") {
+			return nil, errors.New("fragments are not supported")
+		}
+		if p.peek('@') {
+			return nil, errors.New("directives are not supported")
+		}
+		field := &gqlField{}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek(':') {
+			p.pos++
+			field.Alias = name
+			if name, err = p.name(); err != nil {
+				return nil, err
+			}
+		}
+		field.Name = name
+		if p.peek('(') {
+			if field.Arguments, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		if p.peek('@') {
+			return nil, errors.New("directives are not supported")
+		}
+		if p.peek('{') {
+			if field.Selection, err = p.selectionSet(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		fields = append(fields, field)
+	}
+	p.pos++
+	if len(fields) == 0 {
+		return nil, p.errorf("empty selection set")
+	}
+	return fields, nil
+}
+
+func (p *gqlParser) arguments() (map[string]interface{}, error) {
+	p.pos++
+	arguments := make(map[string]interface{})
+	for !p.peek(')') {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(':'); err != nil {
+			return nil, err
+		}
+		if arguments[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	p.pos++
+	return arguments, nil
+}
+
+// value reads an argument value. Numbers are kept as json.Number so that
+// integers such as lamports are not rounded through float64.
+func (p *gqlParser) value(constant bool) (interface{}, error) {
+	p.skip()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("expected a value")
+	}
+	switch c := p.src[p.pos]; {
+	case c == '$':
+		if constant {
+			return nil, p.errorf("variables are not allowed in default values")
+		}
+		p.pos++
+		name, err := p.name()
+		return gqlVariable(name), err
+	case c == '"':
+		return p.stringValue()
+	case c == '-' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.src) && strings.IndexByte("+-.eE0123456789", p.src[p.pos]) >= 0 {
+			p.pos++
+		}
+		if _, err := strconv.ParseFloat(p.src[start:p.pos], 64); err != nil {
+			return nil, p.errorf("invalid number %q", p.src[start:p.pos])
+		}
+		return json.Number(p.src[start:p.pos]), nil
+	case c == '[':
+		p.pos++
+		list := []interface{}{}
+		for !p.peek(']') {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		p.pos++
+		return list, nil
+	case c == '{':
+		p.pos++
+		object := make(map[string]interface{})
+		for !p.peek('}') {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(':'); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.pos++
+		return object, nil
+	}
+
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	// Enum values are passed on as their names
+	return name, nil
+}
+
+// stringValue reads a quoted string, whose escapes match JSON's
+func (p *gqlParser) stringValue() (string, error) {
+	start := p.pos
+	for p.pos++; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos++
+		case '\n':
+			return "", p.errorf("unterminated string")
+		case '"':
+			p.pos++
+			var value string
+			if err := json.Unmarshal([]byte(p.src[start:p.pos]), &value); err != nil {
+				return "", p.errorf("invalid string")
+			}
+			return value, nil
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// gqlObject is a value with fields. resolve returns the value of a field:
+// a scalar that marshals to JSON, a gqlObject, a []gqlObject, or nil.
+type gqlObject interface {
+	typename() string
+	resolve(ctx context.Context, x *gqlExecution, field *gqlField) (interface{}, error)
+}
+
+// errUnknownField is returned by resolvers for fields their type lacks
+var errUnknownField = errors.New("unknown field")
+
+// gqlError is an entry of the errors of a GraphQL response
+type gqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// gqlResult is an object of a response, keeping the order of the fields
+// selected
+type gqlResult struct {
+	keys   []string
+	values []interface{}
+}
+
+func (r *gqlResult) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// gqlExecution is the state of one query. Every field is nullable, so a
+// field that fails resolves to null and records an error.
+type gqlExecution struct {
+	variables map[string]interface{}
+	fetches   int
+	errors    []gqlError
+}
+
+// fetch counts an upstream call, failing once the query has made too many
+func (x *gqlExecution) fetch() error {
+	x.fetches++
+	if x.fetches > maxGraphQLFetches {
+		return fmt.Errorf("query needs more than %d upstream calls", maxGraphQLFetches)
+	}
+	return nil
+}
+
+// argument returns the value of an argument, with variables substituted,
+// or nil when it was not given
+func (x *gqlExecution) argument(field *gqlField, name string) interface{} {
+	return x.substitute(field.Arguments[name])
+}
+
+func (x *gqlExecution) substitute(value interface{}) interface{} {
+	switch v := value.(type) {
+	case gqlVariable:
+		return x.variables[string(v)]
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = x.substitute(item)
+		}
+		return list
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			object[key] = x.substitute(item)
+		}
+		return object
+	}
+	return value
+}
+
+// stringArgument returns a string argument, "" when not given
+func (x *gqlExecution) stringArgument(field *gqlField, name string) (string, error) {
+	switch value := x.argument(field, name).(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	}
+	return "", fmt.Errorf("argument %s must be a string", name)
+}
+
+// uintArgument returns a non-negative integer argument and whether it was
+// given
+func (x *gqlExecution) uintArgument(field *gqlField, name string) (uint64, bool, error) {
+	var text string
+	switch value := x.argument(field, name).(type) {
+	case nil:
+		return 0, false, nil
+	case json.Number:
+		text = value.String()
+	case float64:
+		text = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return 0, false, fmt.Errorf("argument %s must be an integer", name)
+	}
+	parsed, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("argument %s must be a non-negative integer", name)
+	}
+	return parsed, true, nil
+}
+
+// object resolves the selection of an object, recording field errors
+func (x *gqlExecution) object(ctx context.Context, object gqlObject, selection []*gqlField, path []interface{}) *gqlResult {
+	result := &gqlResult{}
+	for _, field := range selection {
+		fieldPath := append(path[:len(path):len(path)], field.key())
+		value, err := x.field(ctx, object, field, fieldPath)
+		if err != nil {
+			x.errors = append(x.errors, gqlError{Message: err.Error(), Path: fieldPath})
+			value = nil
+		}
+		if i := indexOf(result.keys, field.key()); i >= 0 {
+			// Repeated fields are merged by GraphQL; the last one wins here
+			result.values[i] = value
+			continue
+		}
+		result.keys = append(result.keys, field.key())
+		result.values = append(result.values, value)
+	}
+	return result
+}
+
+func indexOf(keys []string, key string) int {
+	for i, k := range keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (x *gqlExecution) field(ctx context.Context, object gqlObject, field *gqlField, path []interface{}) (interface{}, error) {
+	if field.Name == "__typename" {
+		return object.typename(), nil
+	}
+	value, err := object.resolve(ctx, x, field)
+	if errors.Is(err, errUnknownField) {
+		return nil, fmt.Errorf("%s has no field %s", object.typename(), field.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case gqlObject:
+		if len(field.Selection) == 0 {
+			return nil, fmt.Errorf("field %s of type %s needs a selection of subfields", field.Name, v.typename())
+		}
+		return x.object(ctx, v, field.Selection, path), nil
+	case []gqlObject:
+		if len(field.Selection) == 0 {
+			return nil, fmt.Errorf("field %s needs a selection of subfields", field.Name)
+		}
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			if item != nil {
+				list[i] = x.object(ctx, item, field.Selection, append(path[:len(path):len(path)], i))
+			}
+		}
+		return list, nil
+	}
+	if value != nil && len(field.Selection) > 0 {
+		return nil, fmt.Errorf("field %s has no subfields to select", field.Name)
+	}
+	return value, nil
+}
+
+// graphqlRequest is the body of a POST to /graphql
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlResponse is the response of /graphql. Data is left out when the
+// query could not be run at all.
+type graphqlResponse struct {
+	Data   *gqlResult `json:"data,omitempty"`
+	Errors []gqlError `json:"errors,omitempty"`
+}
+
+// readGraphQLRequest reads a query from a POSTed JSON body or from the
+// ?query=, ?operationName= and JSON ?variables= of a GET
+func readGraphQLRequest(w http.ResponseWriter, r *http.Request) (graphqlRequest, error) {
+	var req graphqlRequest
+	if r.Method == http.MethodPost {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxGraphQLBodyBytes))
+		if err != nil {
+			return req, errors.New("request body too large")
+		}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if err := decoder.Decode(&req); err != nil {
+			return req, errors.New("invalid request body")
+		}
+	} else {
+		query := r.URL.Query()
+		req.Query, req.OperationName = query.Get("query"), query.Get("operationName")
+		if value := query.Get("variables"); value != "" {
+			decoder := json.NewDecoder(strings.NewReader(value))
+			decoder.UseNumber()
+			if err := decoder.Decode(&req.Variables); err != nil {
+				return req, errors.New("variables must be a JSON object")
+			}
+		}
+	}
+	if req.Query == "" {
+		return req, errors.New("query is required")
+	}
+	return req, nil
+}
+
+// handleGraphQL runs GraphQL queries against the schema of graphqlQuery.
+// Queries that cannot be parsed are answered with 400; errors in resolving
+// fields are reported alongside the data, with the failed fields null.
+func handleGraphQL(client SolanaRPCClient) http.HandlerFunc {
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		writeError := func(err error) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			jsonData, _ := json.Marshal(graphqlResponse{Errors: []gqlError{{Message: err.Error()}}})
+			w.Write(jsonData)
+		}
+
+		req, err := readGraphQLRequest(w, r)
+		if err != nil {
+			writeError(err)
+			return
+		}
+		operation, err := parseGraphQL(req.Query, req.OperationName)
+		if err != nil {
+			writeError(err)
+			return
+		}
+
+		x := &gqlExecution{variables: make(map[string]interface{})}
+		for name, value := range operation.Defaults {
+			x.variables[name] = value
+		}
+		for name, value := range req.Variables {
+			x.variables[name] = value
+		}
+		data := x.object(r.Context(), graphqlQuery{client: client}, operation.Selection, nil)
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(graphqlResponse{Data: data, Errors: x.errors})
+		w.Write(jsonData)
+	}
+	return byMethod(map[string]http.HandlerFunc{http.MethodGet: serve, http.MethodPost: serve})
+}
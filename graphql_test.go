@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// countingClient counts the block, transaction and account fetches made
+// through it
+type countingClient struct {
+	mockRPCClient
+	calls int
+}
+
+func (c *countingClient) getBlockDetails(ctx context.Context, slot uint64, opts BlockOptions) (json.RawMessage, error) {
+	c.calls++
+	return c.mockRPCClient.getBlockDetails(ctx, slot, opts)
+}
+
+func (c *countingClient) getTransaction(ctx context.Context, signature string, opts TransactionOptions) (json.RawMessage, error) {
+	c.calls++
+	return c.mockRPCClient.getTransaction(ctx, signature, opts)
+}
+
+func (c *countingClient) getAccountInfo(ctx context.Context, address string, opts AccountOptions) (*AccountInfo, error) {
+	c.calls++
+	return c.mockRPCClient.getAccountInfo(ctx, address, opts)
+}
+
+func TestParseGraphQL(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		operationName string
+		expectedErr   string
+		expectedField string
+	}{
+		{name: "Shorthand", query: `{ latestSlot }`, expectedField: "latestSlot"},
+		{name: "Named With Variables", query: "query Q($slot: Int! = 5, $keys: [String!]) {\n  # a comment\n  b: block(slot: $slot) { slot }\n}", expectedField: "block"},
+		{name: "Selects Operation", query: `query A { latestSlot } query B { block(slot: 1) { slot } }`, operationName: "B", expectedField: "block"},
+		{name: "Several Operations", query: `query A { latestSlot } query B { latestSlot }`, expectedErr: "operationName is required"},
+		{name: "Unknown Operation", query: `query A { latestSlot }`, operationName: "B", expectedErr: "unknown operation"},
+		{name: "Mutation", query: `mutation { send }`, expectedErr: "mutation operations are not supported"},
+		{name: "Fragment Spread", query: `{ block(slot: 1) { ...fields } }`, expectedErr: "fragments are not supported"},
+		{name: "Directive", query: `{ latestSlot @skip(if: true) }`, expectedErr: "directives are not supported"},
+		{name: "Unterminated", query: `{ block(slot: 1) { slot }`, expectedErr: "unterminated selection set"},
+		{name: "Unterminated String", query: `{ transaction(signature: "abc) { slot } }`, expectedErr: "unterminated string"},
+		{name: "Empty", query: `  # nothing `, expectedErr: "query has no operation"},
+		{name: "Too Deep", query: `{ a { b { c { d { e { f { g { h { i } } } } } } } } }`, expectedErr: "nested deeper than 8 levels"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operation, err := parseGraphQL(tt.query, tt.operationName)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if operation.Selection[0].Name != tt.expectedField {
+				t.Errorf("Expected field %s, got %s", tt.expectedField, operation.Selection[0].Name)
+			}
+		})
+	}
+
+	operation, _ := parseGraphQL(`query Q($slot: Int = 5) { b: block(slot: $slot, tags: ["a", -1.5, null, {x: true}]) { slot } }`, "")
+	field := operation.Selection[0]
+	if field.Alias != "b" || field.Arguments["slot"] != gqlVariable("slot") || operation.Defaults["slot"] != json.Number("5") {
+		t.Errorf("Unexpected parse: %+v defaults %+v", field, operation.Defaults)
+	}
+	tags, _ := json.Marshal(field.Arguments["tags"])
+	if string(tags) != `["a",-1.5,null,{"x":true}]` {
+		t.Errorf("Unexpected list argument %s", tags)
+	}
+}
+
+func TestHandleGraphQL(t *testing.T) {
+	block := json.RawMessage(`{"blockhash":"bh","previousBlockhash":"pbh","parentSlot":99,"blockTime":1700000000,"blockHeight":90,
+		"transactions":[{"transaction":{"signatures":["sig1"]},"meta":{"fee":5000,"err":null}},{"transaction":{"signatures":["sig2"]},"meta":{"fee":5000,"err":{"InstructionError":[0,"Custom"]}}}],
+		"rewards":[{"pubkey":"11111111111111111111111111111111","lamports":10,"postBalance":20,"rewardType":"Fee"}]}`)
+	transaction := json.RawMessage(`{"slot":100,"blockTime":1700000000,"transaction":{"signatures":["sig1"],"message":{"accountKeys":["11111111111111111111111111111111","Vote111111111111111111111111111111111111111"],
+		"instructions":[{"programIdIndex":1,"accounts":[0],"data":"3Bxs"}]}},"meta":{"fee":5000,"err":null}}`)
+	newClient := func() *countingClient {
+		return &countingClient{mockRPCClient: mockRPCClient{
+			latestSlot:   100,
+			blocks:       map[uint64]json.RawMessage{100: block},
+			transactions: map[string]json.RawMessage{"sig1": transaction},
+			accountInfo:  &AccountInfo{Lamports: 42, Owner: "11111111111111111111111111111111"},
+			signatures:   []SignatureInfo{{Signature: "sig1", Slot: 100}, {Signature: "sig2", Slot: 99}},
+		}}
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		query          string
+		variables      string
+		expectedStatus int
+		expectedBody   string
+		expectedCalls  int
+	}{
+		{name: "Latest Slot", query: `{ latestSlot }`, expectedStatus: http.StatusOK, expectedBody: `{"data":{"latestSlot":100}}`},
+		{name: "Block Without Transactions", query: `{ block(slot: 100) { slot blockhash parentSlot __typename } }`, expectedStatus: http.StatusOK,
+			expectedBody: `{"data":{"block":{"slot":100,"blockhash":"bh","parentSlot":99,"__typename":"Block"}}}`, expectedCalls: 1},
+		{name: "Block Transactions From Summaries", query: `{ block(slot: 100) { transactionCount transactions { signature fee status } } }`, expectedStatus: http.StatusOK,
+			expectedBody: `{"data":{"block":{"transactionCount":2,"transactions":[{"signature":"sig1","fee":5000,"status":"success"},{"signature":"sig2","fee":5000,"status":"failed"}]}}}`, expectedCalls: 1},
+		{name: "Skipped Slot", query: `{ block(slot: 101) { blockhash } }`, expectedStatus: http.StatusOK, expectedBody: `{"data":{"block":null}}`, expectedCalls: 1},
+		{name: "Transaction Graph", query: `query Tx($sig: String!) { transaction(signature: $sig) { slot instructions { program { address } data } accounts { lamports } } }`, variables: `{"sig":"sig1"}`,
+			expectedStatus: http.StatusOK, expectedBody: `{"data":{"transaction":{"slot":100,"instructions":[{"program":{"address":"Vote111111111111111111111111111111111111111"},"data":"3Bxs"}],"accounts":[{"lamports":42},{"lamports":42}]}}}`, expectedCalls: 3},
+		{name: "Unknown Transaction", query: `{ transaction(signature: "nope") { fee } }`, expectedStatus: http.StatusOK, expectedBody: `{"data":{"transaction":null}}`, expectedCalls: 1},
+		{name: "Account History", method: http.MethodGet, query: `{ account(address: "11111111111111111111111111111111") { lamports owner { address } transactions(limit: 1) { signature slot } } }`, expectedStatus: http.StatusOK,
+			expectedBody: `{"data":{"account":{"lamports":42,"owner":{"address":"11111111111111111111111111111111"},"transactions":[{"signature":"sig1","slot":100}]}}}`, expectedCalls: 1},
+		{name: "Field Errors", query: `{ latestSlot block(slot: "x") { slot } nope }`, expectedStatus: http.StatusOK,
+			expectedBody: `{"data":{"latestSlot":100,"block":null,"nope":null},"errors":[{"message":"argument slot must be an integer","path":["block"]},{"message":"Query has no field nope","path":["nope"]}]}`},
+		{name: "Missing Subfields", query: `{ block(slot: 100) }`, expectedStatus: http.StatusOK,
+			expectedBody: `{"data":{"block":null},"errors":[{"message":"field block of type Block needs a selection of subfields","path":["block"]}]}`, expectedCalls: 1},
+		{name: "Syntax Error", query: `{ block(slot: 100) {`, expectedStatus: http.StatusBadRequest},
+		{name: "Missing Query", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient()
+			var req *http.Request
+			if tt.method == http.MethodGet {
+				req = httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(tt.query), nil)
+			} else {
+				body, _ := json.Marshal(map[string]interface{}{"query": tt.query, "variables": json.RawMessage("null")})
+				if tt.variables != "" {
+					body, _ = json.Marshal(map[string]interface{}{"query": tt.query, "variables": json.RawMessage(tt.variables)})
+				}
+				req = httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body)))
+			}
+			rr := httptest.NewRecorder()
+			handleGraphQL(client).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("Expected body %s, got %s", tt.expectedBody, rr.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK && client.calls != tt.expectedCalls {
+				t.Errorf("Expected %d upstream fetches, got %d", tt.expectedCalls, client.calls)
+			}
+		})
+	}
+}
+
+func TestGraphQLFetchLimit(t *testing.T) {
+	client := &countingClient{mockRPCClient: mockRPCClient{accountInfo: &AccountInfo{Lamports: 1}}}
+	var query strings.Builder
+	query.WriteString("{")
+	for i := 0; i <= maxGraphQLFetches; i++ {
+		query.WriteString(` a` + strings.Repeat("x", i) + `: account(address: "11111111111111111111111111111111") { lamports }`)
+	}
+	query.WriteString("}")
+	body, _ := json.Marshal(graphqlRequest{Query: query.String()})
+
+	rr := httptest.NewRecorder()
+	handleGraphQL(client).ServeHTTP(rr, httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body))))
+
+	var response struct {
+		Errors []gqlError `json:"errors"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if client.calls != maxGraphQLFetches {
+		t.Errorf("Expected %d fetches, got %d", maxGraphQLFetches, client.calls)
+	}
+	if len(response.Errors) != 1 || !strings.Contains(response.Errors[0].Message, "upstream calls") {
+		t.Errorf("Expected one fetch limit error, got %+v", response.Errors)
+	}
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// defaultGraphQLHistory is the number of transactions Account.transactions
+// returns unless given a limit
+const defaultGraphQLHistory = 10
+
+// The types below make up the schema served by /graphql:
+//
+//	type Query {
+//	  latestSlot: Int
+//	  block(slot: Int!): Block
+//	  transaction(signature: String!): Transaction
+//	  account(address: String!): Account
+//	}
+//	type Block {
+//	  slot: Int, blockhash: String, previousBlockhash: String
+//	  parentSlot: Int, parent: Block, blockTime: Int, blockHeight: Int
+//	  transactionCount: Int, transactions: [Transaction], rewards: [Reward]
+//	}
+//	type Transaction {
+//	  signature: String, slot: Int, block: Block, blockTime: Int, fee: Int
+//	  status: String, err: JSON, accounts: [Account]
+//	  instructions: [Instruction]
+//	}
+//	type Instruction { program: Account, accounts: [Account], data: String }
+//	type Reward {
+//	  account: Account, lamports: Int, postBalance: Int, rewardType: String
+//	  commission: Int
+//	}
+//	type Account {
+//	  address: String, lamports: Int, owner: Account, executable: Boolean
+//	  rentEpoch: Int, space: Int
+//	  transactions(limit: Int, before: String): [Transaction]
+//	}
+//
+// Objects are fetched when a field needs them, so a query only costs the
+// upstream calls for the data it selects.
+type graphqlQuery struct {
+	client SolanaRPCClient
+}
+
+func (q graphqlQuery) typename() string {
+	return "Query"
+}
+
+func (q graphqlQuery) resolve(ctx context.Context, x *gqlExecution, field *gqlField) (interface{}, error) {
+	switch field.Name {
+	case "latestSlot":
+		if err := x.fetch(); err != nil {
+			return nil, err
+		}
+		return q.client.getLatestSlot(ctx)
+	case "block":
+		slot, ok, err := x.uintArgument(field, "slot")
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("argument slot is required")
+		}
+		block := &gqlBlock{client: q.client, slot: slot}
+		if err := block.load(ctx, x, field.selects("transactions") || field.selects("transactionCount")); err != nil {
+			return nil, err
+		}
+		if block.block == nil {
+			return nil, nil
+		}
+		return block, nil
+	case "transaction":
+		signature, err := x.stringArgument(field, "signature")
+		if err != nil {
+			return nil, err
+		}
+		if signature == "" {
+			return nil, fmt.Errorf("argument signature is required")
+		}
+		tx := &gqlTransaction{client: q.client, signature: signature}
+		if err := tx.load(ctx, x); err != nil {
+			return nil, err
+		}
+		if tx.details == nil {
+			return nil, nil
+		}
+		return tx, nil
+	case "account":
+		address, err := x.stringArgument(field, "address")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := decodePubkey(address); err != nil {
+			return nil, err
+		}
+		account := &gqlAccount{client: q.client, address: address}
+		if err := account.load(ctx, x); err != nil {
+			return nil, err
+		}
+		if account.info == nil {
+			return nil, nil
+		}
+		return account, nil
+	}
+	return nil, errUnknownField
+}
+
+// gqlBlock is a Block, fetched with its transactions only once they are
+// selected
+type gqlBlock struct {
+	client  SolanaRPCClient
+	slot    uint64
+	block   *Block
+	full    bool
+	fetched bool
+}
+
+func (b *gqlBlock) typename() string {
+	return "Block"
+}
+
+// load fetches the block, again with transactions when it was first
+// fetched without and full asks for them. Skipped slots leave block nil.
+func (b *gqlBlock) load(ctx context.Context, x *gqlExecution, full bool) error {
+	if b.fetched && (b.full || !full) {
+		return nil
+	}
+	if err := x.fetch(); err != nil {
+		return err
+	}
+	opts := BlockOptions{MaxSupportedTransactionVersion: new(int), Encoding: "json", TransactionDetails: "none"}
+	if full {
+		opts.TransactionDetails = "full"
+	}
+	raw, err := b.client.getBlockDetails(ctx, b.slot, opts)
+	if slotSkipped(err) || (err == nil && (len(raw) == 0 || string(raw) == "null")) {
+		b.fetched, b.full = true, full
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if b.block, err = parseBlock(b.slot, raw); err != nil {
+		return err
+	}
+	b.fetched, b.full = true, full
+	return nil
+}
+
+func (b *gqlBlock) resolve(ctx context.Context, x *gqlExecution, field *gqlField) (interface{}, error) {
+	if field.Name == "slot" {
+		return b.slot, nil
+	}
+	full := field.Name == "transactions" || field.Name == "transactionCount"
+	if err := b.load(ctx, x, full); err != nil {
+		return nil, err
+	}
+	if b.block == nil {
+		return nil, nil
+	}
+
+	switch field.Name {
+	case "blockhash":
+		return b.block.Blockhash, nil
+	case "previousBlockhash":
+		return b.block.PreviousBlockhash, nil
+	case "parentSlot":
+		return b.block.ParentSlot, nil
+	case "parent":
+		return &gqlBlock{client: b.client, slot: b.block.ParentSlot}, nil
+	case "blockTime":
+		return b.block.BlockTime, nil
+	case "blockHeight":
+		return b.block.BlockHeight, nil
+	case "transactionCount":
+		return len(b.block.Transactions), nil
+	case "transactions":
+		transactions := make([]gqlObject, len(b.block.Transactions))
+		for i, summary := range b.block.Transactions {
+			summary := summary
+			transactions[i] = &gqlTransaction{client: b.client, signature: summary.Signature, slot: &b.slot, blockTime: b.block.BlockTime, summary: &summary}
+		}
+		return transactions, nil
+	case "rewards":
+		rewards := make([]gqlObject, len(b.block.Rewards))
+		for i, reward := range b.block.Rewards {
+			rewards[i] = gqlReward{client: b.client, reward: reward}
+		}
+		return rewards, nil
+	}
+	return nil, errUnknownField
+}
+
+// gqlTransaction is a Transaction. Whatever the block or account history it
+// was listed in says about it is served without fetching it.
+type gqlTransaction struct {
+	client    SolanaRPCClient
+	signature string
+	slot      *uint64
+	blockTime *int64
+	summary   *TransactionSummary
+	details   *TransactionDetails
+	fetched   bool
+}
+
+func (t *gqlTransaction) typename() string {
+	return "Transaction"
+}
+
+// load fetches the transaction, leaving details nil if the node does not
+// know it
+func (t *gqlTransaction) load(ctx context.Context, x *gqlExecution) error {
+	if t.fetched {
+		return nil
+	}
+	if err := x.fetch(); err != nil {
+		return err
+	}
+	raw, err := t.client.getTransaction(ctx, t.signature, TransactionOptions{Encoding: "json", MaxSupportedTransactionVersion: new(int)})
+	if err != nil {
+		return err
+	}
+	if len(raw) > 0 && string(raw) != "null" {
+		if t.details, err = parseTransactionDetails(raw); err != nil {
+			return err
+		}
+		t.slot, t.blockTime = &t.details.Slot, t.details.BlockTime
+		t.summary = &TransactionSummary{Signature: t.signature, Fee: t.details.Fee, Status: t.details.Status, Err: t.details.Err}
+	}
+	t.fetched = true
+	return nil
+}
+
+func (t *gqlTransaction) resolve(ctx context.Context, x *gqlExecution, field *gqlField) (interface{}, error) {
+	switch field.Name {
+	case "signature":
+		return t.signature, nil
+	case "slot", "block", "blockTime":
+		if t.slot == nil {
+			if err := t.load(ctx, x); err != nil || t.slot == nil {
+				return nil, err
+			}
+		}
+		switch field.Name {
+		case "slot":
+			return *t.slot, nil
+		case "block":
+			return &gqlBlock{client: t.client, slot: *t.slot}, nil
+		}
+		return t.blockTime, nil
+	case "fee", "status", "err":
+		if t.summary == nil {
+			if err := t.load(ctx, x); err != nil || t.summary == nil {
+				return nil, err
+			}
+		}
+		switch field.Name {
+		case "fee":
+			return t.summary.Fee, nil
+		case "status":
+			return t.summary.Status, nil
+		}
+		return t.summary.Err, nil
+	case "accounts", "instructions":
+		if err := t.load(ctx, x); err != nil || t.details == nil {
+			return nil, err
+		}
+		if field.Name == "accounts" {
+			return gqlAccounts(t.client, t.details.AccountKeys), nil
+		}
+		instructions := make([]gqlObject, len(t.details.Instructions))
+		for i, instruction := range t.details.Instructions {
+			instructions[i] = gqlInstruction{client: t.client, instruction: instruction}
+		}
+		return instructions, nil
+	}
+	return nil, errUnknownField
+}
+
+// gqlInstruction is an Instruction of a transaction
+type gqlInstruction struct {
+	client      SolanaRPCClient
+	instruction InstructionDetails
+}
+
+func (i gqlInstruction) typename() string {
+	return "Instruction"
+}
+
+func (i gqlInstruction) resolve(ctx context.Context, x *gqlExecution, field *gqlField) (interface{}, error) {
+	switch field.Name {
+	case "program":
+		return &gqlAccount{client: i.client, address: i.instruction.ProgramID}, nil
+	case "accounts":
+		return gqlAccounts(i.client, i.instruction.Accounts), nil
+	case "data":
+		return i.instruction.Data, nil
+	}
+	return nil, errUnknownField
+}
+
+// gqlReward is a Reward credited by a block
+type gqlReward struct {
+	client SolanaRPCClient
+	reward Reward
+}
+
+func (r gqlReward) typename() string {
+	return "Reward"
+}
+
+func (r gqlReward) resolve(ctx context.Context, x *gqlExecution, field *gqlField) (interface{}, error) {
+	switch field.Name {
+	case "account":
+		return &gqlAccount{client: r.client, address: r.reward.Pubkey}, nil
+	case "lamports":
+		return r.reward.Lamports, nil
+	case "postBalance":
+		return r.reward.PostBalance, nil
+	case "rewardType":
+		return r.reward.RewardType, nil
+	case "commission":
+		return r.reward.Commission, nil
+	}
+	return nil, errUnknownField
+}
+
+// gqlAccount is an Account, fetched when a field of its state is selected.
+// The state fields of accounts that do not exist are null.
+type gqlAccount struct {
+	client  SolanaRPCClient
+	address string
+	info    *AccountInfo
+	fetched bool
+}
+
+func gqlAccounts(client SolanaRPCClient, addresses []string) []gqlObject {
+	accounts := make([]gqlObject, len(addresses))
+	for i, address := range addresses {
+		accounts[i] = &gqlAccount{client: client, address: address}
+	}
+	return accounts
+}
+
+func (a *gqlAccount) typename() string {
+	return "Account"
+}
+
+func (a *gqlAccount) load(ctx context.Context, x *gqlExecution) error {
+	if a.fetched {
+		return nil
+	}
+	if err := x.fetch(); err != nil {
+		return err
+	}
+	info, err := a.client.getAccountInfo(ctx, a.address, AccountOptions{})
+	if err != nil {
+		return err
+	}
+	a.info, a.fetched = info, true
+	return nil
+}
+
+func (a *gqlAccount) resolve(ctx context.Context, x *gqlExecution, field *gqlField) (interface{}, error) {
+	switch field.Name {
+	case "address":
+		return a.address, nil
+	case "transactions":
+		return a.transactions(ctx, x, field)
+	case "lamports", "owner", "executable", "rentEpoch", "space":
+	default:
+		return nil, errUnknownField
+	}
+
+	if err := a.load(ctx, x); err != nil || a.info == nil {
+		return nil, err
+	}
+	switch field.Name {
+	case "lamports":
+		return a.info.Lamports, nil
+	case "owner":
+		return &gqlAccount{client: a.client, address: a.info.Owner}, nil
+	case "executable":
+		return a.info.Executable, nil
+	case "rentEpoch":
+		return a.info.RentEpoch, nil
+	}
+	return a.info.Space, nil
+}
+
+// transactions lists the transactions of the account, newest first
+func (a *gqlAccount) transactions(ctx context.Context, x *gqlExecution, field *gqlField) (interface{}, error) {
+	limit, ok, err := x.uintArgument(field, "limit")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		limit = defaultGraphQLHistory
+	}
+	if limit < 1 || limit > maxSignaturesPerPage {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxSignaturesPerPage)
+	}
+	before, err := x.stringArgument(field, "before")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := x.fetch(); err != nil {
+		return nil, err
+	}
+	signatures, err := a.client.getSignaturesForAddress(ctx, a.address, SignatureOptions{Limit: int(limit), Before: before})
+	if err != nil {
+		return nil, err
+	}
+	transactions := make([]gqlObject, len(signatures))
+	for i, info := range signatures {
+		info := info
+		transactions[i] = &gqlTransaction{client: a.client, signature: info.Signature, slot: &info.Slot, blockTime: info.BlockTime}
+	}
+	return transactions, nil
+}
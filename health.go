@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthProbeTimeout bounds each per-endpoint probe in the bulk health check
+const healthProbeTimeout = 3 * time.Second
+
+// endpointHealth is the probe result for a single upstream endpoint
+type endpointHealth struct {
+	Endpoint    string `json:"endpoint"`
+	Healthy     bool   `json:"healthy"`
+	Slot        uint64 `json:"slot,omitempty"`
+	SlotsBehind uint64 `json:"slots_behind,omitempty"`
+	LatencyMs   int64  `json:"latency_ms"`
+	Error       string `json:"error,omitempty"`
+}
+
+// healthAllResponse is the body returned by /healthz/all
+type healthAllResponse struct {
+	Healthy   int              `json:"healthy"`
+	Total     int              `json:"total"`
+	Endpoints []endpointHealth `json:"endpoints"`
+}
+
+// probeEndpoints queries the latest slot from every endpoint concurrently.
+// Probes run on their own goroutines rather than the worker pool, so that a
+// saturated pool cannot make healthy endpoints look down.
+func probeEndpoints(ctx context.Context, endpoints []string, probes []SolanaRPCClient) []endpointHealth {
+	results := make([]endpointHealth, len(endpoints))
+
+	var wg sync.WaitGroup
+	wg.Add(len(endpoints))
+	for i := range endpoints {
+		i := i
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			slot, err := probes[i].getLatestSlot(ctx)
+			results[i] = endpointHealth{
+				Endpoint:  endpoints[i],
+				Healthy:   err == nil,
+				Slot:      slot,
+				LatencyMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report how far each healthy endpoint trails the highest observed slot
+	var highest uint64
+	for _, res := range results {
+		if res.Healthy && res.Slot > highest {
+			highest = res.Slot
+		}
+	}
+	for i := range results {
+		if results[i].Healthy {
+			results[i].SlotsBehind = highest - results[i].Slot
+		}
+	}
+
+	return results
+}
+
+// handleHealthAll probes every configured upstream endpoint through the
+// upstream transport, or the default one when nil. It always responds with
+// 200 so that partial outages are reported rather than masked.
+func handleHealthAll(endpoints []string, transport http.RoundTripper) http.HandlerFunc {
+	probes := make([]SolanaRPCClient, len(endpoints))
+	for i, endpoint := range endpoints {
+		probe := newRPCClient(endpoint)
+		probe.client.Timeout = healthProbeTimeout
+		probe.client.Transport = transport
+		probe.maxAttempts = 1
+		probes[i] = probe
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		results := probeEndpoints(r.Context(), endpoints, probes)
+
+		response := healthAllResponse{Total: len(results), Endpoints: results}
+		for _, res := range results {
+			if res.Healthy {
+				response.Healthy++
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+// readiness tracks whether the service should be sent new requests
+type readiness struct {
+	draining atomic.Bool
+}
+
+// drain marks the service as shutting down
+func (r *readiness) drain() {
+	r.draining.Store(true)
+}
+
+// probeResponse is the body returned by /healthz and /readyz
+type probeResponse struct {
+	Status string `json:"status"`
+	Slot   uint64 `json:"slot,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func writeProbe(w http.ResponseWriter, status int, response probeResponse) {
+	jsonData, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(jsonData)
+}
+
+// handleHealthz is the liveness probe. It answers as long as the process
+// serves requests, without looking upstream, so that an upstream outage does
+// not get the service restarted.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeProbe(w, http.StatusOK, probeResponse{Status: "ok"})
+}
+
+// handleReadyz is the readiness probe. It answers 503 while the service
+// drains for shutdown or when the upstream cannot return its latest slot
+// within healthProbeTimeout.
+func handleReadyz(client SolanaRPCClient, ready *readiness) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ready.draining.Load() {
+			writeProbe(w, http.StatusServiceUnavailable, probeResponse{Status: "draining"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), healthProbeTimeout)
+		defer cancel()
+		slot, err := client.getLatestSlot(ctx)
+		if err != nil {
+			writeProbe(w, http.StatusServiceUnavailable, probeResponse{Status: "upstream unavailable", Error: err.Error()})
+			return
+		}
+		writeProbe(w, http.StatusOK, probeResponse{Status: "ready", Slot: slot})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleHealthAll(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","result":1000,"id":1}`))
+	}))
+	defer healthy.Close()
+
+	lagging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","result":990,"id":1}`))
+	}))
+	defer lagging.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","error":{"code":-32005,"message":"Node is behind"},"id":1}`))
+	}))
+	defer failing.Close()
+
+	endpoints := []string{healthy.URL, lagging.URL, failing.URL}
+	req := httptest.NewRequest("GET", "/healthz/all", nil)
+	rr := httptest.NewRecorder()
+	handleHealthAll(endpoints, nil).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+
+	var response healthAllResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response.Total != 3 || response.Healthy != 2 {
+		t.Errorf("unexpected counts: got healthy=%d total=%d", response.Healthy, response.Total)
+	}
+
+	for i, endpoint := range endpoints {
+		if response.Endpoints[i].Endpoint != endpoint {
+			t.Errorf("endpoint %d out of order: got %v want %v", i, response.Endpoints[i].Endpoint, endpoint)
+		}
+	}
+
+	if response.Endpoints[1].SlotsBehind != 10 {
+		t.Errorf("Expected lagging endpoint to be 10 slots behind, got %d", response.Endpoints[1].SlotsBehind)
+	}
+
+	if response.Endpoints[2].Healthy || response.Endpoints[2].Error == "" {
+		t.Errorf("Expected failing endpoint to be unhealthy with an error, got %+v", response.Endpoints[2])
+	}
+}
+
+func TestHandleReadyz(t *testing.T) {
+	tests := []struct {
+		name           string
+		client         *mockRPCClient
+		draining       bool
+		expectedStatus int
+		expectedBody   probeResponse
+	}{
+		{name: "Ready", client: &mockRPCClient{latestSlot: 42}, expectedStatus: http.StatusOK, expectedBody: probeResponse{Status: "ready", Slot: 42}},
+		{name: "Upstream Down", client: &mockRPCClient{shouldFail: true, errorMessage: "connection refused"}, expectedStatus: http.StatusServiceUnavailable, expectedBody: probeResponse{Status: "upstream unavailable", Error: "connection refused"}},
+		{name: "Draining", client: &mockRPCClient{latestSlot: 42}, draining: true, expectedStatus: http.StatusServiceUnavailable, expectedBody: probeResponse{Status: "draining"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready := &readiness{}
+			if tt.draining {
+				ready.drain()
+			}
+			rr := httptest.NewRecorder()
+			handleReadyz(tt.client, ready)(rr, httptest.NewRequest("GET", "/readyz", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			var body probeResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if body != tt.expectedBody {
+				t.Errorf("Expected %+v, got %+v", tt.expectedBody, body)
+			}
+		})
+	}
+
+	// Liveness does not depend on the upstream
+	rr := httptest.NewRecorder()
+	handleHealthz(rr, httptest.NewRequest("GET", "/healthz", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected /healthz status %d, got %d", http.StatusOK, rr.Code)
+	}
+}
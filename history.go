@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Signature history settings
+const (
+	maxSignaturesPerPage  = 1000
+	defaultActivityWindow = 100
+	defaultHistoryLimit   = 20
+	// Hydrating costs a getTransaction call per signature
+	maxHydratedHistory = 100
+)
+
+// SignatureInfo is a single entry returned by getSignaturesForAddress
+type SignatureInfo struct {
+	Signature          string          `json:"signature"`
+	Slot               uint64          `json:"slot"`
+	Err                json.RawMessage `json:"err"`
+	Memo               *string         `json:"memo"`
+	BlockTime          *int64          `json:"blockTime"`
+	ConfirmationStatus string          `json:"confirmationStatus,omitempty"`
+}
+
+// SignatureOptions paginates getSignaturesForAddress. Results are ordered
+// newest first; Before and Until bound the page by signature.
+type SignatureOptions struct {
+	Limit  int
+	Before string
+	Until  string
+}
+
+// activityRateResponse is the response of /account/activity-rate
+type activityRateResponse struct {
+	Address             string  `json:"address"`
+	Window              int     `json:"window"`
+	Transactions        int     `json:"transactions"`
+	FirstBlockTime      *int64  `json:"first_block_time,omitempty"`
+	LastBlockTime       *int64  `json:"last_block_time,omitempty"`
+	SpanSeconds         int64   `json:"span_seconds"`
+	TransactionsPerHour float64 `json:"transactions_per_hour"`
+	TransactionsPerDay  float64 `json:"transactions_per_day"`
+	Note                string  `json:"note,omitempty"`
+}
+
+// getSignaturesForAddress gets signatures of transactions involving an
+// address, newest first
+func (c *rpcClient) getSignaturesForAddress(ctx context.Context, address string, opts SignatureOptions) ([]SignatureInfo, error) {
+	config := map[string]interface{}{}
+	if opts.Limit > 0 {
+		config["limit"] = opts.Limit
+	}
+	if opts.Before != "" {
+		config["before"] = opts.Before
+	}
+	if opts.Until != "" {
+		config["until"] = opts.Until
+	}
+
+	response, err := c.sendRequest(ctx, "getSignaturesForAddress", []interface{}{address, config})
+	if err != nil {
+		return nil, err
+	}
+
+	var signatures []SignatureInfo
+	if err := json.Unmarshal(response.Result, &signatures); err != nil {
+		return nil, fmt.Errorf("failed to parse signatures: %w", err)
+	}
+
+	return signatures, nil
+}
+
+// activityRate computes the transaction rate over the time spanned by the
+// signatures' block times. Signatures without a block time are counted but
+// do not affect the span.
+func activityRate(address string, window int, signatures []SignatureInfo) activityRateResponse {
+	response := activityRateResponse{Address: address, Window: window, Transactions: len(signatures)}
+
+	for _, sig := range signatures {
+		if sig.BlockTime == nil {
+			continue
+		}
+		if response.LastBlockTime == nil || *sig.BlockTime > *response.LastBlockTime {
+			response.LastBlockTime = sig.BlockTime
+		}
+		if response.FirstBlockTime == nil || *sig.BlockTime < *response.FirstBlockTime {
+			response.FirstBlockTime = sig.BlockTime
+		}
+	}
+
+	switch {
+	case len(signatures) == 0:
+		response.Note = "no transactions found for this address"
+		return response
+	case response.FirstBlockTime == nil:
+		response.Note = "block times unavailable for these transactions"
+		return response
+	}
+
+	response.SpanSeconds = *response.LastBlockTime - *response.FirstBlockTime
+	if response.SpanSeconds == 0 {
+		response.Note = "all transactions share a single block time, so no rate can be computed"
+		return response
+	}
+
+	span := time.Duration(response.SpanSeconds) * time.Second
+	response.TransactionsPerHour = float64(len(signatures)) / span.Hours()
+	response.TransactionsPerDay = response.TransactionsPerHour * 24
+	if len(signatures) < window {
+		response.Note = "fewer transactions than the requested window; rate covers the account's full history"
+	}
+	return response
+}
+
+func handleGetActivityRate(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			http.Error(w, "address parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		window := defaultActivityWindow
+		if value := r.URL.Query().Get("window"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxSignaturesPerPage {
+				http.Error(w, fmt.Sprintf("window must be between 1 and %d", maxSignaturesPerPage), http.StatusBadRequest)
+				return
+			}
+			window = parsed
+		}
+
+		signatures, err := client.getSignaturesForAddress(r.Context(), address, SignatureOptions{Limit: window})
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(activityRate(address, window, signatures))
+		w.Write(jsonData)
+	}
+}
+
+// historyEntry is a transaction in /transactions, with its summary when the
+// history is hydrated
+type historyEntry struct {
+	Signature          string              `json:"signature"`
+	Slot               uint64              `json:"slot"`
+	BlockTime          *int64              `json:"block_time"`
+	Err                json.RawMessage     `json:"err"`
+	Memo               *string             `json:"memo"`
+	ConfirmationStatus string              `json:"confirmation_status,omitempty"`
+	Summary            *TransactionSummary `json:"summary,omitempty"`
+}
+
+// transactionHistoryResponse is the response of /transactions. NextBefore
+// is passed as ?before= to fetch the following page.
+type transactionHistoryResponse struct {
+	Address      string         `json:"address"`
+	Transactions []historyEntry `json:"transactions"`
+	NextBefore   string         `json:"next_before,omitempty"`
+}
+
+// hydrateHistory adds the summary of every transaction in entries, fetching
+// them in JSON-RPC batches from a single pool task. Transactions the node no
+// longer has are left without one.
+func hydrateHistory(ctx context.Context, client SolanaRPCClient, pool *workerPool, entries []historyEntry) error {
+	signatures := make([]string, len(entries))
+	for i, entry := range entries {
+		signatures[i] = entry.Signature
+	}
+
+	var transactions []json.RawMessage
+	var errs []error
+	var fetchErr error
+	if err := pool.runAt(ctx, pool.priorityFor(len(signatures)), []func(){func() {
+		transactions, errs, fetchErr = client.getTransactions(ctx, signatures, TransactionOptions{Encoding: "json", MaxSupportedTransactionVersion: new(int)})
+	}}); err != nil {
+		return err
+	}
+	if fetchErr != nil {
+		return fetchErr
+	}
+
+	for i, transaction := range transactions {
+		if errs[i] != nil {
+			return errs[i]
+		}
+		if transaction == nil {
+			continue
+		}
+		details, err := parseTransactionDetails(transaction)
+		if err != nil {
+			return err
+		}
+		entries[i].Summary = &TransactionSummary{Signature: details.Signature, Fee: details.Fee, Status: details.Status, Err: details.Err}
+	}
+	return nil
+}
+
+// handleGetTransactionHistory pages through the transactions of ?address=,
+// newest first, ?limit= at a time, starting before the ?before= signature
+// and stopping at the ?until= one. With ?hydrate=true each transaction
+// carries its fee and status.
+func handleGetTransactionHistory(client SolanaRPCClient, pool *workerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			http.Error(w, "address parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := decodePubkey(address); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		hydrate := r.URL.Query().Get("hydrate") == "true"
+		maxLimit := maxSignaturesPerPage
+		if hydrate {
+			maxLimit = maxHydratedHistory
+		}
+		limit := defaultHistoryLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxLimit {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxLimit), http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		opts := SignatureOptions{Limit: limit, Before: r.URL.Query().Get("before"), Until: r.URL.Query().Get("until")}
+		signatures, err := client.getSignaturesForAddress(r.Context(), address, opts)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := transactionHistoryResponse{Address: address, Transactions: make([]historyEntry, len(signatures))}
+		for i, sig := range signatures {
+			response.Transactions[i] = historyEntry{
+				Signature:          sig.Signature,
+				Slot:               sig.Slot,
+				BlockTime:          sig.BlockTime,
+				Err:                sig.Err,
+				Memo:               sig.Memo,
+				ConfirmationStatus: sig.ConfirmationStatus,
+			}
+			if len(sig.Err) == 0 {
+				response.Transactions[i].Err = json.RawMessage("null")
+			}
+		}
+		// A full page may have more behind it
+		if len(signatures) == limit {
+			response.NextBefore = signatures[len(signatures)-1].Signature
+		}
+
+		if hydrate && len(signatures) > 0 {
+			pool.setPriorityHeader(w, len(signatures))
+			err := hydrateHistory(r.Context(), client, pool, response.Transactions)
+			if errors.Is(err, errOverloaded) {
+				shed(w, priorityBulk)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// testSignatures returns n signatures spaced interval seconds apart, newest first
+func testSignatures(n int, interval int64) []SignatureInfo {
+	signatures := make([]SignatureInfo, n)
+	for i := range signatures {
+		blockTime := 1700000000 - int64(i)*interval
+		signatures[i] = SignatureInfo{Signature: fmt.Sprintf("sig%d", i), Slot: uint64(1000 - i), BlockTime: &blockTime}
+	}
+	return signatures
+}
+
+func TestHandleGetActivityRate(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+		expectedCount  int
+		expectedHourly float64
+		expectNote     bool
+	}{
+		{
+			name:           "Full Window",
+			mockClient:     mockRPCClient{signatures: testSignatures(200, 36)},
+			queryParam:     "?address=abc&window=101",
+			expectedStatus: http.StatusOK,
+			expectedCount:  101,
+			expectedHourly: 101,
+		},
+		{
+			name:           "Fewer Than Window",
+			mockClient:     mockRPCClient{signatures: testSignatures(11, 360)},
+			queryParam:     "?address=abc",
+			expectedStatus: http.StatusOK,
+			expectedCount:  11,
+			expectedHourly: 11,
+			expectNote:     true,
+		},
+		{
+			name:           "No Activity",
+			mockClient:     mockRPCClient{},
+			queryParam:     "?address=abc",
+			expectedStatus: http.StatusOK,
+			expectNote:     true,
+		},
+		{
+			name:           "Single Transaction",
+			mockClient:     mockRPCClient{signatures: testSignatures(1, 0)},
+			queryParam:     "?address=abc",
+			expectedStatus: http.StatusOK,
+			expectedCount:  1,
+			expectNote:     true,
+		},
+		{name: "Missing Address", mockClient: mockRPCClient{}, queryParam: "", expectedStatus: http.StatusBadRequest},
+		{name: "Window Too Large", mockClient: mockRPCClient{}, queryParam: "?address=abc&window=1001", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/account/activity-rate"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetActivityRate(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response activityRateResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Transactions != tt.expectedCount {
+				t.Errorf("Expected %d transactions, got %d", tt.expectedCount, response.Transactions)
+			}
+			if response.TransactionsPerHour != tt.expectedHourly {
+				t.Errorf("Expected %v transactions per hour, got %v", tt.expectedHourly, response.TransactionsPerHour)
+			}
+			if tt.expectNote != (response.Note != "") {
+				t.Errorf("unexpected note: %q", response.Note)
+			}
+		})
+	}
+}
+
+func TestHandleGetTransactionHistory(t *testing.T) {
+	pool := newWorkerPool(2, 8)
+	defer pool.stop()
+
+	failedErr := json.RawMessage(`{"InstructionError":[0,"Custom"]}`)
+	signatures := testSignatures(3, 60)
+	signatures[1].Err = failedErr
+	transactions := map[string]json.RawMessage{
+		"sig0": json.RawMessage(`{"slot":1000,"transaction":{"signatures":["sig0"],"message":{"accountKeys":[],"instructions":[]}},"meta":{"fee":5000,"err":null}}`),
+		"sig1": json.RawMessage(`{"slot":999,"transaction":{"signatures":["sig1"],"message":{"accountKeys":[],"instructions":[]}},"meta":{"fee":5000,"err":{"InstructionError":[0,"Custom"]}}}`),
+	}
+
+	tests := []struct {
+		name               string
+		query              string
+		mock               mockRPCClient
+		expectedStatus     int
+		expectedCount      int
+		expectedNextBefore string
+		expectedOptions    SignatureOptions
+		expectedSummaries  []*TransactionSummary
+	}{
+		{
+			name:            "Default Page",
+			query:           "?address=" + testOwner,
+			mock:            mockRPCClient{signatures: signatures},
+			expectedStatus:  http.StatusOK,
+			expectedCount:   3,
+			expectedOptions: SignatureOptions{Limit: defaultHistoryLimit},
+		},
+		{
+			name:               "Full Page Has Next",
+			query:              "?address=" + testOwner + "&limit=2&before=sigX&until=sigY",
+			mock:               mockRPCClient{signatures: signatures},
+			expectedStatus:     http.StatusOK,
+			expectedCount:      2,
+			expectedNextBefore: "sig1",
+			expectedOptions:    SignatureOptions{Limit: 2, Before: "sigX", Until: "sigY"},
+		},
+		{
+			name:            "Hydrated",
+			query:           "?address=" + testOwner + "&hydrate=true",
+			mock:            mockRPCClient{signatures: signatures, transactions: transactions},
+			expectedStatus:  http.StatusOK,
+			expectedCount:   3,
+			expectedOptions: SignatureOptions{Limit: defaultHistoryLimit},
+			expectedSummaries: []*TransactionSummary{
+				{Signature: "sig0", Fee: 5000, Status: transactionSucceeded, Err: json.RawMessage("null")},
+				{Signature: "sig1", Fee: 5000, Status: transactionFailed, Err: failedErr},
+				nil,
+			},
+		},
+		{name: "Missing Address", query: "", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Address", query: "?address=abc", expectedStatus: http.StatusBadRequest},
+		{name: "Limit Too High", query: "?address=" + testOwner + "&limit=1001", expectedStatus: http.StatusBadRequest},
+		{name: "Hydrated Limit Too High", query: "?address=" + testOwner + "&limit=101&hydrate=true", expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Error", query: "?address=" + testOwner, mock: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/transactions"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetTransactionHistory(&tt.mock, pool).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response transactionHistoryResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(response.Transactions) != tt.expectedCount {
+				t.Errorf("Expected %d transactions, got %d", tt.expectedCount, len(response.Transactions))
+			}
+			if response.NextBefore != tt.expectedNextBefore {
+				t.Errorf("Expected next_before %q, got %q", tt.expectedNextBefore, response.NextBefore)
+			}
+			if tt.mock.sigOptions != tt.expectedOptions {
+				t.Errorf("Expected signature options %+v, got %+v", tt.expectedOptions, tt.mock.sigOptions)
+			}
+			for i, expected := range tt.expectedSummaries {
+				got := response.Transactions[i].Summary
+				if (got == nil) != (expected == nil) || got != nil && (got.Signature != expected.Signature || got.Fee != expected.Fee || got.Status != expected.Status || string(got.Err) != string(expected.Err)) {
+					t.Errorf("Expected summary %+v at %d, got %+v", expected, i, got)
+				}
+			}
+		})
+	}
+}
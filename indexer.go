@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Indexer settings
+const (
+	indexInterval  = 2 * time.Second
+	indexBatchSize = 100
+)
+
+// indexerCheckpointKey names the indexer's checkpoint in the block store
+const indexerCheckpointKey = "indexer"
+
+// indexerCheckpoint is the next slot the indexer will store
+type indexerCheckpoint struct {
+	Slot uint64 `json:"slot"`
+}
+
+// indexer follows the finalized tip, writing every block to a store so it
+// holds the chain from the indexer's first slot on. Progress is
+// checkpointed in the store after each batch, so a restarted indexer
+// backfills the slots finalized while it was down before catching up.
+type indexer struct {
+	client   SolanaRPCClient
+	store    blockStore
+	opts     BlockOptions
+	interval time.Duration
+	batch    uint64
+
+	// addresses, when set, indexes the transactions of each stored block
+	// by address
+	addresses *addressIndex
+
+	// from is the first slot to index when the store has no checkpoint;
+	// zero starts at the finalized tip
+	from uint64
+}
+
+// newIndexer stores blocks fetched with opts, which should match those
+// /block-details requests by default so that the stored blocks serve it
+func newIndexer(client SolanaRPCClient, store blockStore, opts BlockOptions, from uint64) *indexer {
+	return &indexer{client: client, store: store, opts: opts, interval: indexInterval, batch: indexBatchSize, from: from}
+}
+
+// run indexes until ctx is done, working through a backlog batch by batch
+// and then polling for new finalized slots every interval
+func (ix *indexer) run(ctx context.Context) {
+	ticker := time.NewTicker(ix.interval)
+	defer ticker.Stop()
+	for {
+		caughtUp, err := ix.step(ctx)
+		if err != nil && ctx.Err() == nil {
+			logFields("indexer failed", "error", err)
+		}
+		if caughtUp || err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// step stores the blocks of up to batch slots past the checkpoint, as far
+// as the finalized tip, and advances the checkpoint past them. A block that
+// fails to fetch or store holds the checkpoint at its slot so it is retried.
+// It reports whether the indexer has reached the tip.
+func (ix *indexer) step(ctx context.Context) (bool, error) {
+	ctx = context.WithValue(ctx, requestCommitmentKey{}, commitmentFinalized)
+	tip, err := ix.client.getLatestSlot(ctx)
+	if err != nil {
+		return false, err
+	}
+	next, err := ix.checkpoint(ctx, tip)
+	if err != nil {
+		return false, err
+	}
+	if next > tip {
+		ix.publish(next, tip)
+		return true, nil
+	}
+
+	end := tip
+	if tip-next >= ix.batch {
+		end = next + ix.batch - 1
+	}
+	slots, err := ix.client.getConfirmedBlocks(ctx, next, end)
+	if err != nil {
+		return false, err
+	}
+	blocks, errs, err := ix.client.getBlocks(ctx, slots, ix.opts)
+	if err != nil {
+		return false, err
+	}
+
+	for i, slot := range slots {
+		if errs[i] != nil && !slotSkipped(errs[i]) {
+			return false, ix.failAt(ctx, slot, tip, fmt.Errorf("failed to fetch block %d: %w", slot, errs[i]))
+		}
+		if errs[i] != nil || len(blocks[i]) == 0 || string(blocks[i]) == "null" {
+			continue
+		}
+		if err := ix.store.put(ctx, storeKindBlock, blockStoreKey(slot, ix.opts), blocks[i]); err != nil {
+			return false, ix.failAt(ctx, slot, tip, err)
+		}
+		if ix.addresses != nil {
+			if err := ix.addresses.add(ctx, slot, blocks[i]); err != nil {
+				return false, ix.failAt(ctx, slot, tip, err)
+			}
+		}
+		metrics.addCounter("solana_client_indexer_blocks_total", "Blocks written to the block store by the indexer.", 1)
+	}
+
+	if err := ix.saveCheckpoint(ctx, end+1, tip); err != nil {
+		return false, err
+	}
+	return end == tip, nil
+}
+
+// failAt checkpoints slot as the next to index and returns err
+func (ix *indexer) failAt(ctx context.Context, slot, tip uint64, err error) error {
+	if saveErr := ix.saveCheckpoint(ctx, slot, tip); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// checkpoint returns the next slot to index, from the store or else the
+// configured start
+func (ix *indexer) checkpoint(ctx context.Context, tip uint64) (uint64, error) {
+	data, ok, err := ix.store.get(ctx, storeKindCheckpoint, indexerCheckpointKey)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		if ix.from == 0 {
+			return tip, nil
+		}
+		return ix.from, nil
+	}
+
+	var checkpoint indexerCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return 0, fmt.Errorf("failed to parse indexer checkpoint: %w", err)
+	}
+	return checkpoint.Slot, nil
+}
+
+func (ix *indexer) saveCheckpoint(ctx context.Context, next, tip uint64) error {
+	data, _ := json.Marshal(indexerCheckpoint{Slot: next})
+	if err := ix.store.put(ctx, storeKindCheckpoint, indexerCheckpointKey, data); err != nil {
+		return fmt.Errorf("failed to save indexer checkpoint: %w", err)
+	}
+	ix.publish(next, tip)
+	return nil
+}
+
+// publish updates the indexer gauges
+func (ix *indexer) publish(next, tip uint64) {
+	lag := uint64(0)
+	if tip >= next {
+		lag = tip - next + 1
+	}
+	metrics.setGauge("solana_client_indexer_next_slot", "Next slot the indexer will store.", float64(next))
+	metrics.setGauge("solana_client_indexer_lag_slots", "Finalized slots the indexer has yet to store.", float64(lag))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestIndexer(t *testing.T) {
+	upstream := &mockRPCClient{latestSlot: 5, blocks: map[uint64]json.RawMessage{
+		1: json.RawMessage(`{"blockhash":"a","transactions":[{"transaction":{"signatures":["sig1"],"message":{"accountKeys":["payer"]}}}]}`),
+		2: json.RawMessage(`{"blockhash":"b"}`),
+		4: json.RawMessage(`{"blockhash":"d"}`),
+		5: json.RawMessage(`{"blockhash":"e"}`),
+	}}
+	store, err := newFileBlockStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFileBlockStore returned error: %v", err)
+	}
+	ctx := context.Background()
+	version := defaultMaxTransactionVersion
+	opts := BlockOptions{MaxSupportedTransactionVersion: &version}
+
+	ix := newIndexer(upstream, store, opts, 1)
+	ix.batch = 2
+	ix.addresses = newAddressIndex(store)
+
+	tests := []struct {
+		name             string
+		indexer          *indexer
+		blockErrs        map[uint64]error
+		expectedCaughtUp bool
+		expectedErr      bool
+		expectedNext     uint64
+		expectedStored   []uint64
+	}{
+		{name: "Starts At From", indexer: ix, expectedNext: 3, expectedStored: []uint64{1, 2}},
+		{name: "Failed Block Holds Checkpoint", indexer: ix, blockErrs: map[uint64]error{4: errors.New("node unavailable")}, expectedErr: true, expectedNext: 4},
+		{name: "Restart Resumes From Checkpoint", indexer: newIndexer(upstream, store, opts, 0), expectedCaughtUp: true, expectedNext: 6, expectedStored: []uint64{4, 5}},
+		{name: "Caught Up", indexer: ix, expectedCaughtUp: true, expectedNext: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream.blockErrs = tt.blockErrs
+			caughtUp, err := tt.indexer.step(ctx)
+			if (err != nil) != tt.expectedErr {
+				t.Fatalf("Expected error %v, got %v", tt.expectedErr, err)
+			}
+			if caughtUp != tt.expectedCaughtUp {
+				t.Errorf("Expected caught up %v, got %v", tt.expectedCaughtUp, caughtUp)
+			}
+			if next, _ := tt.indexer.checkpoint(ctx, 0); next != tt.expectedNext {
+				t.Errorf("Expected checkpoint %d, got %d", tt.expectedNext, next)
+			}
+			for _, slot := range tt.expectedStored {
+				block, ok, _ := store.get(ctx, storeKindBlock, blockStoreKey(slot, opts))
+				if !ok || string(block) != string(upstream.blocks[slot]) {
+					t.Errorf("Expected block %d stored, got %s", slot, block)
+				}
+			}
+		})
+	}
+
+	if entries, _, _, err := ix.addresses.activity(ctx, "payer", -1, 10); err != nil || len(entries) != 1 || entries[0].Signature != "sig1" {
+		t.Errorf("Expected sig1 indexed for payer, got %+v (%v)", entries, err)
+	}
+
+	// Without a checkpoint or start slot, indexing begins at the tip
+	tipStore, _ := newFileBlockStore(t.TempDir())
+	tip := newIndexer(upstream, tipStore, opts, 0)
+	if caughtUp, err := tip.step(ctx); !caughtUp || err != nil {
+		t.Fatalf("Expected to catch up at once, got %v (%v)", caughtUp, err)
+	}
+	if _, ok, _ := tipStore.get(ctx, storeKindBlock, blockStoreKey(4, opts)); ok {
+		t.Error("Expected no blocks before the tip stored")
+	}
+	if _, ok, _ := tipStore.get(ctx, storeKindBlock, blockStoreKey(5, opts)); !ok {
+		t.Error("Expected the tip block stored")
+	}
+
+	// run returns once its context is done
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	tip.run(cancelled)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// InflationRate is the result of getInflationRate. The rates are yearly
+// fractions of the supply.
+type InflationRate struct {
+	Total      float64 `json:"total"`
+	Validator  float64 `json:"validator"`
+	Foundation float64 `json:"foundation"`
+	Epoch      uint64  `json:"epoch"`
+}
+
+// InflationReward is a single entry of getInflationReward
+type InflationReward struct {
+	Epoch         uint64 `json:"epoch"`
+	EffectiveSlot uint64 `json:"effectiveSlot"`
+	Amount        uint64 `json:"amount"`
+	PostBalance   uint64 `json:"postBalance"`
+	Commission    *uint8 `json:"commission"`
+}
+
+// inflationResponse is the response of /inflation
+type inflationResponse struct {
+	Epoch             uint64  `json:"epoch"`
+	Total             float64 `json:"total"`
+	Validator         float64 `json:"validator"`
+	Foundation        float64 `json:"foundation"`
+	TotalPercent      float64 `json:"total_percent"`
+	ValidatorPercent  float64 `json:"validator_percent"`
+	FoundationPercent float64 `json:"foundation_percent"`
+}
+
+// getInflationRate gets the inflation rates of the current epoch
+func (c *rpcClient) getInflationRate(ctx context.Context) (*InflationRate, error) {
+	response, err := c.sendRequest(ctx, "getInflationRate", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var rate InflationRate
+	if err := json.Unmarshal(response.Result, &rate); err != nil {
+		return nil, fmt.Errorf("failed to parse inflation rate: %w", err)
+	}
+
+	return &rate, nil
+}
+
+// getInflationReward gets the inflation rewards paid to addresses in an
+// epoch, with nil entries for addresses that earned none
+func (c *rpcClient) getInflationReward(ctx context.Context, addresses []string, epoch uint64) ([]*InflationReward, error) {
+	response, err := c.sendRequest(ctx, "getInflationReward", []interface{}{addresses, map[string]interface{}{"epoch": epoch}})
+	if err != nil {
+		return nil, err
+	}
+
+	var rewards []*InflationReward
+	if err := json.Unmarshal(response.Result, &rewards); err != nil {
+		return nil, fmt.Errorf("failed to parse inflation rewards: %w", err)
+	}
+	if len(rewards) != len(addresses) {
+		return nil, fmt.Errorf("RPC returned %d inflation rewards for %d addresses", len(rewards), len(addresses))
+	}
+
+	return rewards, nil
+}
+
+func handleGetInflation(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rate, err := client.getInflationRate(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := inflationResponse{
+			Epoch:             rate.Epoch,
+			Total:             rate.Total,
+			Validator:         rate.Validator,
+			Foundation:        rate.Foundation,
+			TotalPercent:      rate.Total * 100,
+			ValidatorPercent:  rate.Validator * 100,
+			FoundationPercent: rate.Foundation * 100,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
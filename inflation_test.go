@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetInflation(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		expectedStatus int
+		expected       inflationResponse
+	}{
+		{
+			name:           "Rates",
+			mockClient:     mockRPCClient{inflation: &InflationRate{Total: 0.0475, Validator: 0.0475, Foundation: 0, Epoch: 700}},
+			expectedStatus: http.StatusOK,
+			expected:       inflationResponse{Epoch: 700, Total: 0.0475, Validator: 0.0475, TotalPercent: 4.75, ValidatorPercent: 4.75},
+		},
+		{
+			name:           "RPC Error",
+			mockClient:     mockRPCClient{shouldFail: true, errorMessage: "RPC connection failed"},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/inflation", nil)
+			rr := httptest.NewRecorder()
+			handleGetInflation(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response inflationResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, response)
+			}
+		})
+	}
+}
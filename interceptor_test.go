@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// hmacSigner signs the request body the way some enterprise gateways require
+func hmacSigner(key []byte) RequestInterceptor {
+	return func(req *http.Request) error {
+		body, err := req.GetBody()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		req.Header.Set("X-Signature", hex.EncodeToString(mac.Sum(nil)))
+		return nil
+	}
+}
+
+func TestRequestInterceptor(t *testing.T) {
+	key := []byte("secret")
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, key)
+		mac.Write(body)
+		if r.Header.Get("X-Signature") != hex.EncodeToString(mac.Sum(nil)) || r.Header.Get("X-Gateway") != "enterprise" {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":42,"id":%d}`, hits)
+	}))
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+	client.addInterceptor(hmacSigner(key))
+	client.addInterceptor(func(req *http.Request) error {
+		req.Header.Set("X-Gateway", "enterprise")
+		return nil
+	})
+
+	slot, err := client.getLatestSlot(context.Background())
+	if err != nil || slot != 42 {
+		t.Fatalf("Expected slot 42 from a signed request, got %d (%v)", slot, err)
+	}
+
+	client.addInterceptor(func(req *http.Request) error {
+		return errors.New("signing key expired")
+	})
+	if _, err := client.getLatestSlot(context.Background()); err == nil || !strings.Contains(err.Error(), "signing key expired") {
+		t.Errorf("Expected interceptor error, got %v", err)
+	}
+	if hits != 1 {
+		t.Errorf("Expected a failing interceptor to stop the request, got %d upstream requests", hits)
+	}
+}
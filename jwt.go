@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWT verification settings
+const (
+	jwksRefreshInterval = time.Hour
+	// jwksMinRefetch spaces out the refetches made for unknown key ids, so
+	// that tokens with made up ids cannot hammer the identity provider
+	jwksMinRefetch = time.Minute
+	jwtLeeway      = time.Minute
+)
+
+// errInvalidToken wraps every reason a bearer token is refused
+var errInvalidToken = errors.New("invalid token")
+
+// jwtClaims are the registered claims checked on every token. Audience is
+// a string or an array of them.
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// audiences lists the aud claim
+func (c *jwtClaims) audiences() []string {
+	var one string
+	if json.Unmarshal(c.Audience, &one) == nil {
+		return []string{one}
+	}
+	var many []string
+	json.Unmarshal(c.Audience, &many)
+	return many
+}
+
+// jwtVerifier checks bearer tokens signed by an identity provider against
+// the keys it publishes as a JWKS. Without a JWKS URL, the keys are found
+// through the issuer's OpenID discovery document.
+type jwtVerifier struct {
+	issuer   string
+	audience string
+	jwksURL  string
+	client   *http.Client
+	now      func() time.Time
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	// fetch is the JWKS fetch in flight, which callers needing fresh keys
+	// wait on rather than fetching again
+	fetch *jwksFetch
+}
+
+// jwksFetch is a JWKS fetch shared by the callers that wait for it
+type jwksFetch struct {
+	done chan struct{}
+	keys map[string]crypto.PublicKey
+	err  error
+}
+
+func newJWTVerifier(issuer, audience, jwksURL string) *jwtVerifier {
+	return &jwtVerifier{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		audience: audience,
+		jwksURL:  jwksURL,
+		client:   &http.Client{Timeout: httpTimeout},
+		now:      time.Now,
+	}
+}
+
+// jwtAlgorithms maps the accepted signing algorithms to their hash. "none"
+// and the HMAC algorithms are refused, as the keys are public.
+var jwtAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256, "RS384": crypto.SHA384, "RS512": crypto.SHA512,
+	"PS256": crypto.SHA256, "PS384": crypto.SHA384, "PS512": crypto.SHA512,
+	"ES256": crypto.SHA256, "ES384": crypto.SHA384, "ES512": crypto.SHA512,
+	"EdDSA": 0,
+}
+
+func newHash(h crypto.Hash) hash.Hash {
+	switch h {
+	case crypto.SHA384:
+		return sha512.New384()
+	case crypto.SHA512:
+		return sha512.New()
+	}
+	return sha256.New()
+}
+
+// verify checks the signature and claims of a compact JWT
+func (v *jwtVerifier) verify(ctx context.Context, token string) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", errInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	hashID, ok := jwtAlgorithms[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", errInvalidToken, header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", errInvalidToken)
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, hashID, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	now := v.now()
+	switch {
+	case claims.ExpiresAt == nil:
+		return nil, fmt.Errorf("%w: no expiry", errInvalidToken)
+	case now.Add(-jwtLeeway).After(time.Unix(int64(*claims.ExpiresAt), 0)):
+		return nil, fmt.Errorf("%w: expired", errInvalidToken)
+	case claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(int64(*claims.NotBefore), 0)):
+		return nil, fmt.Errorf("%w: not valid yet", errInvalidToken)
+	case v.issuer != "" && strings.TrimSuffix(claims.Issuer, "/") != v.issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer", errInvalidToken)
+	}
+	if v.audience != "" {
+		found := false
+		for _, audience := range claims.audiences() {
+			found = found || audience == v.audience
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: unexpected audience", errInvalidToken)
+		}
+	}
+	return &claims, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil || json.Unmarshal(data, v) != nil {
+		return fmt.Errorf("%w: malformed JWT", errInvalidToken)
+	}
+	return nil
+}
+
+// verifyJWTSignature checks a signature with a key of the type alg needs
+func verifyJWTSignature(alg string, hashID crypto.Hash, key crypto.PublicKey, signed, signature []byte) error {
+	var digest []byte
+	if hashID != 0 {
+		h := newHash(hashID)
+		h.Write(signed)
+		digest = h.Sum(nil)
+	}
+
+	valid := false
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			valid = rsa.VerifyPKCS1v15(k, hashID, digest, signature) == nil
+		case "PS":
+			valid = rsa.VerifyPSS(k, hashID, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		// ES signatures are r and s concatenated, each the size of the curve
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg[:2] == "ES" && len(signature) == 2*size {
+			r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+			valid = ecdsa.Verify(k, digest, r, s)
+		}
+	case ed25519.PublicKey:
+		valid = alg == "EdDSA" && ed25519.Verify(k, signed, signature)
+	}
+	if !valid {
+		return fmt.Errorf("%w: bad signature", errInvalidToken)
+	}
+	return nil
+}
+
+// key returns the signing key with id kid, refetching the JWKS when it is
+// stale or, at most every jwksMinRefetch, when the id is unknown, as after
+// the provider rotates its keys. Tokens without a kid need a JWKS of one
+// key. The JWKS is fetched without holding v.mu, so tokens signed with known
+// keys are verified meanwhile, and concurrent callers share one fetch.
+func (v *jwtVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	now := v.now()
+	key := lookupJWK(v.keys, kid)
+	age := now.Sub(v.fetched)
+	if v.keys != nil && age <= jwksRefreshInterval && (key != nil || age <= jwksMinRefetch) {
+		v.mu.Unlock()
+		return knownJWK(key, kid)
+	}
+	fetch := v.fetch
+	if fetch == nil {
+		fetch = &jwksFetch{done: make(chan struct{})}
+		v.fetch = fetch
+		v.mu.Unlock()
+
+		fetch.keys, fetch.err = v.fetchKeys(ctx)
+		v.mu.Lock()
+		if fetch.err == nil {
+			v.keys, v.fetched = fetch.keys, now
+		}
+		v.fetch = nil
+		v.mu.Unlock()
+		close(fetch.done)
+	} else {
+		v.mu.Unlock()
+		select {
+		case <-fetch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if fetch.err != nil {
+		if key != nil {
+			// Keep serving with the keys already known
+			logFields("failed to refresh JWKS", "error", fetch.err)
+			return key, nil
+		}
+		return nil, fetch.err
+	}
+	return knownJWK(lookupJWK(fetch.keys, kid), kid)
+}
+
+// lookupJWK returns the key with id kid, or the only key for an empty kid
+func lookupJWK(keys map[string]crypto.PublicKey, kid string) crypto.PublicKey {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return keys[kid]
+}
+
+func knownJWK(key crypto.PublicKey, kid string) (crypto.PublicKey, error) {
+	if key == nil {
+		return nil, fmt.Errorf("%w: unknown key id %q", errInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// getJSON fetches a JSON document from the identity provider
+func (v *jwtVerifier) getJSON(ctx context.Context, url string, value interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: HTTP %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(value); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", url, err)
+	}
+	return nil
+}
+
+// fetchKeys reads the signing keys of the JWKS, skipping encryption keys
+// and key types it cannot verify with. It runs as the only fetch in flight,
+// which is what allows it to set the discovered jwksURL.
+func (v *jwtVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	if v.jwksURL == "" {
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := v.getJSON(ctx, v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+			return nil, err
+		}
+		if discovery.JWKSURI == "" {
+			return nil, errors.New("OpenID configuration has no jwks_uri")
+		}
+		v.jwksURL = discovery.JWKSURI
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, v.jwksURL, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	decode := func(s string) *big.Int {
+		b, _ := base64.RawURLEncoding.DecodeString(s)
+		return new(big.Int).SetBytes(b)
+	}
+	for _, k := range jwks.Keys {
+		if k.Use == "enc" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA" && k.N != "" && k.E != "":
+			keys[k.Kid] = &rsa.PublicKey{N: decode(k.N), E: int(decode(k.E).Int64())}
+		case k.Kty == "EC":
+			curve := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}[k.Crv]
+			if curve != nil {
+				keys[k.Kid] = &ecdsa.PublicKey{Curve: curve, X: decode(k.X), Y: decode(k.Y)}
+			}
+		case k.Kty == "OKP" && k.Crv == "Ed25519":
+			if x, err := base64.RawURLEncoding.DecodeString(k.X); err == nil && len(x) == ed25519.PublicKeySize {
+				keys[k.Kid] = ed25519.PublicKey(x)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS has no usable signing keys")
+	}
+	return keys, nil
+}
+
+type jwtSubjectContextKey struct{}
+
+// jwtSubjectFromContext returns the subject of the token a request was
+// authenticated with
+func jwtSubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(jwtSubjectContextKey{}).(string)
+	return subject
+}
+
+// isJWT reports whether a bearer token has the three parts of a compact
+// JWT, which API keys do not
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// withJWTAuth requires a valid JWT bearer token on every request outside
+// the exempt paths. Requests without one are handed to otherwise, which
+// may accept other credentials, or refused with 401 when it is nil.
+func withJWTAuth(next http.Handler, verifier *jwtVerifier, otherwise http.Handler, exempt ...string) http.Handler {
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !isJWT(token) {
+			if otherwise != nil {
+				otherwise.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "bearer token required", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := verifier.verify(r.Context(), token)
+		if err != nil {
+			metrics.addCounter("solana_client_jwt_rejections_total", "Bearer tokens refused.", 1)
+			if errors.Is(err, errInvalidToken) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+			// The identity provider could not be reached to check the token
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jwtSubjectContextKey{}, claims.Subject)))
+	})
+}
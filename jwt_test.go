@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testIdentityProvider serves an OpenID discovery document and a JWKS, and
+// signs tokens with its keys
+type testIdentityProvider struct {
+	server  *httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	edKey   ed25519.PrivateKey
+	fetches int32
+	// release, when set, holds JWKS responses until it is closed
+	release chan struct{}
+}
+
+func newTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &testIdentityProvider{rsaKey: rsaKey, ecKey: ecKey, edKey: edKey}
+
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": idp.server.URL, "jwks_uri": idp.server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&idp.fetches, 1)
+		if idp.release != nil {
+			<-idp.release
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+			{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64(edKey.Public().(ed25519.PublicKey))},
+			{"kty": "RSA", "kid": "enc", "use": "enc", "n": b64(rsaKey.N.Bytes()), "e": "AQAB"},
+		}})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// sign issues a token with the claims, signed with the key kid by alg
+func (idp *testIdentityProvider) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, idp.rsaKey, crypto.SHA256, digest[:])
+	case "PS256":
+		signature, err = rsa.SignPSS(rand.Reader, idp.rsaKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, idp.ecKey, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case "EdDSA":
+		signature = ed25519.Sign(idp.edKey, []byte(signed))
+	default:
+		signature = []byte("signature")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"iss": idp.server.URL, "sub": "user-1", "aud": "client-api", "exp": now.Add(time.Hour).Unix()}
+		for k, v := range changes {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name        string
+		alg         string
+		kid         string
+		claims      map[string]interface{}
+		token       string
+		expectedErr string
+	}{
+		{name: "RS256", alg: "RS256", kid: "rsa", claims: claims(nil)},
+		{name: "PS256", alg: "PS256", kid: "rsa", claims: claims(nil)},
+		{name: "ES256", alg: "ES256", kid: "ec", claims: claims(nil)},
+		{name: "EdDSA", alg: "EdDSA", kid: "ed", claims: claims(nil)},
+		{name: "Audience Array", alg: "RS256", kid: "rsa", claims: claims(map[string]interface{}{"aud": []string{"other", "client-api"}})},
+		{name: "Expired Within Leeway", alg: "RS256", kid: "rsa", claims: claims(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()})},
+		{name: "Expired", alg: "RS256", kid: "rsa", claims: claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()}), expectedErr: "expired"},
+		{name: "No Expiry", alg: "RS256", kid: "rsa", claims: claims(map[string]interface{}{"exp": nil}), expectedErr: "no expiry"},
+		{name: "Not Valid Yet", alg: "RS256", kid: "rsa", claims: claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()}), expectedErr: "not valid yet"},
+		{name: "Wrong Issuer", alg: "RS256", kid: "rsa", claims: claims(map[string]interface{}{"iss": "https://other.example"}), expectedErr: "unexpected issuer"},
+		{name: "Wrong Audience", alg: "RS256", kid: "rsa", claims: claims(map[string]interface{}{"aud": []string{"other"}}), expectedErr: "unexpected audience"},
+		{name: "Unknown Key", alg: "RS256", kid: "missing", claims: claims(nil), expectedErr: "unknown key id"},
+		{name: "Encryption Key", alg: "RS256", kid: "enc", claims: claims(nil), expectedErr: "unknown key id"},
+		{name: "Key Of Other Type", alg: "ES256", kid: "rsa", claims: claims(nil), expectedErr: "bad signature"},
+		{name: "HMAC", alg: "HS256", kid: "rsa", claims: claims(nil), expectedErr: "unsupported algorithm"},
+		{name: "None", alg: "none", kid: "rsa", claims: claims(nil), expectedErr: "unsupported algorithm"},
+		{name: "Malformed", token: "a.b", expectedErr: "not a JWT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newJWTVerifier(idp.server.URL, "client-api", "")
+			v.now = func() time.Time { return now }
+			token := tt.token
+			if token == "" {
+				token = idp.sign(t, tt.alg, tt.kid, tt.claims)
+			}
+			got, err := v.verify(context.Background(), token)
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.Subject != "user-1" {
+				t.Errorf("Expected subject user-1, got %q", got.Subject)
+			}
+		})
+	}
+
+	t.Run("Tampered Claims", func(t *testing.T) {
+		v := newJWTVerifier(idp.server.URL, "", "")
+		v.now = func() time.Time { return now }
+		parts := strings.Split(idp.sign(t, "RS256", "rsa", claims(nil)), ".")
+		other := strings.Split(idp.sign(t, "RS256", "rsa", claims(map[string]interface{}{"sub": "admin"})), ".")
+		if _, err := v.verify(context.Background(), parts[0]+"."+other[1]+"."+parts[2]); err == nil || !strings.Contains(err.Error(), "bad signature") {
+			t.Errorf("Expected a bad signature, got %v", err)
+		}
+	})
+}
+
+func TestJWTVerifierRefetchesKeys(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	v := newJWTVerifier("", "", idp.server.URL+"/jwks")
+	v.now = func() time.Time { return now }
+	token := idp.sign(t, "RS256", "rsa", map[string]interface{}{"exp": now.Add(2 * time.Hour).Unix()})
+	unknown := idp.sign(t, "RS256", "rotated", map[string]interface{}{"exp": now.Add(2 * time.Hour).Unix()})
+
+	steps := []struct {
+		advance  time.Duration
+		token    string
+		fetches  int32
+		accepted bool
+	}{
+		{token: token, fetches: 1, accepted: true},
+		{token: token, fetches: 1, accepted: true},
+		// Unknown ids refetch, but not more often than jwksMinRefetch
+		{token: unknown, fetches: 1},
+		{advance: 2 * jwksMinRefetch, token: unknown, fetches: 2},
+		{token: unknown, fetches: 2},
+		{advance: jwksRefreshInterval + time.Second, token: token, fetches: 3, accepted: true},
+	}
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		_, err := v.verify(context.Background(), step.token)
+		if (err == nil) != step.accepted {
+			t.Errorf("Step %d: expected accepted %v, got error %v", i, step.accepted, err)
+		}
+		if got := atomic.LoadInt32(&idp.fetches); got != step.fetches {
+			t.Errorf("Step %d: expected %d JWKS fetches, got %d", i, step.fetches, got)
+		}
+	}
+}
+
+func TestJWTVerifierFetchesOutsideLock(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	v := newJWTVerifier("", "", idp.server.URL+"/jwks")
+	v.now = func() time.Time { return now }
+	token := idp.sign(t, "RS256", "rsa", map[string]interface{}{"exp": now.Add(2 * time.Hour).Unix()})
+	unknown := idp.sign(t, "RS256", "rotated", map[string]interface{}{"exp": now.Add(2 * time.Hour).Unix()})
+	if _, err := v.verify(context.Background(), token); err != nil {
+		t.Fatalf("verify returned error: %v", err)
+	}
+
+	// Tokens with an unknown id refetch the JWKS, which hangs
+	now = now.Add(2 * jwksMinRefetch)
+	idp.release = make(chan struct{})
+	var once sync.Once
+	release := func() { once.Do(func() { close(idp.release) }) }
+	t.Cleanup(release)
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := v.verify(context.Background(), unknown)
+			results <- err
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&idp.fetches) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("JWKS refetch never started")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Meanwhile tokens signed with known keys are still verified
+	verified := make(chan error, 1)
+	go func() {
+		_, err := v.verify(context.Background(), token)
+		verified <- err
+	}()
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Errorf("verify returned error during a refetch: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("verify of a known key waited for the JWKS refetch")
+	}
+
+	release()
+	for i := 0; i < 2; i++ {
+		if err := <-results; err == nil {
+			t.Error("Expected a token with an unknown key id to be rejected")
+		}
+	}
+	if got := atomic.LoadInt32(&idp.fetches); got != 2 {
+		t.Errorf("Expected concurrent refetches to share one fetch, got %d fetches", got)
+	}
+}
+
+func TestWithJWTAuth(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	verifier := newJWTVerifier(idp.server.URL, "client-api", "")
+	valid := idp.sign(t, "RS256", "rsa", map[string]interface{}{"iss": idp.server.URL, "aud": "client-api", "sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()})
+	expired := idp.sign(t, "RS256", "rsa", map[string]interface{}{"iss": idp.server.URL, "aud": "client-api", "exp": time.Now().Add(-time.Hour).Unix()})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("subject " + jwtSubjectFromContext(r.Context())))
+	})
+	otherwise := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("api key"))
+	})
+
+	tests := []struct {
+		name           string
+		path           string
+		authorization  string
+		otherwise      http.Handler
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Valid Token", path: "/v1/latest-slot", authorization: "Bearer " + valid, expectedStatus: http.StatusOK, expectedBody: "subject user-1"},
+		{name: "Expired Token", path: "/v1/latest-slot", authorization: "Bearer " + expired, expectedStatus: http.StatusUnauthorized, expectedBody: "expired"},
+		{name: "Missing Token", path: "/v1/latest-slot", expectedStatus: http.StatusUnauthorized, expectedBody: "bearer token required"},
+		{name: "Exempt Path", path: "/", expectedStatus: http.StatusOK, expectedBody: "subject "},
+		{name: "API Key Fallback", path: "/v1/latest-slot", authorization: "Bearer id.secret", otherwise: otherwise, expectedStatus: http.StatusOK, expectedBody: "api key"},
+		{name: "Invalid Token With Fallback", path: "/v1/latest-slot", authorization: "Bearer " + expired, otherwise: otherwise, expectedStatus: http.StatusUnauthorized, expectedBody: "expired"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			withJWTAuth(next, verifier, tt.otherwise, "/").ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body containing %q, got %q", tt.expectedBody, rr.Body.String())
+			}
+			if rr.Code == http.StatusUnauthorized && !strings.HasPrefix(rr.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("Expected a Bearer challenge, got %q", rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	t.Run("Unreachable Provider", func(t *testing.T) {
+		down := newJWTVerifier("http://127.0.0.1:1", "", "")
+		req := httptest.NewRequest(http.MethodGet, "/v1/latest-slot", nil)
+		req.Header.Set("Authorization", "Bearer "+valid)
+		rr := httptest.NewRecorder()
+		withJWTAuth(next, down, nil).ServeHTTP(rr, req)
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requestIDHeader carries the id of a request, both to clients and to the
+// upstream, so a failure can be traced across the proxy and the node
+const requestIDHeader = "X-Request-ID"
+
+// requestIDPattern accepts client supplied ids that are safe to log and
+// forward; anything else is replaced by a generated id
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// requestID returns the id of the request behind ctx, or "" outside one
+func requestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// newRequestID returns a random 128-bit id in hex
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// logFields writes a log line of msg followed by key=value fields, quoting
+// values that contain spaces, quotes or equals signs so lines stay parseable
+func logFields(msg string, fields ...interface{}) {
+	var b strings.Builder
+	b.WriteString("msg=")
+	b.WriteString(logValue(msg))
+	for i := 0; i+1 < len(fields); i += 2 {
+		fmt.Fprintf(&b, " %v=%s", fields[i], logValue(fmt.Sprint(fields[i+1])))
+	}
+	log.Print(b.String())
+}
+
+func logValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// withRequestID tags each request with the id from its X-Request-ID header,
+// or a new one, and echoes it on the response. With accessLog, every request
+// is logged with its method, path, status and duration once served.
+func withRequestID(next http.Handler, accessLog bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		if !accessLog {
+			return
+		}
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		logFields("request", "request_id", id, "method", r.Method, "path", r.URL.Path,
+			"status", rec.status, "duration_ms", time.Since(start).Milliseconds())
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWithRequestID(t *testing.T) {
+	var upstreamID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamID = r.Header.Get(requestIDHeader)
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":5,"id":%d}`, req.ID)
+	}))
+	defer server.Close()
+	client := newRPCClient(server.URL)
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	handler := withRequestID(handleGetLatestSlot(client), true)
+
+	tests := []struct {
+		name       string
+		incoming   string
+		expectedID string
+	}{
+		{name: "Client Supplied", incoming: "abc-123", expectedID: "abc-123"},
+		{name: "Generated When Missing", incoming: ""},
+		{name: "Generated When Malformed", incoming: "bad id\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req := httptest.NewRequest("GET", "/latest-block", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestIDHeader, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			id := rr.Header().Get(requestIDHeader)
+			if tt.expectedID != "" && id != tt.expectedID {
+				t.Errorf("Expected request id %q, got %q", tt.expectedID, id)
+			}
+			if tt.expectedID == "" && len(id) != 32 {
+				t.Errorf("Expected a generated 32 character id, got %q", id)
+			}
+			if upstreamID != id {
+				t.Errorf("Expected request id %q forwarded upstream, got %q", id, upstreamID)
+			}
+			want := fmt.Sprintf("msg=request request_id=%s method=GET path=/latest-block status=200 duration_ms=", id)
+			if !strings.HasPrefix(logs.String(), want) {
+				t.Errorf("Expected access log starting %q, got %q", want, logs.String())
+			}
+		})
+	}
+
+	// Upstream calls made outside a request carry no id
+	upstreamID = "unset"
+	client.getLatestSlot(context.Background())
+	if upstreamID != "" {
+		t.Errorf("Expected no request id upstream, got %q", upstreamID)
+	}
+}
+
+func TestLogFields(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	logFields("failed to write recording", "path", "/tmp/a b", "error", `bad "quote"`, "count", 3, "empty", "")
+
+	want := `msg="failed to write recording" path="/tmp/a b" error="bad \"quote\"" count=3 empty=""` + "\n"
+	if logs.String() != want {
+		t.Errorf("Expected %q, got %q", want, logs.String())
+	}
+}
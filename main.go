@@ -2,54 +2,151 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"solana-blockchain-client/pkg/solana"
 )
 
-// Configuration
+// Configuration defaults, see Config
 const (
 	solanaRPC      = "https://api.mainnet-beta.solana.com"
 	httpServerAddr = ":8080"
 	httpTimeout    = 10 * time.Second
+	maxAttempts    = 3
+	retryBackoff   = 250 * time.Millisecond
+	maxBatchSize   = 100
 )
 
 // SolanaRPCClient defines the interface for Solana RPC operations
 type SolanaRPCClient interface {
-	getLatestSlot() (uint64, error)
-	getBlockDetails(slot uint64) (json.RawMessage, error)
+	getLatestSlot(ctx context.Context) (uint64, error)
+	getBlockDetails(ctx context.Context, slot uint64, opts BlockOptions) (json.RawMessage, error)
+	getBlocks(ctx context.Context, slots []uint64, opts BlockOptions) ([]json.RawMessage, []error, error)
+	getConfirmedBlocks(ctx context.Context, start, end uint64) ([]uint64, error)
+	getTransaction(ctx context.Context, signature string, opts TransactionOptions) (json.RawMessage, error)
+	getTransactions(ctx context.Context, signatures []string, opts TransactionOptions) ([]json.RawMessage, []error, error)
+	getAccountInfo(ctx context.Context, address string, opts AccountOptions) (*AccountInfo, error)
+	getAccountInfoAt(ctx context.Context, address string, slot uint64, opts AccountOptions) (*AccountInfo, uint64, error)
+	getMultipleAccounts(ctx context.Context, addresses []string) ([]*AccountInfo, error)
+	getProgramAccounts(ctx context.Context, program string, filters []interface{}, opts AccountOptions) ([]ProgramAccount, error)
+	getBalance(ctx context.Context, address string) (uint64, uint64, error)
+	getTokenAccountsByOwner(ctx context.Context, owner, program, mint string) ([]TokenAccount, error)
+	getTokenAccountBalance(ctx context.Context, address string) (*TokenBalance, error)
+	getMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64) (uint64, error)
+	getLatestBlockhash(ctx context.Context) (*LatestBlockhash, error)
+	isBlockhashValid(ctx context.Context, blockhash string) (bool, uint64, error)
+	getFeeForMessage(ctx context.Context, message string) (*uint64, error)
+	getRecentPrioritizationFees(ctx context.Context, accounts []string) ([]PrioritizationFee, error)
+	getEpochInfo(ctx context.Context) (*EpochInfo, error)
+	getEpochSchedule(ctx context.Context) (*EpochSchedule, error)
+	getInflationRate(ctx context.Context) (*InflationRate, error)
+	getSupply(ctx context.Context, withAccounts bool) (*Supply, uint64, error)
+	getLargestAccounts(ctx context.Context, filter string) ([]LargestAccount, uint64, error)
+	getInflationReward(ctx context.Context, addresses []string, epoch uint64) ([]*InflationReward, error)
+	getRecentPerformanceSamples(ctx context.Context, limit int) ([]PerformanceSample, error)
+	simulateTransaction(ctx context.Context, transaction string) (*SimulationResult, error)
+	sendTransaction(ctx context.Context, transaction string, skipPreflight bool) (string, error)
+	getSignatureStatuses(ctx context.Context, signatures []string, searchHistory bool) ([]*SignatureStatus, error)
+	getSignaturesForAddress(ctx context.Context, address string, opts SignatureOptions) ([]SignatureInfo, error)
+	getVoteAccounts(ctx context.Context) (*VoteAccounts, error)
+	getClusterNodes(ctx context.Context) ([]ClusterNode, error)
+	getBlockTime(ctx context.Context, slot uint64) (*int64, error)
 }
 
-// JSON-RPC request struct
-type RPCRequest struct {
-	Jsonrpc string        `json:"jsonrpc"`
-	Method  string        `json:"method"`
-	Params  []interface{} `json:"params,omitempty"`
-	ID      int           `json:"id"`
-}
+// The JSON-RPC wire types are shared with the importable client in
+// pkg/solana
+type (
+	RPCRequest  = solana.Request
+	RPCResponse = solana.Response
+	RPCError    = solana.Error
+)
 
-// JSON-RPC response struct
-type RPCResponse struct {
-	Jsonrpc string          `json:"jsonrpc"`
-	Result  json.RawMessage `json:"result,omitempty"`
-	Error   *RPCError       `json:"error,omitempty"`
-	ID      int             `json:"id"`
+// BlockOptions configures a getBlock request
+type BlockOptions struct {
+	// MaxSupportedTransactionVersion is the highest transaction version to
+	// return. When nil only legacy transactions are supported and the node
+	// rejects blocks containing versioned transactions.
+	MaxSupportedTransactionVersion *int
+	// Commitment overrides the node's default commitment when set
+	Commitment string
+	// Encoding of the block's transactions; empty leaves the node's default
+	Encoding string
+	// TransactionDetails limits the transaction data returned, e.g. "none"
+	// for just the block header
+	TransactionDetails string
 }
 
-// RPCError represents an error returned from the RPC server
-type RPCError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-}
+// defaultMaxTransactionVersion is the transaction version requested unless a
+// client asks for legacy-only behavior
+const defaultMaxTransactionVersion = 0
 
 // rpcClient is a client for making RPC requests
 type rpcClient struct {
-	endpoint string
-	client   *http.Client
+	endpoint     string
+	client       *http.Client
+	lastID       *uint64
+	trace        func(requestID, responseID int)
+	maxAttempts  int
+	retryBackoff time.Duration
+	jitter       func(time.Duration) time.Duration
+	commitment   string
+	sleep        func(time.Duration)
+	maxBatchSize int
+	inflight     *callGroup
+	limits       *rateLimiter
+	budget       *upstreamBudget
+	interceptors []RequestInterceptor
+
+	// maxResponseBytes bounds upstream responses; 0 leaves them unbounded
+	maxResponseBytes int64
+	// gzipRequests compresses request bodies of at least gzipMinBytes
+	gzipRequests bool
+
+	// failover, when set, replaces endpoint with an ordered list of them
+	failover *failoverClient
+
+	// errorMappings classify provider-specific JSON-RPC errors
+	errorMappings []errorMapping
+}
+
+// RequestInterceptor may modify an upstream request before it is sent, for
+// example to add signed authentication headers. The body can be read
+// without consuming it through req.GetBody. Returning an error aborts the
+// request.
+type RequestInterceptor func(req *http.Request) error
+
+// addInterceptor registers an interceptor, run on every upstream request in
+// the order registered
+func (c *rpcClient) addInterceptor(interceptor RequestInterceptor) {
+	c.interceptors = append(c.interceptors, interceptor)
+}
+
+// httpStatusError is returned when the upstream answers with a server error
+// or throttles the request. RetryAfter is the wait the upstream asked for.
+type httpStatusError struct {
+	StatusCode int
+	RetryAfter time.Duration
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("RPC server returned HTTP %d", e.StatusCode)
 }
 
 // newRPCClient creates a new RPC client
@@ -59,32 +156,136 @@ func newRPCClient(endpoint string) *rpcClient {
 		client: &http.Client{
 			Timeout: httpTimeout,
 		},
+		lastID:       new(uint64),
+		maxAttempts:  maxAttempts,
+		retryBackoff: retryBackoff,
+		jitter:       halfJitter,
+		maxBatchSize: maxBatchSize,
+		inflight:     newCallGroup(),
+		limits:       newRateLimiter(),
+
+		maxResponseBytes: defaultMaxUpstreamResponseBytes,
+	}
+}
+
+// withTrace returns a copy of the client that reports the JSON-RPC ids of
+// every exchange to fn. The copy shares the id sequence with the original.
+func (c *rpcClient) withTrace(fn func(requestID, responseID int)) *rpcClient {
+	traced := *c
+	traced.trace = fn
+	return &traced
+}
+
+// pause waits d before the next attempt, returning early with the context's
+// error if ctx ends first
+func (c *rpcClient) pause(ctx context.Context, d time.Duration) error {
+	if c.sleep != nil {
+		c.sleep(d)
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sendRequest sends an RPC request to Solana, sharing the response of an
+// identical request already in flight
+func (c *rpcClient) sendRequest(ctx context.Context, method string, params []interface{}) (*RPCResponse, error) {
+	params, err := c.withCommitment(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	if c.inflight == nil || uncoalescedMethods[method] {
+		return c.doRequest(ctx, method, params)
 	}
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	response, err, shared := c.inflight.do(requestKey(method, rawParams), func() (*RPCResponse, error) {
+		return c.doRequest(ctx, method, params)
+	})
+	if !shared {
+		return response, err
+	}
+
+	// The call ran on behalf of another request, which may have been
+	// cancelled while this one is still live
+	if isContextError(err) && ctx.Err() == nil {
+		return c.doRequest(ctx, method, params)
+	}
+	metrics.addCounter("solana_client_coalesced_requests_total", "Requests served by an identical upstream request already in flight.", 1, "method", method)
+	return response, err
 }
 
-// sendRequest sends an RPC request to Solana
-func (c *rpcClient) sendRequest(method string, params []interface{}) (*RPCResponse, error) {
+// doRequest sends an RPC request upstream, retrying failures deemed safe to
+// retry. This is the only retry layer, so maxAttempts bounds the upstream
+// calls a request makes whatever mix of failures it meets.
+func (c *rpcClient) doRequest(ctx context.Context, method string, params []interface{}) (*RPCResponse, error) {
 	reqBody := RPCRequest{
 		Jsonrpc: "2.0",
 		Method:  method,
 		Params:  params,
-		ID:      1,
+		ID:      int(atomic.AddUint64(c.lastID, 1)),
 	}
-
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := c.client.Post(c.endpoint, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("RPC request failed: %w", err)
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		response, err := c.attemptRequest(ctx, reqBody.ID, jsonData)
+		if err == nil || attempt >= c.maxAttempts || ctx.Err() != nil {
+			observeRPC(method, time.Since(start), err)
+			return response, err
+		}
+		delay, retry := c.retryDelay(err, attempt)
+		if !retry {
+			observeRPC(method, time.Since(start), err)
+			return nil, err
+		}
+		if delay > 0 {
+			if err := c.pause(ctx, delay); err != nil {
+				observeRPC(method, time.Since(start), err)
+				return nil, err
+			}
+		}
 	}
-	defer resp.Body.Close()
+}
+
+// backoff returns the wait before retrying a failed attempt, doubling with
+// every attempt and jittered so that clients failing together do not retry
+// in lockstep
+func (c *rpcClient) backoff(attempt int) time.Duration {
+	d := c.retryBackoff << (attempt - 1)
+	if c.jitter != nil {
+		d = c.jitter(d)
+	}
+	return d
+}
+
+// halfJitter picks a wait between half of d and d
+func halfJitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
 
-	body, err := io.ReadAll(resp.Body)
+// attemptRequest posts an encoded RPC request upstream once and decodes the
+// response to it
+func (c *rpcClient) attemptRequest(ctx context.Context, id int, jsonData []byte) (*RPCResponse, error) {
+	body, err := c.post(ctx, jsonData, 1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
 	var response RPCResponse
@@ -92,16 +293,218 @@ func (c *rpcClient) sendRequest(method string, params []interface{}) (*RPCRespon
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
+	if c.trace != nil {
+		c.trace(id, response.ID)
+	}
+
+	if response.ID != id {
+		return nil, fmt.Errorf("RPC response id mismatch: sent %d, got %d", id, response.ID)
+	}
+
 	if response.Error != nil {
-		return nil, fmt.Errorf("RPC error: %d - %s", response.Error.Code, response.Error.Message)
+		return nil, c.rpcError(response.Error)
+	}
+
+	// A compliant response carries either an error or a result, even if null
+	if len(response.Result) == 0 {
+		return nil, fmt.Errorf("malformed RPC response: missing result")
 	}
 
 	return &response, nil
 }
 
+// postWithRetry posts a request that does not go through doRequest,
+// retrying failures deemed safe to retry
+func (c *rpcClient) postWithRetry(ctx context.Context, jsonData []byte, calls int) ([]byte, error) {
+	body, err := c.post(ctx, jsonData, calls)
+	for attempt := 1; err != nil && attempt < c.maxAttempts; attempt++ {
+		delay, retry := c.retryDelay(err, attempt)
+		if !retry || ctx.Err() != nil {
+			break
+		}
+		if delay > 0 {
+			if err := c.pause(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+		body, err = c.post(ctx, jsonData, calls)
+	}
+	return body, err
+}
+
+// post makes a single attempt at delivering a request to the endpoint. With
+// failover, the attempt moves on to the next endpoint whenever one fails.
+// calls is the number of JSON-RPC calls in the request, more than one for a
+// batch, each of which counts against the upstream budget.
+func (c *rpcClient) post(ctx context.Context, jsonData []byte, calls int) ([]byte, error) {
+	if c.budget != nil {
+		wait, ok := c.budget.reserve(calls)
+		if !ok {
+			metrics.addCounter("solana_client_upstream_budget_rejected_total", "Upstream requests shed for exceeding the request budget.", float64(calls))
+			return nil, errUpstreamBudget
+		}
+		if wait > 0 {
+			metrics.addCounter("solana_client_upstream_queue_seconds_total", "Time requests spent queued for the upstream request budget.", wait.Seconds())
+			if err := c.pause(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c.limits != nil {
+		if delay := c.limits.delay(); delay > 0 {
+			metrics.addCounter("solana_client_upstream_throttle_seconds_total", "Time spent delaying requests to stay within the upstream quota.", delay.Seconds())
+			if err := c.pause(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if c.failover == nil {
+		return c.postTo(ctx, c.endpoint, jsonData)
+	}
+	candidates, forced := c.failover.candidates()
+	var err error
+	tries := 0
+	for _, endpoint := range candidates {
+		if c.failover.maxTries > 0 && tries == c.failover.maxTries {
+			break
+		}
+		if !forced && !c.failover.claim(endpoint) {
+			continue
+		}
+		if tries > 0 {
+			metrics.addCounter("solana_client_upstream_failovers_total", "Upstream requests moved on to the next endpoint after one failed.", 1)
+		}
+		tries++
+		var body []byte
+		body, err = c.postTo(ctx, endpoint.url, jsonData)
+		if !c.failover.record(endpoint, err) {
+			return body, err
+		}
+	}
+	if tries == 0 {
+		metrics.addCounter("solana_client_upstream_breaker_rejected_total", "Upstream requests refused while every circuit breaker was open.", 1)
+		return nil, errCircuitOpen
+	}
+	return nil, err
+}
+
+// postTo delivers a request to a single endpoint
+func (c *rpcClient) postTo(ctx context.Context, endpoint string, jsonData []byte) ([]byte, error) {
+	compressed := c.gzipRequests && len(jsonData) >= gzipMinBytes
+	if compressed {
+		jsonData = gzipBytes(jsonData)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if id := requestID(ctx); id != "" {
+		req.Header.Set(requestIDHeader, id)
+	}
+	for _, intercept := range c.interceptors {
+		if err := intercept(req); err != nil {
+			return nil, fmt.Errorf("request interceptor failed: %w", err)
+		}
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("RPC request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	metrics.addCounter("solana_client_upstream_responses_total", "HTTP responses from upstream endpoints by status code.", 1, "code", strconv.Itoa(resp.StatusCode))
+	if c.limits != nil {
+		c.limits.observe(resp.Header)
+	}
+
+	if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusRequestEntityTooLarge || resp.StatusCode == http.StatusTooManyRequests {
+		io.Copy(io.Discard, resp.Body)
+		statusErr := &httpStatusError{StatusCode: resp.StatusCode}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			statusErr.RetryAfter = time.Duration(seconds) * time.Second
+			if resp.StatusCode == http.StatusTooManyRequests && c.limits != nil {
+				c.limits.holdOff(statusErr.RetryAfter)
+			}
+		}
+		return nil, statusErr
+	}
+
+	// The transport asks for gzip and decompresses the body, so the limit
+	// applies to the decompressed size
+	body, err := readBody(resp.Body, resp.ContentLength, c.maxResponseBytes)
+	if errors.Is(err, errResponseTooLarge) {
+		metrics.addCounter("solana_client_upstream_oversized_responses_total", "Upstream responses refused for exceeding the size limit.", 1)
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return body, nil
+}
+
+// retryDelay decides whether a failed attempt is retried and how long to wait
+// first. Connection failures never reached the server, so they are always
+// safe to retry and are retried immediately. Server errors and throttled
+// requests are retried with exponential backoff to give the endpoint time to
+// recover, waiting at least as long as a Retry-After header asks, and so
+// are RPC errors mapped as retriable. Anything else, such as a timeout after
+// the request was sent, is not retried.
+func (c *rpcClient) retryDelay(err error, attempt int) (time.Duration, bool) {
+	if isConnectionError(err) {
+		return 0, true
+	}
+
+	var rpcErr *upstreamError
+	if errors.As(err, &rpcErr) && rpcErr.Retry {
+		return c.backoff(attempt), true
+	}
+
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && (statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests) {
+		delay := c.backoff(attempt)
+		if statusErr.RetryAfter > delay {
+			delay = statusErr.RetryAfter
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// isContextError reports whether err is due to a cancelled or expired
+// request context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// isConnectionError reports whether err happened while establishing the
+// connection (DNS resolution, dialing or the TLS handshake)
+func isConnectionError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+
+	// A certificate that fails verification will fail again, so only a
+	// garbled handshake counts
+	var recordErr tls.RecordHeaderError
+	return errors.As(err, &recordErr)
+}
+
 // getLatestSlot gets the latest block (slot number)
-func (c *rpcClient) getLatestSlot() (uint64, error) {
-	response, err := c.sendRequest("getSlot", nil)
+func (c *rpcClient) getLatestSlot(ctx context.Context) (uint64, error) {
+	response, err := c.sendRequest(ctx, "getSlot", nil)
 	if err != nil {
 		return 0, err
 	}
@@ -114,9 +517,32 @@ func (c *rpcClient) getLatestSlot() (uint64, error) {
 	return slot, nil
 }
 
+// blockParams builds the getBlock parameters for slot
+func blockParams(slot uint64, opts BlockOptions) []interface{} {
+	config := map[string]interface{}{}
+	if opts.MaxSupportedTransactionVersion != nil {
+		config["maxSupportedTransactionVersion"] = *opts.MaxSupportedTransactionVersion
+	}
+	if opts.Commitment != "" {
+		config["commitment"] = opts.Commitment
+	}
+	if opts.Encoding != "" {
+		config["encoding"] = opts.Encoding
+	}
+	if opts.TransactionDetails != "" {
+		config["transactionDetails"] = opts.TransactionDetails
+	}
+
+	params := []interface{}{slot}
+	if len(config) > 0 {
+		params = append(params, config)
+	}
+	return params
+}
+
 // getBlockDetails gets details of a specific block
-func (c *rpcClient) getBlockDetails(slot uint64) (json.RawMessage, error) {
-	response, err := c.sendRequest("getBlock", []interface{}{slot})
+func (c *rpcClient) getBlockDetails(ctx context.Context, slot uint64, opts BlockOptions) (json.RawMessage, error) {
+	response, err := c.sendRequest(ctx, "getBlock", blockParams(slot, opts))
 	if err != nil {
 		return nil, err
 	}
@@ -124,23 +550,49 @@ func (c *rpcClient) getBlockDetails(slot uint64) (json.RawMessage, error) {
 	return response.Result, nil
 }
 
+// latestSlotResponse is the response of /latest-block
+type latestSlotResponse struct {
+	LatestBlock uint64 `json:"latest_block"`
+}
+
 // API handlers
 func handleGetLatestSlot(client SolanaRPCClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		slot, err := client.getLatestSlot()
+		slot, err := client.getLatestSlot(r.Context())
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), upstreamStatus(err))
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		// json.NewEncoder(w).Encode(map[string]uint64{"latest_block": slot})
-		jsonData, _ := json.Marshal(map[string]uint64{"latest_block": slot})
+		jsonData, _ := json.Marshal(latestSlotResponse{LatestBlock: slot})
 		w.Write(jsonData)
 	}
 }
 
-func handleGetBlockDetails(client SolanaRPCClient) http.HandlerFunc {
+// parseMaxTxVersion parses the maxTxVersion query parameter. An empty value
+// selects the default version and "legacy" disables versioned transactions.
+func parseMaxTxVersion(value string) (*int, error) {
+	version := defaultMaxTransactionVersion
+	switch value {
+	case "":
+	case "legacy":
+		return nil, nil
+	default:
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid maxTxVersion")
+		}
+		version = parsed
+	}
+	return &version, nil
+}
+
+// handleGetBlockDetails serves blocks by slot. With a non-zero
+// autoCommitmentSlots, blocks within that many slots of the tip are fetched
+// at confirmed commitment and older ones at finalized. defaultEncoding is
+// used when the request does not name an encoding.
+func handleGetBlockDetails(client SolanaRPCClient, autoCommitmentSlots uint64, defaultEncoding string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		slotStr := r.URL.Query().Get("block")
 		if slotStr == "" {
@@ -154,9 +606,58 @@ func handleGetBlockDetails(client SolanaRPCClient) http.HandlerFunc {
 			return
 		}
 
-		blockDetails, err := client.getBlockDetails(slot)
+		maxTxVersion, err := parseMaxTxVersion(r.URL.Query().Get("maxTxVersion"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		encoding, err := requestEncoding(r, defaultEncoding)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		parsed := false
+		switch r.URL.Query().Get("format") {
+		case "", "raw":
+		case "parsed":
+			if r.URL.Query().Get("encoding") != "" && encoding != "json" {
+				http.Error(w, "format=parsed requires json encoding", http.StatusBadRequest)
+				return
+			}
+			parsed, encoding = true, "json"
+		default:
+			http.Error(w, "format must be raw or parsed", http.StatusBadRequest)
+			return
+		}
+
+		opts := BlockOptions{MaxSupportedTransactionVersion: maxTxVersion, Encoding: encoding}
+		if autoCommitmentSlots > 0 && requestCommitment(r.Context()) == "" {
+			tip, err := client.getLatestSlot(r.Context())
+			if err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+			opts.Commitment = chooseCommitment(slot, tip, autoCommitmentSlots)
+			w.Header().Set(commitmentHeader, opts.Commitment)
+		}
+
+		blockDetails, err := client.getBlockDetails(r.Context(), slot, opts)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		if parsed {
+			block, err := parseBlock(slot, blockDetails)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			jsonData, _ := json.Marshal(block)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jsonData)
 			return
 		}
 
@@ -166,14 +667,452 @@ func handleGetBlockDetails(client SolanaRPCClient) http.HandlerFunc {
 }
 
 func main() {
-	client := newRPCClient(solanaRPC)
+	debug := flag.Bool("debug", false, "expose upstream JSON-RPC ids in the X-RPC-Id response header and worker pool priorities in X-Pool-Priority")
+	recordDir := flag.String("record", "", "record every upstream exchange into this directory")
+	replayDir := flag.String("replay", "", "serve upstream responses from recordings in this directory")
+	idlDir := flag.String("anchor-idl-dir", "", "directory of Anchor IDL files used by /account?decode=anchor")
+	slotCacheTTL := flag.Duration("slot-cache-ttl", defaultSlotCacheTTL, "maximum time the latest slot is served from cache")
+	blockStoreSpec := flag.String("block-store", "", "persist finalized blocks and transactions to serve them after the node prunes them: file:<dir>")
+	index := flag.Bool("index", false, "follow the finalized tip, writing every block to -block-store and resuming from its checkpoint after a restart")
+	indexFrom := flag.Uint64("index-from", 0, "slot the indexer starts from when -block-store holds no checkpoint; 0 starts at the finalized tip")
+	addressIndexing := flag.Bool("address-index", false, "index the transactions of every block the indexer stores by address, served by /address/activity; needs -index and json or jsonParsed -default-encoding")
+	ethRPCURL := flag.String("eth-rpc-url", "", "Ethereum JSON-RPC endpoint served under /v1/ethereum/; unset leaves Ethereum out")
+	btcRPCURL := flag.String("btc-rpc-url", "", "Bitcoin Core JSON-RPC endpoint, version 28 or later, served under /v1/bitcoin/; unset leaves Bitcoin out")
+	btcRPCUser := flag.String("btc-rpc-user", "", "rpcuser of the Bitcoin Core node")
+	btcRPCPassword := flag.String("btc-rpc-password", os.Getenv(envBitcoinRPCPassword), "rpcpassword of the Bitcoin Core node (env "+envBitcoinRPCPassword+")")
+	blockCacheBytes := flag.Int64("block-cache-bytes", defaultBlockCacheBytes, "memory used to cache finalized blocks, evicting the least recently used; 0 disables the cache")
+	slotLagTolerance := flag.Uint64("slot-lag-tolerance", defaultSlotLagTolerance, "slots the cached latest slot may trail before its TTL is shortened")
+	autoCommitmentSlots := flag.Uint64("auto-commitment-slots", 0, "fetch blocks within this many slots of the tip at confirmed commitment and older ones at finalized; 0 leaves the commitment to the node")
+	defaultEncoding := flag.String("default-encoding", "", "transaction encoding used by /transaction and /block-details when the request names none: json, jsonParsed, base64 or base58; jsonParsed is the most expensive for the node to serve")
+	epochBoundarySlots := flag.Uint64("epoch-boundary-slots", defaultEpochBoundarySlots, "slots before the epoch end reported as near the boundary")
+	attempts := flag.Int("max-attempts", maxAttempts, "attempts made at an upstream call before its failure is returned")
+	breakerFailures := flag.Int("breaker-failures", failoverFailureThreshold, "upstream failures in a row after which an RPC endpoint's circuit breaker opens and the endpoint is skipped")
+	breakerCooldown := flag.Duration("breaker-cooldown", failoverCooldown, "time an open circuit breaker waits before letting a single probe request through to its endpoint")
+	failoverMaxEndpoints := flag.Int("failover-max-endpoints", 0, "most RPC endpoints a single upstream call is tried on before its failure is returned, not counting endpoints skipped by their circuit breaker; 0 tries them all")
+	breakerFailFast := flag.Bool("breaker-fail-fast", true, "answer 503 at once while every RPC endpoint's circuit breaker is open, instead of sending requests to them anyway")
+	backoff := flag.Duration("retry-backoff", retryBackoff, "wait before the first retry of a failed upstream call, doubling with every further attempt")
+	upstreamRPS := flag.Float64("upstream-rps", 0, "upstream requests per second to stay within, queueing the excess; 0 disables the budget")
+	upstreamBurst := flag.Int("upstream-burst", defaultUpstreamBurst, "upstream requests that may be sent at once before -upstream-rps applies")
+	upstreamQueueWait := flag.Duration("upstream-max-queue-wait", defaultUpstreamMaxQueueWait, "longest an upstream request is queued for -upstream-rps before it is rejected with 503")
+	batchSize := flag.Int("max-batch-size", maxBatchSize, "maximum calls sent upstream in a single JSON-RPC batch")
+	poolWorkers := flag.Int("workers", defaultPoolWorkers, "workers shared by all fan-out requests to the upstream")
+	poolQueueSize := flag.Int("worker-queue", defaultPoolQueueSize, "tasks that may wait for a free worker")
+	bulkFanOut := flag.Int("bulk-fanout", defaultBulkFanOut, "upstream calls above which a request's worker pool tasks yield to interactive ones; 0 disables")
+	tenantRate := flag.Float64("tenant-rate", 0, "requests per second allowed to each X-Tenant-ID tenant; 0 disables per-tenant limits")
+	tenantBurst := flag.Int("tenant-burst", defaultTenantBurst, "requests a tenant may make at once before -tenant-rate applies")
+	apiKeysPath := flag.String("api-keys", "", "JSON file of API keys required on every API request, as X-API-Key or a bearer token; written back when keys are issued or revoked")
+	apiAdminToken := flag.String("api-admin-token", os.Getenv(envAPIAdminToken), "bearer token of the /admin/keys API for issuing and revoking -api-keys (env "+envAPIAdminToken+")")
+	oidcIssuer := flag.String("oidc-issuer", "", "issuer whose JWT bearer tokens are accepted on every API request, alongside any -api-keys; its keys are found through OpenID discovery unless -jwks-url is set")
+	oidcAudience := flag.String("oidc-audience", "", "audience JWT bearer tokens must be issued for; empty skips the check")
+	jwksURL := flag.String("jwks-url", "", "JWKS of the keys JWT bearer tokens are signed with")
+	corsOrigins := flag.String("cors-origins", "", "comma-separated origins browsers may call the API from, such as https://app.example.com or https://*.example.com; * allows any, and empty disables CORS")
+	corsMethods := flag.String("cors-methods", defaultCORSMethods, "comma-separated methods allowed to -cors-origins")
+	corsHeaders := flag.String("cors-headers", defaultCORSHeaders, "comma-separated request headers allowed to -cors-origins")
+	corsMaxAge := flag.Duration("cors-max-age", defaultCORSMaxAge, "how long browsers may cache a preflight response")
+	tlsCert := flag.String("tls-cert", "", "PEM certificate to serve the API over HTTPS with; reloaded when the file changes")
+	tlsKey := flag.String("tls-key", "", "PEM private key of -tls-cert")
+	tlsClientCA := flag.String("tls-client-ca", "", "PEM bundle of CAs API clients must present a certificate from, for mutual TLS; needs -tls-cert")
+	upstreamProxy := flag.String("upstream-proxy", "", "http://, https:// or socks5:// proxy for upstream RPC requests; empty takes it from HTTPS_PROXY, HTTP_PROXY and NO_PROXY")
+	upstreamDialTimeout := flag.Duration("upstream-dial-timeout", defaultUpstreamDialTimeout, "time allowed to connect to an upstream RPC endpoint")
+	upstreamKeepAlive := flag.Duration("upstream-keepalive", defaultUpstreamKeepAlive, "TCP keep-alive period of upstream connections; negative disables TCP keep-alives")
+	upstreamMaxIdle := flag.Int("upstream-max-idle-conns", defaultUpstreamMaxIdleConns, "idle upstream connections kept across all hosts; 0 means no limit")
+	upstreamMaxIdlePerHost := flag.Int("upstream-max-idle-per-host", defaultUpstreamMaxIdlePerHost, "idle upstream connections kept for each host")
+	upstreamIdleTimeout := flag.Duration("upstream-idle-timeout", defaultUpstreamIdleConnTimeout, "time an idle upstream connection is kept open; 0 means no limit")
+	upstreamNoKeepAlives := flag.Bool("upstream-disable-keepalives", false, "open a new upstream connection for every request")
+	maxUpstreamResponse := flag.Int64("max-upstream-response-bytes", defaultMaxUpstreamResponseBytes, "largest upstream response read, after decompression; 0 means no limit")
+	upstreamGzip := flag.Bool("upstream-gzip-requests", false, "gzip upstream request bodies of 1 KiB or more; the endpoints must accept Content-Encoding: gzip")
+	gzipResponses := flag.Bool("gzip", true, "gzip responses for clients that accept it, and accept gzip request bodies")
+	upstreamCA := flag.String("upstream-ca", "", "PEM bundle of CAs trusted for upstream RPC endpoints, in addition to the system roots")
+	ipRate := flag.Float64("ip-rate", 0, "requests per second allowed to each client address; 0 disables per-address limits")
+	ipBurst := flag.Int("ip-burst", defaultIPBurst, "requests a client address may make at once before -ip-rate applies")
+	globalRate := flag.Float64("global-rate", 0, "requests per second allowed across all clients; 0 disables the global limit")
+	globalBurst := flag.Int("global-burst", defaultGlobalBurst, "requests all clients may make at once before -global-rate applies")
+	trustForwardedFor := flag.Bool("trust-forwarded-for", false, "take client addresses from the last X-Forwarded-For entry, when running behind a proxy that appends it")
+	shedQueueDepth := flag.Int("shed-queue-depth", defaultShedQueueDepth, "queued worker pool tasks above which requests are rejected with 503, bulk requests from half of it; 0 disables shedding")
+	streamBuffer := flag.Int("stream-buffer", defaultSubscriberBufferSize, "notifications buffered per streaming client; the oldest are dropped when a client falls behind")
+	streamMaxDrops := flag.Int("stream-max-drops", defaultSubscriberMaxDrops, "disconnect a streaming client after this many notifications in a row are dropped for it; 0 never disconnects")
+	errorMapPath := flag.String("rpc-error-map", "", "JSON file mapping provider-specific RPC error codes and messages to HTTP statuses and retries")
+	swaggerUI := flag.Bool("swagger-ui", false, "serve Swagger UI for "+openAPIPath+" on "+apiVersion+"/docs, loading its scripts from unpkg.com")
+	adminListen := flag.String("admin-listen", "", "serve /metrics and /healthz/all on this address instead of the API listener")
+	accessLog := flag.Bool("access-log", true, "log every request with its id, method, path, status and duration")
+	drainTimeout := flag.Duration("drain-timeout", shutdownTimeout, "time in-flight requests are given to finish on shutdown")
+	shutdownDelay := flag.Duration("shutdown-delay", 0, "time /readyz fails on SIGTERM before the servers stop accepting requests, so load balancers can take the instance out first")
+	errorFormat := flag.String("error-format", errorFormatText, "format of error responses: text, or problem for RFC 7807 problem details")
+	config, err := newConfig(os.Getenv)
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.bindFlags(flag.CommandLine)
+	flag.Parse()
+
+	if err := config.validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	if *defaultEncoding != "" && !transactionEncodings[*defaultEncoding] {
+		log.Fatalf("unsupported -default-encoding %q", *defaultEncoding)
+	}
+	if *streamBuffer < 1 {
+		log.Fatal("-stream-buffer must be at least 1")
+	}
+	if *streamMaxDrops < 0 {
+		log.Fatal("-stream-max-drops must not be negative")
+	}
+
+	transport, err := newUpstreamTransport(transportOptions{
+		Proxy:               *upstreamProxy,
+		DialTimeout:         *upstreamDialTimeout,
+		KeepAlive:           *upstreamKeepAlive,
+		MaxIdleConns:        *upstreamMaxIdle,
+		MaxIdleConnsPerHost: *upstreamMaxIdlePerHost,
+		IdleConnTimeout:     *upstreamIdleTimeout,
+		DisableKeepAlives:   *upstreamNoKeepAlives,
+		RootCAsFile:         *upstreamCA,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	client := newRPCClient(config.RPCURL)
+	client.client.Timeout = config.RPCTimeout
+	client.client.Transport = transport
+	client.maxResponseBytes = *maxUpstreamResponse
+	client.gzipRequests = *upstreamGzip
+	if *breakerFailures < 1 {
+		log.Fatal("-breaker-failures must be at least 1")
+	}
+	endpoints := config.endpoints()
+	client.failover = newFailoverClient(endpoints)
+	client.failover.threshold = *breakerFailures
+	client.failover.cooldown = *breakerCooldown
+	client.failover.failFast = *breakerFailFast
+	if *failoverMaxEndpoints < 0 {
+		log.Fatal("-failover-max-endpoints must not be negative")
+	}
+	client.failover.maxTries = *failoverMaxEndpoints
+	if len(endpoints) > 1 {
+		logFields("failing over across RPC endpoints", "endpoints", len(endpoints))
+	}
+	client.commitment = config.Commitment
+	client.maxBatchSize = *batchSize
+	if *attempts < 1 {
+		log.Fatal("-max-attempts must be at least 1")
+	}
+	client.maxAttempts = *attempts
+	client.retryBackoff = *backoff
+	client.budget = newUpstreamBudget(*upstreamRPS, *upstreamBurst, *upstreamQueueWait)
+	if *errorMapPath != "" {
+		mappings, err := loadErrorMappings(*errorMapPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		client.errorMappings = mappings
+		logFields("loaded RPC error mappings", "mappings", len(mappings))
+	}
+	if *recordDir != "" && *replayDir != "" {
+		log.Fatal("-record and -replay are mutually exclusive")
+	}
+	var recorder *recordingTransport
+	if *recordDir != "" {
+		recorder, err = newRecordingTransport(*recordDir, transport)
+		if err != nil {
+			log.Fatal(err)
+		}
+		client.client.Transport = recorder
+		logFields("recording upstream RPC traffic", "dir", *recordDir)
+	}
+	if *replayDir != "" {
+		replayer, err := newReplayTransport(*replayDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		client.client.Transport = replayer
+		logFields("replaying upstream RPC traffic", "dir", *replayDir)
+	}
+
+	var idls *anchorRegistry
+	if *idlDir != "" {
+		var err error
+		if idls, err = loadAnchorIDLs(*idlDir); err != nil {
+			log.Fatal(err)
+		}
+		logFields("loaded Anchor IDLs", "programs", len(idls.programs))
+	}
+
+	slots := newLatestSlotCache(*slotCacheTTL, *slotLagTolerance)
+	var blocks *blockCache
+	if *blockCacheBytes > 0 {
+		blocks = newBlockCache(*blockCacheBytes)
+	}
+	var store blockStore
+	if *blockStoreSpec != "" {
+		if store, err = openBlockStore(*blockStoreSpec); err != nil {
+			log.Fatal(err)
+		}
+		logFields("persisting finalized blocks", "store", strings.SplitN(*blockStoreSpec, ":", 2)[0])
+	}
+	if *index && store == nil {
+		log.Fatal("-index requires -block-store")
+	}
+	var addresses *addressIndex
+	if *addressIndexing {
+		if !*index {
+			log.Fatal("-address-index requires -index")
+		}
+		if *defaultEncoding != "" && *defaultEncoding != "json" && *defaultEncoding != "jsonParsed" {
+			log.Fatal("-address-index requires json or jsonParsed -default-encoding")
+		}
+		addresses = newAddressIndex(store)
+	}
+	pool := newWorkerPool(*poolWorkers, *poolQueueSize)
+	pool.bulkFanOut = *bulkFanOut
+	pool.shedDepth = *shedQueueDepth
+	pool.priorityHeader = *debug
+
+	// route builds a handler on top of the shared caches, tracing upstream
+	// RPC ids when debugging, applying any ?commitment= and reporting the
+	// commitment applied
+	route := func(build func(SolanaRPCClient) http.HandlerFunc) http.HandlerFunc {
+		cached := func(c SolanaRPCClient) http.HandlerFunc {
+			return build(blocks.wrap(wrapWithStore(slots.wrap(c), store, config.Commitment), config.Commitment))
+		}
+		handler := cached(client)
+		if *debug {
+			handler = withRPCIDHeader(client, cached)
+		}
+		return withCommitmentHeader(withCommitmentParam(handler))
+	}
 
 	// Setup HTTP API routes
-	mux := http.NewServeMux()
-	mux.HandleFunc("/latest-block", handleGetLatestSlot(client))
-	mux.HandleFunc("/block-details", handleGetBlockDetails(client))
+	rentCache := newRentExemptionCache()
+	subscriptions := newSubscriptionHub(wsEndpoint(config.RPCURL))
+	subscriptions.dial = newWSDialer(transport).dial
+	subscriptions.bufferSize = *streamBuffer
+	subscriptions.maxDrops = *streamMaxDrops
+	topProgramScans := newTopProgramsCache(topProgramsCacheTTL)
+	routes := []apiRoute{
+		{Path: "/latest-block", Description: "Latest slot", handler: route(handleGetLatestSlot), responses: returning(latestSlotResponse{})},
+		{Path: "/blocks", Description: "Confirmed slots between ?start= and ?end=, with block summaries if ?summaries=true", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetBlockRange(c, pool)
+		}), responses: returning(blockRangeResponse{})},
+		{Path: "/blocks-batch", Description: "Blocks at the comma separated ?slots=, fetched in one upstream batch, optionally with ?encoding=, ?maxTxVersion= and ?transactionDetails=", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetBlocksBatch(c, pool, *defaultEncoding)
+		}), responses: returning(blocksBatchResponse{})},
+		{Path: "/block-details", Description: "Block at ?block=<slot>, optionally with ?encoding= and ?maxTxVersion=, or typed with ?format=parsed", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetBlockDetails(c, *autoCommitmentSlots, *defaultEncoding)
+		})},
+		{Path: "/transaction", Description: "Transaction with ?signature=, optionally with ?encoding=jsonParsed and ?maxTxVersion=, or typed with ?format=parsed; POST a base64 transaction to send it, simulating it first with ?simulate=true", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			get := handleGetTransaction(c, *defaultEncoding)
+			return byMethod(map[string]http.HandlerFunc{http.MethodGet: get, http.MethodHead: get, http.MethodPost: handleSubmitTransaction(c)})
+		}), responses: map[string]interface{}{http.MethodGet: nil, http.MethodPost: submitTransactionResponse{}}, request: transactionRequest{}},
+		{Path: "/transaction/status", Description: "Confirmation status and depth of the transaction ?signature=, waiting up to ?wait=<duration> for it to reach ?commitment=", handler: route(handleGetTransactionStatus), responses: returning(transactionStatusResponse{})},
+		{Path: "/account", Description: "Account at ?address=<pubkey>, with ?encoding=base64|base58|jsonParsed or decoded with ?decode=anchor|stake|vote; ?slot= reads the state at that slot where the provider keeps it, or a newer one with ?allowNewer=true", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetAccount(c, idls)
+		})},
+		{Path: "/balance", Description: "Balance of ?address=<pubkey> in lamports and SOL", handler: route(handleGetBalance), responses: returning(balanceResponse{})},
+		{Path: "/tokens", Description: "Token accounts of ?owner= with their mint, amount and decimals, optionally only for ?mint=", handler: route(handleGetTokens), responses: returning(tokensResponse{})},
+		{Path: "/token-balance", Description: "Balance of the token account at ?account=", handler: route(handleGetTokenBalance), responses: returning(tokenBalanceResponse{})},
+		{Path: "/associated-token-addresses", Description: "Associated token accounts of ?owner= for ?mints=, with balances if ?withBalances=true", handler: route(handleGetAssociatedTokenAddresses), responses: returning(associatedTokenAddressesResponse{})},
+		{Path: "/transactions", Description: "Transactions of ?address=<pubkey>, newest first, paged with ?limit=, ?before= and ?until=, with fee and status if ?hydrate=true", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetTransactionHistory(c, pool)
+		}), responses: returning(transactionHistoryResponse{})},
+		{Path: "/account/activity-rate", Description: "Transaction rate of ?address=<pubkey> over its last ?window= transactions", handler: route(handleGetActivityRate), responses: returning(activityRateResponse{})},
+		{Path: "/account/total-fees", Description: "Fees paid by ?address=<pubkey> as fee payer over its last ?limit= transactions", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetTotalFees(c, pool)
+		}), responses: returning(totalFeesResponse{})},
+		{Path: "/rent", Description: "Lamports for an account of ?size= bytes, or of a common ?type=system|nonce|mint|token|token-multisig|stake|vote, to be rent exempt", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetRent(c, rentCache)
+		}), responses: returning(rentResponse{})},
+		{Path: "/rent-due", Description: "Rent exemption status of ?address=<pubkey>", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetRentDue(c, rentCache)
+		}), responses: returning(rentDueResponse{})},
+		{Path: "/blockhash", Description: "Latest blockhash and the last block height a transaction using it is accepted at", handler: route(handleGetBlockhash), responses: returning(blockhashResponse{})},
+		{Path: "/blockhash/valid", Description: "Whether ?blockhash= is still valid for new transactions", handler: route(handleGetBlockhashValid), responses: returning(blockhashValidResponse{})},
+		{Path: "/fee-for-message", Description: "Fee in lamports the network charges for a base64 encoded ?message=", handler: route(handleGetFeeForMessage), responses: returning(feeForMessageResponse{})},
+		{Path: "/blockhash-and-fee", Description: "Latest blockhash with the fee per signature, or the fee of a base64 ?message=", handler: route(handleGetBlockhashAndFee), responses: returning(blockhashAndFeeResponse{})},
+		{Path: "/priority-fee-estimate", Description: "Priority fee at ?percentile= for transactions writing ?accounts=", handler: route(handleGetPriorityFeeEstimate), responses: returning(priorityFeeEstimate{})},
+		{Path: "/fees/priority", Description: "Suggested p50, p75 and p90 priority fees over recent slots for transactions writing ?accounts=", handler: route(handleGetPriorityFees), responses: returning(priorityFeeLevels{})},
+		{Path: "/reorg-check", Description: "Whether the block at ?slot= still has ?expectedBlockhash= at confirmed commitment", handler: route(handleGetReorgCheck), responses: returning(reorgCheckResponse{})},
+		{Path: "/epoch", Description: "Current epoch, its progress and the epoch schedule", handler: route(handleGetEpoch), responses: returning(epochResponse{})},
+		{Path: "/inflation", Description: "Inflation rates of the current epoch", handler: route(handleGetInflation), responses: returning(inflationResponse{})},
+		{Path: "/supply", Description: "Total, circulating and non-circulating supply, listing the non-circulating accounts with ?accounts=true", handler: route(handleGetSupply), responses: returning(supplyResponse{})},
+		{Path: "/largest-accounts", Description: "Accounts holding the most lamports, only ?filter=circulating|nonCirculating ones if given, up to ?limit=; the node refreshes the list every two hours", handler: route(handleGetLargestAccounts), responses: returning(largestAccountsResponse{})},
+		{Path: "/tps", Description: "Current, average and peak transactions per second and slot times over the last ?samples= performance samples, each about a minute long", handler: route(handleGetTPS), responses: returning(tpsResponse{})},
+		{Path: "/epoch-boundary", Description: "Slots and estimated time until the next epoch", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetEpochBoundary(c, *epochBoundarySlots)
+		}), responses: returning(epochBoundaryResponse{})},
+		{Path: "/epoch-progress", Description: "Epochs and slots elapsed between slots ?from= and ?to=", handler: route(handleGetEpochProgress), responses: returning(epochProgressResponse{})},
+		{Path: "/cluster-time", Description: "Cluster time of the most recent slot with a block time", handler: route(handleGetClusterTime), responses: returning(clusterTimeResponse{})},
+		{Path: "/top-programs", Description: "Most invoked programs over the last ?blocks= slots", handler: route(func(c SolanaRPCClient) http.HandlerFunc {
+			return handleGetTopPrograms(c, pool, topProgramScans)
+		}), responses: returning(topProgramsResponse{})},
+		{Path: "/stakes", Description: "Stake accounts whose staker or withdrawer is ?owner=<pubkey>, optionally only those delegated to ?voter=, with their delegation status and the rewards of the last epoch unless ?rewards=false", handler: route(handleGetStakes), responses: returning(stakesResponse{})},
+		{Path: "/validators", Description: "Validators with their stake, commission, last vote and version, filtered by ?status=current|delinquent, ?identity= and ?votePubkey=, ordered by ?sort=stake|commission|lastVote|identity and ?order=asc|desc, up to ?limit=", handler: route(handleGetValidators), responses: returning(validatorsResponse{})},
+		{Path: "/validator-stake-share", Description: "Stake share and rank of the validator with ?votePubkey=", handler: route(handleGetValidatorStakeShare), responses: returning(validatorStakeShareResponse{})},
+		{Path: "/simulate-and-send", Description: "POST a transaction to simulate and send it if the simulation succeeds", handler: route(handleSimulateAndSend), responses: map[string]interface{}{http.MethodPost: simulateAndSendResponse{}}, request: transactionRequest{}},
+		{Path: "/graphql", Description: "GraphQL queries over blocks, transactions and accounts, POSTed as JSON or sent with ?query=, ?operationName= and ?variables=", handler: route(handleGraphQL), responses: map[string]interface{}{http.MethodGet: graphqlResponse{}, http.MethodPost: graphqlResponse{}}, request: graphqlRequest{}},
+		{Path: "/verify-signature", Description: "POST a base64 message with a base58 signature and pubkey to verify offline", handler: handleVerifySignature, responses: map[string]interface{}{http.MethodPost: verifySignatureResponse{}}, request: verifySignatureRequest{}},
+		{Path: "/account/logs/stream", Description: "Server-sent events with the logs of transactions mentioning ?address=<pubkey>", handler: handleAccountLogsStream(subscriptions), responses: returning(eventStream{})},
+		{Path: "/program/accounts", Description: "Accounts owned by ?programId=, filtered by ?dataSize= and ?memcmp=<offset>:<bytes>, in ?encoding= with an optional ?dataSlice=<offset>:<length>, ?limit= at a time after the ?after= pubkey", handler: route(handleGetProgramAccounts), responses: returning(programAccountsResponse{})},
+		{Path: "/program/stream", Description: "Server-sent events for accounts owned by ?programId=, optionally filtered by ?dataSize= and ?memcmp=<offset>:<bytes>", handler: handleProgramStream(subscriptions), responses: returning(eventStream{})},
+		{Path: "/stream/slots", Description: "Server-sent events for every slot the node processes", handler: handleSlotStream(subscriptions), responses: returning(eventStream{})},
+		{Path: "/stream/blocks", Description: "Server-sent events for confirmed blocks, with ?transactionDetails=none|signatures|accounts|full", handler: handleBlockStream(subscriptions), responses: returning(eventStream{})},
+		{Path: "/sse", Description: "Server-sent events for ?stream=slots or ?stream=blocks, with ?transactionDetails= for blocks", handler: handleStreamSSE(subscriptions), responses: returning(eventStream{})},
+		{Path: "/ws", Description: "WebSocket of JSON events for ?stream=slots or ?stream=blocks, with ?transactionDetails= for blocks", handler: handleStreamWS(subscriptions)},
+		{Path: "/buildinfo", Description: "Build and runtime information", handler: handleBuildInfo, responses: returning(BuildInfo{})},
+	}
+	if addresses != nil {
+		routes = append(routes, apiRoute{Path: "/address/activity", Description: "Transactions of ?address=<pubkey> from the local address index, newest first, paged with ?limit= and ?cursor=", handler: handleGetAddressActivity(addresses), responses: returning(addressActivityResponse{})})
+	}
+	routes = versioned(routes)
+	routes = append(routes, chainRoutes("solana", func(build func(ChainClient) http.HandlerFunc) http.HandlerFunc {
+		return route(func(c SolanaRPCClient) http.HandlerFunc { return build(solanaChain{client: c}) })
+	})...)
+	if *ethRPCURL != "" {
+		eth := newRPCClient(*ethRPCURL)
+		eth.client.Timeout = config.RPCTimeout
+		eth.client.Transport = transport
+		eth.maxResponseBytes = *maxUpstreamResponse
+		routes = append(routes, chainRoutes("ethereum", func(build func(ChainClient) http.HandlerFunc) http.HandlerFunc {
+			return build(ethereumChain{client: eth})
+		})...)
+	}
+	if *btcRPCURL != "" {
+		btc := newRPCClient(*btcRPCURL)
+		btc.client.Timeout = config.RPCTimeout
+		btc.client.Transport = transport
+		btc.maxResponseBytes = *maxUpstreamResponse
+		if *btcRPCUser != "" {
+			btc.addInterceptor(withBasicAuth(*btcRPCUser, *btcRPCPassword))
+		}
+		routes = append(routes, chainRoutes("bitcoin", func(build func(ChainClient) http.HandlerFunc) http.HandlerFunc {
+			return build(bitcoinChain{client: btc})
+		})...)
+	}
+	if *swaggerUI {
+		routes = append(routes, apiRoute{Path: apiVersion + "/docs", Description: "Swagger UI for the OpenAPI document at " + openAPIPath, handler: handleSwaggerUI})
+	}
 
-	// Start server
-	log.Printf("Starting Solana Blockchain Client API server on %s...", httpServerAddr)
-	log.Fatal(http.ListenAndServe(httpServerAddr, mux))
+	// Operational endpoints move to their own listener when one is set, so
+	// they can be kept off the public surface
+	ready := &readiness{}
+	adminRoutes := []apiRoute{
+		{Path: "/healthz", Description: "Liveness probe, answering while the process serves requests", handler: handleHealthz, responses: returning(probeResponse{})},
+		{Path: "/readyz", Description: "Readiness probe, failing while draining for shutdown or when the upstream is unreachable", handler: handleReadyz(client, ready), responses: returning(probeResponse{})},
+		{Path: "/healthz/all", Description: "Health and latest slot of every upstream endpoint", handler: handleHealthAll(config.endpoints(), transport), responses: returning(healthAllResponse{})},
+		{Path: "/metrics", Description: "Prometheus metrics", handler: handleMetrics},
+		{Path: "/cache/stats", Description: "Size and hit rates of the block and latest slot caches", handler: handleCacheStats(blocks, slots), responses: returning(cacheStatsResponse{})},
+	}
+	var keys *apiKeyRing
+	if *apiKeysPath != "" {
+		if keys, err = loadAPIKeys(*apiKeysPath); err != nil {
+			log.Fatal(err)
+		}
+		if *apiAdminToken != "" {
+			adminRoutes = append(adminRoutes, apiRoute{Path: "/admin/keys", Description: "API keys with their usage; POST issues a key and DELETE revokes the key ?id=, with the admin token as a bearer token", handler: handleAPIKeys(keys, *apiAdminToken),
+				responses: map[string]interface{}{http.MethodGet: []apiKeyStatus{}, http.MethodPost: issueAPIKeyResponse{}, http.MethodDelete: nil}, request: issueAPIKeyRequest{}})
+		}
+	} else if *apiAdminToken != "" {
+		log.Fatal("-api-admin-token needs -api-keys")
+	}
+	if *adminListen == "" {
+		routes = append(routes, adminRoutes...)
+	}
+	mux := newAPIMux(routes)
+	var streams []string
+	for _, path := range []string{"/program/stream", "/account/logs/stream", "/stream/slots", "/stream/blocks", "/sse", "/ws"} {
+		streams = append(streams, path, apiVersion+path)
+	}
+	handler := withRequestTimeout(mux, config.RequestTimeout, config.MaxRequestTimeout, streams...)
+	probes := []string{"/healthz", "/readyz", "/healthz/all"}
+	handler = withLoadShedding(handler, pool, *shedQueueDepth, probes...)
+	handler = withTenant(handler, newKeyedLimiter(*tenantRate, *tenantBurst), probes...)
+	// The operational endpoints have their own access control, and the index
+	// and API description are public
+	open := []string{"/", "/favicon.ico", openAPIPath, apiVersion + "/docs"}
+	for _, route := range adminRoutes {
+		open = append(open, route.Path)
+	}
+	var keyed http.Handler
+	if keys != nil {
+		keyed = withAPIKeys(handler, keys, open...)
+		logFields("requiring API keys", "keys", len(keys.list()))
+	}
+	if *oidcIssuer != "" || *jwksURL != "" {
+		// Requests without a JWT may still present an API key
+		handler = withJWTAuth(handler, newJWTVerifier(*oidcIssuer, *oidcAudience, *jwksURL), keyed, open...)
+		logFields("requiring JWT bearer tokens", "issuer", *oidcIssuer, "audience", *oidcAudience)
+	} else if keyed != nil {
+		handler = keyed
+	} else if *oidcAudience != "" {
+		log.Fatal("-oidc-audience needs -oidc-issuer or -jwks-url")
+	}
+	handler = withClientRateLimit(handler, newKeyedLimiter(*ipRate, *ipBurst), newKeyedLimiter(*globalRate, *globalBurst), *trustForwardedFor, probes...)
+	handler = withHTTPMetrics(handler, routes, streams...)
+	handler, err = withErrorFormat(handler, *errorFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *gzipResponses {
+		handler = withGzip(handler, streams...)
+	}
+	if *corsOrigins != "" {
+		// Outside the limits and authentication, so that preflights are
+		// answered and browsers can read their errors
+		policy, err := newCORSPolicy(*corsOrigins, *corsMethods, *corsHeaders, *corsMaxAge)
+		if err != nil {
+			log.Fatal(err)
+		}
+		handler = withCORS(handler, policy)
+	}
+	handler = withRequestID(handler, *accessLog)
+
+	servers := []*http.Server{{Addr: config.ListenAddr, Handler: handler}}
+	servers[0].RegisterOnShutdown(subscriptions.close)
+	if *tlsCert != "" || *tlsKey != "" || *tlsClientCA != "" {
+		files, err := newTLSFiles(*tlsCert, *tlsKey, *tlsClientCA)
+		if err != nil {
+			log.Fatal(err)
+		}
+		servers[0].TLSConfig = files.serverConfig()
+		logFields("serving HTTPS", "cert", *tlsCert, "client_ca", *tlsClientCA)
+	}
+	if *adminListen != "" {
+		servers = append(servers, &http.Server{Addr: *adminListen, Handler: newAPIMux(adminRoutes)})
+		logFields("serving operational endpoints", "addr", *adminListen)
+	}
+
+	// Start servers, stopping them together on SIGINT or SIGTERM once
+	// /readyz has failed for -shutdown-delay
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, stopDraining := drainFirst(ctx, ready, *shutdownDelay)
+	defer stopDraining()
+	var indexed chan struct{}
+	if *index {
+		indexVersion := defaultMaxTransactionVersion
+		ix := newIndexer(client, store, BlockOptions{MaxSupportedTransactionVersion: &indexVersion, Encoding: *defaultEncoding}, *indexFrom)
+		ix.addresses = addresses
+		indexed = make(chan struct{})
+		go func() {
+			defer close(indexed)
+			ix.run(ctx)
+		}()
+		logFields("indexing finalized blocks", "from", *indexFrom)
+	}
+	logFields("starting Solana Blockchain Client API server", "addr", config.ListenAddr, "rpc_url", config.RPCURL)
+	err = runServers(ctx, *drainTimeout, servers...)
+	pool.stop()
+	if recorder != nil {
+		recorder.Close()
+	}
+	if indexed != nil {
+		<-indexed
+	}
+	if store != nil {
+		store.close()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	logFields("server stopped")
 }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricFamily holds every labelled series of a single metric
+type metricFamily struct {
+	help       string
+	kind       string
+	series     map[string]float64
+	histograms map[string]*histogramSeries
+}
+
+// latencyBuckets are the upper bounds, in seconds, of latency histograms
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// histogramSeries counts observations per bucket, the last being +Inf
+type histogramSeries struct {
+	labels []string
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// metricsRegistry is a minimal registry rendered in the Prometheus text format
+type metricsRegistry struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+}
+
+// metrics is the registry served on /metrics
+var metrics = newMetricsRegistry()
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{families: make(map[string]*metricFamily)}
+}
+
+// formatLabels renders label name/value pairs as {name="value",...}
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1])
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, labels[i], value))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (r *metricsRegistry) family(name, help, kind string) *metricFamily {
+	f, ok := r.families[name]
+	if !ok {
+		f = &metricFamily{help: help, kind: kind, series: make(map[string]float64), histograms: make(map[string]*histogramSeries)}
+		r.families[name] = f
+	}
+	return f
+}
+
+// setGauge sets the value of a gauge series
+func (r *metricsRegistry) setGauge(name, help string, value float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.family(name, help, "gauge").series[formatLabels(labels)] = value
+}
+
+// addCounter increments a counter series
+func (r *metricsRegistry) addCounter(name, help string, delta float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.family(name, help, "counter").series[formatLabels(labels)] += delta
+}
+
+// observeHistogram records value in a histogram series with latencyBuckets
+func (r *metricsRegistry) observeHistogram(name, help string, value float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.family(name, help, "histogram")
+	key := formatLabels(labels)
+	h, ok := f.histograms[key]
+	if !ok {
+		h = &histogramSeries{labels: labels, counts: make([]uint64, len(latencyBuckets)+1)}
+		f.histograms[key] = h
+	}
+	i := sort.SearchFloat64s(latencyBuckets, value)
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+// histogramCount returns the observations of a histogram series, mostly
+// useful in tests
+func (r *metricsRegistry) histogramCount(name string, labels ...string) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		if h, ok := f.histograms[formatLabels(labels)]; ok {
+			return h.count
+		}
+	}
+	return 0
+}
+
+// value returns the current value of a series, mostly useful in tests
+func (r *metricsRegistry) value(name string, labels ...string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if f, ok := r.families[name]; ok {
+		return f.series[formatLabels(labels)]
+	}
+	return 0
+}
+
+// writeTo renders all metrics sorted by name and labels
+func (r *metricsRegistry) writeTo(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.families))
+	for name := range r.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := r.families[name]
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.kind)
+
+		labels := make([]string, 0, len(f.series)+len(f.histograms))
+		for l := range f.series {
+			labels = append(labels, l)
+		}
+		for l := range f.histograms {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			if h, ok := f.histograms[l]; ok {
+				h.writeTo(w, name)
+				continue
+			}
+			fmt.Fprintf(w, "%s%s %g\n", name, l, f.series[l])
+		}
+	}
+}
+
+// writeTo renders the cumulative buckets, sum and count of the series
+func (h *histogramSeries) writeTo(w io.Writer, name string) {
+	var cumulative uint64
+	for i, count := range h.counts {
+		cumulative += count
+		bound := "+Inf"
+		if i < len(latencyBuckets) {
+			bound = fmt.Sprintf("%g", latencyBuckets[i])
+		}
+		le := append(append([]string{}, h.labels...), "le", bound)
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(le), cumulative)
+	}
+	fmt.Fprintf(w, "%s_sum%s %g\n", name, formatLabels(h.labels), h.sum)
+	fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(h.labels), h.count)
+}
+
+// withHTTPMetrics counts requests by route and status code and observes
+// their latency. Paths outside routes share the "other" label so unknown
+// paths cannot grow the series without bound. The duration of the untimed
+// paths, such as long-lived streams, is not observed.
+func withHTTPMetrics(next http.Handler, routes []apiRoute, untimed ...string) http.Handler {
+	known := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		known[route.Path] = true
+	}
+	untimedPaths := make(map[string]bool, len(untimed))
+	for _, path := range untimed {
+		untimedPaths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		if !known[path] && path != "/" && path != openAPIPath {
+			path = "other"
+		}
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		metrics.addCounter("solana_client_http_requests_total", "HTTP requests by route and status code.", 1,
+			"path", path, "code", strconv.Itoa(rec.status))
+		if !untimedPaths[path] {
+			metrics.observeHistogram("solana_client_http_request_duration_seconds", "Latency of HTTP requests by route.",
+				time.Since(start).Seconds(), "path", path)
+		}
+	})
+}
+
+// observeRPC records the outcome and latency of an upstream RPC call
+func observeRPC(method string, elapsed time.Duration, err error) {
+	metrics.addCounter("solana_client_rpc_requests_total", "Upstream RPC calls by method and result.", 1,
+		"method", method, "result", rpcResult(err))
+	metrics.observeHistogram("solana_client_rpc_request_duration_seconds", "Latency of upstream RPC calls by method, retries included.",
+		elapsed.Seconds(), "method", method)
+
+	var rpcErr *upstreamError
+	if errors.As(err, &rpcErr) {
+		metrics.addCounter("solana_client_rpc_errors_total", "RPC errors returned by the upstream by method and error code.", 1,
+			"method", method, "code", strconv.Itoa(rpcErr.Code))
+	}
+}
+
+// rpcResult classifies the outcome of an upstream RPC call
+func rpcResult(err error) string {
+	var rpcErr *upstreamError
+	var statusErr *httpStatusError
+	switch {
+	case err == nil:
+		return "success"
+	case errors.As(err, &rpcErr):
+		return "rpc_error"
+	case errors.As(err, &statusErr):
+		return "http_error"
+	case isContextError(err):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metrics.writeTo(w)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	registry := newMetricsRegistry()
+	registry.addCounter("test_requests_total", "Test requests.", 2, "path", "/a")
+	registry.addCounter("test_requests_total", "Test requests.", 1, "path", "/a")
+	registry.setGauge("test_temperature", "Test gauge.", 1.5)
+
+	var body strings.Builder
+	registry.writeTo(&body)
+
+	want := "# HELP test_requests_total Test requests.\n# TYPE test_requests_total counter\ntest_requests_total{path=\"/a\"} 3\n" +
+		"# HELP test_temperature Test gauge.\n# TYPE test_temperature gauge\ntest_temperature 1.5\n"
+	if body.String() != want {
+		t.Errorf("unexpected metrics output:\n got %q\nwant %q", body.String(), want)
+	}
+}
+
+func TestHistogramMetrics(t *testing.T) {
+	registry := newMetricsRegistry()
+	registry.observeHistogram("test_duration_seconds", "Test latency.", 0.003, "path", "/a")
+	registry.observeHistogram("test_duration_seconds", "Test latency.", 0.2, "path", "/a")
+	registry.observeHistogram("test_duration_seconds", "Test latency.", 30, "path", "/a")
+
+	var body strings.Builder
+	registry.writeTo(&body)
+
+	for _, want := range []string{
+		"# TYPE test_duration_seconds histogram\n",
+		"test_duration_seconds_bucket{path=\"/a\",le=\"0.005\"} 1\n",
+		"test_duration_seconds_bucket{path=\"/a\",le=\"0.25\"} 2\n",
+		"test_duration_seconds_bucket{path=\"/a\",le=\"10\"} 2\n",
+		"test_duration_seconds_bucket{path=\"/a\",le=\"+Inf\"} 3\n",
+		"test_duration_seconds_sum{path=\"/a\"} 30.203\n",
+		"test_duration_seconds_count{path=\"/a\"} 3\n",
+	} {
+		if !strings.Contains(body.String(), want) {
+			t.Errorf("Expected %q in metrics output:\n%s", want, body.String())
+		}
+	}
+}
+
+func TestWithHTTPMetrics(t *testing.T) {
+	routes := []apiRoute{{Path: "/latest-block"}, {Path: "/stream/slots"}}
+	handler := withHTTPMetrics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest-block" {
+			w.Write([]byte("{}"))
+			return
+		}
+		http.NotFound(w, r)
+	}), routes, "/stream/slots")
+
+	tests := []struct {
+		name         string
+		path         string
+		expectedPath string
+		expectedCode string
+		timed        bool
+	}{
+		{name: "Known Route", path: "/latest-block", expectedPath: "/latest-block", expectedCode: "200", timed: true},
+		{name: "Unknown Route", path: "/no-such-route", expectedPath: "other", expectedCode: "404", timed: true},
+		{name: "Untimed Route", path: "/stream/slots", expectedPath: "/stream/slots", expectedCode: "404", timed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := metrics.value("solana_client_http_requests_total", "path", tt.expectedPath, "code", tt.expectedCode)
+			observed := metrics.histogramCount("solana_client_http_request_duration_seconds", "path", tt.expectedPath)
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			if got := metrics.value("solana_client_http_requests_total", "path", tt.expectedPath, "code", tt.expectedCode); got != requests+1 {
+				t.Errorf("Expected request counted for %s with code %s", tt.expectedPath, tt.expectedCode)
+			}
+			if got := metrics.histogramCount("solana_client_http_request_duration_seconds", "path", tt.expectedPath); (got > observed) != tt.timed {
+				t.Errorf("Expected latency observed %v, got %d observations after %d", tt.timed, got, observed)
+			}
+		})
+	}
+}
+
+func TestRPCMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method == "getBlockTime" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","error":{"code":-32009,"message":"Slot 1 was skipped"},"id":%d}`, req.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":7,"id":%d}`, req.ID)
+	}))
+	defer server.Close()
+	client := newRPCClient(server.URL)
+
+	successes := metrics.value("solana_client_rpc_requests_total", "method", "getSlot", "result", "success")
+	failures := metrics.value("solana_client_rpc_requests_total", "method", "getBlockTime", "result", "rpc_error")
+	codes := metrics.value("solana_client_rpc_errors_total", "method", "getBlockTime", "code", "-32009")
+	responses := metrics.value("solana_client_upstream_responses_total", "code", "200")
+
+	client.getLatestSlot(context.Background())
+	client.getBlockTime(context.Background(), 1)
+
+	if got := metrics.value("solana_client_rpc_requests_total", "method", "getSlot", "result", "success"); got != successes+1 {
+		t.Errorf("Expected getSlot success counted, got %v after %v", got, successes)
+	}
+	if got := metrics.value("solana_client_rpc_requests_total", "method", "getBlockTime", "result", "rpc_error"); got != failures+1 {
+		t.Errorf("Expected getBlockTime RPC error counted, got %v after %v", got, failures)
+	}
+	if got := metrics.value("solana_client_rpc_errors_total", "method", "getBlockTime", "code", "-32009"); got != codes+1 {
+		t.Errorf("Expected RPC error code counted, got %v after %v", got, codes)
+	}
+	if got := metrics.value("solana_client_upstream_responses_total", "code", "200"); got != responses+2 {
+		t.Errorf("Expected 2 upstream responses counted, got %v after %v", got, responses)
+	}
+	if metrics.histogramCount("solana_client_rpc_request_duration_seconds", "method", "getSlot") == 0 {
+		t.Error("Expected getSlot latency observed")
+	}
+}
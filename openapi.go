@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// openAPIPath is where the OpenAPI document of the API is served
+const openAPIPath = "/v1/openapi.json"
+
+// openAPISpec is an OpenAPI 3 document
+type openAPISpec struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       openAPIInfo                     `json:"info"`
+	Paths      map[string]map[string]openAPIOp `json:"paths"`
+	Components openAPIComponents               `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]*openAPISchema `json:"schemas"`
+}
+
+type openAPIOp struct {
+	Summary     string                     `json:"summary"`
+	OperationID string                     `json:"operationId"`
+	Parameters  []openAPIParam             `json:"parameters,omitempty"`
+	RequestBody *openAPIBody               `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParam struct {
+	Name   string         `json:"name"`
+	In     string         `json:"in"`
+	Schema *openAPISchema `json:"schema"`
+}
+
+type openAPIBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema"`
+}
+
+// openAPISchema is the subset of JSON Schema the API's types need. An empty
+// schema accepts any value, as for blocks passed through from the node.
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Nullable             bool                      `json:"nullable,omitempty"`
+	Minimum              *int                      `json:"minimum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty"`
+}
+
+// eventStream stands for the response of a server-sent events route
+type eventStream struct{}
+
+// routeParams finds the ?name= query parameters mentioned in a route
+// description, so documenting a parameter there also publishes it
+var routeParams = regexp.MustCompile(`\?([A-Za-z]+)=`)
+
+// newOpenAPISpec describes the routes, reflecting the schemas of their
+// request and response types. Deprecated aliases are left out.
+func newOpenAPISpec(routes []apiRoute) *openAPISpec {
+	spec := &openAPISpec{
+		OpenAPI:    "3.0.3",
+		Info:       openAPIInfo{Title: "Solana Blockchain Client API", Version: version},
+		Paths:      make(map[string]map[string]openAPIOp),
+		Components: openAPIComponents{Schemas: make(map[string]*openAPISchema)},
+	}
+	for _, route := range routes {
+		if route.successor != "" {
+			continue
+		}
+		var params []openAPIParam
+		seen := make(map[string]bool)
+		for _, match := range routeParams.FindAllStringSubmatch(route.Description, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				params = append(params, openAPIParam{Name: match[1], In: "query", Schema: &openAPISchema{Type: "string"}})
+			}
+		}
+
+		operations := make(map[string]openAPIOp)
+		responses := route.responses
+		if responses == nil {
+			responses = returning(nil)
+		}
+		for method, response := range responses {
+			content := map[string]openAPIMediaType{"text/event-stream": {Schema: &openAPISchema{Type: "string"}}}
+			if _, ok := response.(eventStream); !ok {
+				content = map[string]openAPIMediaType{"application/json": {Schema: spec.schema(reflect.TypeOf(response))}}
+			}
+			op := openAPIOp{
+				Summary:     route.Description,
+				OperationID: operationID(method, route.Path),
+				Parameters:  params,
+				Responses: map[string]openAPIResponse{
+					"200":     {Description: "OK", Content: content},
+					"default": {Description: "Error"},
+				},
+			}
+			if method == http.MethodPost {
+				op.RequestBody = &openAPIBody{Required: true, Content: map[string]openAPIMediaType{"application/json": {Schema: spec.schema(reflect.TypeOf(route.request))}}}
+			}
+			operations[strings.ToLower(method)] = op
+		}
+		spec.Paths[route.Path] = operations
+	}
+	return spec
+}
+
+// operationID names an operation for generated clients, such as
+// getV1TokenBalance for GET /v1/token-balance
+func operationID(method, path string) string {
+	id := strings.ToLower(method)
+	for _, word := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '.' }) {
+		id += strings.ToUpper(word[:1]) + word[1:]
+	}
+	return id
+}
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// schema returns the schema of values of type t as encoding/json marshals
+// them. Named structs are added to the components and referenced.
+func (s *openAPISpec) schema(t reflect.Type) *openAPISchema {
+	if t == nil || t == rawMessageType {
+		return &openAPISchema{}
+	}
+	if t == timeType {
+		return &openAPISchema{Type: "string", Format: "date-time"}
+	}
+	zero := 0
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := s.schema(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &openAPISchema{Type: "number"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: s.schema(t.Elem())}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		name := t.Name()
+		if _, ok := s.Components.Schemas[name]; !ok {
+			// Reserve the name first, as the struct may refer to itself
+			s.Components.Schemas[name] = nil
+			s.Components.Schemas[name] = s.structSchema(t)
+		}
+		return &openAPISchema{Ref: "#/components/schemas/" + name}
+	}
+	return &openAPISchema{}
+}
+
+// structSchema lists the fields encoding/json marshals, promoting those of
+// embedded structs
+func (s *openAPISpec) structSchema(t reflect.Type) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for key, property := range s.structSchema(embedded).Properties {
+					if _, ok := schema.Properties[key]; !ok {
+						schema.Properties[key] = property
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = s.schema(field.Type)
+	}
+	return schema
+}
+
+// handleOpenAPI serves the OpenAPI document of the routes
+func handleOpenAPI(routes []apiRoute) http.HandlerFunc {
+	jsonData, _ := json.Marshal(newOpenAPISpec(routes))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonData)
+	}
+}
+
+// swaggerUIPage renders the OpenAPI document with Swagger UI, loaded from a
+// CDN so that the binary does not embed it
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+<title>Solana Blockchain Client API</title>
+<link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui"></div>
+<script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+<script>SwaggerUIBundle({url: "` + openAPIPath + `", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`
+
+// handleSwaggerUI serves the Swagger UI page
+func handleSwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	routes := versioned([]apiRoute{
+		{Path: "/token-balance", Description: "Balance of the token account at ?account=", handler: ok, responses: returning(tokenBalanceResponse{})},
+		{Path: "/verify-signature", Description: "POST a message to verify", handler: ok, responses: map[string]interface{}{http.MethodPost: verifySignatureResponse{}}, request: verifySignatureRequest{}},
+		{Path: "/stream/slots", Description: "Server-sent events for every slot", handler: ok, responses: returning(eventStream{})},
+	})
+	routes = append(routes, apiRoute{Path: "/v1/solana/block", Description: "The block numbered ?number= or ?number=", handler: ok})
+
+	rr := httptest.NewRecorder()
+	newAPIMux(routes).ServeHTTP(rr, httptest.NewRequest("GET", openAPIPath, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	var spec openAPISpec
+	if err := json.Unmarshal(rr.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Failed to unmarshal spec: %v", err)
+	}
+
+	if len(spec.Paths) != 4 {
+		t.Errorf("Expected 4 paths without the aliases, got %d", len(spec.Paths))
+	}
+	get := spec.Paths["/v1/token-balance"]["get"]
+	if get.OperationID != "getV1TokenBalance" || len(get.Parameters) != 1 || get.Parameters[0].Name != "account" {
+		t.Errorf("Unexpected token balance operation: %+v", get)
+	}
+	if ref := get.Responses["200"].Content["application/json"].Schema.Ref; ref != "#/components/schemas/tokenBalanceResponse" {
+		t.Errorf("Expected a reference to tokenBalanceResponse, got %q", ref)
+	}
+
+	// Embedded fields are promoted and unsigned integers are not negative
+	schema := spec.Components.Schemas["tokenBalanceResponse"]
+	for _, property := range []string{"account", "amount", "decimals", "ui_amount"} {
+		if schema.Properties[property] == nil {
+			t.Errorf("Expected property %s in %+v", property, schema.Properties)
+		}
+	}
+	if decimals := schema.Properties["decimals"]; decimals.Type != "integer" || decimals.Minimum == nil {
+		t.Errorf("Expected decimals to be a non-negative integer, got %+v", decimals)
+	}
+
+	post, found := spec.Paths["/v1/verify-signature"]["post"]
+	if !found || post.RequestBody == nil || post.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/verifySignatureRequest" {
+		t.Errorf("Expected a verifySignatureRequest body, got %+v", post)
+	}
+	if _, ok := spec.Paths["/v1/verify-signature"]["get"]; ok {
+		t.Error("Expected no GET operation for a POST route")
+	}
+	if _, ok := spec.Paths["/v1/stream/slots"]["get"].Responses["200"].Content["text/event-stream"]; !ok {
+		t.Error("Expected an event stream response")
+	}
+	if params := spec.Paths["/v1/solana/block"]["get"].Parameters; len(params) != 1 {
+		t.Errorf("Expected repeated parameters to be listed once, got %+v", params)
+	}
+	if _, ok := spec.Components.Schemas["eventStream"]; ok {
+		t.Error("Expected no schema for event streams")
+	}
+}
+
+func TestOpenAPISchema(t *testing.T) {
+	type nested struct {
+		Self *nested `json:"self"`
+	}
+	spec := newOpenAPISpec(nil)
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected *openAPISchema
+	}{
+		{name: "Raw JSON", value: json.RawMessage(nil), expected: &openAPISchema{}},
+		{name: "Nullable", value: new(int64), expected: &openAPISchema{Type: "integer", Format: "int64", Nullable: true}},
+		{name: "Bytes", value: []byte(nil), expected: &openAPISchema{Type: "string", Format: "byte"}},
+		{name: "Map", value: map[string]bool{}, expected: &openAPISchema{Type: "object", AdditionalProperties: &openAPISchema{Type: "boolean"}}},
+		{name: "Recursive", value: nested{}, expected: &openAPISchema{Ref: "#/components/schemas/nested"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := spec.schema(reflect.TypeOf(tt.value))
+			if !reflect.DeepEqual(schema, tt.expected) {
+				t.Errorf("Expected schema %+v, got %+v", tt.expected, schema)
+			}
+		})
+	}
+
+	if self := spec.Components.Schemas["nested"].Properties["self"]; self.Ref != "#/components/schemas/nested" {
+		t.Errorf("Expected the recursive field to reference its type, got %+v", self)
+	}
+}
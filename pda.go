@@ -0,0 +1,144 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Programs involved in associated token account derivation
+const (
+	tokenProgramID           = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	token2022ProgramID       = "TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb"
+	associatedTokenProgramID = "ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL"
+)
+
+// tokenPrograms are the programs whose mints have associated token accounts
+var tokenPrograms = map[string]bool{tokenProgramID: true, token2022ProgramID: true}
+
+// Program derived address limits
+const (
+	maxSeeds      = 16
+	maxSeedLength = 32
+	pdaMarker     = "ProgramDerivedAddress"
+)
+
+var errNoViableBump = errors.New("unable to find a viable program address bump seed")
+
+// Curve25519 field prime and the twisted Edwards curve constant d
+var (
+	curveP = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+	curveD = func() *big.Int {
+		d := new(big.Int).ModInverse(big.NewInt(121666), curveP)
+		d.Mul(d, big.NewInt(-121665))
+		return d.Mod(d, curveP)
+	}()
+)
+
+// isOnCurve reports whether a 32 byte key decompresses to an ed25519 point,
+// that is whether x² = (y² - 1) / (d·y² + 1) has a solution for its y
+func isOnCurve(key []byte) bool {
+	// y is little endian with the sign of x in the top bit
+	be := make([]byte, 32)
+	for i, b := range key {
+		be[31-i] = b
+	}
+	be[0] &= 0x7f
+	y := new(big.Int).SetBytes(be)
+	y.Mod(y, curveP)
+
+	y2 := new(big.Int).Mul(y, y)
+	u := new(big.Int).Sub(y2, big.NewInt(1))
+	v := new(big.Int).Mul(curveD, y2)
+	v.Add(v, big.NewInt(1))
+	u.Mod(u, curveP)
+	v.Mod(v, curveP)
+
+	x2 := new(big.Int).ModInverse(v, curveP)
+	x2.Mul(x2, u)
+	x2.Mod(x2, curveP)
+	if x2.Sign() == 0 {
+		return true
+	}
+
+	// Euler's criterion: x2 is a square iff x2^((p-1)/2) = 1
+	exp := new(big.Int).Rsh(new(big.Int).Sub(curveP, big.NewInt(1)), 1)
+	return new(big.Int).Exp(x2, exp, curveP).Cmp(big.NewInt(1)) == 0
+}
+
+// createProgramAddress hashes seeds into an address owned by programID. It
+// fails if the hash lands on the curve, as such an address could have a
+// private key.
+func createProgramAddress(seeds [][]byte, programID []byte) ([]byte, error) {
+	if len(seeds) > maxSeeds {
+		return nil, fmt.Errorf("too many seeds: %d", len(seeds))
+	}
+
+	h := sha256.New()
+	for _, seed := range seeds {
+		if len(seed) > maxSeedLength {
+			return nil, fmt.Errorf("seed longer than %d bytes", maxSeedLength)
+		}
+		h.Write(seed)
+	}
+	h.Write(programID)
+	h.Write([]byte(pdaMarker))
+	address := h.Sum(nil)
+
+	if isOnCurve(address) {
+		return nil, fmt.Errorf("program address is on the curve")
+	}
+	return address, nil
+}
+
+// findProgramAddress finds the first off-curve address for seeds, trying
+// bump seeds from 255 down, and returns it with its bump
+func findProgramAddress(seeds [][]byte, programID []byte) ([]byte, uint8, error) {
+	for bump := 255; bump >= 0; bump-- {
+		address, err := createProgramAddress(append(seeds, []byte{byte(bump)}), programID)
+		if err == nil {
+			return address, uint8(bump), nil
+		}
+	}
+	return nil, 0, errNoViableBump
+}
+
+// decodePubkey decodes a base58 public key
+func decodePubkey(key string) ([]byte, error) {
+	decoded, err := base58Decode(key)
+	if err != nil || len(decoded) != 32 {
+		return nil, fmt.Errorf("invalid public key: %s", key)
+	}
+	return decoded, nil
+}
+
+// associatedTokenAddress derives the associated token account of owner for
+// mint under the original token program
+func associatedTokenAddress(owner, mint string) (string, error) {
+	return associatedTokenAddressFor(owner, mint, tokenProgramID)
+}
+
+// associatedTokenAddressFor derives the associated token account of owner for
+// mint under tokenProgram, the program that owns the mint
+func associatedTokenAddressFor(owner, mint, tokenProgram string) (string, error) {
+	ownerKey, err := decodePubkey(owner)
+	if err != nil {
+		return "", err
+	}
+	mintKey, err := decodePubkey(mint)
+	if err != nil {
+		return "", err
+	}
+	programKey, err := decodePubkey(tokenProgram)
+	if err != nil {
+		return "", err
+	}
+	ataProgram, _ := decodePubkey(associatedTokenProgramID)
+
+	address, _, err := findProgramAddress([][]byte{ownerKey, programKey, mintKey}, ataProgram)
+	if err != nil {
+		return "", err
+	}
+	return base58Encode(address), nil
+}
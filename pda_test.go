@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+)
+
+func TestCreateProgramAddress(t *testing.T) {
+	programID, _ := decodePubkey("BPFLoaderUpgradeab1e11111111111111111111111")
+	seedKey, _ := decodePubkey("SeedPubey1111111111111111111111111111111111")
+
+	// Vectors from the Solana SDK's create_program_address tests
+	tests := []struct {
+		name     string
+		seeds    [][]byte
+		expected string
+	}{
+		{name: "Empty Seed", seeds: [][]byte{[]byte(""), {1}}, expected: "BwqrghZA2htAcqq8dzP1WDAhTXYTYWj7CHxF5j7TDBAe"},
+		{name: "Unicode Seed", seeds: [][]byte{[]byte("☉"), {0}}, expected: "13yWmRpaTR4r5nAktwLqMpRNr28tnVUZw26rTvPSSB19"},
+		{name: "Two Seeds", seeds: [][]byte{[]byte("Talking"), []byte("Squirrels")}, expected: "2fnQrngrQT4SeLcdToJAD96phoEjNL2man2kfRLCASVk"},
+		{name: "Pubkey Seed", seeds: [][]byte{seedKey, {1}}, expected: "976ymqVnfE32QFe6NfGDctSvVa36LWnvYxhU6G2232YL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := createProgramAddress(tt.seeds, programID)
+			if err != nil {
+				t.Fatalf("createProgramAddress returned error: %v", err)
+			}
+			if got := base58Encode(address); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	if _, err := createProgramAddress([][]byte{make([]byte, 33)}, programID); err == nil {
+		t.Error("Expected an error for a seed longer than 32 bytes")
+	}
+}
+
+func TestIsOnCurve(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		key, _, _ := ed25519.GenerateKey(rand.Reader)
+		if !isOnCurve(key) {
+			t.Errorf("Expected ed25519 public key %s to be on the curve", base58Encode(key))
+		}
+	}
+
+	address, _, err := findProgramAddress([][]byte{[]byte("seed")}, make([]byte, 32))
+	if err != nil {
+		t.Fatalf("findProgramAddress returned error: %v", err)
+	}
+	if isOnCurve(address) {
+		t.Errorf("Expected program address %s to be off the curve", base58Encode(address))
+	}
+}
@@ -0,0 +1,170 @@
+// Package solana is a client for the Solana JSON-RPC API, for programs that
+// want to call a node directly rather than through the service's HTTP API.
+//
+//	client := solana.NewClient("https://api.mainnet-beta.solana.com", nil)
+//	slot, err := client.GetSlot(ctx)
+package solana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout bounds each call made through a client created without an
+// HTTP client of its own
+const DefaultTimeout = 10 * time.Second
+
+// Request is a JSON-RPC request
+type Request struct {
+	Jsonrpc string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params,omitempty"`
+	ID      int           `json:"id"`
+}
+
+// Response is a JSON-RPC response, carrying either a result or an error
+type Response struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      int             `json:"id"`
+}
+
+// Error is an error returned by the RPC server
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("RPC error: %d - %s", e.Code, e.Message)
+}
+
+// StatusError is returned when the RPC server answers with a status other
+// than 200 OK
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("RPC server returned HTTP %d", e.StatusCode)
+}
+
+// Client calls a single Solana JSON-RPC endpoint. It is safe for concurrent
+// use and makes a single attempt at every call.
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	lastID     uint64
+}
+
+// NewClient creates a client for the endpoint. A nil httpClient uses one
+// with DefaultTimeout.
+func NewClient(endpoint string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	return &Client{endpoint: endpoint, httpClient: httpClient}
+}
+
+// Call invokes method with params and decodes its result into result, which
+// may be nil to discard it. An error answered by the server is returned as
+// an *Error.
+func (c *Client) Call(ctx context.Context, method string, params []interface{}, result interface{}) error {
+	request := Request{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  params,
+		ID:      int(atomic.AddUint64(&c.lastID, 1)),
+	}
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{StatusCode: resp.StatusCode}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var response Response
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if response.ID != request.ID {
+		return fmt.Errorf("RPC response id mismatch: sent %d, got %d", request.ID, response.ID)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	// A compliant response carries either an error or a result, even if null
+	if len(response.Result) == 0 {
+		return fmt.Errorf("malformed RPC response: missing result")
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("failed to parse %s result: %w", method, err)
+	}
+	return nil
+}
+
+// GetSlot returns the slot the node has reached
+func (c *Client) GetSlot(ctx context.Context) (uint64, error) {
+	var slot uint64
+	err := c.Call(ctx, "getSlot", nil, &slot)
+	return slot, err
+}
+
+// GetBlock returns the block at slot as the node encodes it, including
+// versioned transactions. It returns nil for a slot without a block.
+func (c *Client) GetBlock(ctx context.Context, slot uint64) (json.RawMessage, error) {
+	var block json.RawMessage
+	err := c.Call(ctx, "getBlock", []interface{}{slot, map[string]interface{}{"maxSupportedTransactionVersion": 0}}, &block)
+	return nullToNil(block), err
+}
+
+// GetTransaction returns the transaction with signature as the node encodes
+// it, or nil for an unknown signature
+func (c *Client) GetTransaction(ctx context.Context, signature string) (json.RawMessage, error) {
+	var transaction json.RawMessage
+	err := c.Call(ctx, "getTransaction", []interface{}{signature, map[string]interface{}{"maxSupportedTransactionVersion": 0}}, &transaction)
+	return nullToNil(transaction), err
+}
+
+// GetBalance returns the balance of address in lamports
+func (c *Client) GetBalance(ctx context.Context, address string) (uint64, error) {
+	var result struct {
+		Value uint64 `json:"value"`
+	}
+	err := c.Call(ctx, "getBalance", []interface{}{address}, &result)
+	return result.Value, err
+}
+
+func nullToNil(raw json.RawMessage) json.RawMessage {
+	if string(raw) == "null" {
+		return nil
+	}
+	return raw
+}
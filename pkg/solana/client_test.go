@@ -0,0 +1,124 @@
+package solana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTestServer answers every request with result, or with rpcError when
+// it is set
+func newTestServer(t *testing.T, result, rpcError string) (*httptest.Server, *[]Request) {
+	var requests []Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+			return
+		}
+		requests = append(requests, req)
+		if rpcError != "" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","error":%s,"id":%d}`, rpcError, req.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%s,"id":%d}`, result, req.ID)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestClientMethods(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name           string
+		result         string
+		call           func(c *Client) (interface{}, error)
+		expectedMethod string
+		expected       string
+	}{
+		{
+			name:           "GetSlot",
+			result:         `123`,
+			call:           func(c *Client) (interface{}, error) { return c.GetSlot(ctx) },
+			expectedMethod: "getSlot",
+			expected:       "123",
+		},
+		{
+			name:           "GetBlock",
+			result:         `{"blockhash":"abc"}`,
+			call:           func(c *Client) (interface{}, error) { b, err := c.GetBlock(ctx, 5); return string(b), err },
+			expectedMethod: "getBlock",
+			expected:       `{"blockhash":"abc"}`,
+		},
+		{
+			name:           "GetBlock Skipped",
+			result:         `null`,
+			call:           func(c *Client) (interface{}, error) { b, err := c.GetBlock(ctx, 5); return b == nil, err },
+			expectedMethod: "getBlock",
+			expected:       "true",
+		},
+		{
+			name:           "GetTransaction",
+			result:         `{"slot":7}`,
+			call:           func(c *Client) (interface{}, error) { tx, err := c.GetTransaction(ctx, "sig"); return string(tx), err },
+			expectedMethod: "getTransaction",
+			expected:       `{"slot":7}`,
+		},
+		{
+			name:           "GetBalance",
+			result:         `{"context":{"slot":9},"value":5000}`,
+			call:           func(c *Client) (interface{}, error) { return c.GetBalance(ctx, "addr") },
+			expectedMethod: "getBalance",
+			expected:       "5000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newTestServer(t, tt.result, "")
+			got, err := tt.call(NewClient(server.URL, nil))
+			if err != nil {
+				t.Fatalf("call returned error: %v", err)
+			}
+			if fmt.Sprint(got) != tt.expected {
+				t.Errorf("Expected %s, got %v", tt.expected, got)
+			}
+			if len(*requests) != 1 || (*requests)[0].Method != tt.expectedMethod {
+				t.Errorf("Expected a single %s request, got %+v", tt.expectedMethod, *requests)
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+
+	server, _ := newTestServer(t, "", `{"code":-32005,"message":"Node is behind"}`)
+	_, err := NewClient(server.URL, nil).GetSlot(ctx)
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32005 {
+		t.Errorf("Expected an RPC error with code -32005, got %v", err)
+	}
+
+	throttled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer throttled.Close()
+	_, err = NewClient(throttled.URL, nil).GetSlot(ctx)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected a status error with HTTP 429, got %v", err)
+	}
+
+	mismatched := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"jsonrpc":"2.0","result":1,"id":99}`)
+	}))
+	defer mismatched.Close()
+	if _, err := NewClient(mismatched.URL, nil).GetSlot(ctx); err == nil {
+		t.Error("Expected an error for a response to another request")
+	}
+}
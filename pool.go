@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// Worker pool settings
+const (
+	defaultPoolWorkers   = 16
+	defaultPoolQueueSize = 256
+)
+
+// workerPool is a bounded pool shared by every fan-out operation, so that one
+// setting limits the total concurrency against the upstream no matter how
+// many composite requests are in flight. Tasks must not submit further work
+// to the pool and wait on it, as that can deadlock a saturated pool.
+// Interactive tasks are always picked before bulk ones.
+type workerPool struct {
+	workers int
+	tasks   chan func()
+	bulk    chan func()
+	wg      sync.WaitGroup
+
+	// bulkFanOut is the fan-out above which requests run as bulk, and
+	// priorityHeader exposes the assigned priority in responses
+	bulkFanOut     int
+	priorityHeader bool
+
+	// shedDepth is the queue depth above which requests are shed
+	shedDepth int
+
+	mu      sync.Mutex
+	busy    int
+	waiting int
+
+	// stopMu guards stopped against tasks being queued while the channels
+	// are closed
+	stopMu  sync.RWMutex
+	stopped bool
+}
+
+// newWorkerPool starts the pool's workers
+func newWorkerPool(workers, queueSize int) *workerPool {
+	p := &workerPool{workers: workers, tasks: make(chan func(), queueSize), bulk: make(chan func(), queueSize), bulkFanOut: defaultBulkFanOut}
+	metrics.setGauge("solana_client_worker_pool_workers", "Workers in the shared worker pool.", float64(workers))
+	p.publish()
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *workerPool) work() {
+	defer p.wg.Done()
+	tasks, bulk := p.tasks, p.bulk
+	for tasks != nil || bulk != nil {
+		var task func()
+		var ok bool
+		select {
+		case task, ok = <-tasks:
+		default:
+			select {
+			case task, ok = <-tasks:
+			case task, ok = <-bulk:
+				if !ok {
+					bulk = nil
+					continue
+				}
+			}
+		}
+		if !ok {
+			tasks = nil
+			continue
+		}
+
+		p.track(-1, 1)
+		task()
+		p.track(0, -1)
+	}
+}
+
+// track adjusts the queued and busy counts and publishes them
+func (p *workerPool) track(waiting, busy int) {
+	p.mu.Lock()
+	p.waiting += waiting
+	p.busy += busy
+	p.mu.Unlock()
+	p.publish()
+}
+
+func (p *workerPool) publish() {
+	p.mu.Lock()
+	busy, waiting := p.busy, p.waiting
+	p.mu.Unlock()
+	metrics.setGauge("solana_client_worker_pool_busy_workers", "Workers currently running a task.", float64(busy))
+	metrics.setGauge("solana_client_worker_pool_queued_tasks", "Tasks waiting for a free worker.", float64(waiting))
+	metrics.setGauge("solana_client_worker_pool_utilization", "Fraction of workers currently busy.", float64(busy)/float64(p.workers))
+}
+
+// submit queues an interactive task, blocking while the queue is full
+func (p *workerPool) submit(task func()) {
+	p.submitAt(priorityInteractive, task)
+}
+
+// submitAt queues a task at the given priority, blocking while its queue is
+// full. Once the pool is stopped the task runs on the caller's goroutine
+// instead, so that requests outliving a shutdown still complete.
+func (p *workerPool) submitAt(priority poolPriority, task func()) {
+	p.stopMu.RLock()
+	if p.stopped {
+		p.stopMu.RUnlock()
+		task()
+		return
+	}
+	defer p.stopMu.RUnlock()
+
+	p.track(1, 0)
+	if priority == priorityBulk {
+		p.bulk <- task
+		return
+	}
+	p.tasks <- task
+}
+
+// run executes the tasks on the pool at interactive priority and waits for
+// all of them to finish
+func (p *workerPool) run(ctx context.Context, tasks []func()) error {
+	return p.runAt(ctx, priorityInteractive, tasks)
+}
+
+// runAt executes the tasks on the pool at the given priority and waits for
+// all of them to finish. Tasks still queued once ctx is done are skipped
+// rather than run for a request nobody is waiting on, in which case the
+// context's error is returned. Bulk tasks are refused with errOverloaded
+// while the pool sheds bulk work.
+func (p *workerPool) runAt(ctx context.Context, priority poolPriority, tasks []func()) error {
+	if priority == priorityBulk && p.shedsBulk() {
+		return errOverloaded
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(tasks))
+	for _, task := range tasks {
+		task := task
+		p.submitAt(priority, func() {
+			defer wg.Done()
+			if ctx.Err() != nil {
+				metrics.addCounter("solana_client_worker_pool_skipped_tasks_total", "Queued tasks skipped because their request had already ended.", 1)
+				return
+			}
+			task()
+		})
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// stop lets queued tasks finish and then stops the workers
+func (p *workerPool) stop() {
+	p.stopMu.Lock()
+	if p.stopped {
+		p.stopMu.Unlock()
+		return
+	}
+	p.stopped = true
+	p.stopMu.Unlock()
+
+	close(p.tasks)
+	close(p.bulk)
+	p.wg.Wait()
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolBoundsConcurrency(t *testing.T) {
+	pool := newWorkerPool(2, 4)
+	defer pool.stop()
+
+	var mu sync.Mutex
+	var running, peak, done int
+	tasks := make([]func(), 10)
+	for i := range tasks {
+		tasks[i] = func() {
+			mu.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			mu.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			done++
+			mu.Unlock()
+		}
+	}
+
+	pool.run(context.Background(), tasks)
+
+	if done != len(tasks) {
+		t.Errorf("Expected %d tasks to run, got %d", len(tasks), done)
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent tasks, got %d", peak)
+	}
+	if busy := metrics.value("solana_client_worker_pool_busy_workers"); busy != 0 {
+		t.Errorf("Expected no busy workers after run, got %v", busy)
+	}
+}
+
+func TestWorkerPoolPrefersInteractiveTasks(t *testing.T) {
+	pool := newWorkerPool(1, 8)
+	defer pool.stop()
+
+	// Hold the only worker so that both kinds of task queue up
+	release := make(chan struct{})
+	held := make(chan struct{})
+	go pool.run(context.Background(), []func(){func() {
+		close(held)
+		<-release
+	}})
+	<-held
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func() {
+		return func() {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		pool.runAt(context.Background(), priorityBulk, []func(){record("bulk"), record("bulk")})
+	}()
+	waitFor(t, "bulk tasks to queue", func() bool { return pool.queued() == 2 })
+	go func() {
+		defer wg.Done()
+		pool.run(context.Background(), []func(){record("interactive")})
+	}()
+	waitFor(t, "interactive task to queue", func() bool { return pool.queued() == 3 })
+
+	close(release)
+	wg.Wait()
+
+	if len(order) != 3 || order[0] != "interactive" {
+		t.Errorf("Expected the interactive task to run first, got %v", order)
+	}
+}
+
+func TestWorkerPoolSkipsTasksOfEndedRequests(t *testing.T) {
+	pool := newWorkerPool(1, 8)
+	defer pool.stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var ran int32
+	tasks := []func(){
+		func() {
+			atomic.AddInt32(&ran, 1)
+			cancel()
+		},
+		func() { atomic.AddInt32(&ran, 1) },
+		func() { atomic.AddInt32(&ran, 1) },
+	}
+
+	if err := pool.run(ctx, tasks); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancellation error, got %v", err)
+	}
+	if got := atomic.LoadInt32(&ran); got != 1 {
+		t.Errorf("Expected the queued tasks to be skipped, %d ran", got)
+	}
+}
+
+func TestWorkerPoolRunsTasksAfterStop(t *testing.T) {
+	pool := newWorkerPool(2, 4)
+	pool.stop()
+
+	var ran int32
+	pool.run(context.Background(), []func(){
+		func() { atomic.AddInt32(&ran, 1) },
+		func() { atomic.AddInt32(&ran, 1) },
+	})
+	if got := atomic.LoadInt32(&ran); got != 2 {
+		t.Errorf("Expected both tasks to run after stop, %d ran", got)
+	}
+	pool.stop()
+}
+
+func TestWorkerPoolPriorityFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		bulkFanOut int
+		fanOut     int
+		expected   poolPriority
+	}{
+		{name: "Small Request", bulkFanOut: 20, fanOut: 5, expected: priorityInteractive},
+		{name: "At Threshold", bulkFanOut: 20, fanOut: 20, expected: priorityInteractive},
+		{name: "Bulk Request", bulkFanOut: 20, fanOut: 100, expected: priorityBulk},
+		{name: "Disabled", bulkFanOut: 0, fanOut: 100, expected: priorityInteractive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := &workerPool{bulkFanOut: tt.bulkFanOut, priorityHeader: true}
+			if got := pool.priorityFor(tt.fanOut); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+
+			rr := httptest.NewRecorder()
+			pool.setPriorityHeader(rr, tt.fanOut)
+			if got := rr.Header().Get(poolPriorityHeader); got != tt.expected.String() {
+				t.Errorf("Expected %s header %s, got %s", poolPriorityHeader, tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import "net/http"
+
+// defaultBulkFanOut is the number of upstream calls above which a request
+// runs at bulk priority
+const defaultBulkFanOut = 20
+
+// poolPriorityHeader reports the worker pool priority a request ran at
+const poolPriorityHeader = "X-Pool-Priority"
+
+// poolPriority orders tasks waiting for a worker
+type poolPriority int
+
+const (
+	priorityInteractive poolPriority = iota
+	priorityBulk
+)
+
+func (p poolPriority) String() string {
+	if p == priorityBulk {
+		return "bulk"
+	}
+	return "interactive"
+}
+
+// priorityFor maps the number of upstream calls a request fans out into to
+// its priority, so that a few expensive scans cannot starve small
+// interactive requests of workers. A bulkFanOut of 0 runs everything as
+// interactive.
+func (p *workerPool) priorityFor(fanOut int) poolPriority {
+	if p.bulkFanOut > 0 && fanOut > p.bulkFanOut {
+		return priorityBulk
+	}
+	return priorityInteractive
+}
+
+// setPriorityHeader exposes the priority of a request fanning out into
+// fanOut calls when the pool is configured to
+func (p *workerPool) setPriorityHeader(w http.ResponseWriter, fanOut int) {
+	if p.priorityHeader {
+		w.Header().Set(poolPriorityHeader, p.priorityFor(fanOut).String())
+	}
+}
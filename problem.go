@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error response formats
+const (
+	errorFormatText    = "text"
+	errorFormatProblem = "problem"
+	problemContentType = "application/problem+json"
+)
+
+// problemTypes maps the status codes handlers answer with to RFC 7807
+// problem types. Other statuses use "about:blank", whose title is the
+// status text.
+var problemTypes = map[int]struct{ typ, title string }{
+	http.StatusBadRequest:            {"/problems/invalid-request", "Invalid request"},
+	http.StatusNotFound:              {"/problems/not-found", "Not found"},
+	http.StatusMethodNotAllowed:      {"/problems/method-not-allowed", "Method not allowed"},
+	http.StatusRequestEntityTooLarge: {"/problems/request-too-large", "Request too large"},
+	http.StatusUnprocessableEntity:   {"/problems/unprocessable", "Request could not be processed"},
+	http.StatusInternalServerError:   {"/problems/upstream-error", "Upstream RPC request failed"},
+	http.StatusNotImplemented:        {"/problems/not-implemented", "Not implemented"},
+	http.StatusServiceUnavailable:    {"/problems/unavailable", "Service unavailable"},
+}
+
+// problemDetails is an RFC 7807 problem details object
+type problemDetails struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// newProblem describes an error response with the given status and message
+func newProblem(status int, detail, instance string) problemDetails {
+	problem := problemDetails{Type: "about:blank", Title: http.StatusText(status), Status: status, Detail: detail, Instance: instance}
+	if known, ok := problemTypes[status]; ok {
+		problem.Type, problem.Title = known.typ, known.title
+	}
+	return problem
+}
+
+// problemWriter holds back plain-text error responses so they can be
+// rewritten as problem details. Successful and JSON responses pass through.
+type problemWriter struct {
+	http.ResponseWriter
+	status      int
+	detail      bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *problemWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	contentType := w.Header().Get("Content-Type")
+	if status >= 400 && (contentType == "" || strings.HasPrefix(contentType, "text/plain")) {
+		w.status = status
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *problemWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.status != 0 {
+		return w.detail.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *problemWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withErrorFormat rewrites error responses in the given format. Handlers
+// report errors with http.Error, so the default text format leaves them
+// untouched, while the problem format turns them into RFC 7807
+// application/problem+json bodies.
+func withErrorFormat(next http.Handler, format string) (http.Handler, error) {
+	switch format {
+	case errorFormatText:
+		return next, nil
+	case errorFormatProblem:
+	default:
+		return nil, fmt.Errorf("unknown error format %q, want %q or %q", format, errorFormatText, errorFormatProblem)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pw := &problemWriter{ResponseWriter: w}
+		next.ServeHTTP(pw, r)
+		if pw.status == 0 {
+			return
+		}
+
+		w.Header().Set("Content-Type", problemContentType)
+		w.Header().Del("Content-Length")
+		w.WriteHeader(pw.status)
+		jsonData, _ := json.Marshal(newProblem(pw.status, strings.TrimSpace(pw.detail.String()), r.URL.RequestURI()))
+		w.Write(jsonData)
+	}), nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWithErrorFormat(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			http.Error(w, "block parameter is required", http.StatusBadRequest)
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"err":"simulation failed"}`))
+		case "/teapot":
+			http.Error(w, "short and stout", http.StatusTeapot)
+		default:
+			w.Write([]byte("ok"))
+		}
+	})
+
+	if _, err := withErrorFormat(next, "xml"); err == nil {
+		t.Fatal("Expected an error for an unknown format")
+	}
+	text, _ := withErrorFormat(next, errorFormatText)
+	problem, _ := withErrorFormat(next, errorFormatProblem)
+
+	tests := []struct {
+		name                string
+		handler             http.Handler
+		path                string
+		expectedStatus      int
+		expectedContentType string
+		expectedProblem     *problemDetails
+	}{
+		{name: "Text Error", handler: text, path: "/missing?block=x", expectedStatus: http.StatusBadRequest, expectedContentType: "text/plain"},
+		{
+			name:                "Problem Error",
+			handler:             problem,
+			path:                "/missing?block=x",
+			expectedStatus:      http.StatusBadRequest,
+			expectedContentType: problemContentType,
+			expectedProblem: &problemDetails{
+				Type: "/problems/invalid-request", Title: "Invalid request", Status: http.StatusBadRequest,
+				Detail: "block parameter is required", Instance: "/missing?block=x",
+			},
+		},
+		{
+			name:                "Unmapped Status",
+			handler:             problem,
+			path:                "/teapot",
+			expectedStatus:      http.StatusTeapot,
+			expectedContentType: problemContentType,
+			expectedProblem: &problemDetails{
+				Type: "about:blank", Title: "I'm a teapot", Status: http.StatusTeapot,
+				Detail: "short and stout", Instance: "/teapot",
+			},
+		},
+		{name: "JSON Error Passes Through", handler: problem, path: "/json", expectedStatus: http.StatusUnprocessableEntity, expectedContentType: "application/json"},
+		{name: "Success Passes Through", handler: problem, path: "/ok", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+			tt.handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if contentType := rr.Header().Get("Content-Type"); !strings.HasPrefix(contentType, tt.expectedContentType) {
+				t.Errorf("Expected content type %q, got %q", tt.expectedContentType, contentType)
+			}
+			if tt.expectedProblem == nil {
+				return
+			}
+
+			var response problemDetails
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response != *tt.expectedProblem {
+				t.Errorf("Expected problem %+v, got %+v", *tt.expectedProblem, response)
+			}
+		})
+	}
+}
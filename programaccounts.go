@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Program account query settings
+const (
+	defaultProgramAccountsLimit = 100
+	maxProgramAccountsLimit     = 1000
+	// maxProgramFilters is the most filters a node accepts in one query
+	maxProgramFilters = 4
+)
+
+// programAccountsResponse is the response of /program/accounts. Pages run in
+// pubkey order; the last pubkey of a page is passed as ?after= to fetch the
+// next one.
+type programAccountsResponse struct {
+	Program   string           `json:"program"`
+	Accounts  []ProgramAccount `json:"accounts"`
+	Total     int              `json:"total"`
+	NextAfter string           `json:"next_after,omitempty"`
+}
+
+// parseDataSlice reads a dataSlice=<offset>:<length> parameter
+func parseDataSlice(value string) (*DataSlice, error) {
+	offset, length, ok := strings.Cut(value, ":")
+	parsedOffset, offsetErr := strconv.ParseUint(offset, 10, 64)
+	parsedLength, lengthErr := strconv.ParseUint(length, 10, 64)
+	if !ok || offsetErr != nil || lengthErr != nil {
+		return nil, fmt.Errorf("dataSlice must be <offset>:<length>")
+	}
+	return &DataSlice{Offset: parsedOffset, Length: parsedLength}, nil
+}
+
+// pageProgramAccounts sorts accounts by pubkey and returns up to limit of
+// those after the given pubkey, with the cursor of the following page
+func pageProgramAccounts(accounts []ProgramAccount, after string, limit int) ([]ProgramAccount, string) {
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Pubkey < accounts[j].Pubkey })
+	start := sort.Search(len(accounts), func(i int) bool { return accounts[i].Pubkey > after })
+	page := accounts[start:]
+	if len(page) <= limit {
+		return page, ""
+	}
+	page = page[:limit]
+	return page, page[limit-1].Pubkey
+}
+
+// handleGetProgramAccounts serves the accounts owned by ?programId= that
+// pass its dataSize and memcmp filters. The node has no pagination of its
+// own, so every page is cut from a full scan: filters and dataSlice are what
+// keep a query within the upstream response size limit.
+func handleGetProgramAccounts(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		programID := query.Get("programId")
+		if programID == "" {
+			http.Error(w, "programId parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := decodePubkey(programID); err != nil {
+			http.Error(w, "programId must be a base58 public key", http.StatusBadRequest)
+			return
+		}
+
+		filters, err := parseProgramFilters(query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(filters) > maxProgramFilters {
+			http.Error(w, fmt.Sprintf("at most %d filters are allowed", maxProgramFilters), http.StatusBadRequest)
+			return
+		}
+
+		opts := AccountOptions{Encoding: query.Get("encoding")}
+		if opts.Encoding != "" && !accountEncodings[opts.Encoding] {
+			http.Error(w, fmt.Sprintf("unsupported encoding %q", opts.Encoding), http.StatusBadRequest)
+			return
+		}
+		if value := query.Get("dataSlice"); value != "" {
+			if opts.DataSlice, err = parseDataSlice(value); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		limit := defaultProgramAccountsLimit
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxProgramAccountsLimit {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxProgramAccountsLimit), http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		accounts, err := client.getProgramAccounts(r.Context(), programID, filters, opts)
+		if errors.Is(err, errResponseTooLarge) {
+			http.Error(w, err.Error()+"; narrow the query with dataSize, memcmp or dataSlice", http.StatusBadGateway)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := programAccountsResponse{Program: programID, Total: len(accounts)}
+		response.Accounts, response.NextAfter = pageProgramAccounts(accounts, query.Get("after"), limit)
+		if response.Accounts == nil {
+			response.Accounts = []ProgramAccount{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDataSlice(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected *DataSlice
+	}{
+		{value: "0:32", expected: &DataSlice{Offset: 0, Length: 32}},
+		{value: "8:0", expected: &DataSlice{Offset: 8, Length: 0}},
+		{value: "8"},
+		{value: "-1:4"},
+		{value: "a:b"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseDataSlice(tt.value)
+		if (err != nil) != (tt.expected == nil) || !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("parseDataSlice(%q) = %+v, %v, want %+v", tt.value, got, err, tt.expected)
+		}
+	}
+}
+
+func TestHandleGetProgramAccounts(t *testing.T) {
+	program := base58Encode(bytes.Repeat([]byte{9}, 32))
+	var accounts []ProgramAccount
+	for _, name := range []string{"acctC", "acctA", "acctD", "acctB"} {
+		data := bytes.Repeat([]byte{1}, 8)
+		if name == "acctD" {
+			data = bytes.Repeat([]byte{2}, 16)
+		}
+		accounts = append(accounts, ProgramAccount{Pubkey: name, Account: *testAccount(program, data)})
+	}
+	memcmp := base58Encode([]byte{2, 2})
+	tooMany := strings.Repeat("&memcmp=0:"+memcmp, maxProgramFilters+1)
+
+	tests := []struct {
+		name              string
+		mockClient        mockRPCClient
+		queryParam        string
+		expectedStatus    int
+		expectedAccounts  []string
+		expectedTotal     int
+		expectedNextAfter string
+		expectedOptions   AccountOptions
+	}{
+		{
+			name:             "All Accounts",
+			mockClient:       mockRPCClient{programAccts: accounts},
+			expectedStatus:   http.StatusOK,
+			expectedAccounts: []string{"acctA", "acctB", "acctC", "acctD"},
+			expectedTotal:    4,
+		},
+		{
+			name:             "Data Size",
+			mockClient:       mockRPCClient{programAccts: accounts},
+			queryParam:       "&dataSize=8",
+			expectedStatus:   http.StatusOK,
+			expectedAccounts: []string{"acctA", "acctB", "acctC"},
+			expectedTotal:    3,
+		},
+		{
+			name:             "Memcmp",
+			mockClient:       mockRPCClient{programAccts: accounts},
+			queryParam:       "&memcmp=4:" + memcmp,
+			expectedStatus:   http.StatusOK,
+			expectedAccounts: []string{"acctD"},
+			expectedTotal:    1,
+		},
+		{
+			name:              "First Page",
+			mockClient:        mockRPCClient{programAccts: accounts},
+			queryParam:        "&limit=2",
+			expectedStatus:    http.StatusOK,
+			expectedAccounts:  []string{"acctA", "acctB"},
+			expectedTotal:     4,
+			expectedNextAfter: "acctB",
+		},
+		{
+			name:             "Last Page",
+			mockClient:       mockRPCClient{programAccts: accounts},
+			queryParam:       "&limit=2&after=acctB",
+			expectedStatus:   http.StatusOK,
+			expectedAccounts: []string{"acctC", "acctD"},
+			expectedTotal:    4,
+		},
+		{
+			name:             "Encoding And Slice",
+			mockClient:       mockRPCClient{programAccts: accounts},
+			queryParam:       "&encoding=base58&dataSlice=0:4",
+			expectedStatus:   http.StatusOK,
+			expectedAccounts: []string{"acctA", "acctB", "acctC", "acctD"},
+			expectedTotal:    4,
+			expectedOptions:  AccountOptions{Encoding: "base58", DataSlice: &DataSlice{Offset: 0, Length: 4}},
+		},
+		{name: "Missing Program", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Program", queryParam: "x", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Filter", queryParam: "&memcmp=4", expectedStatus: http.StatusBadRequest},
+		{name: "Too Many Filters", queryParam: tooMany, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Encoding", queryParam: "&encoding=hex", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Slice", queryParam: "&dataSlice=4", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Limit", queryParam: fmt.Sprintf("&limit=%d", maxProgramAccountsLimit+1), expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC connection failed"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := "/program/accounts"
+			if tt.name != "Missing Program" {
+				target += "?programId=" + program + tt.queryParam
+			}
+			req := httptest.NewRequest("GET", target, nil)
+			rr := httptest.NewRecorder()
+			handleGetProgramAccounts(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response programAccountsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			var pubkeys []string
+			for _, account := range response.Accounts {
+				pubkeys = append(pubkeys, account.Pubkey)
+			}
+			if !reflect.DeepEqual(pubkeys, tt.expectedAccounts) {
+				t.Errorf("Expected accounts %v, got %v", tt.expectedAccounts, pubkeys)
+			}
+			if response.Total != tt.expectedTotal || response.NextAfter != tt.expectedNextAfter {
+				t.Errorf("Expected %d accounts in total and next page after %q, got %d and %q", tt.expectedTotal, tt.expectedNextAfter, response.Total, response.NextAfter)
+			}
+			if !reflect.DeepEqual(tt.mockClient.acctOptions, tt.expectedOptions) {
+				t.Errorf("Expected options %+v, got %+v", tt.expectedOptions, tt.mockClient.acctOptions)
+			}
+		})
+	}
+}
+
+func TestGetProgramAccountsConfig(t *testing.T) {
+	var params []interface{}
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Params []interface{} `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		params = request.Params
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":[]}`))
+	}))
+	defer node.Close()
+
+	client := newRPCClient(node.URL)
+	filters := []interface{}{map[string]interface{}{"dataSize": uint64(8)}}
+	if _, err := client.getProgramAccounts(context.Background(), "program", filters, AccountOptions{DataSlice: &DataSlice{Offset: 4, Length: 2}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []interface{}{"program", map[string]interface{}{
+		"encoding":  "base64",
+		"dataSlice": map[string]interface{}{"offset": float64(4), "length": float64(2)},
+		"filters":   []interface{}{map[string]interface{}{"dataSize": float64(8)}},
+	}}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("Expected params %v, got %v", expected, params)
+	}
+}
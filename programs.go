@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Top programs settings
+const (
+	defaultTopProgramBlocks = 10
+	maxTopProgramBlocks     = 50
+	defaultTopProgramLimit  = 20
+	topProgramsCacheTTL     = 15 * time.Second
+)
+
+// programInvocations counts how often a program was invoked
+type programInvocations struct {
+	ProgramID   string `json:"program_id"`
+	Invocations int    `json:"invocations"`
+	Inner       int    `json:"inner_invocations"`
+}
+
+// topProgramsResponse is the response of /top-programs
+type topProgramsResponse struct {
+	FromSlot      uint64               `json:"from_slot"`
+	ToSlot        uint64               `json:"to_slot"`
+	BlocksScanned int                  `json:"blocks_scanned"`
+	Transactions  int                  `json:"transactions"`
+	Programs      []programInvocations `json:"programs"`
+}
+
+// blockInstructions is the part of a json encoded block needed to find the
+// program each instruction invokes
+type blockInstructions struct {
+	Transactions []struct {
+		Transaction struct {
+			Message struct {
+				AccountKeys  []string `json:"accountKeys"`
+				Instructions []struct {
+					ProgramIDIndex int `json:"programIdIndex"`
+				} `json:"instructions"`
+			} `json:"message"`
+		} `json:"transaction"`
+		Meta *struct {
+			LoadedAddresses *struct {
+				Writable []string `json:"writable"`
+				Readonly []string `json:"readonly"`
+			} `json:"loadedAddresses"`
+			InnerInstructions []struct {
+				Instructions []struct {
+					ProgramIDIndex int `json:"programIdIndex"`
+				} `json:"instructions"`
+			} `json:"innerInstructions"`
+		} `json:"meta"`
+	} `json:"transactions"`
+}
+
+// countInvocations adds a block's top-level and inner instructions to the
+// per-program counts and returns the number of transactions in the block
+func countInvocations(block json.RawMessage, counts map[string]*programInvocations) (int, error) {
+	var parsed blockInstructions
+	if err := json.Unmarshal(block, &parsed); err != nil {
+		return 0, fmt.Errorf("failed to parse block: %w", err)
+	}
+
+	count := func(keys []string, index int, inner bool) {
+		if index < 0 || index >= len(keys) {
+			return
+		}
+		entry, ok := counts[keys[index]]
+		if !ok {
+			entry = &programInvocations{ProgramID: keys[index]}
+			counts[keys[index]] = entry
+		}
+		if inner {
+			entry.Inner++
+		} else {
+			entry.Invocations++
+		}
+	}
+
+	for _, tx := range parsed.Transactions {
+		// Versioned transactions index into the static keys followed by
+		// the writable and then read-only addresses loaded from tables
+		keys := tx.Transaction.Message.AccountKeys
+		if tx.Meta != nil && tx.Meta.LoadedAddresses != nil {
+			keys = append(append(keys[:len(keys):len(keys)], tx.Meta.LoadedAddresses.Writable...), tx.Meta.LoadedAddresses.Readonly...)
+		}
+
+		for _, ix := range tx.Transaction.Message.Instructions {
+			count(keys, ix.ProgramIDIndex, false)
+		}
+		if tx.Meta == nil {
+			continue
+		}
+		for _, group := range tx.Meta.InnerInstructions {
+			for _, ix := range group.Instructions {
+				count(keys, ix.ProgramIDIndex, true)
+			}
+		}
+	}
+	return len(parsed.Transactions), nil
+}
+
+// topPrograms scans the blocks of the last n slots. Skipped slots have no
+// block and are left out of the scan.
+func topPrograms(ctx context.Context, client SolanaRPCClient, pool *workerPool, n int) (*topProgramsResponse, error) {
+	latest, err := client.getLatestSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	from := uint64(0)
+	if latest >= uint64(n) {
+		from = latest - uint64(n) + 1
+	}
+
+	slots := make([]uint64, latest-from+1)
+	for i := range slots {
+		slots[i] = from + uint64(i)
+	}
+
+	// The blocks are fetched as JSON-RPC batches from a single pool task,
+	// which still queues at the priority of the whole scan
+	var blocks []json.RawMessage
+	var errs []error
+	var fetchErr error
+	if err := pool.runAt(ctx, pool.priorityFor(n), []func(){func() {
+		blocks, errs, fetchErr = client.getBlocks(ctx, slots, BlockOptions{MaxSupportedTransactionVersion: new(int)})
+	}}); err != nil {
+		return nil, err
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	response := &topProgramsResponse{FromSlot: from, ToSlot: latest}
+	counts := make(map[string]*programInvocations)
+	var lastErr error
+	for i, block := range blocks {
+		if slotSkipped(errs[i]) {
+			lastErr = errs[i]
+			continue
+		}
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		transactions, err := countInvocations(block, counts)
+		if err != nil {
+			return nil, err
+		}
+		response.BlocksScanned++
+		response.Transactions += transactions
+	}
+	if response.BlocksScanned == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+
+	response.Programs = make([]programInvocations, 0, len(counts))
+	for _, entry := range counts {
+		response.Programs = append(response.Programs, *entry)
+	}
+	sort.Slice(response.Programs, func(i, j int) bool {
+		a, b := response.Programs[i], response.Programs[j]
+		if a.Invocations+a.Inner != b.Invocations+b.Inner {
+			return a.Invocations+a.Inner > b.Invocations+b.Inner
+		}
+		return a.ProgramID < b.ProgramID
+	})
+	return response, nil
+}
+
+// topProgramsCache keeps recent scans briefly, as each one fetches many
+// full blocks
+type topProgramsCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[int]cachedTopPrograms
+}
+
+type cachedTopPrograms struct {
+	at       time.Time
+	response *topProgramsResponse
+}
+
+func newTopProgramsCache(ttl time.Duration) *topProgramsCache {
+	return &topProgramsCache{ttl: ttl, now: time.Now, entries: make(map[int]cachedTopPrograms)}
+}
+
+func (c *topProgramsCache) get(ctx context.Context, client SolanaRPCClient, pool *workerPool, blocks int) (*topProgramsResponse, error) {
+	// Scans are cached at the configured commitment only
+	if requestCommitment(ctx) != "" {
+		return topPrograms(ctx, client, pool, blocks)
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[blocks]
+	c.mu.Unlock()
+	if ok && c.now().Sub(entry.at) < c.ttl {
+		return entry.response, nil
+	}
+
+	response, err := topPrograms(ctx, client, pool, blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[blocks] = cachedTopPrograms{at: c.now(), response: response}
+	c.mu.Unlock()
+	return response, nil
+}
+
+func handleGetTopPrograms(client SolanaRPCClient, pool *workerPool, cache *topProgramsCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		blocks := defaultTopProgramBlocks
+		if value := r.URL.Query().Get("blocks"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxTopProgramBlocks {
+				http.Error(w, fmt.Sprintf("blocks must be between 1 and %d", maxTopProgramBlocks), http.StatusBadRequest)
+				return
+			}
+			blocks = parsed
+		}
+
+		limit := defaultTopProgramLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		pool.setPriorityHeader(w, blocks)
+
+		response, err := cache.get(r.Context(), client, pool, blocks)
+		if errors.Is(err, errOverloaded) {
+			shed(w, priorityBulk)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		// Trim a copy, as the cached response is shared
+		trimmed := *response
+		if len(trimmed.Programs) > limit {
+			trimmed.Programs = trimmed.Programs[:limit]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(trimmed)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Blocks invoking vote, token and a program loaded from a lookup table
+var topProgramBlocks = map[uint64]json.RawMessage{
+	98: json.RawMessage(`{"transactions":[
+		{"transaction":{"message":{"accountKeys":["payer","Vote"],"instructions":[{"programIdIndex":1}]}},"meta":{}},
+		{"transaction":{"message":{"accountKeys":["payer","Vote"],"instructions":[{"programIdIndex":1}]}},"meta":{}}
+	]}`),
+	100: json.RawMessage(`{"transactions":[
+		{"transaction":{"message":{"accountKeys":["payer","Router","Token"],"instructions":[{"programIdIndex":1}]}},
+		 "meta":{"loadedAddresses":{"writable":["pool"],"readonly":["Amm"]},"innerInstructions":[{"index":0,"instructions":[{"programIdIndex":4},{"programIdIndex":2},{"programIdIndex":2}]}]}},
+		{"transaction":{"message":{"accountKeys":["payer","Vote"],"instructions":[{"programIdIndex":1}]}},"meta":null}
+	]}`),
+}
+
+func TestHandleGetTopPrograms(t *testing.T) {
+	pool := newWorkerPool(4, 16)
+	defer pool.stop()
+
+	tests := []struct {
+		name             string
+		mockClient       mockRPCClient
+		queryParam       string
+		expectedStatus   int
+		expectedScanned  int
+		expectedPrograms []programInvocations
+	}{
+		{
+			name:            "Aggregates Inner And Loaded Programs",
+			mockClient:      mockRPCClient{latestSlot: 100, blocks: topProgramBlocks},
+			queryParam:      "?blocks=3",
+			expectedStatus:  http.StatusOK,
+			expectedScanned: 2,
+			expectedPrograms: []programInvocations{
+				{ProgramID: "Vote", Invocations: 3},
+				{ProgramID: "Token", Inner: 2},
+				{ProgramID: "Amm", Inner: 1},
+				{ProgramID: "Router", Invocations: 1},
+			},
+		},
+		{
+			name:             "Limited",
+			mockClient:       mockRPCClient{latestSlot: 100, blocks: topProgramBlocks},
+			queryParam:       "?blocks=3&limit=1",
+			expectedStatus:   http.StatusOK,
+			expectedScanned:  2,
+			expectedPrograms: []programInvocations{{ProgramID: "Vote", Invocations: 3}},
+		},
+		{
+			name: "Block Error",
+			mockClient: mockRPCClient{latestSlot: 100, blocks: topProgramBlocks, blockErrs: map[uint64]error{
+				99: &upstreamError{Code: -32005, Message: "Node is behind", Status: http.StatusServiceUnavailable},
+			}},
+			queryParam:     "?blocks=3",
+			expectedStatus: http.StatusServiceUnavailable,
+		},
+		{name: "All Skipped", mockClient: mockRPCClient{latestSlot: 200, blocks: topProgramBlocks}, queryParam: "?blocks=2", expectedStatus: http.StatusInternalServerError},
+		{name: "Too Many Blocks", mockClient: mockRPCClient{}, queryParam: "?blocks=51", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Limit", mockClient: mockRPCClient{}, queryParam: "?limit=0", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/top-programs"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetTopPrograms(&tt.mockClient, pool, newTopProgramsCache(topProgramsCacheTTL)).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response topProgramsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.BlocksScanned != tt.expectedScanned || response.FromSlot != 98 || response.ToSlot != 100 || response.Transactions != 4 {
+				t.Errorf("unexpected scan: %+v", response)
+			}
+			if len(response.Programs) != len(tt.expectedPrograms) {
+				t.Fatalf("Expected %d programs, got %+v", len(tt.expectedPrograms), response.Programs)
+			}
+			for i, expected := range tt.expectedPrograms {
+				if response.Programs[i] != expected {
+					t.Errorf("Expected %+v at %d, got %+v", expected, i, response.Programs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTopProgramsCache(t *testing.T) {
+	pool := newWorkerPool(2, 8)
+	defer pool.stop()
+
+	now := time.Unix(1700000000, 0)
+	cache := newTopProgramsCache(10 * time.Second)
+	cache.now = func() time.Time { return now }
+	mock := &mockRPCClient{latestSlot: 100, blocks: topProgramBlocks}
+
+	first, err := cache.get(context.Background(), mock, pool, 3)
+	if err != nil {
+		t.Fatalf("get returned error: %v", err)
+	}
+
+	mock.latestSlot = 98
+	if cached, _ := cache.get(context.Background(), mock, pool, 3); cached != first {
+		t.Error("Expected a scan within the TTL to be served from cache")
+	}
+
+	now = now.Add(11 * time.Second)
+	if fresh, _ := cache.get(context.Background(), mock, pool, 3); fresh == first || fresh.ToSlot != 98 {
+		t.Error("Expected an expired scan to be refreshed")
+	}
+}
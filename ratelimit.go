@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Upstream rate-limit hint settings
+const (
+	rateLimitRemainingHeader = "X-RateLimit-Remaining"
+	rateLimitResetHeader     = "X-RateLimit-Reset"
+
+	// Throttling starts once fewer than rateLimitLowWater requests remain
+	rateLimitLowWater = 10
+	maxThrottleDelay  = 2 * time.Second
+
+	// Reset values above this are Unix timestamps rather than seconds
+	resetEpochThreshold = 1000000000
+)
+
+// rateLimiter tracks the quota hints returned by providers and spaces out
+// requests as the quota runs low, instead of spending it in a burst and
+// then being rejected until the window resets
+type rateLimiter struct {
+	mu        sync.Mutex
+	remaining int
+	reset     time.Time
+	heldUntil time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{remaining: -1, now: time.Now}
+}
+
+// observe records the rate-limit headers of an upstream response, if any
+func (l *rateLimiter) observe(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get(rateLimitRemainingHeader))
+	if err != nil || remaining < 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.remaining = remaining
+	l.reset = time.Time{}
+	if reset, err := strconv.ParseInt(header.Get(rateLimitResetHeader), 10, 64); err == nil && reset >= 0 {
+		if reset > resetEpochThreshold {
+			l.reset = time.Unix(reset, 0)
+		} else {
+			l.reset = l.now().Add(time.Duration(reset) * time.Second)
+		}
+	}
+	metrics.setGauge("solana_client_upstream_ratelimit_remaining", "Requests remaining in the upstream quota as last reported by the provider.", float64(remaining))
+}
+
+// holdOff holds every request back for d, as asked by the Retry-After of a
+// throttled response, rather than only the request that was throttled
+func (l *rateLimiter) holdOff(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := l.now().Add(d); until.After(l.heldUntil) {
+		l.heldUntil = until
+	}
+}
+
+// delay reserves one request from the remaining quota and returns how long
+// to wait before sending it. Requests are spread evenly over the time left
+// until the quota resets once it runs low, and wait out any hold off.
+func (l *rateLimiter) delay() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	var held time.Duration
+	if now.Before(l.heldUntil) {
+		held = l.heldUntil.Sub(now)
+	}
+	if l.remaining < 0 || l.remaining >= rateLimitLowWater || l.reset.IsZero() || !now.Before(l.reset) {
+		return held
+	}
+
+	d := l.reset.Sub(now) / time.Duration(l.remaining+1)
+	if l.remaining > 0 {
+		l.remaining--
+	}
+	if d > maxThrottleDelay {
+		d = maxThrottleDelay
+	}
+	if held > d {
+		return held
+	}
+	return d
+}
+
+// Upstream request budget defaults
+const (
+	defaultUpstreamBurst        = 10
+	defaultUpstreamMaxQueueWait = 2 * time.Second
+)
+
+// errUpstreamBudget is returned for upstream calls shed because the request
+// budget would not let them through within the maximum queue wait
+var errUpstreamBudget = errors.New("upstream request budget exhausted")
+
+// upstreamBudget keeps upstream calls within a requests-per-second budget.
+// Calls over the budget are queued, each reserving the next free slot, as
+// long as their wait stays within maxWait; later ones are shed.
+type upstreamBudget struct {
+	rate    float64
+	burst   float64
+	maxWait time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	tokens  float64
+	updated time.Time
+}
+
+// newUpstreamBudget returns a budget, or nil when rate is not positive and
+// upstream calls are not limited
+func newUpstreamBudget(rate float64, burst int, maxWait time.Duration) *upstreamBudget {
+	if rate <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &upstreamBudget{rate: rate, burst: float64(burst), maxWait: maxWait, now: time.Now, tokens: float64(burst), updated: time.Now()}
+}
+
+// reserve takes slots for n calls, such as those of a batch, and returns
+// how long to wait before sending them, or false when the wait would exceed
+// maxWait. A reservation is kept even if its caller gives up waiting.
+func (b *upstreamBudget) reserve(n int) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens += now.Sub(b.updated).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.updated = now
+
+	need := float64(n)
+	if b.tokens >= need {
+		b.tokens -= need
+		return 0, true
+	}
+	wait := time.Duration((need - b.tokens) / b.rate * float64(time.Second))
+	if wait > b.maxWait {
+		return 0, false
+	}
+	b.tokens -= need
+	return wait, true
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterDelay(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name          string
+		remaining     string
+		reset         string
+		expectedDelay time.Duration
+	}{
+		{name: "No Headers", expectedDelay: 0},
+		{name: "Plenty Remaining", remaining: "500", reset: "10", expectedDelay: 0},
+		{name: "Running Low", remaining: "4", reset: "1", expectedDelay: 200 * time.Millisecond},
+		{name: "Unix Reset", remaining: "1", reset: "1700000001", expectedDelay: 500 * time.Millisecond},
+		{name: "Exhausted", remaining: "0", reset: "1", expectedDelay: time.Second},
+		{name: "Capped", remaining: "0", reset: "3600", expectedDelay: maxThrottleDelay},
+		{name: "No Reset", remaining: "0", expectedDelay: 0},
+		{name: "Reset Passed", remaining: "0", reset: "1699999999", expectedDelay: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := newRateLimiter()
+			limiter.now = func() time.Time { return now }
+			header := http.Header{}
+			if tt.remaining != "" {
+				header.Set(rateLimitRemainingHeader, tt.remaining)
+			}
+			if tt.reset != "" {
+				header.Set(rateLimitResetHeader, tt.reset)
+			}
+			limiter.observe(header)
+
+			if delay := limiter.delay(); delay != tt.expectedDelay {
+				t.Errorf("Expected delay %v, got %v", tt.expectedDelay, delay)
+			}
+		})
+	}
+}
+
+func TestSendRequestThrottlesNearQuota(t *testing.T) {
+	transport := &faultTransport{faults: []func() (*http.Response, error){
+		func() (*http.Response, error) {
+			resp := jsonResponse(http.StatusOK, `{"jsonrpc":"2.0","result":42,"id":1}`)
+			resp.Header.Set(rateLimitRemainingHeader, "1")
+			resp.Header.Set(rateLimitResetHeader, "1")
+			return resp, nil
+		},
+	}}
+	client := newRPCClient("http://rpc.invalid")
+	client.client.Transport = transport
+	var slept []time.Duration
+	client.sleep = func(d time.Duration) { slept = append(slept, d) }
+
+	if _, err := client.getLatestSlot(context.Background()); err != nil {
+		t.Fatalf("getLatestSlot returned error: %v", err)
+	}
+	// Only the delay before the second request matters here
+	client.getLatestSlot(context.Background())
+
+	if len(slept) != 1 || slept[0] <= 0 || slept[0] > 500*time.Millisecond {
+		t.Errorf("Expected one delay of at most 500ms before the second request, got %v", slept)
+	}
+	if remaining := metrics.value("solana_client_upstream_ratelimit_remaining"); remaining != 1 {
+		t.Errorf("Expected remaining quota gauge of 1, got %v", remaining)
+	}
+}
+
+func TestRateLimiterHoldOff(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := newRateLimiter()
+	limiter.now = func() time.Time { return now }
+
+	limiter.holdOff(3 * time.Second)
+	limiter.holdOff(time.Second)
+	if d := limiter.delay(); d != 3*time.Second {
+		t.Errorf("Expected the longest hold off of 3s, got %v", d)
+	}
+
+	now = now.Add(2 * time.Second)
+	if d := limiter.delay(); d != time.Second {
+		t.Errorf("Expected 1s of hold off left, got %v", d)
+	}
+
+	now = now.Add(time.Second)
+	if d := limiter.delay(); d != 0 {
+		t.Errorf("Expected no delay after the hold off, got %v", d)
+	}
+}
+
+func TestUpstreamBudget(t *testing.T) {
+	now := time.Unix(0, 0)
+	budget := newUpstreamBudget(10, 2, 150*time.Millisecond)
+	budget.now = func() time.Time { return now }
+	budget.updated = now
+
+	tests := []struct {
+		name         string
+		advance      time.Duration
+		calls        int
+		expectedWait time.Duration
+		expectedOK   bool
+	}{
+		{name: "First Of Burst", expectedOK: true},
+		{name: "Second Of Burst", expectedOK: true},
+		{name: "Queued", expectedWait: 100 * time.Millisecond, expectedOK: true},
+		{name: "Shed Beyond Max Wait", expectedOK: false},
+		{name: "Queued Behind Reservation", advance: 100 * time.Millisecond, expectedWait: 100 * time.Millisecond, expectedOK: true},
+		{name: "Refilled", advance: time.Second, expectedOK: true},
+		// A batch takes a slot for each of its calls
+		{name: "Batch Queued", advance: time.Second, calls: 3, expectedWait: 100 * time.Millisecond, expectedOK: true},
+		{name: "Batch Shed Beyond Max Wait", calls: 2, expectedOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			calls := tt.calls
+			if calls == 0 {
+				calls = 1
+			}
+			wait, ok := budget.reserve(calls)
+			if ok != tt.expectedOK || wait != tt.expectedWait {
+				t.Errorf("Expected wait %v and ok %v, got %v and %v", tt.expectedWait, tt.expectedOK, wait, ok)
+			}
+		})
+	}
+
+	if newUpstreamBudget(0, 10, time.Second) != nil {
+		t.Error("Expected no budget without a rate")
+	}
+}
+
+func TestSendRequestShedsOverBudget(t *testing.T) {
+	client := newRPCClient("http://rpc.test")
+	client.client.Transport = &faultTransport{}
+	client.budget = newUpstreamBudget(1, 1, 0)
+
+	client.sendRequest(context.Background(), "getSlot", nil)
+	_, err := client.sendRequest(context.Background(), "getHealth", nil)
+	if !errors.Is(err, errUpstreamBudget) {
+		t.Fatalf("Expected errUpstreamBudget, got %v", err)
+	}
+	if status := upstreamStatus(err); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, status)
+	}
+}
+
+func TestSendBatchReservesBudgetPerCall(t *testing.T) {
+	var sizes []int
+	server := batchServer(t, 100, &sizes)
+	defer server.Close()
+	client := newRPCClient(server.URL)
+	client.budget = newUpstreamBudget(1, 5, 0)
+
+	if _, err := client.sendBatch(context.Background(), testCalls(3)); err != nil {
+		t.Fatalf("sendBatch returned error: %v", err)
+	}
+	_, err := client.sendBatch(context.Background(), testCalls(3))
+	if !errors.Is(err, errUpstreamBudget) {
+		t.Fatalf("Expected errUpstreamBudget for a batch beyond the budget, got %v", err)
+	}
+	if fmt.Sprint(sizes) != "[3]" {
+		t.Errorf("Expected a single upstream batch of 3, got %v", sizes)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// recordingQueueSize bounds the exchanges waiting to be written to disk
+const recordingQueueSize = 256
+
+// recording is the on-disk format of a single upstream exchange. Recordings
+// are stored as <method>-<params hash>-<sequence>.json so that repeated calls
+// with the same parameters replay in the order they were captured.
+// Batches are recorded under the method "batch" with the list of calls as
+// params, along with the request ids needed to remap the replayed responses.
+type recording struct {
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	IDs      []int           `json:"ids,omitempty"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// rpcEnvelope is the part of a JSON-RPC request needed to key a recording
+type rpcEnvelope struct {
+	ID       int             `json:"id"`
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	batchIDs []int
+}
+
+// recordingKey identifies the exchanges for a method and parameter set.
+// Params are normalized so equivalent requests replay the same recording.
+func recordingKey(method string, params json.RawMessage) string {
+	sum := sha256.Sum256([]byte(requestKey(method, params)))
+	return method + "-" + hex.EncodeToString(sum[:8])
+}
+
+// readEnvelope reads the request body. A RoundTripper must not modify the
+// request, so the body is read from a fresh copy when the request can make
+// one, and otherwise from a clone whose body is restored for the next reader.
+func readEnvelope(req *http.Request) (*http.Request, rpcEnvelope, error) {
+	var envelope rpcEnvelope
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, envelope, fmt.Errorf("request has no body")
+	}
+
+	var body []byte
+	var err error
+	if req.GetBody != nil {
+		var copied io.ReadCloser
+		if copied, err = req.GetBody(); err == nil {
+			body, err = io.ReadAll(copied)
+			copied.Close()
+		}
+	} else {
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if err != nil {
+		return req, envelope, fmt.Errorf("failed to read request body: %w", err)
+	}
+	// Bodies compressed by -upstream-gzip-requests are keyed by their JSON
+	if strings.EqualFold(req.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return req, envelope, fmt.Errorf("failed to decompress request body: %w", err)
+		}
+		body, err = io.ReadAll(gz)
+		if err != nil {
+			return req, envelope, fmt.Errorf("failed to decompress request body: %w", err)
+		}
+	}
+
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var calls []rpcEnvelope
+		if err := json.Unmarshal(body, &calls); err != nil {
+			return req, envelope, fmt.Errorf("failed to parse batch request body: %w", err)
+		}
+		type call struct {
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params,omitempty"`
+		}
+		keyed := make([]call, len(calls))
+		for i, c := range calls {
+			keyed[i] = call{Method: c.Method, Params: c.Params}
+			envelope.batchIDs = append(envelope.batchIDs, c.ID)
+		}
+		envelope.Method = "batch"
+		envelope.Params, _ = json.Marshal(keyed)
+		return req, envelope, nil
+	}
+
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return req, envelope, fmt.Errorf("failed to parse request body: %w", err)
+	}
+	return req, envelope, nil
+}
+
+// recordingName splits a recording file name into its key and sequence number
+func recordingName(path string) (string, int, bool) {
+	name := strings.TrimSuffix(filepath.Base(path), ".json")
+	sep := strings.LastIndex(name, "-")
+	if sep < 0 {
+		return "", 0, false
+	}
+	seq, err := strconv.Atoi(name[sep+1:])
+	if err != nil || seq < 0 {
+		return "", 0, false
+	}
+	return name[:sep], seq, true
+}
+
+// remapIDs rewrites the ids of a recorded response to those of the request
+// being replayed
+func remapIDs(rec recording, envelope rpcEnvelope) []byte {
+	body := []byte(rec.Response)
+
+	if envelope.batchIDs == nil {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err == nil {
+			fields["id"], _ = json.Marshal(envelope.ID)
+			body, _ = json.Marshal(fields)
+		}
+		return body
+	}
+
+	ids := make(map[string]json.RawMessage, len(rec.IDs))
+	for i, id := range rec.IDs {
+		if i < len(envelope.batchIDs) {
+			ids[fmt.Sprint(id)], _ = json.Marshal(envelope.batchIDs[i])
+		}
+	}
+
+	var responses []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &responses); err != nil {
+		return body
+	}
+	for _, fields := range responses {
+		if id, ok := ids[string(fields["id"])]; ok {
+			fields["id"] = id
+		}
+	}
+	body, _ = json.Marshal(responses)
+	return body
+}
+
+// recordingTransport writes every upstream exchange to a directory. Writes
+// happen on a background goroutine so recording adds no disk I/O to the
+// request path; exchanges are dropped rather than queued without bound.
+type recordingTransport struct {
+	dir   string
+	base  http.RoundTripper
+	queue chan recording
+	done  chan struct{}
+
+	closeMu sync.RWMutex
+	closed  bool
+
+	mu  sync.Mutex
+	seq map[string]int
+}
+
+// newRecordingTransport starts recording into dir, which is created if needed.
+// Recordings already in dir are kept, and new exchanges are numbered after
+// them so that recording again appends to the sequence.
+func newRecordingTransport(dir string, base http.RoundTripper) (*recordingTransport, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+
+	t := &recordingTransport{
+		dir:   dir,
+		base:  base,
+		queue: make(chan recording, recordingQueueSize),
+		done:  make(chan struct{}),
+		seq:   make(map[string]int),
+	}
+	for _, path := range paths {
+		if key, seq, ok := recordingName(path); ok && seq >= t.seq[key] {
+			t.seq[key] = seq + 1
+		}
+	}
+	go t.writeLoop()
+	return t, nil
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, envelope, err := readEnvelope(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := recording{Method: envelope.Method, Params: envelope.Params, IDs: envelope.batchIDs, Status: resp.StatusCode, Response: body}
+	if !json.Valid(body) {
+		rec.Response, _ = json.Marshal(string(body))
+	}
+
+	// Exchanges that finish after Close are not recorded
+	t.closeMu.RLock()
+	if !t.closed {
+		select {
+		case t.queue <- rec:
+		default:
+			logFields("recording queue full, dropping exchange", "method", envelope.Method, "request_id", requestID(req.Context()))
+		}
+	}
+	t.closeMu.RUnlock()
+
+	return resp, nil
+}
+
+func (t *recordingTransport) writeLoop() {
+	defer close(t.done)
+	for rec := range t.queue {
+		key := recordingKey(rec.Method, rec.Params)
+		t.mu.Lock()
+		seq := t.seq[key]
+		t.seq[key]++
+		t.mu.Unlock()
+
+		data, _ := json.MarshalIndent(rec, "", "  ")
+		path := filepath.Join(t.dir, fmt.Sprintf("%s-%d.json", key, seq))
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			logFields("failed to write recording", "path", path, "error", err)
+		}
+	}
+}
+
+// Close flushes pending recordings to disk. It may be called more than once.
+func (t *recordingTransport) Close() {
+	t.closeMu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.queue)
+	}
+	t.closeMu.Unlock()
+	<-t.done
+}
+
+// replayTransport serves upstream responses from a recording directory.
+// Exchanges with the same key are served in recorded order, and the last one
+// is repeated once the sequence is exhausted.
+type replayTransport struct {
+	recordings map[string][]recording
+
+	mu     sync.Mutex
+	cursor map[string]int
+}
+
+// newReplayTransport loads every recording in dir
+func newReplayTransport(dir string) (*replayTransport, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+
+	type sequenced struct {
+		seq int
+		rec recording
+	}
+	loaded := make(map[string][]sequenced)
+	for _, path := range paths {
+		key, seq, ok := recordingName(path)
+		if !ok {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recording %s: %w", path, err)
+		}
+		var rec recording
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("failed to parse recording %s: %w", path, err)
+		}
+		loaded[key] = append(loaded[key], sequenced{seq, rec})
+	}
+
+	t := &replayTransport{recordings: make(map[string][]recording), cursor: make(map[string]int)}
+	for key, recs := range loaded {
+		sort.Slice(recs, func(i, j int) bool { return recs[i].seq < recs[j].seq })
+		for _, r := range recs {
+			t.recordings[key] = append(t.recordings[key], r.rec)
+		}
+	}
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req, envelope, err := readEnvelope(req)
+	if err != nil {
+		return nil, err
+	}
+
+	key := recordingKey(envelope.Method, envelope.Params)
+	t.mu.Lock()
+	recs := t.recordings[key]
+	if len(recs) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("no recording for %s with params %s", envelope.Method, envelope.Params)
+	}
+	idx := t.cursor[key]
+	if idx < len(recs)-1 {
+		t.cursor[key]++
+	}
+	rec := recs[idx]
+	t.mu.Unlock()
+
+	// Replies carry the ids of the replayed request, not the recorded ones
+	body := remapIDs(rec, envelope)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
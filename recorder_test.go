@@ -0,0 +1,187 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	slot := 100
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Method {
+		case "getSlot":
+			slot++
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%d,"id":%d}`, slot, req.ID)
+		case "getBlock":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","result":{"blockhash":"abc","parentSlot":%v},"id":%d}`, req.Params[0], req.ID)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	recorder, err := newRecordingTransport(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("newRecordingTransport returned error: %v", err)
+	}
+
+	live := newRPCClient(server.URL)
+	live.client.Transport = recorder
+
+	var recordedSlots []uint64
+	for i := 0; i < 3; i++ {
+		s, err := live.getLatestSlot(context.Background())
+		if err != nil {
+			t.Fatalf("getLatestSlot returned error: %v", err)
+		}
+		recordedSlots = append(recordedSlots, s)
+	}
+	recordedBlock, err := live.getBlockDetails(context.Background(), 42, BlockOptions{})
+	if err != nil {
+		t.Fatalf("getBlockDetails returned error: %v", err)
+	}
+	recorder.Close()
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 4 {
+		t.Fatalf("Expected 4 recordings, got %d", len(files))
+	}
+
+	// Replay against a fresh client whose request ids start over
+	server.Close()
+	replayer, err := newReplayTransport(dir)
+	if err != nil {
+		t.Fatalf("newReplayTransport returned error: %v", err)
+	}
+	replayed := newRPCClient(server.URL)
+	replayed.client.Transport = replayer
+
+	for i, want := range recordedSlots {
+		got, err := replayed.getLatestSlot(context.Background())
+		if err != nil {
+			t.Fatalf("replayed getLatestSlot returned error: %v", err)
+		}
+		if got != want {
+			t.Errorf("replayed slot %d: got %d want %d", i, got, want)
+		}
+	}
+
+	// The final recording repeats once the sequence is exhausted
+	if got, _ := replayed.getLatestSlot(context.Background()); got != recordedSlots[2] {
+		t.Errorf("Expected exhausted sequence to repeat %d, got %d", recordedSlots[2], got)
+	}
+
+	block, err := replayed.getBlockDetails(context.Background(), 42, BlockOptions{})
+	if err != nil {
+		t.Fatalf("replayed getBlockDetails returned error: %v", err)
+	}
+	if string(block) != string(recordedBlock) {
+		t.Errorf("replayed block mismatch: got %s want %s", block, recordedBlock)
+	}
+
+	if _, err := replayed.getBlockDetails(context.Background(), 43, BlockOptions{}); err == nil {
+		t.Error("Expected error replaying an unrecorded request")
+	}
+}
+
+func TestRecordingAppendsToExistingRecordings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":100,"id":%d}`, req.ID)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for run := 0; run < 2; run++ {
+		recorder, err := newRecordingTransport(dir, http.DefaultTransport)
+		if err != nil {
+			t.Fatalf("newRecordingTransport returned error: %v", err)
+		}
+
+		req, _ := http.NewRequest("POST", server.URL, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"getSlot"}`))
+		body := req.Body
+		resp, err := recorder.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip returned error: %v", err)
+		}
+		resp.Body.Close()
+		if req.Body != body {
+			t.Error("Expected RoundTrip to leave the request body in place")
+		}
+		recorder.Close()
+		recorder.Close()
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 2 {
+		t.Fatalf("Expected the second run to add a recording, got %d files", len(files))
+	}
+	seqs := make(map[int]bool)
+	for _, path := range files {
+		_, seq, _ := recordingName(path)
+		seqs[seq] = true
+	}
+	if !seqs[0] || !seqs[1] {
+		t.Errorf("Expected recordings numbered 0 and 1, got %v", files)
+	}
+}
+
+func TestRecordThenReplayGzipRequests(t *testing.T) {
+	var encodings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			body, _ = gzip.NewReader(r.Body)
+		}
+		var req RPCRequest
+		json.NewDecoder(body).Decode(&req)
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%q,"id":%d}`, req.Method, req.ID)
+	}))
+	defer server.Close()
+	request := []byte(`{"jsonrpc":"2.0","id":1,"method":"getMultipleAccounts","params":[["` + strings.Repeat("a", gzipMinBytes) + `"]]}`)
+
+	dir := t.TempDir()
+	recorder, err := newRecordingTransport(dir, http.DefaultTransport)
+	if err != nil {
+		t.Fatalf("newRecordingTransport returned error: %v", err)
+	}
+	live := newRPCClient(server.URL)
+	live.client.Transport = recorder
+	live.gzipRequests = true
+	recorded, err := live.postTo(context.Background(), server.URL, request)
+	if err != nil {
+		t.Fatalf("Recording a gzipped request returned error: %v", err)
+	}
+	recorder.Close()
+	if len(encodings) != 1 || encodings[0] != "gzip" {
+		t.Fatalf("Expected a gzipped request upstream, got %q", encodings)
+	}
+
+	replayer, err := newReplayTransport(dir)
+	if err != nil {
+		t.Fatalf("newReplayTransport returned error: %v", err)
+	}
+	replayed := newRPCClient(server.URL)
+	replayed.client.Transport = replayer
+	replayed.gzipRequests = true
+	body, err := replayed.postTo(context.Background(), server.URL, request)
+	if err != nil {
+		t.Fatalf("Replaying a gzipped request returned error: %v", err)
+	}
+	var want, got RPCResponse
+	json.Unmarshal(recorded, &want)
+	json.Unmarshal(body, &got)
+	if got.ID != want.ID || string(got.Result) != string(want.Result) {
+		t.Errorf("replayed response mismatch: got %s want %s", body, recorded)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxAccountDataSize is the largest data size an account can have
+const maxAccountDataSize = 10 << 20
+
+// commonAccountSizes are the data sizes of the accounts transaction builders
+// most often create, by the names /rent accepts as ?type=
+var commonAccountSizes = map[string]uint64{
+	"system":         0,
+	"nonce":          80,
+	"mint":           mintAccountSize,
+	"token":          tokenAccountSize,
+	"token-multisig": 355,
+	"stake":          stakeAccountSize,
+	"vote":           3762,
+}
+
+// rentResponse is the response of /rent
+type rentResponse struct {
+	Type     string  `json:"type,omitempty"`
+	DataSize uint64  `json:"data_size"`
+	Lamports uint64  `json:"lamports"`
+	SOL      float64 `json:"sol"`
+}
+
+// rentDueResponse reports whether an account holds enough lamports to be rent exempt
+type rentDueResponse struct {
+	Address           string `json:"address"`
+	DataSize          uint64 `json:"data_size"`
+	Balance           uint64 `json:"balance"`
+	RentExemptMinimum uint64 `json:"rent_exempt_minimum"`
+	RentExempt        bool   `json:"rent_exempt"`
+	Shortfall         uint64 `json:"shortfall,omitempty"`
+}
+
+// getMinimumBalanceForRentExemption gets the lamports needed for an account of
+// the given data size to be rent exempt
+func (c *rpcClient) getMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64) (uint64, error) {
+	response, err := c.sendRequest(ctx, "getMinimumBalanceForRentExemption", []interface{}{dataSize})
+	if err != nil {
+		return 0, err
+	}
+
+	var lamports uint64
+	if err := json.Unmarshal(response.Result, &lamports); err != nil {
+		return 0, fmt.Errorf("failed to parse rent exemption minimum: %w", err)
+	}
+
+	return lamports, nil
+}
+
+// rentExemptionCache remembers the rent exemption minimums of the common
+// account sizes. Rent parameters only change with a feature activation, so
+// entries never expire. Other sizes come from clients and are fetched every
+// time, so that the cache cannot be grown without bound.
+type rentExemptionCache struct {
+	mu     sync.Mutex
+	values map[uint64]uint64
+}
+
+func newRentExemptionCache() *rentExemptionCache {
+	return &rentExemptionCache{values: make(map[uint64]uint64)}
+}
+
+func (c *rentExemptionCache) get(ctx context.Context, client SolanaRPCClient, dataSize uint64) (uint64, error) {
+	c.mu.Lock()
+	lamports, ok := c.values[dataSize]
+	c.mu.Unlock()
+	if ok {
+		return lamports, nil
+	}
+
+	lamports, err := client.getMinimumBalanceForRentExemption(ctx, dataSize)
+	if err != nil || !commonAccountSize(dataSize) {
+		return lamports, err
+	}
+
+	c.mu.Lock()
+	c.values[dataSize] = lamports
+	c.mu.Unlock()
+	return lamports, nil
+}
+
+// commonAccountSize reports whether dataSize is that of a common account type
+func commonAccountSize(dataSize uint64) bool {
+	for _, size := range commonAccountSizes {
+		if size == dataSize {
+			return true
+		}
+	}
+	return false
+}
+
+// rentForAccountType gets the rent exemption minimum of one of the common
+// account types
+func rentForAccountType(ctx context.Context, client SolanaRPCClient, cache *rentExemptionCache, accountType string) (*rentResponse, error) {
+	size, ok := commonAccountSizes[accountType]
+	if !ok {
+		return nil, fmt.Errorf("unknown account type %q", accountType)
+	}
+	lamports, err := cache.get(ctx, client, size)
+	if err != nil {
+		return nil, err
+	}
+	return &rentResponse{Type: accountType, DataSize: size, Lamports: lamports, SOL: float64(lamports) / lamportsPerSOL}, nil
+}
+
+// accountTypeNames lists the common account types for error messages
+func accountTypeNames() string {
+	names := make([]string, 0, len(commonAccountSizes))
+	for name := range commonAccountSizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func handleGetRent(client SolanaRPCClient, cache *rentExemptionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sizeParam, accountType := r.URL.Query().Get("size"), r.URL.Query().Get("type")
+		if (sizeParam == "") == (accountType == "") {
+			http.Error(w, "exactly one of size and type is required", http.StatusBadRequest)
+			return
+		}
+
+		var response *rentResponse
+		if accountType != "" {
+			if _, ok := commonAccountSizes[accountType]; !ok {
+				http.Error(w, fmt.Sprintf("type must be one of %s", accountTypeNames()), http.StatusBadRequest)
+				return
+			}
+			var err error
+			if response, err = rentForAccountType(r.Context(), client, cache, accountType); err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+		} else {
+			size, err := strconv.ParseUint(sizeParam, 10, 64)
+			if err != nil || size > maxAccountDataSize {
+				http.Error(w, fmt.Sprintf("size must be a number of bytes up to %d", maxAccountDataSize), http.StatusBadRequest)
+				return
+			}
+			lamports, err := cache.get(r.Context(), client, size)
+			if err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+			response = &rentResponse{DataSize: size, Lamports: lamports, SOL: float64(lamports) / lamportsPerSOL}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+func handleGetRentDue(client SolanaRPCClient, cache *rentExemptionCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			http.Error(w, "address parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		account, err := client.getAccountInfo(r.Context(), address, AccountOptions{})
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		if account == nil {
+			http.Error(w, "account not found", http.StatusNotFound)
+			return
+		}
+
+		dataSize := account.Space
+		if dataSize == 0 {
+			if data, err := account.rawData(); err == nil {
+				dataSize = uint64(len(data))
+			}
+		}
+
+		minimum, err := cache.get(r.Context(), client, dataSize)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := rentDueResponse{
+			Address:           address,
+			DataSize:          dataSize,
+			Balance:           account.Lamports,
+			RentExemptMinimum: minimum,
+			RentExempt:        account.Lamports >= minimum,
+		}
+		if !response.RentExempt {
+			response.Shortfall = minimum - account.Lamports
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
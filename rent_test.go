@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetRentDue(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		expectedStatus int
+		expected       rentDueResponse
+	}{
+		{
+			name:           "Rent Exempt",
+			mockClient:     mockRPCClient{accountInfo: &AccountInfo{Lamports: 2039280, Space: 165}, rentMinimum: 2039280},
+			expectedStatus: http.StatusOK,
+			expected:       rentDueResponse{Address: "abc", DataSize: 165, Balance: 2039280, RentExemptMinimum: 2039280, RentExempt: true},
+		},
+		{
+			name:           "Below Minimum",
+			mockClient:     mockRPCClient{accountInfo: testAccount("owner", make([]byte, 165)), rentMinimum: 2039280},
+			expectedStatus: http.StatusOK,
+			expected:       rentDueResponse{Address: "abc", DataSize: 165, Balance: 1000, RentExemptMinimum: 2039280, Shortfall: 2038280},
+		},
+		{
+			name:           "Account Not Found",
+			mockClient:     mockRPCClient{},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/rent-due?address=abc", nil)
+			rr := httptest.NewRecorder()
+			handleGetRentDue(&tt.mockClient, newRentExemptionCache()).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var got rentDueResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("unexpected response: got %+v want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRentExemptionCache(t *testing.T) {
+	mock := &mockRPCClient{rentMinimum: 890880}
+	cache := newRentExemptionCache()
+
+	for i := 0; i < 3; i++ {
+		if lamports, err := cache.get(context.Background(), mock, 0); err != nil || lamports != 890880 {
+			t.Fatalf("cache.get returned %d, %v", lamports, err)
+		}
+	}
+
+	if mock.rentCalls != 1 {
+		t.Errorf("Expected a single upstream call, got %d", mock.rentCalls)
+	}
+
+	// Sizes other than those of the common account types are not cached
+	for i := 0; i < 2; i++ {
+		cache.get(context.Background(), mock, 1234)
+	}
+	if mock.rentCalls != 3 || len(cache.values) != 1 {
+		t.Errorf("Expected an uncommon size to be fetched every time, got %d calls and %d entries", mock.rentCalls, len(cache.values))
+	}
+}
+
+func TestHandleGetRent(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+		expected       rentResponse
+	}{
+		{
+			name:           "Size",
+			mockClient:     mockRPCClient{rentMinimum: 1461600},
+			queryParam:     "?size=82",
+			expectedStatus: http.StatusOK,
+			expected:       rentResponse{DataSize: 82, Lamports: 1461600, SOL: 0.0014616},
+		},
+		{
+			name:           "Token Account",
+			mockClient:     mockRPCClient{rentMinimum: 2039280},
+			queryParam:     "?type=token",
+			expectedStatus: http.StatusOK,
+			expected:       rentResponse{Type: "token", DataSize: tokenAccountSize, Lamports: 2039280, SOL: 0.00203928},
+		},
+		{name: "Neither", expectedStatus: http.StatusBadRequest},
+		{name: "Both", queryParam: "?size=82&type=mint", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown Type", queryParam: "?type=program", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Size", queryParam: "?size=-1", expectedStatus: http.StatusBadRequest},
+		{name: "Size Too Large", queryParam: "?size=10485761", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, queryParam: "?type=stake", expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/rent"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetRent(&tt.mockClient, newRentExemptionCache()).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var got rentResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("unexpected response: got %+v want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRentForAccountType(t *testing.T) {
+	client := &mockRPCClient{rentMinimum: 890880}
+	cache := newRentExemptionCache()
+
+	for _, accountType := range []string{"system", "system"} {
+		rent, err := rentForAccountType(context.Background(), client, cache, accountType)
+		if err != nil || rent.Lamports != 890880 || rent.DataSize != 0 {
+			t.Fatalf("Expected 890880 lamports for a system account, got %+v, %v", rent, err)
+		}
+	}
+	if client.rentCalls != 1 {
+		t.Errorf("Expected the minimum to be cached, got %d calls", client.rentCalls)
+	}
+
+	if _, err := rentForAccountType(context.Background(), client, cache, "program"); err == nil {
+		t.Error("Expected an error for an unknown account type")
+	}
+}
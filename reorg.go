@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// JSON-RPC codes for slots that have no block, or none available yet
+const (
+	rpcBlockNotAvailable   = -32004
+	rpcSlotSkipped         = -32007
+	rpcLongTermSlotMissing = -32009
+)
+
+// reorgCheckResponse is the response of /reorg-check
+type reorgCheckResponse struct {
+	Slot              uint64 `json:"slot"`
+	Reorged           bool   `json:"reorged"`
+	Skipped           bool   `json:"skipped"`
+	ExpectedBlockhash string `json:"expectedBlockhash"`
+	CurrentBlockhash  string `json:"currentBlockhash,omitempty"`
+}
+
+// slotSkipped reports whether the upstream answered that a slot has no block
+func slotSkipped(err error) bool {
+	var rpcErr *upstreamError
+	if !errors.As(err, &rpcErr) {
+		return false
+	}
+	return rpcErr.Code == rpcSlotSkipped || rpcErr.Code == rpcLongTermSlotMissing
+}
+
+// checkReorg compares the blockhash the cluster now has at a slot, at
+// confirmed commitment, with the one a client saw earlier. A slot that is
+// now skipped had its block dropped from the fork the cluster settled on,
+// which is also a reorg.
+func checkReorg(ctx context.Context, client SolanaRPCClient, slot uint64, expected string) (*reorgCheckResponse, error) {
+	response := &reorgCheckResponse{Slot: slot, ExpectedBlockhash: expected}
+
+	block, err := client.getBlockDetails(ctx, slot, BlockOptions{
+		MaxSupportedTransactionVersion: new(int),
+		Commitment:                     commitmentConfirmed,
+		TransactionDetails:             "none",
+	})
+	if slotSkipped(err) {
+		response.Reorged = true
+		response.Skipped = true
+		return response, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		Blockhash string `json:"blockhash"`
+	}
+	if err := json.Unmarshal(block, &header); err != nil || header.Blockhash == "" {
+		return nil, fmt.Errorf("failed to parse block %d", slot)
+	}
+
+	response.CurrentBlockhash = header.Blockhash
+	response.Reorged = header.Blockhash != expected
+	return response, nil
+}
+
+func handleGetReorgCheck(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		slot, err := strconv.ParseUint(r.URL.Query().Get("slot"), 10, 64)
+		if err != nil {
+			http.Error(w, "slot parameter is required and must be a number", http.StatusBadRequest)
+			return
+		}
+
+		expected := r.URL.Query().Get("expectedBlockhash")
+		if hash, err := base58Decode(expected); err != nil || len(hash) != 32 {
+			http.Error(w, "expectedBlockhash must be a base58 blockhash", http.StatusBadRequest)
+			return
+		}
+
+		response, err := checkReorg(r.Context(), client, slot, expected)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetReorgCheck(t *testing.T) {
+	seen := "4sGjMW1sUnHzSxGspuhpqLDx6wiyjNtZAMdL4VZHirAn"
+	replaced := "8FFzxDB7UeE6aXzSvD3jcmSkgXEfyzNjhSh5TtWXs1rj"
+	blocks := map[uint64]json.RawMessage{
+		100: json.RawMessage(`{"blockhash":"` + seen + `","previousBlockhash":"x","blockHeight":90}`),
+		101: json.RawMessage(`{"blockhash":"` + replaced + `","previousBlockhash":"y","blockHeight":91}`),
+	}
+
+	tests := []struct {
+		name           string
+		queryParam     string
+		mockClient     mockRPCClient
+		expectedStatus int
+		expected       reorgCheckResponse
+	}{
+		{
+			name:           "Unchanged",
+			queryParam:     "?slot=100&expectedBlockhash=" + seen,
+			mockClient:     mockRPCClient{blocks: blocks},
+			expectedStatus: http.StatusOK,
+			expected:       reorgCheckResponse{Slot: 100, ExpectedBlockhash: seen, CurrentBlockhash: seen},
+		},
+		{
+			name:           "Blockhash Changed",
+			queryParam:     "?slot=101&expectedBlockhash=" + seen,
+			mockClient:     mockRPCClient{blocks: blocks},
+			expectedStatus: http.StatusOK,
+			expected:       reorgCheckResponse{Slot: 101, Reorged: true, ExpectedBlockhash: seen, CurrentBlockhash: replaced},
+		},
+		{
+			name:           "Slot Now Skipped",
+			queryParam:     "?slot=102&expectedBlockhash=" + seen,
+			mockClient:     mockRPCClient{blocks: blocks},
+			expectedStatus: http.StatusOK,
+			expected:       reorgCheckResponse{Slot: 102, Reorged: true, Skipped: true, ExpectedBlockhash: seen},
+		},
+		{name: "Missing Slot", queryParam: "?expectedBlockhash=" + seen, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Blockhash", queryParam: "?slot=100&expectedBlockhash=abc", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", queryParam: "?slot=100&expectedBlockhash=" + seen, mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/reorg-check"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetReorgCheck(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response reorgCheckResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, response)
+			}
+			if opts := tt.mockClient.blockOptions; opts.TransactionDetails != "none" || opts.Commitment != commitmentConfirmed {
+				t.Errorf("unexpected block options: %+v", opts)
+			}
+		})
+	}
+}
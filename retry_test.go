@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// faultTransport replays a scripted sequence of failures before succeeding
+type faultTransport struct {
+	faults   []func() (*http.Response, error)
+	attempts int
+}
+
+func (f *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.attempts++
+	if f.attempts <= len(f.faults) {
+		return f.faults[f.attempts-1]()
+	}
+	return jsonResponse(http.StatusOK, `{"jsonrpc":"2.0","result":42,"id":1}`), nil
+}
+
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func dialFault() (*http.Response, error) {
+	return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+}
+
+func dnsFault() (*http.Response, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: "rpc.invalid"}
+}
+
+func tlsFault() (*http.Response, error) {
+	return nil, tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}
+}
+
+func certFault() (*http.Response, error) {
+	return nil, &tls.CertificateVerificationError{Err: errors.New("certificate signed by unknown authority")}
+}
+
+func statusFault(status int) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		return jsonResponse(status, "upstream failure"), nil
+	}
+}
+
+func throttleFault(retryAfter string) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		resp := jsonResponse(http.StatusTooManyRequests, "slow down")
+		if retryAfter != "" {
+			resp.Header.Set("Retry-After", retryAfter)
+		}
+		return resp, nil
+	}
+}
+
+func nodeBehindFault() (*http.Response, error) {
+	return jsonResponse(http.StatusOK, `{"jsonrpc":"2.0","error":{"code":-32005,"message":"Node is behind by 42 slots"},"id":1}`), nil
+}
+
+func readFault() (*http.Response, error) {
+	return nil, &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+}
+
+func TestSendRequestRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		faults           []func() (*http.Response, error)
+		expectedAttempts int
+		expectedDelays   []time.Duration
+		expectError      bool
+	}{
+		{name: "Dial Failure Retried Immediately", faults: []func() (*http.Response, error){dialFault}, expectedAttempts: 2},
+		{name: "DNS Failure Retried Immediately", faults: []func() (*http.Response, error){dnsFault}, expectedAttempts: 2},
+		{name: "TLS Failure Retried Immediately", faults: []func() (*http.Response, error){tlsFault}, expectedAttempts: 2},
+		{
+			name:             "Server Errors Back Off",
+			faults:           []func() (*http.Response, error){statusFault(502), statusFault(503)},
+			expectedAttempts: 3,
+			expectedDelays:   []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name:             "Throttled Backs Off",
+			faults:           []func() (*http.Response, error){throttleFault("")},
+			expectedAttempts: 2,
+			expectedDelays:   []time.Duration{100 * time.Millisecond},
+		},
+		{
+			name:             "Throttled Waits For Retry-After",
+			faults:           []func() (*http.Response, error){throttleFault("2")},
+			expectedAttempts: 2,
+			expectedDelays:   []time.Duration{2 * time.Second},
+		},
+		{
+			name:             "Attempts Exhausted",
+			faults:           []func() (*http.Response, error){dialFault, dialFault, dialFault},
+			expectedAttempts: 3,
+			expectError:      true,
+		},
+		{
+			name:             "Mixed Failures Share Attempts",
+			faults:           []func() (*http.Response, error){statusFault(503), nodeBehindFault, statusFault(503), nodeBehindFault},
+			expectedAttempts: 3,
+			expectedDelays:   []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+			expectError:      true,
+		},
+		{name: "Certificate Failure Not Retried", faults: []func() (*http.Response, error){certFault}, expectedAttempts: 1, expectError: true},
+		{name: "Client Error Not Retried", faults: []func() (*http.Response, error){statusFault(400)}, expectedAttempts: 1, expectError: true},
+		{name: "Failure After Send Not Retried", faults: []func() (*http.Response, error){readFault}, expectedAttempts: 1, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &faultTransport{faults: tt.faults}
+			client := newRPCClient("http://rpc.test")
+			client.client.Transport = transport
+			client.retryBackoff = 100 * time.Millisecond
+			client.jitter = nil
+
+			// The upstream limiter sees the time spent sleeping pass
+			now := time.Unix(0, 0)
+			client.limits.now = func() time.Time { return now }
+			var delays []time.Duration
+			client.sleep = func(d time.Duration) {
+				delays = append(delays, d)
+				now = now.Add(d)
+			}
+
+			_, err := client.sendRequest(context.Background(), "getSlot", nil)
+			if tt.expectError != (err != nil) {
+				t.Errorf("sendRequest returned error %v, expected error: %v", err, tt.expectError)
+			}
+
+			if transport.attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, transport.attempts)
+			}
+
+			if len(delays) != len(tt.expectedDelays) {
+				t.Fatalf("Expected delays %v, got %v", tt.expectedDelays, delays)
+			}
+			for i := range delays {
+				if delays[i] != tt.expectedDelays[i] {
+					t.Errorf("Expected delays %v, got %v", tt.expectedDelays, delays)
+				}
+			}
+		})
+	}
+}
+
+func TestHalfJitter(t *testing.T) {
+	d := 100 * time.Millisecond
+	for i := 0; i < 100; i++ {
+		if got := halfJitter(d); got < d/2 || got > d {
+			t.Fatalf("Expected a wait between %v and %v, got %v", d/2, d, got)
+		}
+	}
+}
+
+func TestRequestContextStopsUpstreamCalls(t *testing.T) {
+	// done releases handlers still holding a request when a test ends, as
+	// the server only notices a client going away once the body is read
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, r *http.Request, done <-chan struct{})
+	}{
+		{
+			name: "Abandons Request In Flight",
+			handler: func(w http.ResponseWriter, r *http.Request, done <-chan struct{}) {
+				select {
+				case <-r.Context().Done():
+				case <-done:
+				}
+			},
+		},
+		{
+			name: "Abandons Backoff",
+			handler: func(w http.ResponseWriter, r *http.Request, done <-chan struct{}) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			done := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&hits, 1)
+				tt.handler(w, r, done)
+			}))
+			defer server.Close()
+			defer close(done)
+
+			client := newRPCClient(server.URL)
+			client.retryBackoff = time.Minute
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			_, err := client.getLatestSlot(ctx)
+
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected a deadline error, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Expected the call to end with its context, took %v", elapsed)
+			}
+			if got := atomic.LoadInt32(&hits); got != 1 {
+				t.Errorf("Expected 1 upstream request, got %d", got)
+			}
+		})
+	}
+}
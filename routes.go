@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// apiRoute is an endpoint registered on the API mux. The route list doubles
+// as the source of the index served on / and of the OpenAPI document, so
+// every endpoint is described.
+type apiRoute struct {
+	Path        string `json:"path"`
+	Description string `json:"description"`
+	handler     http.HandlerFunc
+
+	// responses holds a zero value of the JSON response to each method the
+	// route answers, nil where the node's JSON is passed through. Routes
+	// without any answer GET.
+	responses map[string]interface{}
+	// request is a zero value of the JSON body accepted by POST
+	request interface{}
+	// successor is the versioned path of a deprecated unversioned alias
+	successor string
+}
+
+// returning lists a GET response for apiRoute.responses
+func returning(response interface{}) map[string]interface{} {
+	return map[string]interface{}{http.MethodGet: response}
+}
+
+// apiVersion prefixes the paths of the versioned API
+const apiVersion = "/v1"
+
+// versioned moves routes under apiVersion, keeping each old path as a
+// deprecated alias that points clients at its successor
+func versioned(routes []apiRoute) []apiRoute {
+	moved := make([]apiRoute, 0, 2*len(routes))
+	for _, route := range routes {
+		alias := route
+		route.Path = apiVersion + route.Path
+		alias.successor = route.Path
+		alias.handler = withDeprecation(route.Path, route.handler)
+		moved = append(moved, route, alias)
+	}
+	return moved
+}
+
+// withDeprecation marks responses as deprecated, linking to the successor
+// path in the Link header
+func withDeprecation(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		next(w, r)
+	}
+}
+
+// apiIndex is the response of /
+type apiIndex struct {
+	Service   string     `json:"service"`
+	Version   string     `json:"version"`
+	Endpoints []apiRoute `json:"endpoints"`
+}
+
+// newAPIMux registers the routes along with the index, OpenAPI document and
+// favicon handlers
+func newAPIMux(routes []apiRoute) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		mux.HandleFunc(route.Path, route.handler)
+	}
+	mux.HandleFunc("/", handleIndex(routes))
+	mux.HandleFunc(openAPIPath, handleOpenAPI(routes))
+	mux.HandleFunc("/favicon.ico", handleFavicon)
+	return mux
+}
+
+// handleIndex lists the available endpoints, leaving out deprecated aliases.
+// The "/" pattern matches every unregistered path, so anything other than
+// the root itself is a 404.
+func handleIndex(routes []apiRoute) http.HandlerFunc {
+	index := apiIndex{Service: "Solana Blockchain Client API", Version: version, Endpoints: []apiRoute{}}
+	for _, route := range routes {
+		if route.successor == "" {
+			index.Endpoints = append(index.Endpoints, route)
+		}
+	}
+	jsonData, _ := json.Marshal(index)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(jsonData)
+	}
+}
+
+// handleFavicon answers browser favicon requests without content
+func handleFavicon(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// byMethod dispatches requests to the handler for their HTTP method,
+// answering 405 with the allowed methods otherwise
+func byMethod(handlers map[string]http.HandlerFunc) http.HandlerFunc {
+	allowed := make([]string, 0, len(handlers))
+	for method := range handlers {
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler, ok := handlers[r.Method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIMuxIndex(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	routes := []apiRoute{
+		{Path: "/latest-block", Description: "Latest slot", handler: ok},
+		{Path: "/metrics", Description: "Prometheus metrics", handler: ok},
+	}
+	mux := newAPIMux(routes)
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "Index", path: "/", expectedStatus: http.StatusOK},
+		{name: "Favicon", path: "/favicon.ico", expectedStatus: http.StatusNoContent},
+		{name: "Registered Route", path: "/metrics", expectedStatus: http.StatusOK},
+		{name: "Unknown Path", path: "/unknown", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	var index apiIndex
+	if err := json.Unmarshal(rr.Body.Bytes(), &index); err != nil {
+		t.Fatalf("Failed to unmarshal index: %v", err)
+	}
+	if len(index.Endpoints) != len(routes) {
+		t.Fatalf("Expected %d endpoints in the index, got %d", len(routes), len(index.Endpoints))
+	}
+	for i, route := range routes {
+		if index.Endpoints[i].Path != route.Path || index.Endpoints[i].Description != route.Description {
+			t.Errorf("unexpected index entry %d: %+v", i, index.Endpoints[i])
+		}
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	mux := newAPIMux(versioned([]apiRoute{{Path: "/latest-block", Description: "Latest slot", handler: ok}}))
+
+	tests := []struct {
+		name               string
+		path               string
+		expectedDeprecated bool
+	}{
+		{name: "Versioned", path: "/v1/latest-block"},
+		{name: "Deprecated Alias", path: "/latest-block", expectedDeprecated: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, httptest.NewRequest("GET", tt.path, nil))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+			}
+			if deprecated := rr.Header().Get("Deprecation") != ""; deprecated != tt.expectedDeprecated {
+				t.Errorf("Expected deprecated %v, got %v", tt.expectedDeprecated, deprecated)
+			}
+			if tt.expectedDeprecated && rr.Header().Get("Link") != `</v1/latest-block>; rel="successor-version"` {
+				t.Errorf("Unexpected Link header %q", rr.Header().Get("Link"))
+			}
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	var index apiIndex
+	if err := json.Unmarshal(rr.Body.Bytes(), &index); err != nil {
+		t.Fatalf("Failed to unmarshal index: %v", err)
+	}
+	if len(index.Endpoints) != 1 || index.Endpoints[0].Path != "/v1/latest-block" {
+		t.Errorf("Expected only the versioned route in the index, got %+v", index.Endpoints)
+	}
+}
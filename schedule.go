@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"net/http"
+	"strconv"
+)
+
+// minimumSlotsPerEpoch is the length of the first epoch when the schedule
+// warms up; each following warmup epoch is twice as long
+const minimumSlotsPerEpoch = 32
+
+// EpochSchedule is the result of getEpochSchedule
+type EpochSchedule struct {
+	SlotsPerEpoch            uint64 `json:"slotsPerEpoch"`
+	LeaderScheduleSlotOffset uint64 `json:"leaderScheduleSlotOffset"`
+	Warmup                   bool   `json:"warmup"`
+	FirstNormalEpoch         uint64 `json:"firstNormalEpoch"`
+	FirstNormalSlot          uint64 `json:"firstNormalSlot"`
+}
+
+// slotEpoch locates a slot within the epoch schedule
+type slotEpoch struct {
+	Slot         uint64 `json:"slot"`
+	Epoch        uint64 `json:"epoch"`
+	SlotIndex    uint64 `json:"slot_index"`
+	SlotsInEpoch uint64 `json:"slots_in_epoch"`
+}
+
+// epochProgressResponse is the response of /epoch-progress
+type epochProgressResponse struct {
+	From          slotEpoch `json:"from"`
+	To            slotEpoch `json:"to"`
+	SlotsElapsed  uint64    `json:"slots_elapsed"`
+	EpochsElapsed uint64    `json:"epochs_elapsed"`
+}
+
+// getEpochSchedule gets the cluster's epoch schedule
+func (c *rpcClient) getEpochSchedule(ctx context.Context) (*EpochSchedule, error) {
+	response, err := c.sendRequest(ctx, "getEpochSchedule", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var schedule EpochSchedule
+	if err := json.Unmarshal(response.Result, &schedule); err != nil {
+		return nil, fmt.Errorf("failed to parse epoch schedule: %w", err)
+	}
+
+	return &schedule, nil
+}
+
+// slotsInEpoch returns the length of an epoch, which doubles from
+// minimumSlotsPerEpoch during warmup
+func (s *EpochSchedule) slotsInEpoch(epoch uint64) uint64 {
+	if s.Warmup && epoch < s.FirstNormalEpoch {
+		return minimumSlotsPerEpoch << epoch
+	}
+	return s.SlotsPerEpoch
+}
+
+// epochOf finds the epoch of a slot and its index within that epoch,
+// following the Solana runtime's EpochSchedule::get_epoch_and_slot_index
+func (s *EpochSchedule) epochOf(slot uint64) slotEpoch {
+	if s.Warmup && slot < s.FirstNormalSlot {
+		// Warmup epoch n spans slots [32·(2^n - 1), 32·(2^(n+1) - 1))
+		epoch := uint64(bits.Len64(slot/minimumSlotsPerEpoch+1) - 1)
+		first := minimumSlotsPerEpoch * ((uint64(1) << epoch) - 1)
+		return slotEpoch{Slot: slot, Epoch: epoch, SlotIndex: slot - first, SlotsInEpoch: s.slotsInEpoch(epoch)}
+	}
+
+	normal := slot - s.FirstNormalSlot
+	return slotEpoch{
+		Slot:         slot,
+		Epoch:        s.FirstNormalEpoch + normal/s.SlotsPerEpoch,
+		SlotIndex:    normal % s.SlotsPerEpoch,
+		SlotsInEpoch: s.SlotsPerEpoch,
+	}
+}
+
+func handleGetEpochProgress(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, err := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+		if err != nil {
+			http.Error(w, "from must be a slot number", http.StatusBadRequest)
+			return
+		}
+		to, err := strconv.ParseUint(r.URL.Query().Get("to"), 10, 64)
+		if err != nil {
+			http.Error(w, "to must be a slot number", http.StatusBadRequest)
+			return
+		}
+		if to < from {
+			http.Error(w, "to must not be before from", http.StatusBadRequest)
+			return
+		}
+
+		schedule, err := client.getEpochSchedule(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		if schedule.SlotsPerEpoch == 0 {
+			http.Error(w, "epoch schedule has no slots per epoch", http.StatusInternalServerError)
+			return
+		}
+
+		response := epochProgressResponse{From: schedule.epochOf(from), To: schedule.epochOf(to), SlotsElapsed: to - from}
+		response.EpochsElapsed = response.To.Epoch - response.From.Epoch
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
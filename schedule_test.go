@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// warmupSchedule warms up over 8 epochs of 32 to 4096 slots before settling
+// on 8192 slot epochs at slot 8160
+var warmupSchedule = &EpochSchedule{SlotsPerEpoch: 8192, LeaderScheduleSlotOffset: 8192, Warmup: true, FirstNormalEpoch: 8, FirstNormalSlot: 8160}
+
+func TestEpochScheduleEpochOf(t *testing.T) {
+	mainnet := &EpochSchedule{SlotsPerEpoch: 432000, LeaderScheduleSlotOffset: 432000}
+
+	tests := []struct {
+		name     string
+		schedule *EpochSchedule
+		slot     uint64
+		expected slotEpoch
+	}{
+		{name: "First Slot", schedule: warmupSchedule, slot: 0, expected: slotEpoch{Epoch: 0, SlotIndex: 0, SlotsInEpoch: 32}},
+		{name: "End Of First Epoch", schedule: warmupSchedule, slot: 31, expected: slotEpoch{Epoch: 0, SlotIndex: 31, SlotsInEpoch: 32}},
+		{name: "Second Warmup Epoch", schedule: warmupSchedule, slot: 32, expected: slotEpoch{Epoch: 1, SlotIndex: 0, SlotsInEpoch: 64}},
+		{name: "Mid Warmup", schedule: warmupSchedule, slot: 1000, expected: slotEpoch{Epoch: 5, SlotIndex: 8, SlotsInEpoch: 1024}},
+		{name: "Last Warmup Slot", schedule: warmupSchedule, slot: 8159, expected: slotEpoch{Epoch: 7, SlotIndex: 4095, SlotsInEpoch: 4096}},
+		{name: "First Normal Slot", schedule: warmupSchedule, slot: 8160, expected: slotEpoch{Epoch: 8, SlotIndex: 0, SlotsInEpoch: 8192}},
+		{name: "Normal Epoch", schedule: warmupSchedule, slot: 8160 + 3*8192 + 5, expected: slotEpoch{Epoch: 11, SlotIndex: 5, SlotsInEpoch: 8192}},
+		{name: "No Warmup", schedule: mainnet, slot: 250000000, expected: slotEpoch{Epoch: 578, SlotIndex: 304000, SlotsInEpoch: 432000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.expected.Slot = tt.slot
+			if got := tt.schedule.epochOf(tt.slot); got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHandleGetEpochProgress(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+		expectedEpochs uint64
+	}{
+		{name: "Across Warmup Boundary", mockClient: mockRPCClient{schedule: warmupSchedule}, queryParam: "?from=1000&to=20000", expectedStatus: http.StatusOK, expectedEpochs: 4},
+		{name: "Same Epoch", mockClient: mockRPCClient{schedule: warmupSchedule}, queryParam: "?from=8160&to=8200", expectedStatus: http.StatusOK, expectedEpochs: 0},
+		{name: "Reversed", mockClient: mockRPCClient{schedule: warmupSchedule}, queryParam: "?from=2&to=1", expectedStatus: http.StatusBadRequest},
+		{name: "Missing To", mockClient: mockRPCClient{schedule: warmupSchedule}, queryParam: "?from=2", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, queryParam: "?from=1&to=2", expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/epoch-progress"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetEpochProgress(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response epochProgressResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.EpochsElapsed != tt.expectedEpochs || response.SlotsElapsed != response.To.Slot-response.From.Slot {
+				t.Errorf("unexpected progress: %+v", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// shutdownTimeout is the default bound on how long in-flight requests may
+// take to finish once the servers are asked to stop
+const shutdownTimeout = 15 * time.Second
+
+// runServers serves on every server, over TLS for those with a TLS config,
+// until ctx is done or one of them fails, then shuts all of them down
+// together, letting in-flight requests finish within drainTimeout. It
+// returns the error that stopped the servers, if any.
+func runServers(ctx context.Context, drainTimeout time.Duration, servers ...*http.Server) error {
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		srv := srv
+		go func() {
+			if srv.TLSConfig != nil {
+				// The certificates come from the TLS config
+				errs <- srv.ListenAndServeTLS("", "")
+				return
+			}
+			errs <- srv.ListenAndServe()
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(len(servers))
+	for _, srv := range servers {
+		srv := srv
+		go func() {
+			defer wg.Done()
+			if shutdownErr := srv.Shutdown(shutdownCtx); shutdownErr != nil {
+				mu.Lock()
+				if err == nil {
+					err = shutdownErr
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// drainFirst returns a context that is done delay after ctx. As soon as ctx
+// is done ready is marked as draining, so that load balancers stop sending
+// new requests before the servers stop accepting them.
+func drainFirst(ctx context.Context, ready *readiness, delay time.Duration) (context.Context, context.CancelFunc) {
+	stopping, stop := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopping.Done():
+			return
+		}
+		ready.drain()
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-stopping.Done():
+		}
+		stop()
+	}()
+	return stopping, stop
+}
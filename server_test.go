@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// freeAddr returns a loopback address that is free to listen on
+func freeAddr(t *testing.T) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().String()
+}
+
+func TestRunServersShutsDownTogether(t *testing.T) {
+	api := &http.Server{Addr: freeAddr(t), Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	admin := &http.Server{Addr: freeAddr(t), Handler: http.HandlerFunc(handleMetrics)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runServers(ctx, shutdownTimeout, api, admin) }()
+
+	for _, addr := range []string{api.Addr, admin.Addr} {
+		addr := addr
+		waitFor(t, "listener on "+addr, func() bool {
+			resp, err := http.Get("http://" + addr + "/")
+			if err != nil {
+				return false
+			}
+			resp.Body.Close()
+			return true
+		})
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the servers to stop")
+	}
+
+	for _, addr := range []string{api.Addr, admin.Addr} {
+		if resp, err := http.Get("http://" + addr + "/"); err == nil {
+			resp.Body.Close()
+			t.Errorf("Expected %s to be closed", addr)
+		}
+	}
+}
+
+func TestRunServersStopsAllOnFailure(t *testing.T) {
+	api := &http.Server{Addr: freeAddr(t), Handler: http.NotFoundHandler()}
+	broken := &http.Server{Addr: "127.0.0.1:-1", Handler: http.NotFoundHandler()}
+
+	done := make(chan error, 1)
+	go func() { done <- runServers(context.Background(), shutdownTimeout, api, broken) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected the listen error to be returned")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the servers to stop")
+	}
+}
+
+func TestDrainFirst(t *testing.T) {
+	ready := &readiness{}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopping, stop := drainFirst(ctx, ready, 50*time.Millisecond)
+	defer stop()
+
+	if ready.draining.Load() {
+		t.Fatal("Expected ready before the signal")
+	}
+	cancel()
+	waitFor(t, "draining", ready.draining.Load)
+	if stopping.Err() != nil {
+		t.Error("Expected servers kept running during the shutdown delay")
+	}
+	select {
+	case <-stopping.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the shutdown delay to pass")
+	}
+}
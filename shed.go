@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Load shedding settings
+const (
+	defaultShedQueueDepth = 192
+	shedRetryAfter        = time.Second
+)
+
+// errOverloaded is returned for bulk work refused while the worker pool is
+// backed up
+var errOverloaded = errors.New("server overloaded, retry later")
+
+// queued returns the number of tasks waiting for a free worker
+func (p *workerPool) queued() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.waiting
+}
+
+// shedsBulk reports whether bulk work should be refused. Bulk requests are
+// shed once the queue is half as deep as the limit for every request, so
+// that interactive requests keep being served for longer.
+func (p *workerPool) shedsBulk() bool {
+	return p.shedDepth > 0 && p.queued() > p.shedDepth/2
+}
+
+// shed answers a request with 503 and a Retry-After header
+func shed(w http.ResponseWriter, priority poolPriority) {
+	metrics.addCounter("solana_client_shed_requests_total", "Requests rejected because the worker pool queue was too deep.", 1, "priority", priority.String())
+	w.Header().Set("Retry-After", strconv.Itoa(int(shedRetryAfter/time.Second)))
+	http.Error(w, errOverloaded.Error(), http.StatusServiceUnavailable)
+}
+
+// withLoadShedding answers requests with 503 and a Retry-After header while
+// more than depth tasks are waiting on the worker pool, failing fast rather
+// than queueing behind an overloaded upstream. Requests for the exempt paths,
+// such as health checks, are always served. A depth of 0 disables shedding.
+// Bulk requests are refused earlier, once the pool finds their priority.
+func withLoadShedding(next http.Handler, pool *workerPool, depth int, exempt ...string) http.Handler {
+	if depth <= 0 {
+		return next
+	}
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !exemptPaths[r.URL.Path] && pool.queued() > depth {
+			shed(w, priorityInteractive)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
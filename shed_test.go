@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithLoadShedding(t *testing.T) {
+	pool := newWorkerPool(1, 8)
+	defer pool.stop()
+
+	// Occupy the only worker and queue two more tasks behind it
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pool.submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	pool.submit(func() {})
+	pool.submit(func() {})
+	defer close(release)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	before := metrics.value("solana_client_shed_requests_total", "priority", "interactive")
+
+	tests := []struct {
+		name           string
+		depth          int
+		path           string
+		expectedStatus int
+	}{
+		{name: "Below Threshold", depth: 2, path: "/latest-block", expectedStatus: http.StatusOK},
+		{name: "Above Threshold", depth: 1, path: "/latest-block", expectedStatus: http.StatusServiceUnavailable},
+		{name: "Exempt Path", depth: 1, path: "/healthz/all", expectedStatus: http.StatusOK},
+		{name: "Disabled", depth: 0, path: "/latest-block", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+			withLoadShedding(ok, pool, tt.depth, "/healthz/all").ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if shed := rr.Header().Get("Retry-After") != ""; shed != (tt.expectedStatus == http.StatusServiceUnavailable) {
+				t.Errorf("Expected Retry-After only on shed requests, got %q", rr.Header().Get("Retry-After"))
+			}
+		})
+	}
+
+	if shed := metrics.value("solana_client_shed_requests_total", "priority", "interactive") - before; shed != 1 {
+		t.Errorf("Expected 1 shed request, got %v", shed)
+	}
+}
+
+func TestWorkerPoolShedsBulkWorkFirst(t *testing.T) {
+	pool := newWorkerPool(1, 8)
+	defer pool.stop()
+	pool.shedDepth = 4
+
+	// Occupy the only worker and queue three more tasks behind it, past half
+	// the shedding depth but within it
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pool.submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	for i := 0; i < 3; i++ {
+		pool.submit(func() {})
+	}
+
+	if err := pool.runAt(context.Background(), priorityBulk, []func(){func() {}}); !errors.Is(err, errOverloaded) {
+		t.Errorf("Expected bulk work to be refused, got %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- pool.run(context.Background(), []func(){func() {}}) }()
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("Expected interactive work to run, got %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Latest slot cache settings
+const (
+	defaultSlotCacheTTL     = time.Second
+	minSlotCacheTTL         = 100 * time.Millisecond
+	defaultSlotLagTolerance = 2
+	slotLagCheckInterval    = 10
+)
+
+// latestSlotCache serves the latest slot from memory for a short TTL. Every
+// slotLagCheckInterval cache hits it fetches a fresh slot anyway and compares
+// the two: when the cached slot trailed by more than the tolerated lag the TTL
+// is halved, and while it keeps up the TTL recovers towards its configured
+// value. This keeps freshness steady as block times vary.
+type latestSlotCache struct {
+	maxTTL    time.Duration
+	tolerance uint64
+	now       func() time.Time
+
+	mu         sync.Mutex
+	ttl        time.Duration
+	slot       uint64
+	commitment string
+	fetched    time.Time
+	hits       int
+	misses     int
+}
+
+func newLatestSlotCache(ttl time.Duration, tolerance uint64) *latestSlotCache {
+	c := &latestSlotCache{maxTTL: ttl, tolerance: tolerance, ttl: ttl, now: time.Now}
+	c.publishTTL()
+	return c
+}
+
+// get returns the cached slot while it is fresh, fetching it otherwise
+func (c *latestSlotCache) get(ctx context.Context, client SolanaRPCClient) (uint64, error) {
+	c.mu.Lock()
+	if !c.fetched.IsZero() && c.now().Sub(c.fetched) < c.ttl {
+		c.hits++
+		slot, commitment, check := c.slot, c.commitment, c.hits%slotLagCheckInterval == 0
+		c.mu.Unlock()
+		metrics.addCounter("solana_client_latest_slot_cache_requests_total", "Latest slot lookups by cache result.", 1, "result", "hit")
+
+		if check {
+			if fresh, freshCommitment, err := c.fetch(ctx, client); err == nil {
+				c.observeLag(slot, fresh)
+				slot, commitment = fresh, freshCommitment
+			}
+		}
+		noteCommitment(ctx, commitment)
+		return slot, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+	metrics.addCounter("solana_client_latest_slot_cache_requests_total", "Latest slot lookups by cache result.", 1, "result", "miss")
+
+	slot, commitment, err := c.fetch(ctx, client)
+	if err != nil {
+		return 0, err
+	}
+	noteCommitment(ctx, commitment)
+	return slot, nil
+}
+
+// fetch gets the latest slot upstream and stores it along with the
+// commitment it was fetched at, which cache hits report
+func (c *latestSlotCache) fetch(ctx context.Context, client SolanaRPCClient) (uint64, string, error) {
+	fetchCtx, noted := capturingCommitment(ctx)
+	slot, err := client.getLatestSlot(fetchCtx)
+	if err != nil {
+		return 0, "", err
+	}
+	commitment := noted()
+	c.store(slot, commitment)
+	return slot, commitment, nil
+}
+
+func (c *latestSlotCache) store(slot uint64, commitment string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if slot >= c.slot {
+		c.slot = slot
+		c.commitment = commitment
+		c.fetched = c.now()
+	}
+}
+
+// observeLag adapts the TTL to how far a cached slot trailed a fresh one
+func (c *latestSlotCache) observeLag(cached, fresh uint64) {
+	var lag uint64
+	if fresh > cached {
+		lag = fresh - cached
+	}
+
+	c.mu.Lock()
+	switch {
+	case lag > c.tolerance:
+		c.ttl /= 2
+		if c.ttl < minSlotCacheTTL {
+			c.ttl = minSlotCacheTTL
+		}
+	case lag <= c.tolerance/2:
+		c.ttl += c.ttl / 4
+		if c.ttl > c.maxTTL {
+			c.ttl = c.maxTTL
+		}
+	}
+	c.mu.Unlock()
+
+	metrics.setGauge("solana_client_latest_slot_cache_lag_slots", "Slots the cached latest slot trailed at the last check.", float64(lag))
+	c.publishTTL()
+}
+
+// effectiveTTL returns the TTL currently applied
+func (c *latestSlotCache) effectiveTTL() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ttl
+}
+
+// latestSlotCacheStats is the latest slot cache part of /cache/stats
+type latestSlotCacheStats struct {
+	Slot       uint64  `json:"slot"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+	MaxTTL     float64 `json:"max_ttl_seconds"`
+	Hits       int     `json:"hits"`
+	Misses     int     `json:"misses"`
+}
+
+func (c *latestSlotCache) stats() latestSlotCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return latestSlotCacheStats{Slot: c.slot, TTLSeconds: c.ttl.Seconds(), MaxTTL: c.maxTTL.Seconds(), Hits: c.hits, Misses: c.misses}
+}
+
+func (c *latestSlotCache) publishTTL() {
+	metrics.setGauge("solana_client_latest_slot_cache_ttl_seconds", "Effective TTL of the latest slot cache.", c.effectiveTTL().Seconds())
+}
+
+// wrap returns a client whose getLatestSlot is served through the cache
+func (c *latestSlotCache) wrap(client SolanaRPCClient) SolanaRPCClient {
+	return &slotCachingClient{SolanaRPCClient: client, cache: c}
+}
+
+// slotCachingClient serves getLatestSlot from a shared latestSlotCache
+type slotCachingClient struct {
+	SolanaRPCClient
+	cache *latestSlotCache
+}
+
+// Requests that choose their own commitment bypass the cache, which holds
+// the slot at the configured one
+func (c *slotCachingClient) getLatestSlot(ctx context.Context) (uint64, error) {
+	if requestCommitment(ctx) != "" {
+		return c.SolanaRPCClient.getLatestSlot(ctx)
+	}
+	return c.cache.get(ctx, c.SolanaRPCClient)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// slotSequenceClient returns an increasing slot on every call
+type slotSequenceClient struct {
+	mockRPCClient
+	calls int
+	step  uint64
+}
+
+func (c *slotSequenceClient) getLatestSlot(ctx context.Context) (uint64, error) {
+	c.calls++
+	return 1000 + uint64(c.calls)*c.step, nil
+}
+
+func TestLatestSlotCacheServesWithinTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	upstream := &slotSequenceClient{step: 1}
+	cache := newLatestSlotCache(time.Second, 2)
+	cache.now = func() time.Time { return now }
+	client := cache.wrap(upstream)
+
+	first, _ := client.getLatestSlot(context.Background())
+	second, _ := client.getLatestSlot(context.Background())
+	if first != second || upstream.calls != 1 {
+		t.Errorf("Expected cached slot within TTL, got %d then %d after %d calls", first, second, upstream.calls)
+	}
+
+	now = now.Add(time.Second)
+	if third, _ := client.getLatestSlot(context.Background()); third == first || upstream.calls != 2 {
+		t.Errorf("Expected refetch after TTL, got %d after %d calls", third, upstream.calls)
+	}
+}
+
+func TestLatestSlotCacheAdaptsTTL(t *testing.T) {
+	now := time.Unix(0, 0)
+	upstream := &slotSequenceClient{step: 5}
+	cache := newLatestSlotCache(time.Second, 2)
+	cache.now = func() time.Time { return now }
+	client := cache.wrap(upstream)
+
+	// Each lag check sees the upstream 5 slots ahead, beyond the tolerance of 2
+	client.getLatestSlot(context.Background())
+	for i := 0; i < slotLagCheckInterval; i++ {
+		client.getLatestSlot(context.Background())
+	}
+	if ttl := cache.effectiveTTL(); ttl != 500*time.Millisecond {
+		t.Errorf("Expected TTL halved to 500ms, got %v", ttl)
+	}
+	if gauge := metrics.value("solana_client_latest_slot_cache_ttl_seconds"); gauge != 0.5 {
+		t.Errorf("Expected TTL gauge of 0.5, got %v", gauge)
+	}
+
+	for i := 0; i < 10*slotLagCheckInterval; i++ {
+		client.getLatestSlot(context.Background())
+	}
+	if ttl := cache.effectiveTTL(); ttl != minSlotCacheTTL {
+		t.Errorf("Expected TTL floored at %v, got %v", minSlotCacheTTL, ttl)
+	}
+
+	// Once the cache keeps up again the TTL recovers to its configured value
+	upstream.step = 0
+	for i := 0; i < 20*slotLagCheckInterval; i++ {
+		client.getLatestSlot(context.Background())
+	}
+	if ttl := cache.effectiveTTL(); ttl != time.Second {
+		t.Errorf("Expected TTL to recover to 1s, got %v", ttl)
+	}
+}
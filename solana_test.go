@@ -1,35 +1,405 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 )
 
+// mockOptionsMu guards the options mocks record, as fan-out handlers call
+// them concurrently
+var mockOptionsMu sync.Mutex
+
 // Mock RPC client for testing
 type mockRPCClient struct {
-	latestSlot   uint64
-	blockDetails json.RawMessage
-	shouldFail   bool
-	errorMessage string
+	latestSlot    uint64
+	blockDetails  json.RawMessage
+	blocks        map[uint64]json.RawMessage
+	blockErrs     map[uint64]error
+	blockOptions  BlockOptions
+	transactions  map[string]json.RawMessage
+	txOptions     TransactionOptions
+	acctOptions   AccountOptions
+	accountInfo   *AccountInfo
+	accountErr    error
+	accounts      map[string]*AccountInfo
+	programAccts  []ProgramAccount
+	rewards       map[string]*InflationReward
+	rewardEpochs  []uint64
+	tokenAccts    []TokenAccount
+	tokenBals     map[string]*TokenBalance
+	rentMinimum   uint64
+	rentCalls     int
+	blockhash     *LatestBlockhash
+	messageFees   map[string]uint64
+	validHashes   map[string]bool
+	priorityFees  []PrioritizationFee
+	epochInfo     *EpochInfo
+	schedule      *EpochSchedule
+	inflation     *InflationRate
+	supply        *Supply
+	supplyLists   []bool
+	largest       []LargestAccount
+	largestFilter string
+	perfSamples   []PerformanceSample
+	simulation    *SimulationResult
+	sentTxs       []string
+	sigStatuses   map[string]*SignatureStatus
+	skipPreflight bool
+	signatures    []SignatureInfo
+	sigOptions    SignatureOptions
+	voteAccounts  *VoteAccounts
+	clusterNodes  []ClusterNode
+	blockTimes    map[uint64]int64
+	blockTimeErr  map[uint64]error
+	shouldFail    bool
+	errorMessage  string
 }
 
-func (m *mockRPCClient) getLatestSlot() (uint64, error) {
+func (m *mockRPCClient) getLatestSlot(ctx context.Context) (uint64, error) {
 	if m.shouldFail {
 		return 0, fmt.Errorf(m.errorMessage)
 	}
 	return m.latestSlot, nil
 }
 
-func (m *mockRPCClient) getBlockDetails(slot uint64) (json.RawMessage, error) {
+func (m *mockRPCClient) getBlockDetails(ctx context.Context, slot uint64, opts BlockOptions) (json.RawMessage, error) {
 	if m.shouldFail {
 		return nil, fmt.Errorf(m.errorMessage)
 	}
+	mockOptionsMu.Lock()
+	m.blockOptions = opts
+	mockOptionsMu.Unlock()
+	if err, ok := m.blockErrs[slot]; ok {
+		return nil, err
+	}
+	if m.blocks != nil {
+		block, ok := m.blocks[slot]
+		if !ok {
+			return nil, &upstreamError{Code: rpcSlotSkipped, Message: fmt.Sprintf("Slot %d was skipped", slot), Status: http.StatusInternalServerError}
+		}
+		return block, nil
+	}
 	return m.blockDetails, nil
 }
 
+func (m *mockRPCClient) getBlocks(ctx context.Context, slots []uint64, opts BlockOptions) ([]json.RawMessage, []error, error) {
+	if m.shouldFail {
+		return nil, nil, fmt.Errorf(m.errorMessage)
+	}
+	blocks := make([]json.RawMessage, len(slots))
+	errs := make([]error, len(slots))
+	for i, slot := range slots {
+		blocks[i], errs[i] = m.getBlockDetails(ctx, slot, opts)
+	}
+	return blocks, errs, nil
+}
+
+func (m *mockRPCClient) getConfirmedBlocks(ctx context.Context, start, end uint64) ([]uint64, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	var slots []uint64
+	for slot := start; slot <= end; slot++ {
+		if _, ok := m.blocks[slot]; ok {
+			slots = append(slots, slot)
+		}
+	}
+	return slots, nil
+}
+
+func (m *mockRPCClient) getTransactions(ctx context.Context, signatures []string, opts TransactionOptions) ([]json.RawMessage, []error, error) {
+	if m.shouldFail {
+		return nil, nil, fmt.Errorf(m.errorMessage)
+	}
+	transactions := make([]json.RawMessage, len(signatures))
+	errs := make([]error, len(signatures))
+	for i, signature := range signatures {
+		transactions[i], errs[i] = m.getTransaction(ctx, signature, opts)
+	}
+	return transactions, errs, nil
+}
+
+func (m *mockRPCClient) getTransaction(ctx context.Context, signature string, opts TransactionOptions) (json.RawMessage, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	mockOptionsMu.Lock()
+	m.txOptions = opts
+	mockOptionsMu.Unlock()
+	return m.transactions[signature], nil
+}
+
+func (m *mockRPCClient) getAccountInfo(ctx context.Context, address string, opts AccountOptions) (*AccountInfo, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	mockOptionsMu.Lock()
+	m.acctOptions = opts
+	mockOptionsMu.Unlock()
+	return m.accountInfo, nil
+}
+
+func (m *mockRPCClient) getBalance(ctx context.Context, address string) (uint64, uint64, error) {
+	if m.shouldFail {
+		return 0, 0, fmt.Errorf(m.errorMessage)
+	}
+	if m.accountInfo == nil {
+		return 0, m.latestSlot, nil
+	}
+	return m.accountInfo.Lamports, m.latestSlot, nil
+}
+
+func (m *mockRPCClient) getAccountInfoAt(ctx context.Context, address string, slot uint64, opts AccountOptions) (*AccountInfo, uint64, error) {
+	if m.shouldFail {
+		return nil, 0, fmt.Errorf(m.errorMessage)
+	}
+	mockOptionsMu.Lock()
+	m.acctOptions = opts
+	mockOptionsMu.Unlock()
+	if m.accountErr != nil {
+		return nil, 0, m.accountErr
+	}
+	contextSlot := m.latestSlot
+	if contextSlot < slot {
+		contextSlot = slot
+	}
+	return m.accountInfo, contextSlot, nil
+}
+
+func (m *mockRPCClient) getMultipleAccounts(ctx context.Context, addresses []string) ([]*AccountInfo, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	accounts := make([]*AccountInfo, len(addresses))
+	for i, address := range addresses {
+		accounts[i] = m.accounts[address]
+	}
+	return accounts, nil
+}
+
+// getProgramAccounts applies filters as built by parseProgramFilters to the
+// program accounts
+func (m *mockRPCClient) getProgramAccounts(ctx context.Context, program string, filters []interface{}, opts AccountOptions) ([]ProgramAccount, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	mockOptionsMu.Lock()
+	m.acctOptions = opts
+	mockOptionsMu.Unlock()
+	var matched []ProgramAccount
+	for _, account := range m.programAccts {
+		data, err := account.Account.rawData()
+		if err != nil || account.Account.Owner != program {
+			continue
+		}
+		keep := true
+		for _, filter := range filters {
+			filter := filter.(map[string]interface{})
+			if size, ok := filter["dataSize"].(uint64); ok {
+				keep = keep && uint64(len(data)) == size
+			}
+			if memcmp, ok := filter["memcmp"].(map[string]interface{}); ok {
+				offset := int(memcmp["offset"].(uint64))
+				want, _ := base58Decode(memcmp["bytes"].(string))
+				keep = keep && offset+len(want) <= len(data) && bytes.Equal(data[offset:offset+len(want)], want)
+			}
+		}
+		if keep {
+			matched = append(matched, account)
+		}
+	}
+	return matched, nil
+}
+
+func (m *mockRPCClient) getMinimumBalanceForRentExemption(ctx context.Context, dataSize uint64) (uint64, error) {
+	m.rentCalls++
+	if m.shouldFail {
+		return 0, fmt.Errorf(m.errorMessage)
+	}
+	return m.rentMinimum, nil
+}
+
+func (m *mockRPCClient) getLatestBlockhash(ctx context.Context) (*LatestBlockhash, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	return m.blockhash, nil
+}
+
+func (m *mockRPCClient) isBlockhashValid(ctx context.Context, blockhash string) (bool, uint64, error) {
+	if m.shouldFail {
+		return false, 0, fmt.Errorf(m.errorMessage)
+	}
+	return m.validHashes[blockhash], m.latestSlot, nil
+}
+
+func (m *mockRPCClient) getFeeForMessage(ctx context.Context, message string) (*uint64, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	if fee, ok := m.messageFees[message]; ok {
+		return &fee, nil
+	}
+	return nil, nil
+}
+
+func (m *mockRPCClient) getRecentPrioritizationFees(ctx context.Context, accounts []string) ([]PrioritizationFee, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	return m.priorityFees, nil
+}
+
+func (m *mockRPCClient) getEpochInfo(ctx context.Context) (*EpochInfo, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	return m.epochInfo, nil
+}
+
+func (m *mockRPCClient) getEpochSchedule(ctx context.Context) (*EpochSchedule, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	return m.schedule, nil
+}
+
+func (m *mockRPCClient) getInflationRate(ctx context.Context) (*InflationRate, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	return m.inflation, nil
+}
+
+func (m *mockRPCClient) getInflationReward(ctx context.Context, addresses []string, epoch uint64) ([]*InflationReward, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	m.rewardEpochs = append(m.rewardEpochs, epoch)
+	rewards := make([]*InflationReward, len(addresses))
+	for i, address := range addresses {
+		rewards[i] = m.rewards[address]
+	}
+	return rewards, nil
+}
+
+func (m *mockRPCClient) getSupply(ctx context.Context, withAccounts bool) (*Supply, uint64, error) {
+	if m.shouldFail {
+		return nil, 0, fmt.Errorf(m.errorMessage)
+	}
+	m.supplyLists = append(m.supplyLists, withAccounts)
+	return m.supply, m.latestSlot, nil
+}
+
+func (m *mockRPCClient) getLargestAccounts(ctx context.Context, filter string) ([]LargestAccount, uint64, error) {
+	if m.shouldFail {
+		return nil, 0, fmt.Errorf(m.errorMessage)
+	}
+	m.largestFilter = filter
+	return m.largest, m.latestSlot, nil
+}
+
+func (m *mockRPCClient) getRecentPerformanceSamples(ctx context.Context, limit int) ([]PerformanceSample, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	return m.perfSamples, nil
+}
+
+func (m *mockRPCClient) simulateTransaction(ctx context.Context, transaction string) (*SimulationResult, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	return m.simulation, nil
+}
+
+func (m *mockRPCClient) sendTransaction(ctx context.Context, transaction string, skipPreflight bool) (string, error) {
+	if m.shouldFail {
+		return "", fmt.Errorf(m.errorMessage)
+	}
+	m.sentTxs = append(m.sentTxs, transaction)
+	m.skipPreflight = skipPreflight
+	return "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW", nil
+}
+
+func (m *mockRPCClient) getVoteAccounts(ctx context.Context) (*VoteAccounts, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	return m.voteAccounts, nil
+}
+
+func (m *mockRPCClient) getClusterNodes(ctx context.Context) ([]ClusterNode, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	return m.clusterNodes, nil
+}
+
+func (m *mockRPCClient) getTokenAccountsByOwner(ctx context.Context, owner, program, mint string) ([]TokenAccount, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	var accounts []TokenAccount
+	for _, account := range m.tokenAccts {
+		if account.Owner == owner && (account.TokenProgram == program || account.Mint == mint) {
+			accounts = append(accounts, account)
+		}
+	}
+	return accounts, nil
+}
+
+func (m *mockRPCClient) getTokenAccountBalance(ctx context.Context, address string) (*TokenBalance, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	if balance, ok := m.tokenBals[address]; ok {
+		return balance, nil
+	}
+	return nil, &upstreamError{Code: -32602, Message: "Invalid param: could not find account", Status: http.StatusInternalServerError}
+}
+
+func (m *mockRPCClient) getSignatureStatuses(ctx context.Context, signatures []string, searchHistory bool) ([]*SignatureStatus, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	statuses := make([]*SignatureStatus, len(signatures))
+	for i, signature := range signatures {
+		statuses[i] = m.sigStatuses[signature]
+	}
+	return statuses, nil
+}
+
+func (m *mockRPCClient) getBlockTime(ctx context.Context, slot uint64) (*int64, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	if err, ok := m.blockTimeErr[slot]; ok {
+		return nil, err
+	}
+	if blockTime, ok := m.blockTimes[slot]; ok {
+		return &blockTime, nil
+	}
+	return nil, nil
+}
+
+func (m *mockRPCClient) getSignaturesForAddress(ctx context.Context, address string, opts SignatureOptions) ([]SignatureInfo, error) {
+	if m.shouldFail {
+		return nil, fmt.Errorf(m.errorMessage)
+	}
+	m.sigOptions = opts
+	if opts.Limit > 0 && len(m.signatures) > opts.Limit {
+		return m.signatures[:opts.Limit], nil
+	}
+	return m.signatures, nil
+}
+
 func TestHandleGetLatestSlot(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -139,7 +509,7 @@ func TestHandleGetBlockDetails(t *testing.T) {
 			rr := httptest.NewRecorder()
 
 			// Call the handler
-			handler := handleGetBlockDetails(&tt.mockClient)
+			handler := handleGetBlockDetails(&tt.mockClient, 0, "")
 			handler.ServeHTTP(rr, req)
 
 			// Check the status code
@@ -205,7 +575,7 @@ func TestSendRequest(t *testing.T) {
 	client := newRPCClient(server.URL)
 
 	// Send request
-	response, err := client.sendRequest("testMethod", []interface{}{1, "test"})
+	response, err := client.sendRequest(context.Background(), "testMethod", []interface{}{1, "test"})
 
 	// Check for errors
 	if err != nil {
@@ -226,3 +596,84 @@ func TestSendRequest(t *testing.T) {
 		}
 	}
 }
+
+// TestGetBlockDetailsVersionedTransactions checks that blocks containing v0
+// transactions are only rejected when legacy-only behavior is requested
+func TestGetBlockDetailsVersionedTransactions(t *testing.T) {
+	v0Block := `{"blockhash":"5mHZ","parentSlot":99,"transactions":[{"meta":{"fee":5000},"transaction":{"message":{"accountKeys":["abc123"]}},"version":0}]}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req RPCRequest
+		json.NewDecoder(r.Body).Decode(&req)
+
+		// Mimic a node serving a block that contains a v0 transaction
+		if len(req.Params) < 2 {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","error":{"code":-32015,"message":"Transaction version (0) is not supported by the requesting client. Please try the request again with the following configuration parameter: \"maxSupportedTransactionVersion\": 0"},"id":%d}`, req.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%s,"id":%d}`, v0Block, req.ID)
+	}))
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+
+	tests := []struct {
+		name           string
+		queryParam     string
+		expectedStatus int
+	}{
+		{name: "Default Version", queryParam: "?block=100", expectedStatus: http.StatusOK},
+		{name: "Explicit Version", queryParam: "?block=100&maxTxVersion=0", expectedStatus: http.StatusOK},
+		{name: "Legacy Only", queryParam: "?block=100&maxTxVersion=legacy", expectedStatus: http.StatusInternalServerError},
+		{name: "Invalid Version", queryParam: "?block=100&maxTxVersion=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/block-details"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetBlockDetails(client, 0, "").ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus == http.StatusOK && rr.Body.String() != v0Block {
+				t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), v0Block)
+			}
+		})
+	}
+}
+
+// TestSendRequestMissingResult checks that a response with neither result nor
+// error is reported as malformed
+func TestSendRequestMissingResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1}`))
+	}))
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+
+	_, err := client.sendRequest(context.Background(), "getSlot", nil)
+	if err == nil || err.Error() != "malformed RPC response: missing result" {
+		t.Errorf("Expected malformed response error, got %v", err)
+	}
+}
+
+// TestSendRequestNullResult checks that an explicit null result is accepted
+func TestSendRequestNullResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"2.0","result":null,"id":1}`))
+	}))
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+
+	response, err := client.sendRequest(context.Background(), "getBlockTime", []interface{}{1})
+	if err != nil {
+		t.Fatalf("sendRequest returned error: %v", err)
+	}
+	if string(response.Result) != "null" {
+		t.Errorf("Expected null result, got %s", response.Result)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// stakeProgramID owns every stake account
+const stakeProgramID = "Stake11111111111111111111111111111111111111"
+
+// StakeState discriminants of the stake program's account layout
+const (
+	stakeStateUninitialized = iota
+	stakeStateInitialized
+	stakeStateStake
+	stakeStateRewardsPool
+)
+
+// Stake account layout, for getProgramAccounts filters: the u32 state, then
+// the rent exempt reserve, staker and withdrawer of the meta, then the
+// lockup and the delegation's voter
+const (
+	stakeAccountSize      = 200
+	stakeStakerOffset     = 12
+	stakeWithdrawerOffset = 44
+	stakeVoterOffset      = 124
+)
+
+// inflationRewardBatch bounds the addresses of one getInflationReward call
+const inflationRewardBatch = 100
+
+// StakeAuthorized holds the keys allowed to manage a stake account
+type StakeAuthorized struct {
+	Staker     string `json:"staker"`
+	Withdrawer string `json:"withdrawer"`
+}
+
+// StakeLockup restricts withdrawals until a time or epoch unless the
+// custodian signs
+type StakeLockup struct {
+	UnixTimestamp int64  `json:"unixTimestamp"`
+	Epoch         uint64 `json:"epoch"`
+	Custodian     string `json:"custodian"`
+}
+
+// StakeMeta is common to initialized and delegated stake accounts
+type StakeMeta struct {
+	RentExemptReserve uint64          `json:"rentExemptReserve"`
+	Authorized        StakeAuthorized `json:"authorized"`
+	Lockup            StakeLockup     `json:"lockup"`
+}
+
+// StakeDelegation describes the stake delegated to a vote account. A
+// deactivation epoch of the maximum u64 value means the stake is not
+// deactivating.
+type StakeDelegation struct {
+	Voter              string  `json:"voter"`
+	Stake              uint64  `json:"stake"`
+	ActivationEpoch    uint64  `json:"activationEpoch"`
+	DeactivationEpoch  uint64  `json:"deactivationEpoch"`
+	WarmupCooldownRate float64 `json:"warmupCooldownRate"`
+	CreditsObserved    uint64  `json:"creditsObserved"`
+}
+
+// StakeAccount is a decoded stake program account
+type StakeAccount struct {
+	State string           `json:"state"`
+	Meta  *StakeMeta       `json:"meta,omitempty"`
+	Stake *StakeDelegation `json:"stake,omitempty"`
+	Flags *uint8           `json:"flags,omitempty"`
+}
+
+func readPubkey(r *borshReader) (string, error) {
+	b, err := r.read(32)
+	if err != nil {
+		return "", err
+	}
+	return base58Encode(b), nil
+}
+
+func readU64(r *borshReader) (uint64, error) {
+	b, err := r.read(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+func readU32(r *borshReader) (uint32, error) {
+	b, err := r.read(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func readStakeMeta(r *borshReader) (*StakeMeta, error) {
+	var meta StakeMeta
+	var err error
+	if meta.RentExemptReserve, err = readU64(r); err != nil {
+		return nil, err
+	}
+	if meta.Authorized.Staker, err = readPubkey(r); err != nil {
+		return nil, err
+	}
+	if meta.Authorized.Withdrawer, err = readPubkey(r); err != nil {
+		return nil, err
+	}
+	timestamp, err := readU64(r)
+	if err != nil {
+		return nil, err
+	}
+	meta.Lockup.UnixTimestamp = int64(timestamp)
+	if meta.Lockup.Epoch, err = readU64(r); err != nil {
+		return nil, err
+	}
+	if meta.Lockup.Custodian, err = readPubkey(r); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func readStakeDelegation(r *borshReader) (*StakeDelegation, error) {
+	var stake StakeDelegation
+	var err error
+	if stake.Voter, err = readPubkey(r); err != nil {
+		return nil, err
+	}
+	if stake.Stake, err = readU64(r); err != nil {
+		return nil, err
+	}
+	if stake.ActivationEpoch, err = readU64(r); err != nil {
+		return nil, err
+	}
+	if stake.DeactivationEpoch, err = readU64(r); err != nil {
+		return nil, err
+	}
+	rate, err := readU64(r)
+	if err != nil {
+		return nil, err
+	}
+	stake.WarmupCooldownRate = math.Float64frombits(rate)
+	if stake.CreditsObserved, err = readU64(r); err != nil {
+		return nil, err
+	}
+	return &stake, nil
+}
+
+// decodeStakeAccount decodes the stake program's StakeStateV2 layout
+func decodeStakeAccount(account *AccountInfo) (string, interface{}, error) {
+	if account.Owner != stakeProgramID {
+		return "", nil, fmt.Errorf("account is not owned by the stake program")
+	}
+
+	data, err := account.rawData()
+	if err != nil {
+		return "", nil, err
+	}
+
+	r := &borshReader{data: data}
+	tag, err := readU32(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode stake account: %w", err)
+	}
+
+	var stake StakeAccount
+	switch tag {
+	case stakeStateUninitialized:
+		stake.State = "uninitialized"
+	case stakeStateInitialized:
+		stake.State = "initialized"
+		if stake.Meta, err = readStakeMeta(r); err != nil {
+			return "", nil, fmt.Errorf("failed to decode stake account: %w", err)
+		}
+	case stakeStateStake:
+		stake.State = "delegated"
+		if stake.Meta, err = readStakeMeta(r); err != nil {
+			return "", nil, fmt.Errorf("failed to decode stake account: %w", err)
+		}
+		if stake.Stake, err = readStakeDelegation(r); err != nil {
+			return "", nil, fmt.Errorf("failed to decode stake account: %w", err)
+		}
+		// Accounts written before stake flags existed end here
+		if flags, err := r.read(1); err == nil {
+			value := flags[0]
+			stake.Flags = &value
+		}
+	case stakeStateRewardsPool:
+		stake.State = "rewards_pool"
+	default:
+		return "", nil, fmt.Errorf("unknown stake account state %d", tag)
+	}
+
+	return "stake", stake, nil
+}
+
+// stakeReward is the inflation reward a stake account earned in an epoch
+type stakeReward struct {
+	Epoch       uint64 `json:"epoch"`
+	Amount      uint64 `json:"amount"`
+	PostBalance uint64 `json:"post_balance"`
+	Commission  *uint8 `json:"commission"`
+}
+
+// stakeAccountEntry is a stake account listed by /stakes. Status is active,
+// activating, deactivating or inactive for delegated accounts, and empty
+// for the others.
+type stakeAccountEntry struct {
+	Address           string       `json:"address"`
+	Lamports          uint64       `json:"lamports"`
+	State             string       `json:"state"`
+	Status            string       `json:"status,omitempty"`
+	Staker            string       `json:"staker,omitempty"`
+	Withdrawer        string       `json:"withdrawer,omitempty"`
+	RentExemptReserve uint64       `json:"rent_exempt_reserve"`
+	Lockup            *StakeLockup `json:"lockup,omitempty"`
+	Voter             string       `json:"voter,omitempty"`
+	DelegatedStake    uint64       `json:"delegated_stake"`
+	ActivationEpoch   *uint64      `json:"activation_epoch,omitempty"`
+	DeactivationEpoch *uint64      `json:"deactivation_epoch,omitempty"`
+	Reward            *stakeReward `json:"reward,omitempty"`
+}
+
+// stakesResponse is the response of /stakes
+type stakesResponse struct {
+	Owner          string              `json:"owner"`
+	Epoch          uint64              `json:"epoch"`
+	TotalLamports  uint64              `json:"total_lamports"`
+	TotalDelegated uint64              `json:"total_delegated"`
+	Accounts       []stakeAccountEntry `json:"accounts"`
+}
+
+// delegationStatus tells where a delegation stands in epoch. Stake warms up
+// and cools down over the epochs after it is activated or deactivated, as
+// far as the cluster's rate allows; these are reported as activating or
+// deactivating only in their first epoch, which needs no stake history.
+func delegationStatus(delegation *StakeDelegation, epoch uint64) string {
+	switch {
+	case delegation.DeactivationEpoch < epoch:
+		return "inactive"
+	case delegation.DeactivationEpoch == epoch:
+		return "deactivating"
+	case delegation.ActivationEpoch == math.MaxUint64:
+		// Stake delegated at genesis is active from the start
+		return "active"
+	case delegation.ActivationEpoch >= epoch:
+		return "activating"
+	}
+	return "active"
+}
+
+// stakeEntry flattens a decoded stake account
+func stakeEntry(account ProgramAccount, epoch uint64) (stakeAccountEntry, error) {
+	entry := stakeAccountEntry{Address: account.Pubkey, Lamports: account.Account.Lamports}
+	_, decoded, err := decodeStakeAccount(&account.Account)
+	if err != nil {
+		return entry, fmt.Errorf("stake account %s: %w", account.Pubkey, err)
+	}
+	stake := decoded.(StakeAccount)
+	entry.State = stake.State
+	if stake.Meta != nil {
+		entry.Staker = stake.Meta.Authorized.Staker
+		entry.Withdrawer = stake.Meta.Authorized.Withdrawer
+		entry.RentExemptReserve = stake.Meta.RentExemptReserve
+		if stake.Meta.Lockup.UnixTimestamp != 0 || stake.Meta.Lockup.Epoch != 0 {
+			lockup := stake.Meta.Lockup
+			entry.Lockup = &lockup
+		}
+	}
+	if delegation := stake.Stake; delegation != nil {
+		entry.Status = delegationStatus(delegation, epoch)
+		entry.Voter = delegation.Voter
+		entry.DelegatedStake = delegation.Stake
+		activation := delegation.ActivationEpoch
+		entry.ActivationEpoch = &activation
+		if delegation.DeactivationEpoch != math.MaxUint64 {
+			deactivation := delegation.DeactivationEpoch
+			entry.DeactivationEpoch = &deactivation
+		}
+	}
+	return entry, nil
+}
+
+func handleGetStakes(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner := r.URL.Query().Get("owner")
+		if owner == "" {
+			http.Error(w, "owner parameter is required", http.StatusBadRequest)
+			return
+		}
+		if key, err := base58Decode(owner); err != nil || len(key) != 32 {
+			http.Error(w, "owner must be a base58 public key", http.StatusBadRequest)
+			return
+		}
+		voter := r.URL.Query().Get("voter")
+		if voter != "" {
+			if key, err := base58Decode(voter); err != nil || len(key) != 32 {
+				http.Error(w, "voter must be a base58 public key", http.StatusBadRequest)
+				return
+			}
+		}
+		withRewards := r.URL.Query().Get("rewards") != "false"
+
+		info, err := client.getEpochInfo(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		// An owner may be the staker, the withdrawer or both of an account,
+		// which takes one query each
+		var accounts []ProgramAccount
+		seen := make(map[string]bool)
+		for _, offset := range []uint64{stakeStakerOffset, stakeWithdrawerOffset} {
+			filters := []interface{}{
+				map[string]interface{}{"dataSize": uint64(stakeAccountSize)},
+				map[string]interface{}{"memcmp": map[string]interface{}{"offset": offset, "bytes": owner}},
+			}
+			if voter != "" {
+				filters = append(filters, map[string]interface{}{"memcmp": map[string]interface{}{"offset": uint64(stakeVoterOffset), "bytes": voter}})
+			}
+			found, err := client.getProgramAccounts(r.Context(), stakeProgramID, filters, AccountOptions{})
+			if err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+			for _, account := range found {
+				if !seen[account.Pubkey] {
+					seen[account.Pubkey] = true
+					accounts = append(accounts, account)
+				}
+			}
+		}
+
+		response := stakesResponse{Owner: owner, Epoch: info.Epoch, Accounts: make([]stakeAccountEntry, 0, len(accounts))}
+		var delegated []string
+		for _, account := range accounts {
+			entry, err := stakeEntry(account, info.Epoch)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			response.TotalLamports += entry.Lamports
+			response.TotalDelegated += entry.DelegatedStake
+			if entry.Voter != "" {
+				delegated = append(delegated, entry.Address)
+			}
+			response.Accounts = append(response.Accounts, entry)
+		}
+		sort.Slice(response.Accounts, func(i, j int) bool {
+			a, b := response.Accounts[i], response.Accounts[j]
+			if a.DelegatedStake != b.DelegatedStake {
+				return a.DelegatedStake > b.DelegatedStake
+			}
+			return a.Address < b.Address
+		})
+
+		// Rewards of the last completed epoch
+		if withRewards && info.Epoch > 0 && len(delegated) > 0 {
+			rewards := make(map[string]*stakeReward, len(delegated))
+			for start := 0; start < len(delegated); start += inflationRewardBatch {
+				end := start + inflationRewardBatch
+				if end > len(delegated) {
+					end = len(delegated)
+				}
+				batch, err := client.getInflationReward(r.Context(), delegated[start:end], info.Epoch-1)
+				if err != nil {
+					http.Error(w, err.Error(), upstreamStatus(err))
+					return
+				}
+				for i, reward := range batch {
+					if reward != nil {
+						rewards[delegated[start+i]] = &stakeReward{Epoch: reward.Epoch, Amount: reward.Amount, PostBalance: reward.PostBalance, Commission: reward.Commission}
+					}
+				}
+			}
+			for i := range response.Accounts {
+				response.Accounts[i].Reward = rewards[response.Accounts[i].Address]
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// encodeStakeMeta builds a Meta with distinct key bytes for each role
+func encodeStakeMeta() []byte {
+	data := binary.LittleEndian.AppendUint64(nil, 2282880)
+	data = append(data, bytes.Repeat([]byte{1}, 32)...)
+	data = append(data, bytes.Repeat([]byte{2}, 32)...)
+	data = binary.LittleEndian.AppendUint64(data, 0)
+	data = binary.LittleEndian.AppendUint64(data, 0)
+	return append(data, make([]byte, 32)...)
+}
+
+func encodeDelegatedStake() []byte {
+	data := binary.LittleEndian.AppendUint32(nil, stakeStateStake)
+	data = append(data, encodeStakeMeta()...)
+	data = append(data, bytes.Repeat([]byte{3}, 32)...)
+	data = binary.LittleEndian.AppendUint64(data, 5000000000)
+	data = binary.LittleEndian.AppendUint64(data, 420)
+	data = binary.LittleEndian.AppendUint64(data, math.MaxUint64)
+	data = binary.LittleEndian.AppendUint64(data, math.Float64bits(0.25))
+	data = binary.LittleEndian.AppendUint64(data, 123456)
+	data = append(data, 0)
+
+	// Stake accounts are allocated 200 bytes, leaving trailing padding
+	return append(data, make([]byte, 200-len(data))...)
+}
+
+func TestDecodeStakeAccount(t *testing.T) {
+	delegated := encodeDelegatedStake()
+	if len(delegated) != 200 {
+		t.Fatalf("Expected a 200 byte stake account fixture, got %d", len(delegated))
+	}
+
+	_, decoded, err := decodeStakeAccount(testAccount(stakeProgramID, delegated))
+	if err != nil {
+		t.Fatalf("decodeStakeAccount returned error: %v", err)
+	}
+
+	stake := decoded.(StakeAccount)
+	if stake.State != "delegated" || stake.Meta == nil || stake.Stake == nil {
+		t.Fatalf("unexpected stake account: %+v", stake)
+	}
+	if stake.Meta.Authorized.Staker != base58Encode(bytes.Repeat([]byte{1}, 32)) {
+		t.Errorf("unexpected staker: %s", stake.Meta.Authorized.Staker)
+	}
+	if stake.Stake.Voter != base58Encode(bytes.Repeat([]byte{3}, 32)) {
+		t.Errorf("unexpected voter: %s", stake.Stake.Voter)
+	}
+	if stake.Stake.Stake != 5000000000 || stake.Stake.ActivationEpoch != 420 || stake.Stake.DeactivationEpoch != math.MaxUint64 {
+		t.Errorf("unexpected delegation: %+v", stake.Stake)
+	}
+	if stake.Stake.CreditsObserved != 123456 || stake.Stake.WarmupCooldownRate != 0.25 {
+		t.Errorf("unexpected delegation: %+v", stake.Stake)
+	}
+
+	initialized := append(binary.LittleEndian.AppendUint32(nil, stakeStateInitialized), encodeStakeMeta()...)
+	initialized = append(initialized, make([]byte, 200-len(initialized))...)
+
+	tests := []struct {
+		name          string
+		account       *AccountInfo
+		expectedState string
+		wantErr       string
+	}{
+		{name: "Uninitialized", account: testAccount(stakeProgramID, make([]byte, 200)), expectedState: "uninitialized"},
+		{name: "Initialized", account: testAccount(stakeProgramID, initialized), expectedState: "initialized"},
+		{name: "Rewards Pool", account: testAccount(stakeProgramID, binary.LittleEndian.AppendUint32(nil, stakeStateRewardsPool)), expectedState: "rewards_pool"},
+		{name: "Truncated", account: testAccount(stakeProgramID, delegated[:100]), wantErr: "unexpected end of account data"},
+		{name: "Unknown State", account: testAccount(stakeProgramID, binary.LittleEndian.AppendUint32(nil, 9)), wantErr: "unknown stake account state"},
+		{name: "Wrong Owner", account: testAccount("11111111111111111111111111111111", delegated), wantErr: "not owned by the stake program"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, decoded, err := decodeStakeAccount(tt.account)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeStakeAccount returned error: %v", err)
+			}
+			if state := decoded.(StakeAccount).State; state != tt.expectedState {
+				t.Errorf("Expected state %s, got %s", tt.expectedState, state)
+			}
+		})
+	}
+}
+
+func TestHandleGetAccountStake(t *testing.T) {
+	mock := &mockRPCClient{accountInfo: testAccount(stakeProgramID, encodeDelegatedStake())}
+	req := httptest.NewRequest("GET", "/account?address=abc&decode=stake", nil)
+	rr := httptest.NewRecorder()
+	handleGetAccount(mock, nil).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var response struct {
+		Decoder string       `json:"decoder"`
+		Data    StakeAccount `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Decoder != "stake" || response.Data.Stake == nil || response.Data.Stake.Stake != 5000000000 {
+		t.Errorf("unexpected response: %s", rr.Body.String())
+	}
+}
+
+func TestDelegationStatus(t *testing.T) {
+	tests := []struct {
+		name         string
+		activation   uint64
+		deactivation uint64
+		expected     string
+	}{
+		{name: "Active", activation: 400, deactivation: math.MaxUint64, expected: "active"},
+		{name: "Activating", activation: 500, deactivation: math.MaxUint64, expected: "activating"},
+		{name: "Genesis", activation: math.MaxUint64, deactivation: math.MaxUint64, expected: "active"},
+		{name: "Deactivating", activation: 400, deactivation: 500, expected: "deactivating"},
+		{name: "Inactive", activation: 400, deactivation: 450, expected: "inactive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delegation := &StakeDelegation{ActivationEpoch: tt.activation, DeactivationEpoch: tt.deactivation}
+			if got := delegationStatus(delegation, 500); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestHandleGetStakes(t *testing.T) {
+	key := func(b byte) []byte { return bytes.Repeat([]byte{b}, 32) }
+	// stakeAccount patches the delegated fixture with its keys and stake
+	stakeAccount := func(address string, staker, withdrawer, voter byte, stake uint64) ProgramAccount {
+		data := encodeDelegatedStake()
+		copy(data[stakeStakerOffset:], key(staker))
+		copy(data[stakeWithdrawerOffset:], key(withdrawer))
+		copy(data[stakeVoterOffset:], key(voter))
+		binary.LittleEndian.PutUint64(data[stakeVoterOffset+32:], stake)
+		return ProgramAccount{Pubkey: address, Account: *testAccount(stakeProgramID, data)}
+	}
+	initialized := append(binary.LittleEndian.AppendUint32(nil, stakeStateInitialized), encodeStakeMeta()...)
+	initialized = append(initialized, make([]byte, stakeAccountSize-len(initialized))...)
+	copy(initialized[stakeWithdrawerOffset:], key(7))
+
+	owner := base58Encode(key(7))
+	accounts := []ProgramAccount{
+		stakeAccount("stakeA", 7, 7, 3, 100),
+		stakeAccount("stakeB", 1, 7, 4, 300),
+		stakeAccount("stakeC", 7, 2, 3, 200),
+		stakeAccount("other", 1, 2, 3, 900),
+		{Pubkey: "fresh", Account: *testAccount(stakeProgramID, initialized)},
+	}
+	rewards := map[string]*InflationReward{"stakeB": {Epoch: 499, Amount: 42, PostBalance: 1042}}
+
+	tests := []struct {
+		name             string
+		queryParam       string
+		shouldFail       bool
+		expectedStatus   int
+		expectedAccounts []string
+		expectedDelegate uint64
+		expectedRewards  int
+	}{
+		{name: "Staker Or Withdrawer", queryParam: "?owner=" + owner, expectedStatus: http.StatusOK, expectedAccounts: []string{"stakeB", "stakeC", "stakeA", "fresh"}, expectedDelegate: 600, expectedRewards: 1},
+		{name: "By Voter", queryParam: "?owner=" + owner + "&voter=" + base58Encode(key(3)), expectedStatus: http.StatusOK, expectedAccounts: []string{"stakeC", "stakeA"}, expectedDelegate: 300},
+		{name: "Without Rewards", queryParam: "?owner=" + owner + "&rewards=false", expectedStatus: http.StatusOK, expectedAccounts: []string{"stakeB", "stakeC", "stakeA", "fresh"}, expectedDelegate: 600},
+		{name: "Missing Owner", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Owner", queryParam: "?owner=abc", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Voter", queryParam: "?owner=" + owner + "&voter=abc", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", queryParam: "?owner=" + owner, shouldFail: true, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockRPCClient{epochInfo: &EpochInfo{Epoch: 500}, programAccts: accounts, rewards: rewards, shouldFail: tt.shouldFail, errorMessage: "RPC connection failed"}
+			req := httptest.NewRequest("GET", "/stakes"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetStakes(mock).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response stakesResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			var addresses []string
+			rewarded := 0
+			for _, account := range response.Accounts {
+				addresses = append(addresses, account.Address)
+				if account.Reward != nil {
+					rewarded++
+					if account.Reward.Amount != 42 || account.Reward.Epoch != 499 {
+						t.Errorf("unexpected reward: %+v", account.Reward)
+					}
+				}
+			}
+			if strings.Join(addresses, ",") != strings.Join(tt.expectedAccounts, ",") {
+				t.Errorf("Expected accounts %v, got %v", tt.expectedAccounts, addresses)
+			}
+			if response.TotalDelegated != tt.expectedDelegate {
+				t.Errorf("Expected %d delegated, got %d", tt.expectedDelegate, response.TotalDelegated)
+			}
+			if rewarded != tt.expectedRewards {
+				t.Errorf("Expected %d rewarded accounts, got %d", tt.expectedRewards, rewarded)
+			}
+			if first := response.Accounts[0]; first.Status != "active" || first.ActivationEpoch == nil || *first.ActivationEpoch != 420 || first.DeactivationEpoch != nil {
+				t.Errorf("unexpected delegation: %+v", first)
+			}
+		})
+	}
+}
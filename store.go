@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Kinds of records kept by a blockStore
+const (
+	storeKindBlock       = "block"
+	storeKindTransaction = "transaction"
+	storeKindCheckpoint  = "checkpoint"
+	storeKindAddressHead = "address"
+	storeKindAddressPage = "address-page"
+)
+
+// blockStore persists finalized blocks and transactions fetched upstream,
+// so they can still be served once the node has pruned them. Records are
+// keyed by kind and by the slot or signature together with the options they
+// were fetched with. get reports false for records it does not hold, and put
+// replaces any record already under the key.
+type blockStore interface {
+	get(ctx context.Context, kind, key string) (json.RawMessage, bool, error)
+	put(ctx context.Context, kind, key string, value json.RawMessage) error
+	close() error
+}
+
+// openBlockStore opens the store described by spec, which is
+// file:<directory>
+func openBlockStore(spec string) (blockStore, error) {
+	backend, target, ok := strings.Cut(spec, ":")
+	if !ok || target == "" {
+		return nil, fmt.Errorf("block store must be file:<dir>, got %q", spec)
+	}
+	switch backend {
+	case "file":
+		return newFileBlockStore(target)
+	}
+	return nil, fmt.Errorf("unknown block store backend %q", backend)
+}
+
+// fileBlockStore keeps each record in its own file under dir/<kind>, named
+// by the hash of its key
+type fileBlockStore struct {
+	dir string
+}
+
+func newFileBlockStore(dir string) (*fileBlockStore, error) {
+	for _, kind := range []string{storeKindBlock, storeKindTransaction, storeKindCheckpoint, storeKindAddressHead, storeKindAddressPage} {
+		if err := os.MkdirAll(filepath.Join(dir, kind), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create block store directory: %w", err)
+		}
+	}
+	return &fileBlockStore{dir: dir}, nil
+}
+
+func (s *fileBlockStore) path(kind, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(s.dir, kind, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *fileBlockStore) get(ctx context.Context, kind, key string) (json.RawMessage, bool, error) {
+	data, err := os.ReadFile(s.path(kind, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read stored %s: %w", kind, err)
+	}
+	return data, true, nil
+}
+
+// put writes the record to a temporary file first, so a reader never sees
+// a partly written one
+func (s *fileBlockStore) put(ctx context.Context, kind, key string, value json.RawMessage) error {
+	path := s.path(kind, key)
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to store %s: %w", kind, err)
+	}
+	_, err = tmp.Write(value)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store %s: %w", kind, err)
+	}
+	return nil
+}
+
+func (s *fileBlockStore) close() error {
+	return nil
+}
+
+// blockStoreKey identifies a block in a store
+func blockStoreKey(slot uint64, opts BlockOptions) string {
+	key := newBlockCacheKey(slot, opts)
+	version := "legacy"
+	if !key.legacy {
+		version = fmt.Sprint(key.maxVersion)
+	}
+	return fmt.Sprintf("%d/%s/%s/%s", slot, version, key.encoding, key.transactionDetails)
+}
+
+// transactionStoreKey identifies a transaction in a store
+func transactionStoreKey(signature string, opts TransactionOptions) string {
+	version := "legacy"
+	if opts.MaxSupportedTransactionVersion != nil {
+		version = fmt.Sprint(*opts.MaxSupportedTransactionVersion)
+	}
+	return fmt.Sprintf("%s/%s/%s", signature, version, opts.Encoding)
+}
+
+// wrapWithStore returns a client that serves finalized blocks and
+// transactions from store, fetching and storing those it does not hold yet.
+// commitment is the one configured for upstream calls. A nil store returns
+// client unchanged.
+func wrapWithStore(client SolanaRPCClient, store blockStore, commitment string) SolanaRPCClient {
+	if store == nil {
+		return client
+	}
+	return &storingClient{SolanaRPCClient: client, store: store, commitment: commitment}
+}
+
+// storingClient reads through a blockStore. Store failures are logged and
+// fall back on the upstream, as the store only adds to what it can serve.
+type storingClient struct {
+	SolanaRPCClient
+	store      blockStore
+	commitment string
+}
+
+// load returns a stored record, treating a failed read as a miss
+func (c *storingClient) load(ctx context.Context, kind, key string) (json.RawMessage, bool) {
+	value, ok, err := c.store.get(ctx, kind, key)
+	if err != nil {
+		logFields("block store read failed", "kind", kind, "key", key, "error", err)
+		return nil, false
+	}
+	if ok {
+		metrics.addCounter("solana_client_block_store_requests_total", "Block store lookups by kind and result.", 1, "kind", kind, "result", "hit")
+	} else {
+		metrics.addCounter("solana_client_block_store_requests_total", "Block store lookups by kind and result.", 1, "kind", kind, "result", "miss")
+	}
+	return value, ok
+}
+
+// save stores a record, skipping missing ones
+func (c *storingClient) save(ctx context.Context, kind, key string, value json.RawMessage) {
+	if len(value) == 0 || string(value) == "null" {
+		return
+	}
+	if err := c.store.put(ctx, kind, key, value); err != nil {
+		logFields("block store write failed", "kind", kind, "key", key, "error", err)
+	}
+}
+
+func (c *storingClient) getBlockDetails(ctx context.Context, slot uint64, opts BlockOptions) (json.RawMessage, error) {
+	if !finalizedRead(ctx, opts.Commitment, c.commitment) {
+		return c.SolanaRPCClient.getBlockDetails(ctx, slot, opts)
+	}
+	key := blockStoreKey(slot, opts)
+	if block, ok := c.load(ctx, storeKindBlock, key); ok {
+		return block, nil
+	}
+	block, err := c.SolanaRPCClient.getBlockDetails(ctx, slot, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.save(ctx, storeKindBlock, key, block)
+	return block, nil
+}
+
+// getBlocks fetches only the blocks missing from the store upstream
+func (c *storingClient) getBlocks(ctx context.Context, slots []uint64, opts BlockOptions) ([]json.RawMessage, []error, error) {
+	if !finalizedRead(ctx, opts.Commitment, c.commitment) {
+		return c.SolanaRPCClient.getBlocks(ctx, slots, opts)
+	}
+
+	blocks := make([]json.RawMessage, len(slots))
+	errs := make([]error, len(slots))
+	var missing []uint64
+	var indexes []int
+	for i, slot := range slots {
+		if block, ok := c.load(ctx, storeKindBlock, blockStoreKey(slot, opts)); ok {
+			blocks[i] = block
+			continue
+		}
+		missing = append(missing, slot)
+		indexes = append(indexes, i)
+	}
+	if len(missing) == 0 {
+		return blocks, errs, nil
+	}
+
+	fetched, fetchErrs, err := c.SolanaRPCClient.getBlocks(ctx, missing, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for j, i := range indexes {
+		blocks[i], errs[i] = fetched[j], fetchErrs[j]
+		if fetchErrs[j] == nil {
+			c.save(ctx, storeKindBlock, blockStoreKey(missing[j], opts), fetched[j])
+		}
+	}
+	return blocks, errs, nil
+}
+
+func (c *storingClient) getTransaction(ctx context.Context, signature string, opts TransactionOptions) (json.RawMessage, error) {
+	if !finalizedRead(ctx, "", c.commitment) {
+		return c.SolanaRPCClient.getTransaction(ctx, signature, opts)
+	}
+	key := transactionStoreKey(signature, opts)
+	if transaction, ok := c.load(ctx, storeKindTransaction, key); ok {
+		return transaction, nil
+	}
+	transaction, err := c.SolanaRPCClient.getTransaction(ctx, signature, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.save(ctx, storeKindTransaction, key, transaction)
+	return transaction, nil
+}
+
+// getTransactions fetches only the transactions missing from the store
+// upstream
+func (c *storingClient) getTransactions(ctx context.Context, signatures []string, opts TransactionOptions) ([]json.RawMessage, []error, error) {
+	if !finalizedRead(ctx, "", c.commitment) {
+		return c.SolanaRPCClient.getTransactions(ctx, signatures, opts)
+	}
+
+	transactions := make([]json.RawMessage, len(signatures))
+	errs := make([]error, len(signatures))
+	var missing []string
+	var indexes []int
+	for i, signature := range signatures {
+		if transaction, ok := c.load(ctx, storeKindTransaction, transactionStoreKey(signature, opts)); ok {
+			transactions[i] = transaction
+			continue
+		}
+		missing = append(missing, signature)
+		indexes = append(indexes, i)
+	}
+	if len(missing) == 0 {
+		return transactions, errs, nil
+	}
+
+	fetched, fetchErrs, err := c.SolanaRPCClient.getTransactions(ctx, missing, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	for j, i := range indexes {
+		transactions[i], errs[i] = fetched[j], fetchErrs[j]
+		if fetchErrs[j] == nil {
+			c.save(ctx, storeKindTransaction, transactionStoreKey(missing[j], opts), fetched[j])
+		}
+	}
+	return transactions, errs, nil
+}
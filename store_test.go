@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFileBlockStore(t *testing.T) {
+	store, err := openBlockStore("file:" + t.TempDir())
+	if err != nil {
+		t.Fatalf("openBlockStore returned error: %v", err)
+	}
+	defer store.close()
+	ctx := context.Background()
+
+	if _, ok, err := store.get(ctx, storeKindBlock, "1"); ok || err != nil {
+		t.Fatalf("Expected a miss on an empty store, got %v (%v)", ok, err)
+	}
+	if err := store.put(ctx, storeKindBlock, "1", json.RawMessage(`{"blockhash":"a"}`)); err != nil {
+		t.Fatalf("put returned error: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		kind          string
+		key           string
+		expectedFound bool
+	}{
+		{name: "Stored", kind: storeKindBlock, key: "1", expectedFound: true},
+		{name: "Other Key", kind: storeKindBlock, key: "2"},
+		{name: "Other Kind", kind: storeKindTransaction, key: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok, err := store.get(ctx, tt.kind, tt.key)
+			if err != nil {
+				t.Fatalf("get returned error: %v", err)
+			}
+			if ok != tt.expectedFound {
+				t.Fatalf("Expected found %v, got %v", tt.expectedFound, ok)
+			}
+			if ok && string(value) != `{"blockhash":"a"}` {
+				t.Errorf("unexpected value %s", value)
+			}
+		})
+	}
+}
+
+func TestOpenBlockStore(t *testing.T) {
+	tests := []struct {
+		name          string
+		spec          string
+		expectedError string
+	}{
+		{name: "Missing Target", spec: "file:", expectedError: "block store must be"},
+		{name: "Unknown Backend", spec: "mysql:db", expectedError: "unknown block store backend"},
+		{name: "SQLite", spec: "sqlite:blocks.db", expectedError: "unknown block store backend"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := openBlockStore(tt.spec)
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Expected error containing %q, got %v", tt.expectedError, err)
+			}
+		})
+	}
+
+}
+
+func TestStoringClient(t *testing.T) {
+	upstream := &countingBlockClient{mockRPCClient: mockRPCClient{
+		blocks:       map[uint64]json.RawMessage{1: json.RawMessage(`{"blockhash":"a"}`), 2: json.RawMessage(`{"blockhash":"b"}`)},
+		transactions: map[string]json.RawMessage{"sig": json.RawMessage(`{"slot":1}`)},
+	}}
+	store, err := newFileBlockStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("newFileBlockStore returned error: %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		client          SolanaRPCClient
+		ctx             context.Context
+		slot            uint64
+		expectedFetched int
+	}{
+		{name: "Miss", client: wrapWithStore(upstream, store, ""), ctx: ctx, slot: 1, expectedFetched: 1},
+		{name: "Stored", client: wrapWithStore(upstream, store, ""), ctx: ctx, slot: 1, expectedFetched: 0},
+		{name: "Confirmed Commitment Bypasses", client: wrapWithStore(upstream, store, "confirmed"), ctx: ctx, slot: 1, expectedFetched: 1},
+		{name: "Requested Commitment Bypasses", client: wrapWithStore(upstream, store, ""), ctx: context.WithValue(ctx, requestCommitmentKey{}, "confirmed"), slot: 1, expectedFetched: 1},
+		{name: "Skipped Slot Not Stored", client: wrapWithStore(upstream, store, ""), ctx: ctx, slot: 9, expectedFetched: 1},
+		{name: "Skipped Slot Fetched Again", client: wrapWithStore(upstream, store, ""), ctx: ctx, slot: 9, expectedFetched: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream.fetched = 0
+			tt.client.getBlockDetails(tt.ctx, tt.slot, BlockOptions{})
+			if upstream.fetched != tt.expectedFetched {
+				t.Errorf("Expected %d upstream fetches, got %d", tt.expectedFetched, upstream.fetched)
+			}
+		})
+	}
+
+	// Stored blocks survive the upstream pruning them
+	client := wrapWithStore(upstream, store, "")
+	client.getBlocks(ctx, []uint64{2}, BlockOptions{})
+	upstream.blocks = nil
+	upstream.fetched = 0
+	blocks, errs, err := client.getBlocks(ctx, []uint64{1, 2}, BlockOptions{})
+	if err != nil || errs[0] != nil || errs[1] != nil {
+		t.Fatalf("getBlocks returned errors: %v %v", err, errs)
+	}
+	if string(blocks[0]) != `{"blockhash":"a"}` || string(blocks[1]) != `{"blockhash":"b"}` || upstream.fetched != 0 {
+		t.Errorf("Expected both blocks from the store, got %s %s after %d fetches", blocks[0], blocks[1], upstream.fetched)
+	}
+
+	client.getTransaction(ctx, "sig", TransactionOptions{})
+	upstream.transactions = nil
+	transaction, err := client.getTransaction(ctx, "sig", TransactionOptions{})
+	if err != nil || string(transaction) != `{"slot":1}` {
+		t.Errorf("Expected the stored transaction, got %s (%v)", transaction, err)
+	}
+	transactions, _, err := client.getTransactions(ctx, []string{"sig", "other"}, TransactionOptions{})
+	if err != nil || string(transactions[0]) != `{"slot":1}` || transactions[1] != nil {
+		t.Errorf("Expected only the stored transaction, got %s (%v)", transactions, err)
+	}
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxTransactionBodyBytes bounds transaction submission bodies. A serialized
+// transaction is at most 1232 bytes, so this leaves ample room for base64.
+const maxTransactionBodyBytes = 16 << 10
+
+// SimulationResult is the value returned by simulateTransaction
+type SimulationResult struct {
+	Err           json.RawMessage `json:"err"`
+	Logs          []string        `json:"logs"`
+	UnitsConsumed uint64          `json:"unitsConsumed"`
+}
+
+// failed reports whether the simulated transaction returned an error
+func (s *SimulationResult) failed() bool {
+	return len(s.Err) > 0 && string(s.Err) != "null"
+}
+
+// transactionRequest is the body accepted by transaction submission endpoints
+type transactionRequest struct {
+	Transaction string `json:"transaction"`
+}
+
+// simulateAndSendResponse is the response of /simulate-and-send
+type simulateAndSendResponse struct {
+	Simulation *SimulationResult `json:"simulation"`
+	Sent       bool              `json:"sent"`
+	Forced     bool              `json:"forced,omitempty"`
+	Signature  string            `json:"signature,omitempty"`
+}
+
+// simulateTransaction simulates a base64 encoded signed transaction
+func (c *rpcClient) simulateTransaction(ctx context.Context, transaction string) (*SimulationResult, error) {
+	response, err := c.sendRequest(ctx, "simulateTransaction", []interface{}{
+		transaction,
+		map[string]interface{}{"encoding": "base64", "sigVerify": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value SimulationResult `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse simulation result: %w", err)
+	}
+
+	return &result.Value, nil
+}
+
+// sendTransaction submits a base64 encoded signed transaction and returns its
+// signature
+func (c *rpcClient) sendTransaction(ctx context.Context, transaction string, skipPreflight bool) (string, error) {
+	response, err := c.sendRequest(ctx, "sendTransaction", []interface{}{
+		transaction,
+		map[string]interface{}{"encoding": "base64", "skipPreflight": skipPreflight},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var signature string
+	if err := json.Unmarshal(response.Result, &signature); err != nil {
+		return "", fmt.Errorf("failed to parse transaction signature: %w", err)
+	}
+
+	return signature, nil
+}
+
+// readTransactionRequest decodes and validates a transaction submission body
+func readTransactionRequest(w http.ResponseWriter, r *http.Request) (string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTransactionBodyBytes))
+	if err != nil {
+		return "", fmt.Errorf("request body too large")
+	}
+
+	var req transactionRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return "", fmt.Errorf("invalid request body")
+	}
+	if req.Transaction == "" {
+		return "", fmt.Errorf("transaction is required")
+	}
+	if _, err := base64.StdEncoding.DecodeString(req.Transaction); err != nil {
+		return "", fmt.Errorf("transaction must be base64 encoded")
+	}
+
+	return req.Transaction, nil
+}
+
+// handleSimulateAndSend simulates a transaction and only broadcasts it if the
+// simulation succeeds, unless force=true is given
+func handleSimulateAndSend(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		force := false
+		if value := r.URL.Query().Get("force"); value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, "invalid force value", http.StatusBadRequest)
+				return
+			}
+			force = parsed
+		}
+
+		transaction, err := readTransactionRequest(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		simulation, err := client.simulateTransaction(r.Context(), transaction)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := simulateAndSendResponse{Simulation: simulation, Forced: force && simulation.failed()}
+		status := http.StatusOK
+
+		if simulation.failed() && !force {
+			status = http.StatusUnprocessableEntity
+		} else {
+			// The transaction was just simulated, so skip the node's preflight
+			signature, err := client.sendTransaction(r.Context(), transaction, true)
+			if err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+			response.Sent = true
+			response.Signature = signature
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+// SignatureStatus is an entry returned by getSignatureStatuses
+type SignatureStatus struct {
+	Slot               uint64          `json:"slot"`
+	Confirmations      *uint64         `json:"confirmations"`
+	Err                json.RawMessage `json:"err"`
+	ConfirmationStatus string          `json:"confirmationStatus"`
+}
+
+// transactionPending is reported for transactions the node has not seen yet
+const transactionPending = "pending"
+
+// getSignatureStatuses gets the status of transactions by signature, with nil
+// entries for those the node does not know. Without searchHistory only the
+// recent status cache is consulted.
+func (c *rpcClient) getSignatureStatuses(ctx context.Context, signatures []string, searchHistory bool) ([]*SignatureStatus, error) {
+	response, err := c.sendRequest(ctx, "getSignatureStatuses", []interface{}{
+		signatures,
+		map[string]interface{}{"searchTransactionHistory": searchHistory},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value []*SignatureStatus `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse signature statuses: %w", err)
+	}
+	if len(result.Value) != len(signatures) {
+		return nil, fmt.Errorf("RPC returned %d statuses for %d signatures", len(result.Value), len(signatures))
+	}
+	return result.Value, nil
+}
+
+// submitTransactionResponse is the response of POST /transaction
+type submitTransactionResponse struct {
+	Signature          string            `json:"signature,omitempty"`
+	Sent               bool              `json:"sent"`
+	Simulation         *SimulationResult `json:"simulation,omitempty"`
+	ConfirmationStatus string            `json:"confirmation_status,omitempty"`
+}
+
+// handleSubmitTransaction broadcasts a transaction and reports its
+// confirmation status right after. With ?simulate=true it is simulated
+// first and only sent if the simulation succeeds; otherwise the node runs its
+// own preflight checks unless ?skipPreflight=true.
+func handleSubmitTransaction(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var simulate, skipPreflight bool
+		for name, value := range map[string]*bool{"simulate": &simulate, "skipPreflight": &skipPreflight} {
+			if raw := r.URL.Query().Get(name); raw != "" {
+				parsed, err := strconv.ParseBool(raw)
+				if err != nil {
+					http.Error(w, "invalid "+name+" value", http.StatusBadRequest)
+					return
+				}
+				*value = parsed
+			}
+		}
+
+		transaction, err := readTransactionRequest(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var response submitTransactionResponse
+		if simulate {
+			response.Simulation, err = client.simulateTransaction(r.Context(), transaction)
+			if err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+			if response.Simulation.failed() {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				jsonData, _ := json.Marshal(response)
+				w.Write(jsonData)
+				return
+			}
+			// The transaction was just simulated, so skip the node's preflight
+			skipPreflight = true
+		}
+
+		response.Signature, err = client.sendTransaction(r.Context(), transaction, skipPreflight)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		response.Sent = true
+
+		// The transaction is sent either way, so a failed status lookup
+		// leaves it reported as pending
+		response.ConfirmationStatus = transactionPending
+		statuses, err := client.getSignatureStatuses(r.Context(), []string{response.Signature}, false)
+		if err == nil && statuses[0] != nil && statuses[0].ConfirmationStatus != "" {
+			response.ConfirmationStatus = statuses[0].ConfirmationStatus
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
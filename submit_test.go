@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleSimulateAndSend(t *testing.T) {
+	succeeded := &SimulationResult{Err: json.RawMessage("null"), Logs: []string{"Program log: ok"}, UnitsConsumed: 150}
+	failed := &SimulationResult{Err: json.RawMessage(`{"InstructionError":[0,{"Custom":1}]}`), Logs: []string{"Program log: insufficient funds"}}
+	body := `{"transaction":"AQID"}`
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		method         string
+		queryParam     string
+		body           string
+		expectedStatus int
+		expectSent     bool
+	}{
+		{name: "Simulation Succeeds", mockClient: mockRPCClient{simulation: succeeded}, method: "POST", body: body, expectedStatus: http.StatusOK, expectSent: true},
+		{name: "Simulation Fails", mockClient: mockRPCClient{simulation: failed}, method: "POST", body: body, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Forced Despite Failure", mockClient: mockRPCClient{simulation: failed}, method: "POST", queryParam: "?force=true", body: body, expectedStatus: http.StatusOK, expectSent: true},
+		{name: "Invalid Base64", mockClient: mockRPCClient{simulation: succeeded}, method: "POST", body: `{"transaction":"not base64!"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Missing Transaction", mockClient: mockRPCClient{simulation: succeeded}, method: "POST", body: `{}`, expectedStatus: http.StatusBadRequest},
+		{name: "Wrong Method", mockClient: mockRPCClient{simulation: succeeded}, method: "GET", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/simulate-and-send"+tt.queryParam, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			handleSimulateAndSend(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if sent := len(tt.mockClient.sentTxs) > 0; sent != tt.expectSent {
+				t.Errorf("Expected sent=%v, transactions sent: %v", tt.expectSent, tt.mockClient.sentTxs)
+			}
+			if tt.expectedStatus == http.StatusBadRequest || tt.expectedStatus == http.StatusMethodNotAllowed {
+				return
+			}
+
+			var response simulateAndSendResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Sent != tt.expectSent || (response.Signature != "") != tt.expectSent {
+				t.Errorf("unexpected response: %+v", response)
+			}
+			if response.Simulation == nil {
+				t.Error("Expected simulation result in response")
+			}
+		})
+	}
+}
+
+func TestHandleSubmitTransaction(t *testing.T) {
+	const signature = "5VERv8NMvzbJMEkV8xnrLkEaWRtSz9CosKDYjCJjBRnbJLgp8uirBgmQpjKhoR4tjF3ZpRzrFmBV6UjKdiSZkQUW"
+	succeeded := &SimulationResult{Err: json.RawMessage("null"), UnitsConsumed: 150}
+	failed := &SimulationResult{Err: json.RawMessage(`{"InstructionError":[0,{"Custom":1}]}`)}
+	processed := map[string]*SignatureStatus{signature: {Slot: 42, ConfirmationStatus: "processed"}}
+	body := `{"transaction":"AQID"}`
+
+	tests := []struct {
+		name                  string
+		mockClient            mockRPCClient
+		queryParam            string
+		body                  string
+		expectedStatus        int
+		expectSent            bool
+		expectSkipPreflight   bool
+		expectedConfirmStatus string
+	}{
+		{name: "Sent", mockClient: mockRPCClient{sigStatuses: processed}, body: body, expectedStatus: http.StatusOK, expectSent: true, expectedConfirmStatus: "processed"},
+		{name: "Not Yet Seen", mockClient: mockRPCClient{}, body: body, expectedStatus: http.StatusOK, expectSent: true, expectedConfirmStatus: transactionPending},
+		{name: "Skip Preflight", mockClient: mockRPCClient{}, queryParam: "?skipPreflight=true", body: body, expectedStatus: http.StatusOK, expectSent: true, expectSkipPreflight: true, expectedConfirmStatus: transactionPending},
+		{name: "Simulated First", mockClient: mockRPCClient{simulation: succeeded, sigStatuses: processed}, queryParam: "?simulate=true", body: body, expectedStatus: http.StatusOK, expectSent: true, expectSkipPreflight: true, expectedConfirmStatus: "processed"},
+		{name: "Simulation Fails", mockClient: mockRPCClient{simulation: failed}, queryParam: "?simulate=true", body: body, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Invalid Simulate", queryParam: "?simulate=maybe", body: body, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Base64", body: `{"transaction":"not base64!"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, body: body, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/transaction"+tt.queryParam, strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			handleSubmitTransaction(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if sent := len(tt.mockClient.sentTxs) > 0; sent != tt.expectSent {
+				t.Errorf("Expected sent %v, got %v", tt.expectSent, sent)
+			}
+			if tt.mockClient.skipPreflight != tt.expectSkipPreflight {
+				t.Errorf("Expected skipPreflight %v, got %v", tt.expectSkipPreflight, tt.mockClient.skipPreflight)
+			}
+			if rr.Code != http.StatusOK && rr.Code != http.StatusUnprocessableEntity {
+				return
+			}
+
+			var response submitTransactionResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Sent != tt.expectSent {
+				t.Errorf("Expected sent %v in response, got %v", tt.expectSent, response.Sent)
+			}
+			if response.ConfirmationStatus != tt.expectedConfirmStatus {
+				t.Errorf("Expected confirmation status %q, got %q", tt.expectedConfirmStatus, response.ConfirmationStatus)
+			}
+		})
+	}
+}
+
+func TestByMethod(t *testing.T) {
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+	handler := byMethod(map[string]http.HandlerFunc{http.MethodGet: ok, http.MethodPost: ok})
+
+	for method, expected := range map[string]int{"GET": http.StatusOK, "POST": http.StatusOK, "DELETE": http.StatusMethodNotAllowed} {
+		rr := httptest.NewRecorder()
+		handler(rr, httptest.NewRequest(method, "/transaction", nil))
+		if rr.Code != expected {
+			t.Errorf("%s: expected status %d, got %d", method, expected, rr.Code)
+		}
+		if expected == http.StatusMethodNotAllowed && rr.Header().Get("Allow") != "GET, POST" {
+			t.Errorf("Expected Allow of GET, POST, got %q", rr.Header().Get("Allow"))
+		}
+	}
+}
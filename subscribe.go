@@ -0,0 +1,604 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Subscription streaming settings
+const (
+	defaultSubscriberBufferSize = 64
+	defaultSubscriberMaxDrops   = 64
+	sseKeepAlive                = 15 * time.Second
+)
+
+// subscriber receives the notifications of one upstream subscription. The
+// channel is closed when the upstream subscription ends, or when the hub
+// evicts the subscriber for falling behind, which sets evicted first.
+type subscriber struct {
+	messages chan json.RawMessage
+	maxDrops int
+	drops    int
+	evicted  bool
+}
+
+// deliver queues a notification without blocking. When the buffer is full
+// the oldest notification is dropped to make room, so a client that falls
+// behind skips ahead instead of stalling the stream. The hub is the only
+// sender, so a slot freed here cannot be taken by another notification.
+// It reports false once maxDrops notifications in a row had to make room,
+// as the client is then too slow to keep up at all.
+func (s *subscriber) deliver(message json.RawMessage, method string) bool {
+	select {
+	case s.messages <- message:
+		s.drops = 0
+		return true
+	default:
+	}
+
+	for {
+		select {
+		case <-s.messages:
+			s.drops++
+			metrics.addCounter("solana_client_stream_dropped_messages_total", "Notifications dropped because a stream client fell behind.", 1, "method", method)
+		default:
+		}
+
+		select {
+		case s.messages <- message:
+			return s.maxDrops == 0 || s.drops < s.maxDrops
+		default:
+		}
+	}
+}
+
+// closeReason explains why the subscriber's channel was closed. It may only
+// be called once the channel is.
+func (s *subscriber) closeReason() string {
+	if s.evicted {
+		return "client fell too far behind"
+	}
+	return "upstream subscription closed"
+}
+
+// subscriptionStream is one upstream subscription fanned out to every
+// subscriber watching the same method and params. Only the hub's run
+// goroutine replaces conn and subscriptionID, under the hub's lock.
+type subscriptionStream struct {
+	key            string
+	method         string
+	params         json.RawMessage
+	subscriptionID json.RawMessage
+	conn           *wsConn
+	subscribers    map[*subscriber]bool
+	closed         bool
+}
+
+// subscriptionHub shares upstream WebSocket subscriptions between clients.
+// Each distinct method and params combination holds one upstream
+// connection, opened by the first subscriber and closed after the last one
+// leaves. Upstream connections are pinged every pingInterval, and one that
+// stays silent for readTimeout is replaced by a new subscription. A
+// subscriber that drops maxDrops notifications in a row is evicted, so one
+// stalled client cannot hold an upstream subscription open; zero keeps
+// every subscriber.
+type subscriptionHub struct {
+	endpoint     string
+	dial         func(string) (*wsConn, error)
+	bufferSize   int
+	maxDrops     int
+	pingInterval time.Duration
+	readTimeout  time.Duration
+
+	mu      sync.Mutex
+	streams map[string]*subscriptionStream
+}
+
+func newSubscriptionHub(endpoint string) *subscriptionHub {
+	return &subscriptionHub{
+		endpoint:     endpoint,
+		dial:         dialWebSocket,
+		bufferSize:   defaultSubscriberBufferSize,
+		maxDrops:     defaultSubscriberMaxDrops,
+		pingInterval: wsPingInterval,
+		readTimeout:  wsReadTimeout,
+		streams:      make(map[string]*subscriptionStream),
+	}
+}
+
+// subscribe joins the upstream subscription for method and params, opening
+// it if needed. The returned function must be called to leave it.
+func (h *subscriptionHub) subscribe(method string, params []interface{}) (*subscriber, func(), error) {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	key := requestKey(method, rawParams)
+	sub := &subscriber{messages: make(chan json.RawMessage, h.bufferSize), maxDrops: h.maxDrops}
+
+	h.mu.Lock()
+	stream, ok := h.streams[key]
+	h.mu.Unlock()
+
+	if !ok {
+		opened, err := h.open(key, method, rawParams)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Another client may have opened the same subscription meanwhile
+		h.mu.Lock()
+		if stream, ok = h.streams[key]; !ok {
+			stream = opened
+			h.streams[key] = stream
+			go h.run(stream)
+		}
+		h.mu.Unlock()
+		if ok {
+			opened.conn.close()
+		}
+	}
+
+	h.mu.Lock()
+	if h.streams[key] != stream {
+		h.mu.Unlock()
+		return nil, nil, fmt.Errorf("upstream subscription closed")
+	}
+	stream.subscribers[sub] = true
+	h.publish()
+	h.mu.Unlock()
+
+	return sub, func() { h.leave(stream, sub) }, nil
+}
+
+// open dials the upstream and waits for it to confirm the subscription
+func (h *subscriptionHub) open(key, method string, params json.RawMessage) (*subscriptionStream, error) {
+	conn, err := h.dial(h.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	conn.readTimeout = h.readTimeout
+
+	request, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err := conn.writeText(request); err != nil {
+		conn.close()
+		return nil, err
+	}
+
+	for {
+		message, err := conn.readMessage()
+		if err != nil {
+			conn.close()
+			return nil, fmt.Errorf("failed to read subscription response: %w", err)
+		}
+
+		var response RPCResponse
+		if err := json.Unmarshal(message, &response); err != nil || response.ID != 1 {
+			continue
+		}
+		if response.Error != nil {
+			conn.close()
+			return nil, fmt.Errorf("RPC error: %d - %s", response.Error.Code, response.Error.Message)
+		}
+		return &subscriptionStream{
+			key:            key,
+			method:         method,
+			params:         params,
+			subscriptionID: response.Result,
+			conn:           conn,
+			subscribers:    make(map[*subscriber]bool),
+		}, nil
+	}
+}
+
+// run fans notifications out until the upstream subscription ends,
+// reconnecting when the connection stops responding
+func (h *subscriptionHub) run(stream *subscriptionStream) {
+	for {
+		err := h.relay(stream)
+		if !isTimeout(err) || !h.reconnect(stream) {
+			break
+		}
+	}
+
+	h.mu.Lock()
+	stream.closed = true
+	if h.streams[stream.key] == stream {
+		delete(h.streams, stream.key)
+	}
+	for sub := range stream.subscribers {
+		close(sub.messages)
+		delete(stream.subscribers, sub)
+	}
+	h.publish()
+	h.mu.Unlock()
+	stream.conn.close()
+}
+
+// relay fans the notifications of the stream's connection out until it
+// fails. A subscriber that falls behind loses its oldest notifications
+// rather than stalling the others, and is evicted if it stays behind. When
+// that leaves no subscribers the stream ends.
+func (h *subscriptionHub) relay(stream *subscriptionStream) error {
+	stop := stream.conn.keepAlive(h.pingInterval)
+	defer stop()
+
+	for {
+		message, err := stream.conn.readMessage()
+		if err != nil {
+			return err
+		}
+
+		var notification struct {
+			Params struct {
+				Result json.RawMessage `json:"result"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(message, &notification); err != nil || notification.Params.Result == nil {
+			continue
+		}
+
+		h.mu.Lock()
+		for sub := range stream.subscribers {
+			if !sub.deliver(notification.Params.Result, stream.method) {
+				h.evict(stream, sub)
+			}
+		}
+		last := len(stream.subscribers) == 0 && !stream.closed
+		if last {
+			stream.closed = true
+			if h.streams[stream.key] == stream {
+				delete(h.streams, stream.key)
+			}
+		}
+		h.mu.Unlock()
+		if last {
+			return errSubscribersEvicted
+		}
+	}
+}
+
+// errSubscribersEvicted ends a stream whose last subscribers were evicted
+var errSubscribersEvicted = errors.New("every subscriber was evicted")
+
+// evict removes a subscriber that cannot keep up and closes its channel.
+// The caller must hold h.mu.
+func (h *subscriptionHub) evict(stream *subscriptionStream, sub *subscriber) {
+	delete(stream.subscribers, sub)
+	sub.evicted = true
+	close(sub.messages)
+	h.publish()
+	metrics.addCounter("solana_client_stream_evicted_clients_total", "Stream clients disconnected for falling too far behind.", 1, "method", stream.method)
+}
+
+// reconnect replaces a connection that stopped responding with a new
+// upstream subscription, unless the stream was closed meanwhile. It reports
+// whether the stream carries on.
+func (h *subscriptionHub) reconnect(stream *subscriptionStream) bool {
+	h.mu.Lock()
+	closed := stream.closed
+	h.mu.Unlock()
+	if closed {
+		return false
+	}
+
+	stream.conn.close()
+	opened, err := h.open(stream.key, stream.method, stream.params)
+	if err != nil {
+		logFields("failed to reopen subscription", "method", stream.method, "error", err)
+		return false
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if stream.closed {
+		opened.conn.close()
+		return false
+	}
+	stream.conn, stream.subscriptionID = opened.conn, opened.subscriptionID
+	metrics.addCounter("solana_client_stream_reconnects_total", "Upstream subscriptions reopened after the connection stopped responding.", 1, "method", stream.method)
+	return true
+}
+
+// leave removes a subscriber, unsubscribing upstream once none are left
+func (h *subscriptionHub) leave(stream *subscriptionStream, sub *subscriber) {
+	h.mu.Lock()
+	if !stream.subscribers[sub] {
+		h.mu.Unlock()
+		return
+	}
+	delete(stream.subscribers, sub)
+	last := len(stream.subscribers) == 0
+	if last {
+		stream.closed = true
+		if h.streams[stream.key] == stream {
+			delete(h.streams, stream.key)
+		}
+	}
+	conn, subscriptionID := stream.conn, stream.subscriptionID
+	h.publish()
+	h.mu.Unlock()
+
+	if last {
+		unsubscribe := strings.Replace(stream.method, "Subscribe", "Unsubscribe", 1)
+		request, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": 2, "method": unsubscribe, "params": []json.RawMessage{subscriptionID}})
+		conn.writeText(request)
+		conn.close()
+	}
+}
+
+// close ends every upstream subscription, which in turn ends the streams of
+// their clients. It is used on shutdown, as streams never finish on their
+// own. The connections are closed after releasing the lock, as closing
+// writes to them.
+func (h *subscriptionHub) close() {
+	h.mu.Lock()
+	conns := make([]*wsConn, 0, len(h.streams))
+	for _, stream := range h.streams {
+		stream.closed = true
+		conns = append(conns, stream.conn)
+	}
+	h.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.close()
+	}
+}
+
+// publish updates the stream gauges. The caller must hold h.mu.
+func (h *subscriptionHub) publish() {
+	clients := 0
+	for _, stream := range h.streams {
+		clients += len(stream.subscribers)
+	}
+	metrics.setGauge("solana_client_stream_upstream_subscriptions", "Upstream WebSocket subscriptions currently open.", float64(len(h.streams)))
+	metrics.setGauge("solana_client_stream_clients", "Clients currently streaming subscription notifications.", float64(clients))
+}
+
+// serveSSE streams a subscriber's notifications as server-sent events until
+// the client disconnects or the upstream subscription ends. A non-nil format
+// rewrites each notification; notifications it rejects are skipped.
+func serveSSE(w http.ResponseWriter, r *http.Request, sub *subscriber, event string, format func(json.RawMessage) (json.RawMessage, error)) {
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case message, ok := <-sub.messages:
+			if !ok {
+				fmt.Fprintf(w, "event: error\ndata: %s\n\n", sub.closeReason())
+				rc.Flush()
+				return
+			}
+			if format != nil {
+				formatted, err := format(message)
+				if err != nil {
+					continue
+				}
+				message = formatted
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, message)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// parseProgramFilters reads getProgramAccounts style filters: an optional
+// dataSize=<bytes> and any number of memcmp=<offset>:<base58 bytes>
+func parseProgramFilters(query map[string][]string) ([]interface{}, error) {
+	var filters []interface{}
+	if values := query["dataSize"]; len(values) > 0 {
+		size, err := strconv.ParseUint(values[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dataSize parameter")
+		}
+		filters = append(filters, map[string]interface{}{"dataSize": size})
+	}
+
+	for _, value := range query["memcmp"] {
+		offset, encoded, ok := strings.Cut(value, ":")
+		parsed, err := strconv.ParseUint(offset, 10, 64)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("memcmp must be <offset>:<base58 bytes>")
+		}
+		if _, err := base58Decode(encoded); err != nil || encoded == "" {
+			return nil, fmt.Errorf("memcmp bytes must be base58 encoded")
+		}
+		filters = append(filters, map[string]interface{}{"memcmp": map[string]interface{}{"offset": parsed, "bytes": encoded}})
+	}
+	return filters, nil
+}
+
+func handleProgramStream(hub *subscriptionHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		programID := r.URL.Query().Get("programId")
+		if programID == "" {
+			http.Error(w, "programId parameter is required", http.StatusBadRequest)
+			return
+		}
+		if key, err := base58Decode(programID); err != nil || len(key) != 32 {
+			http.Error(w, "programId must be a base58 public key", http.StatusBadRequest)
+			return
+		}
+
+		filters, err := parseProgramFilters(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		config := map[string]interface{}{"encoding": "base64"}
+		if len(filters) > 0 {
+			config["filters"] = filters
+		}
+
+		sub, leave, err := hub.subscribe("programSubscribe", []interface{}{programID, config})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer leave()
+
+		serveSSE(w, r, sub, "account", nil)
+	}
+}
+
+// logEvent is a flattened logsNotification sent to /account/logs/stream
+// clients
+type logEvent struct {
+	Slot      uint64          `json:"slot"`
+	Signature string          `json:"signature"`
+	Err       json.RawMessage `json:"err"`
+	Logs      []string        `json:"logs"`
+}
+
+// formatLogNotification flattens the result of a logsNotification
+func formatLogNotification(result json.RawMessage) (json.RawMessage, error) {
+	var notification struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value struct {
+			Signature string          `json:"signature"`
+			Err       json.RawMessage `json:"err"`
+			Logs      []string        `json:"logs"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(result, &notification); err != nil {
+		return nil, fmt.Errorf("failed to parse log notification: %w", err)
+	}
+	if notification.Value.Signature == "" {
+		return nil, fmt.Errorf("log notification has no signature")
+	}
+
+	event := logEvent{
+		Slot:      notification.Context.Slot,
+		Signature: notification.Value.Signature,
+		Err:       notification.Value.Err,
+		Logs:      notification.Value.Logs,
+	}
+	if event.Err == nil {
+		event.Err = json.RawMessage("null")
+	}
+	return json.Marshal(event)
+}
+
+// handleAccountLogsStream streams the logs of every transaction mentioning
+// an account as it is processed
+func handleAccountLogsStream(hub *subscriptionHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			http.Error(w, "address parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := decodePubkey(address); err != nil {
+			http.Error(w, "address must be a base58 public key", http.StatusBadRequest)
+			return
+		}
+
+		sub, leave, err := hub.subscribe("logsSubscribe", []interface{}{map[string]interface{}{"mentions": []string{address}}})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer leave()
+
+		serveSSE(w, r, sub, "logs", formatLogNotification)
+	}
+}
+
+// handleSlotStream streams every slot the upstream node processes
+func handleSlotStream(hub *subscriptionHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub, leave, err := hub.subscribe("slotSubscribe", []interface{}{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer leave()
+
+		serveSSE(w, r, sub, "slot", nil)
+	}
+}
+
+// blockTransactionDetails are the transactionDetails levels /stream/blocks
+// accepts
+var blockTransactionDetails = map[string]bool{
+	"none":       true,
+	"signatures": true,
+	"accounts":   true,
+	"full":       true,
+}
+
+// formatBlockNotification unwraps the block of a blockNotification
+func formatBlockNotification(result json.RawMessage) (json.RawMessage, error) {
+	var notification struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(result, &notification); err != nil {
+		return nil, fmt.Errorf("failed to parse block notification: %w", err)
+	}
+	if notification.Value == nil {
+		return nil, fmt.Errorf("block notification has no value")
+	}
+	return notification.Value, nil
+}
+
+// blockSubscribeParams builds the blockSubscribe parameters for the
+// ?transactionDetails= of a request, signatures unless given
+func blockSubscribeParams(query url.Values) ([]interface{}, error) {
+	details := query.Get("transactionDetails")
+	if details == "" {
+		details = "signatures"
+	}
+	if !blockTransactionDetails[details] {
+		return nil, fmt.Errorf("transactionDetails must be none, signatures, accounts or full")
+	}
+
+	config := map[string]interface{}{
+		"encoding":                       "json",
+		"transactionDetails":             details,
+		"showRewards":                    false,
+		"maxSupportedTransactionVersion": defaultMaxTransactionVersion,
+	}
+	return []interface{}{"all", config}, nil
+}
+
+// handleBlockStream streams blocks as they are confirmed. The node must run
+// with --rpc-pubsub-enable-block-subscription.
+func handleBlockStream(hub *subscriptionHub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, err := blockSubscribeParams(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sub, leave, err := hub.subscribe("blockSubscribe", params)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer leave()
+
+		serveSSE(w, r, sub, "block", formatBlockNotification)
+	}
+}
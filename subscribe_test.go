@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSubscriptionServer confirms every subscription as id 42 and pushes
+// the notifications sent to it. Sending "close" drops the connection.
+type fakeSubscriptionServer struct {
+	*httptest.Server
+	dials    int32
+	requests chan RPCRequest
+	notify   chan string
+}
+
+func newFakeSubscriptionServer(t *testing.T) *fakeSubscriptionServer {
+	f := &fakeSubscriptionServer{requests: make(chan RPCRequest, 16), notify: make(chan string)}
+	f.Server = newWSTestServer(t, func(c *wsConn) {
+		atomic.AddInt32(&f.dials, 1)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				message, err := c.readMessage()
+				if err != nil {
+					return
+				}
+				var req RPCRequest
+				json.Unmarshal(message, &req)
+				if strings.HasSuffix(req.Method, "Subscribe") {
+					c.writeText([]byte(`{"jsonrpc":"2.0","result":42,"id":1}`))
+				}
+				f.requests <- req
+			}
+		}()
+
+		for {
+			select {
+			case <-done:
+				return
+			case n := <-f.notify:
+				if n == "close" {
+					c.close()
+					return
+				}
+				c.writeText([]byte(`{"jsonrpc":"2.0","method":"programNotification","params":{"subscription":42,"result":` + n + `}}`))
+			}
+		}
+	})
+	return f
+}
+
+// openStream starts an SSE request and returns a reader over its events
+func openStream(t *testing.T, ctx context.Context, url string) *bufio.Reader {
+	req, _ := http.NewRequestWithContext(ctx, "GET", url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("stream returned wrong status code: got %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	return bufio.NewReader(resp.Body)
+}
+
+// readEvent reads the next event, skipping keep-alive comments
+func readEvent(t *testing.T, r *bufio.Reader) (string, string) {
+	var event, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && event != "":
+			return event, data
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, what string, cond func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHandleProgramStream(t *testing.T) {
+	upstream := newFakeSubscriptionServer(t)
+	defer upstream.Close()
+	hub := newSubscriptionHub(wsEndpoint(upstream.URL))
+	server := httptest.NewServer(handleProgramStream(hub))
+	defer server.Close()
+
+	programID := stakeProgramID
+	url := server.URL + "?programId=" + programID + "&dataSize=200&memcmp=12:" + programID
+	clients := func() float64 { return metrics.value("solana_client_stream_clients") }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := openStream(t, ctx, url)
+	second := openStream(t, ctx, url)
+	waitFor(t, "both clients to subscribe", func() bool { return clients() == 2 })
+
+	subscribe := <-upstream.requests
+	params, _ := json.Marshal(subscribe.Params)
+	expected := `["` + programID + `",{"encoding":"base64","filters":[{"dataSize":200},{"memcmp":{"bytes":"` + programID + `","offset":12}}]}]`
+	if string(params) != expected {
+		t.Errorf("Expected subscribe params %s, got %s", expected, params)
+	}
+
+	upstream.notify <- `{"context":{"slot":5},"value":{"pubkey":"abc"}}`
+	for _, stream := range []*bufio.Reader{first, second} {
+		event, data := readEvent(t, stream)
+		if event != "account" || data != `{"context":{"slot":5},"value":{"pubkey":"abc"}}` {
+			t.Errorf("unexpected event %s: %s", event, data)
+		}
+	}
+	if dials := atomic.LoadInt32(&upstream.dials); dials != 1 {
+		t.Errorf("Expected clients to share 1 upstream subscription, got %d", dials)
+	}
+
+	cancel()
+	select {
+	case req := <-upstream.requests:
+		params, _ := json.Marshal(req.Params)
+		if req.Method != "programUnsubscribe" || string(params) != "[42]" {
+			t.Errorf("Expected programUnsubscribe [42], got %s %s", req.Method, params)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the upstream unsubscribe")
+	}
+	waitFor(t, "clients to leave", func() bool { return clients() == 0 })
+
+	// A client is told when the upstream subscription ends
+	stream := openStream(t, context.Background(), url)
+	waitFor(t, "client to subscribe", func() bool { return clients() == 1 })
+	upstream.notify <- "close"
+	if event, _ := readEvent(t, stream); event != "error" {
+		t.Errorf("Expected error event, got %s", event)
+	}
+}
+
+func TestHandleProgramStreamValidation(t *testing.T) {
+	hub := newSubscriptionHub("ws://127.0.0.1:0")
+
+	tests := []struct {
+		name           string
+		queryParam     string
+		expectedStatus int
+	}{
+		{name: "Missing Program", queryParam: "", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Program", queryParam: "?programId=0OIl", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Data Size", queryParam: "?programId=" + stakeProgramID + "&dataSize=big", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Memcmp", queryParam: "?programId=" + stakeProgramID + "&memcmp=12", expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Unavailable", queryParam: "?programId=" + stakeProgramID, expectedStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/program/stream"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleProgramStream(hub).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestSubscriberDeliverDropsOldest(t *testing.T) {
+	sub := &subscriber{messages: make(chan json.RawMessage, 2)}
+	dropped := func() float64 {
+		return metrics.value("solana_client_stream_dropped_messages_total", "method", "logsSubscribe")
+	}
+	before := dropped()
+
+	for _, message := range []string{"1", "2", "3", "4"} {
+		sub.deliver(json.RawMessage(message), "logsSubscribe")
+	}
+
+	var got []string
+	for len(sub.messages) > 0 {
+		got = append(got, string(<-sub.messages))
+	}
+	if strings.Join(got, ",") != "3,4" {
+		t.Errorf("Expected the newest notifications 3,4, got %v", got)
+	}
+	if delta := dropped() - before; delta != 2 {
+		t.Errorf("Expected 2 dropped notifications, got %v", delta)
+	}
+}
+
+func TestHandleAccountLogsStream(t *testing.T) {
+	upstream := newFakeSubscriptionServer(t)
+	defer upstream.Close()
+	hub := newSubscriptionHub(wsEndpoint(upstream.URL))
+	server := httptest.NewServer(handleAccountLogsStream(hub))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := openStream(t, ctx, server.URL+"?address="+stakeProgramID)
+
+	subscribe := <-upstream.requests
+	params, _ := json.Marshal(subscribe.Params)
+	if subscribe.Method != "logsSubscribe" || string(params) != `[{"mentions":["`+stakeProgramID+`"]}]` {
+		t.Errorf("unexpected subscribe request %s %s", subscribe.Method, params)
+	}
+
+	// Malformed notifications are skipped
+	upstream.notify <- `{"context":{"slot":7},"value":{}}`
+	upstream.notify <- `{"context":{"slot":8},"value":{"signature":"sig","err":null,"logs":["Program log: hi"]}}`
+	event, data := readEvent(t, stream)
+	if event != "logs" || data != `{"slot":8,"signature":"sig","err":null,"logs":["Program log: hi"]}` {
+		t.Errorf("unexpected event %s: %s", event, data)
+	}
+}
+
+func TestHandleAccountLogsStreamValidation(t *testing.T) {
+	hub := newSubscriptionHub("ws://127.0.0.1:0")
+
+	tests := []struct {
+		name           string
+		queryParam     string
+		expectedStatus int
+	}{
+		{name: "Missing Address", queryParam: "", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Address", queryParam: "?address=0OIl", expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Unavailable", queryParam: "?address=" + stakeProgramID, expectedStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/account/logs/stream"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleAccountLogsStream(hub).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestSubscriptionHubClose(t *testing.T) {
+	upstream := newFakeSubscriptionServer(t)
+	defer upstream.Close()
+	hub := newSubscriptionHub(wsEndpoint(upstream.URL))
+	server := httptest.NewServer(handleProgramStream(hub))
+	defer server.Close()
+
+	stream := openStream(t, context.Background(), server.URL+"?programId="+stakeProgramID)
+	<-upstream.requests
+
+	hub.close()
+	if event, _ := readEvent(t, stream); event != "error" {
+		t.Errorf("Expected error event, got %s", event)
+	}
+}
+
+func TestSubscriptionHubReconnectsSilentUpstream(t *testing.T) {
+	var dials int32
+	release := make(chan struct{})
+	defer close(release)
+	upstream := newWSTestServer(t, func(c *wsConn) {
+		n := atomic.AddInt32(&dials, 1)
+		if _, err := c.readMessage(); err != nil {
+			return
+		}
+		c.writeText([]byte(`{"jsonrpc":"2.0","result":42,"id":1}`))
+		if n == 1 {
+			// Stop reading, so pings go unanswered
+			<-release
+			return
+		}
+
+		go func() {
+			for {
+				if _, err := c.readMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		c.writeText([]byte(`{"jsonrpc":"2.0","method":"programNotification","params":{"subscription":42,"result":{"n":1}}}`))
+		<-release
+	})
+	defer upstream.Close()
+
+	hub := newSubscriptionHub(wsEndpoint(upstream.URL))
+	hub.pingInterval = 10 * time.Millisecond
+	hub.readTimeout = 50 * time.Millisecond
+	server := httptest.NewServer(handleProgramStream(hub))
+	defer server.Close()
+
+	stream := openStream(t, context.Background(), server.URL+"?programId="+stakeProgramID)
+	if event, data := readEvent(t, stream); event != "account" || data != `{"n":1}` {
+		t.Errorf("Expected notification from the reopened subscription, got %s %s", event, data)
+	}
+	if got := atomic.LoadInt32(&dials); got != 2 {
+		t.Errorf("Expected 2 upstream connections, got %d", got)
+	}
+	hub.close()
+}
+
+func TestHandleSlotAndBlockStreams(t *testing.T) {
+	upstream := newFakeSubscriptionServer(t)
+	defer upstream.Close()
+	hub := newSubscriptionHub(wsEndpoint(upstream.URL))
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		query          string
+		expectedMethod string
+		expectedParams string
+		notifications  []string
+		expectedEvent  string
+		expectedData   string
+	}{
+		{
+			name:           "Slots",
+			handler:        handleSlotStream(hub),
+			expectedMethod: "slotSubscribe",
+			expectedParams: "[]",
+			notifications:  []string{`{"parent":41,"root":10,"slot":42}`},
+			expectedEvent:  "slot",
+			expectedData:   `{"parent":41,"root":10,"slot":42}`,
+		},
+		{
+			name:           "Blocks",
+			handler:        handleBlockStream(hub),
+			query:          "?transactionDetails=none",
+			expectedMethod: "blockSubscribe",
+			expectedParams: `["all",{"encoding":"json","maxSupportedTransactionVersion":0,"showRewards":false,"transactionDetails":"none"}]`,
+			notifications: []string{
+				`{"context":{"slot":7}}`,
+				`{"context":{"slot":8},"value":{"slot":8,"block":{"blockhash":"abc"},"err":null}}`,
+			},
+			expectedEvent: "block",
+			expectedData:  `{"slot":8,"block":{"blockhash":"abc"},"err":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stream := openStream(t, ctx, server.URL+tt.query)
+
+			// Skip the unsubscribe of the previous case
+			subscribe := <-upstream.requests
+			for strings.HasSuffix(subscribe.Method, "Unsubscribe") {
+				subscribe = <-upstream.requests
+			}
+			params, _ := json.Marshal(subscribe.Params)
+			if subscribe.Method != tt.expectedMethod || string(params) != tt.expectedParams {
+				t.Errorf("unexpected subscribe request %s %s", subscribe.Method, params)
+			}
+
+			for _, n := range tt.notifications {
+				upstream.notify <- n
+			}
+			if event, data := readEvent(t, stream); event != tt.expectedEvent || data != tt.expectedData {
+				t.Errorf("unexpected event %s: %s", event, data)
+			}
+		})
+	}
+}
+
+func TestHandleBlockStreamValidation(t *testing.T) {
+	req := httptest.NewRequest("GET", "/stream/blocks?transactionDetails=some", nil)
+	rr := httptest.NewRecorder()
+	handleBlockStream(newSubscriptionHub("ws://127.0.0.1:0")).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
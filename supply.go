@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// maxLargestAccounts is the number of accounts getLargestAccounts returns
+const maxLargestAccounts = 20
+
+// Supply is the value of getSupply, in lamports
+type Supply struct {
+	Total                  uint64   `json:"total"`
+	Circulating            uint64   `json:"circulating"`
+	NonCirculating         uint64   `json:"nonCirculating"`
+	NonCirculatingAccounts []string `json:"nonCirculatingAccounts"`
+}
+
+// LargestAccount is a single entry of getLargestAccounts
+type LargestAccount struct {
+	Address  string `json:"address"`
+	Lamports uint64 `json:"lamports"`
+}
+
+// supplyResponse is the response of /supply
+type supplyResponse struct {
+	Slot                   uint64   `json:"slot"`
+	TotalLamports          uint64   `json:"total_lamports"`
+	CirculatingLamports    uint64   `json:"circulating_lamports"`
+	NonCirculatingLamports uint64   `json:"non_circulating_lamports"`
+	TotalSOL               float64  `json:"total_sol"`
+	CirculatingSOL         float64  `json:"circulating_sol"`
+	NonCirculatingSOL      float64  `json:"non_circulating_sol"`
+	CirculatingPercent     float64  `json:"circulating_percent"`
+	NonCirculatingAccounts []string `json:"non_circulating_accounts,omitempty"`
+}
+
+// largestAccountEntry is an account listed by /largest-accounts
+type largestAccountEntry struct {
+	Address       string  `json:"address"`
+	Lamports      uint64  `json:"lamports"`
+	SOL           float64 `json:"sol"`
+	SupplyPercent float64 `json:"supply_percent"`
+}
+
+// largestAccountsResponse is the response of /largest-accounts
+type largestAccountsResponse struct {
+	Slot     uint64                `json:"slot"`
+	Filter   string                `json:"filter,omitempty"`
+	Accounts []largestAccountEntry `json:"accounts"`
+}
+
+// getSupply gets the total, circulating and non-circulating supply, with
+// the slot it was computed at. The list of non-circulating accounts is only
+// requested when withAccounts is set, as it is long.
+func (c *rpcClient) getSupply(ctx context.Context, withAccounts bool) (*Supply, uint64, error) {
+	response, err := c.sendRequest(ctx, "getSupply", []interface{}{map[string]interface{}{"excludeNonCirculatingAccountsList": !withAccounts}})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value Supply `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse supply: %w", err)
+	}
+
+	return &result.Value, result.Context.Slot, nil
+}
+
+// getLargestAccounts gets the accounts with the most lamports, optionally
+// only the circulating or non-circulating ones, with the slot they were
+// read at. Nodes cache the result for up to two hours.
+func (c *rpcClient) getLargestAccounts(ctx context.Context, filter string) ([]LargestAccount, uint64, error) {
+	var params []interface{}
+	if filter != "" {
+		params = []interface{}{map[string]interface{}{"filter": filter}}
+	}
+	response, err := c.sendRequest(ctx, "getLargestAccounts", params)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var result struct {
+		Context struct {
+			Slot uint64 `json:"slot"`
+		} `json:"context"`
+		Value []LargestAccount `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse largest accounts: %w", err)
+	}
+
+	return result.Value, result.Context.Slot, nil
+}
+
+func handleGetSupply(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		withAccounts := r.URL.Query().Get("accounts") == "true"
+
+		supply, slot, err := client.getSupply(r.Context(), withAccounts)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := supplyResponse{
+			Slot:                   slot,
+			TotalLamports:          supply.Total,
+			CirculatingLamports:    supply.Circulating,
+			NonCirculatingLamports: supply.NonCirculating,
+			TotalSOL:               float64(supply.Total) / lamportsPerSOL,
+			CirculatingSOL:         float64(supply.Circulating) / lamportsPerSOL,
+			NonCirculatingSOL:      float64(supply.NonCirculating) / lamportsPerSOL,
+		}
+		if supply.Total > 0 {
+			response.CirculatingPercent = float64(supply.Circulating) / float64(supply.Total) * 100
+		}
+		if withAccounts {
+			response.NonCirculatingAccounts = supply.NonCirculatingAccounts
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+func handleGetLargestAccounts(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter := r.URL.Query().Get("filter")
+		if filter != "" && filter != "circulating" && filter != "nonCirculating" {
+			http.Error(w, "filter must be circulating or nonCirculating", http.StatusBadRequest)
+			return
+		}
+		limit := maxLargestAccounts
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxLargestAccounts {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxLargestAccounts), http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		accounts, slot, err := client.getLargestAccounts(r.Context(), filter)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		// The share of the supply is worth the extra call; its account list
+		// is left out
+		supply, _, err := client.getSupply(r.Context(), false)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		if len(accounts) > limit {
+			accounts = accounts[:limit]
+		}
+		response := largestAccountsResponse{Slot: slot, Filter: filter, Accounts: make([]largestAccountEntry, 0, len(accounts))}
+		for _, account := range accounts {
+			entry := largestAccountEntry{Address: account.Address, Lamports: account.Lamports, SOL: float64(account.Lamports) / lamportsPerSOL}
+			if supply.Total > 0 {
+				entry.SupplyPercent = float64(account.Lamports) / float64(supply.Total) * 100
+			}
+			response.Accounts = append(response.Accounts, entry)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetSupply(t *testing.T) {
+	supply := &Supply{Total: 4 * lamportsPerSOL, Circulating: 3 * lamportsPerSOL, NonCirculating: lamportsPerSOL, NonCirculatingAccounts: []string{"locked"}}
+
+	tests := []struct {
+		name             string
+		mockClient       mockRPCClient
+		queryParam       string
+		expectedStatus   int
+		expectedAccounts int
+	}{
+		{name: "Supply", mockClient: mockRPCClient{supply: supply, latestSlot: 900}, expectedStatus: http.StatusOK},
+		{name: "With Accounts", mockClient: mockRPCClient{supply: supply, latestSlot: 900}, queryParam: "?accounts=true", expectedStatus: http.StatusOK, expectedAccounts: 1},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC connection failed"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/supply"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetSupply(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response supplyResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Slot != 900 || response.CirculatingSOL != 3 || response.NonCirculatingSOL != 1 || response.CirculatingPercent != 75 {
+				t.Errorf("unexpected supply: %+v", response)
+			}
+			if len(response.NonCirculatingAccounts) != tt.expectedAccounts {
+				t.Errorf("Expected %d non-circulating accounts, got %d", tt.expectedAccounts, len(response.NonCirculatingAccounts))
+			}
+			if asked := tt.mockClient.supplyLists[0]; asked != (tt.expectedAccounts > 0) {
+				t.Errorf("Expected the account list to be requested only when asked for, got %v", asked)
+			}
+		})
+	}
+}
+
+func TestHandleGetLargestAccounts(t *testing.T) {
+	largest := []LargestAccount{{Address: "whale", Lamports: 2 * lamportsPerSOL}, {Address: "dolphin", Lamports: lamportsPerSOL}}
+	supply := &Supply{Total: 8 * lamportsPerSOL}
+
+	tests := []struct {
+		name             string
+		mockClient       mockRPCClient
+		queryParam       string
+		expectedStatus   int
+		expectedFilter   string
+		expectedAccounts int
+	}{
+		{name: "All", mockClient: mockRPCClient{largest: largest, supply: supply}, expectedStatus: http.StatusOK, expectedAccounts: 2},
+		{name: "Circulating", mockClient: mockRPCClient{largest: largest, supply: supply}, queryParam: "?filter=circulating&limit=1", expectedStatus: http.StatusOK, expectedFilter: "circulating", expectedAccounts: 1},
+		{name: "Invalid Filter", queryParam: "?filter=all", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Limit", queryParam: "?limit=21", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC connection failed"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/largest-accounts"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetLargestAccounts(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response largestAccountsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if tt.mockClient.largestFilter != tt.expectedFilter || response.Filter != tt.expectedFilter {
+				t.Errorf("Expected filter %q, got %q", tt.expectedFilter, tt.mockClient.largestFilter)
+			}
+			if len(response.Accounts) != tt.expectedAccounts {
+				t.Fatalf("Expected %d accounts, got %d", tt.expectedAccounts, len(response.Accounts))
+			}
+			if whale := response.Accounts[0]; whale.SOL != 2 || whale.SupplyPercent != 25 {
+				t.Errorf("unexpected account: %+v", whale)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Tenant settings
+const (
+	tenantHeader       = "X-Tenant-ID"
+	defaultTenant      = "default"
+	defaultTenantBurst = 20
+)
+
+// tenantPattern restricts tenant ids to short, label-safe identifiers
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,63}$`)
+
+type tenantContextKey struct{}
+
+// tenantFromContext returns the tenant a request was tagged with
+func tenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantContextKey{}).(string); ok {
+		return tenant
+	}
+	return defaultTenant
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// withTenant tags each request with the tenant named in the X-Tenant-ID
+// header, or the default tenant when it is absent, and counts requests per
+// tenant. Malformed tenant ids are rejected so they cannot pollute metrics.
+// With a limiter, tenants over their rate are answered with 429, except on
+// the exempt paths. Server errors are logged with the tenant that hit them.
+func withTenant(next http.Handler, limiter *keyedLimiter, exempt ...string) http.Handler {
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenant := defaultTenant
+		if value := r.Header.Get(tenantHeader); value != "" {
+			if !tenantPattern.MatchString(value) {
+				http.Error(w, "invalid "+tenantHeader+" header", http.StatusBadRequest)
+				return
+			}
+			tenant = value
+		}
+
+		allowed, wait := true, time.Duration(0)
+		if limiter != nil && !exemptPaths[r.URL.Path] {
+			allowed, wait = limiter.allow(tenant)
+		}
+
+		rec := &statusRecorder{ResponseWriter: w}
+		if allowed {
+			next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), tenantContextKey{}, tenant)))
+		} else {
+			retryAfter(rec, wait, "tenant rate limit exceeded")
+		}
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if rec.status >= http.StatusInternalServerError {
+			logFields("server error", "request_id", requestID(r.Context()), "tenant", tenant, "method", r.Method, "path", r.URL.Path, "status", rec.status)
+		}
+		metrics.addCounter("solana_client_tenant_requests_total", "HTTP requests by tenant and status code.", 1,
+			"tenant", tenant, "code", strconv.Itoa(rec.status))
+	})
+}
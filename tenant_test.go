@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithTenant(t *testing.T) {
+	var seen string
+	handler := withTenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = tenantFromContext(r.Context())
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}), nil)
+
+	tests := []struct {
+		name           string
+		header         string
+		path           string
+		expectedStatus int
+		expectedTenant string
+	}{
+		{name: "Default Tenant", path: "/latest-block", expectedStatus: http.StatusOK, expectedTenant: defaultTenant},
+		{name: "Named Tenant", header: "team-a", path: "/latest-block", expectedStatus: http.StatusOK, expectedTenant: "team-a"},
+		{name: "Named Tenant Error", header: "team-a", path: "/missing", expectedStatus: http.StatusNotFound, expectedTenant: "team-a"},
+		{name: "Invalid Tenant", header: "team a\"}", path: "/latest-block", expectedStatus: http.StatusBadRequest},
+	}
+
+	okBefore := metrics.value("solana_client_tenant_requests_total", "tenant", "team-a", "code", "200")
+	notFoundBefore := metrics.value("solana_client_tenant_requests_total", "tenant", "team-a", "code", "404")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seen = ""
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(tenantHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if seen != tt.expectedTenant {
+				t.Errorf("Expected tenant %q, got %q", tt.expectedTenant, seen)
+			}
+		})
+	}
+
+	if count := metrics.value("solana_client_tenant_requests_total", "tenant", "team-a", "code", "200") - okBefore; count != 1 {
+		t.Errorf("Expected 1 successful request for team-a, got %v", count)
+	}
+	if count := metrics.value("solana_client_tenant_requests_total", "tenant", "team-a", "code", "404") - notFoundBefore; count != 1 {
+		t.Errorf("Expected 1 not found request for team-a, got %v", count)
+	}
+}
+
+func TestWithTenantRateLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newKeyedLimiter(1, 2)
+	limiter.now = func() time.Time { return now }
+	handler := withTenant(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), limiter, "/healthz/all")
+
+	tests := []struct {
+		name           string
+		tenant         string
+		path           string
+		advance        time.Duration
+		expectedStatus int
+	}{
+		{name: "First Of Burst", tenant: "team-a", path: "/latest-block", expectedStatus: http.StatusOK},
+		{name: "Second Of Burst", tenant: "team-a", path: "/latest-block", expectedStatus: http.StatusOK},
+		{name: "Over Rate", tenant: "team-a", path: "/latest-block", expectedStatus: http.StatusTooManyRequests},
+		{name: "Exempt Path", tenant: "team-a", path: "/healthz/all", expectedStatus: http.StatusOK},
+		{name: "Other Tenant", tenant: "team-b", path: "/latest-block", expectedStatus: http.StatusOK},
+		{name: "Refilled", tenant: "team-a", path: "/latest-block", advance: time.Second, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set(tenantHeader, tt.tenant)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if limited := rr.Header().Get("Retry-After") != ""; limited != (tt.expectedStatus == http.StatusTooManyRequests) {
+				t.Errorf("Expected Retry-After only on limited requests, got %q", rr.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
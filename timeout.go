@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"time"
+)
+
+// Request timeout settings
+const (
+	defaultRequestTimeout = 30 * time.Second
+	maxRequestTimeout     = 60 * time.Second
+	requestTimeoutHeader  = "X-Request-Timeout"
+	requestTimedOutBody   = "request timed out"
+)
+
+// withRequestTimeout bounds the total time spent serving each request,
+// covering every retry and sub-call, independently of the per-attempt HTTP
+// client timeout. Clients may ask for a different timeout with the
+// X-Request-Timeout header (a Go duration such as "5s"), capped at max. The
+// deadline is set on the request context, and a request that runs over it
+// is answered with 503. Long-lived streams on the exempt paths are served
+// without a timeout.
+func withRequestTimeout(next http.Handler, timeout, max time.Duration, exempt ...string) http.Handler {
+	exemptPaths := make(map[string]bool, len(exempt))
+	for _, path := range exempt {
+		exemptPaths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if exemptPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		d := timeout
+		if value := r.Header.Get(requestTimeoutHeader); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "invalid "+requestTimeoutHeader+" header", http.StatusBadRequest)
+				return
+			}
+			d = parsed
+		}
+		if d > max {
+			d = max
+		}
+
+		http.TimeoutHandler(next, d, requestTimedOutBody).ServeHTTP(w, r)
+	})
+}
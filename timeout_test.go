@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithRequestTimeout(t *testing.T) {
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(200 * time.Millisecond):
+			w.Write([]byte("done"))
+		case <-r.Context().Done():
+		}
+	})
+
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		max            time.Duration
+		header         string
+		expectedStatus int
+	}{
+		{name: "Completes Within Default", timeout: time.Second, max: time.Second, expectedStatus: http.StatusOK},
+		{name: "Default Timeout Exceeded", timeout: 20 * time.Millisecond, max: time.Second, expectedStatus: http.StatusServiceUnavailable},
+		{name: "Header Shortens Timeout", timeout: time.Second, max: time.Second, header: "20ms", expectedStatus: http.StatusServiceUnavailable},
+		{name: "Header Bounded By Max", timeout: 20 * time.Millisecond, max: 40 * time.Millisecond, header: "10s", expectedStatus: http.StatusServiceUnavailable},
+		{name: "Invalid Header", timeout: time.Second, max: time.Second, header: "soon", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/latest-block", nil)
+			if tt.header != "" {
+				req.Header.Set(requestTimeoutHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+
+			start := time.Now()
+			withRequestTimeout(slow, tt.timeout, tt.max).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus == http.StatusServiceUnavailable && time.Since(start) > 150*time.Millisecond {
+				t.Errorf("Expected the timeout to cut the request short, took %v", time.Since(start))
+			}
+		})
+	}
+}
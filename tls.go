@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// tlsReloadCheckInterval bounds how often handshakes look for rotated
+// certificate files
+const tlsReloadCheckInterval = 10 * time.Second
+
+// tlsFiles serves TLS with a certificate, and optionally a client CA bundle
+// for mutual TLS, read from files. The files are read again once they
+// change, so that rotated certificates are picked up without a restart.
+type tlsFiles struct {
+	certFile, keyFile string
+	// clientCAFile, when set, requires clients to present a certificate
+	// signed by one of its CAs
+	clientCAFile string
+	checkEvery   time.Duration
+
+	mu      sync.Mutex
+	config  *tls.Config
+	modTime map[string]time.Time
+	checked time.Time
+}
+
+// newTLSFiles reads the files, failing if they do not make a usable config
+func newTLSFiles(certFile, keyFile, clientCAFile string) (*tlsFiles, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS needs both a certificate and a key file")
+	}
+	files := &tlsFiles{certFile: certFile, keyFile: keyFile, clientCAFile: clientCAFile, checkEvery: tlsReloadCheckInterval}
+	modTime, err := files.modTimes()
+	if err != nil {
+		return nil, err
+	}
+	if files.config, err = files.load(); err != nil {
+		return nil, err
+	}
+	files.modTime, files.checked = modTime, time.Now()
+	return files, nil
+}
+
+// serverConfig is the TLS config of an http.Server, resolving the current
+// files on every handshake
+func (f *tlsFiles) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return f.current(), nil
+		},
+	}
+}
+
+func (f *tlsFiles) paths() []string {
+	paths := []string{f.certFile, f.keyFile}
+	if f.clientCAFile != "" {
+		paths = append(paths, f.clientCAFile)
+	}
+	return paths
+}
+
+func (f *tlsFiles) modTimes() (map[string]time.Time, error) {
+	modTime := make(map[string]time.Time)
+	for _, path := range f.paths() {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		modTime[path] = info.ModTime()
+	}
+	return modTime, nil
+}
+
+// load reads the certificate, key and client CAs into a config
+func (f *tlsFiles) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	config := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{cert}}
+	if f.clientCAFile != "" {
+		pem, err := os.ReadFile(f.clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS client CAs: %w", err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		if !config.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in TLS client CA file %s", f.clientCAFile)
+		}
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// current returns the config, reloading it at most every checkEvery when a
+// file has changed. A failed reload, as while a rotation has replaced the
+// certificate but not yet the key, keeps the previous config.
+func (f *tlsFiles) current() *tls.Config {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(f.checked) < f.checkEvery {
+		return f.config
+	}
+	f.checked = now
+	modTime, err := f.modTimes()
+	if err != nil {
+		logFields("failed to check TLS files", "error", err)
+		return f.config
+	}
+	changed := false
+	for path, t := range modTime {
+		changed = changed || !t.Equal(f.modTime[path])
+	}
+	if !changed {
+		return f.config
+	}
+	config, err := f.load()
+	if err != nil {
+		logFields("failed to reload TLS files", "error", err)
+		return f.config
+	}
+	f.config, f.modTime = config, modTime
+	logFields("reloaded TLS certificate", "cert", f.certFile)
+	return f.config
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCertificate issues a certificate for name, signed by parent or self
+// signed, returning it with its key
+func testCertificate(t *testing.T, name string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewTLSFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, _, certPEM, keyPEM := testCertificate(t, "server", false, nil, nil)
+	writeFile(t, filepath.Join(dir, "cert.pem"), certPEM, time.Now())
+	writeFile(t, filepath.Join(dir, "key.pem"), keyPEM, time.Now())
+	writeFile(t, filepath.Join(dir, "empty.pem"), []byte("not a certificate"), time.Now())
+
+	tests := []struct {
+		name        string
+		cert, key   string
+		clientCA    string
+		expectedErr string
+	}{
+		{name: "Certificate", cert: "cert.pem", key: "key.pem"},
+		{name: "Client CA", cert: "cert.pem", key: "key.pem", clientCA: "cert.pem"},
+		{name: "Missing Key", cert: "cert.pem", expectedErr: "both a certificate and a key"},
+		{name: "Missing File", cert: "cert.pem", key: "missing.pem", expectedErr: "no such file"},
+		{name: "Mismatched Files", cert: "key.pem", key: "cert.pem", expectedErr: "failed to load TLS certificate"},
+		{name: "Empty Client CA", cert: "cert.pem", key: "key.pem", clientCA: "empty.pem", expectedErr: "no certificates"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := func(name string) string {
+				if name == "" {
+					return ""
+				}
+				return filepath.Join(dir, name)
+			}
+			files, err := newTLSFiles(path(tt.cert), path(tt.key), path(tt.clientCA))
+			if tt.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tt.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if mutual := files.current().ClientAuth == tls.RequireAndVerifyClientCert; mutual != (tt.clientCA != "") {
+				t.Errorf("Expected mutual TLS %v, got %v", tt.clientCA != "", mutual)
+			}
+		})
+	}
+}
+
+func TestRunServersTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey, caPEM, _ := testCertificate(t, "ca", true, nil, nil)
+	_, _, certPEM, keyPEM := testCertificate(t, "server-1", false, ca, caKey)
+	_, _, clientCertPEM, clientKeyPEM := testCertificate(t, "client", false, ca, caKey)
+	_, _, rotatedPEM, rotatedKeyPEM := testCertificate(t, "server-2", false, ca, caKey)
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	start := time.Now().Add(-time.Minute)
+	writeFile(t, certFile, certPEM, start)
+	writeFile(t, keyFile, keyPEM, start)
+	writeFile(t, caFile, caPEM, start)
+
+	files, err := newTLSFiles(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files.checkEvery = 0
+	srv := &http.Server{Addr: freeAddr(t), TLSConfig: files.serverConfig(), Handler: http.NotFoundHandler()}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- runServers(ctx, shutdownTimeout, srv) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(caPEM)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// get returns the common name of the server certificate
+	get := func(certs []tls.Certificate) (string, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}, DisableKeepAlives: true}}
+		resp, err := client.Get("https://" + srv.Addr + "/")
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName, nil
+	}
+	waitFor(t, "the TLS listener", func() bool {
+		_, err := get([]tls.Certificate{clientCert})
+		return err == nil
+	})
+
+	if name, err := get([]tls.Certificate{clientCert}); err != nil || name != "server-1" {
+		t.Errorf("Expected server-1, got %q, %v", name, err)
+	}
+	if _, err := get(nil); err == nil {
+		t.Error("Expected a client without a certificate to be refused")
+	}
+
+	// A half-done rotation keeps the previous certificate
+	writeFile(t, certFile, rotatedPEM, time.Now())
+	if name, err := get([]tls.Certificate{clientCert}); err != nil || name != "server-1" {
+		t.Errorf("Expected server-1 during the rotation, got %q, %v", name, err)
+	}
+	writeFile(t, keyFile, rotatedKeyPEM, time.Now())
+	if name, err := get([]tls.Certificate{clientCert}); err != nil || name != "server-2" {
+		t.Errorf("Expected server-2 after the rotation, got %q, %v", name, err)
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxTokenMints caps /associated-token-addresses so that the mints, and the
+// token accounts when balances are requested, each fit in one
+// getMultipleAccounts call
+const maxTokenMints = 50
+
+// SPL token account layouts
+const (
+	tokenAccountSize   = 165
+	mintAccountSize    = 82
+	mintDecimalsOffset = 44
+	tokenAmountOffset  = 64
+)
+
+// associatedTokenAccount is one mint's entry in /associated-token-addresses.
+// Balance fields are only set when balances are requested.
+type associatedTokenAccount struct {
+	Mint         string `json:"mint"`
+	Address      string `json:"address"`
+	TokenProgram string `json:"token_program"`
+	Exists       *bool  `json:"exists,omitempty"`
+	Amount       string `json:"amount,omitempty"`
+	Decimals     *uint8 `json:"decimals,omitempty"`
+	UIAmount     string `json:"ui_amount,omitempty"`
+}
+
+// associatedTokenAddressesResponse is the response of /associated-token-addresses
+type associatedTokenAddressesResponse struct {
+	Owner    string                   `json:"owner"`
+	Accounts []associatedTokenAccount `json:"accounts"`
+}
+
+// tokenAmount reads the balance of an SPL token account holding mint
+func tokenAmount(account *AccountInfo, mint string) (uint64, error) {
+	if !tokenPrograms[account.Owner] {
+		return 0, fmt.Errorf("account is not owned by a token program")
+	}
+	data, err := account.rawData()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < tokenAccountSize {
+		return 0, errBorshEOF
+	}
+	if base58Encode(data[:32]) != mint {
+		return 0, fmt.Errorf("token account does not hold mint %s", mint)
+	}
+	return binary.LittleEndian.Uint64(data[tokenAmountOffset:]), nil
+}
+
+// mintDecimals reads the decimals of an SPL token mint. Token-2022 mints
+// share the original layout, followed by their extensions.
+func mintDecimals(account *AccountInfo) (uint8, error) {
+	if !tokenPrograms[account.Owner] {
+		return 0, fmt.Errorf("account is not owned by a token program")
+	}
+	data, err := account.rawData()
+	if err != nil {
+		return 0, err
+	}
+	if len(data) < mintAccountSize {
+		return 0, errBorshEOF
+	}
+	return data[mintDecimalsOffset], nil
+}
+
+// formatTokenAmount renders a raw token amount as a decimal string
+func formatTokenAmount(amount uint64, decimals uint8) string {
+	digits := strconv.FormatUint(amount, 10)
+	if decimals == 0 {
+		return digits
+	}
+	if pad := int(decimals) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	whole, fraction := digits[:len(digits)-int(decimals)], strings.TrimRight(digits[len(digits)-int(decimals):], "0")
+	if fraction == "" {
+		return whole
+	}
+	return whole + "." + fraction
+}
+
+// tokenProgramOf returns the token program owning mint, falling back to the
+// original program for mints that do not exist
+func tokenProgramOf(mint *AccountInfo) string {
+	if mint != nil && tokenPrograms[mint.Owner] {
+		return mint.Owner
+	}
+	return tokenProgramID
+}
+
+// fillBalances fetches every token account in one call and records their
+// balances, scaled by the decimals of mints that could be decoded
+func fillBalances(ctx context.Context, client SolanaRPCClient, accounts []associatedTokenAccount, mints []*AccountInfo) error {
+	addresses := make([]string, len(accounts))
+	for i, account := range accounts {
+		addresses[i] = account.Address
+	}
+
+	infos, err := client.getMultipleAccounts(ctx, addresses)
+	if err != nil {
+		return err
+	}
+
+	for i := range accounts {
+		exists := infos[i] != nil
+		accounts[i].Exists = &exists
+
+		var amount uint64
+		if exists {
+			if amount, err = tokenAmount(infos[i], accounts[i].Mint); err != nil {
+				return fmt.Errorf("failed to read token account %s: %w", accounts[i].Address, err)
+			}
+		}
+		accounts[i].Amount = strconv.FormatUint(amount, 10)
+
+		if mints[i] == nil {
+			continue
+		}
+		decimals, err := mintDecimals(mints[i])
+		if err != nil {
+			continue
+		}
+		accounts[i].Decimals = &decimals
+		accounts[i].UIAmount = formatTokenAmount(amount, decimals)
+	}
+	return nil
+}
+
+func handleGetAssociatedTokenAddresses(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner := r.URL.Query().Get("owner")
+		if owner == "" {
+			http.Error(w, "owner parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		mints := parseAccountList(r.URL.Query().Get("mints"))
+		if len(mints) == 0 {
+			http.Error(w, "mints parameter is required", http.StatusBadRequest)
+			return
+		}
+		if len(mints) > maxTokenMints {
+			http.Error(w, fmt.Sprintf("at most %d mints are allowed", maxTokenMints), http.StatusBadRequest)
+			return
+		}
+
+		for _, key := range append([]string{owner}, mints...) {
+			if _, err := decodePubkey(key); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// The address depends on which token program owns the mint
+		mintInfos, err := client.getMultipleAccounts(r.Context(), mints)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := associatedTokenAddressesResponse{Owner: owner, Accounts: make([]associatedTokenAccount, len(mints))}
+		for i, mint := range mints {
+			program := tokenProgramOf(mintInfos[i])
+			address, err := associatedTokenAddressFor(owner, mint, program)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			response.Accounts[i] = associatedTokenAccount{Mint: mint, Address: address, TokenProgram: program}
+		}
+
+		if r.URL.Query().Get("withBalances") == "true" {
+			if err := fillBalances(r.Context(), client, response.Accounts, mintInfos); err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+// TokenAccount is an SPL token account with its balance
+type TokenAccount struct {
+	Address      string `json:"address"`
+	Mint         string `json:"mint"`
+	Owner        string `json:"owner"`
+	TokenProgram string `json:"token_program"`
+	Amount       string `json:"amount"`
+	Decimals     uint8  `json:"decimals"`
+	UIAmount     string `json:"ui_amount"`
+}
+
+// TokenBalance is the balance of an SPL token account
+type TokenBalance struct {
+	Amount   string `json:"amount"`
+	Decimals uint8  `json:"decimals"`
+	UIAmount string `json:"ui_amount"`
+}
+
+// rpcTokenAmount is the tokenAmount object of jsonParsed token accounts and
+// of getTokenAccountBalance
+type rpcTokenAmount struct {
+	Amount         string `json:"amount"`
+	Decimals       uint8  `json:"decimals"`
+	UIAmountString string `json:"uiAmountString"`
+}
+
+func (a rpcTokenAmount) balance() TokenBalance {
+	return TokenBalance{Amount: a.Amount, Decimals: a.Decimals, UIAmount: a.UIAmountString}
+}
+
+// getTokenAccountsByOwner lists the token accounts of owner under program,
+// or only those holding mint when it is set. The node parses the accounts,
+// which covers Token-2022 extensions without decoding them here.
+func (c *rpcClient) getTokenAccountsByOwner(ctx context.Context, owner, program, mint string) ([]TokenAccount, error) {
+	filter := map[string]interface{}{"programId": program}
+	if mint != "" {
+		filter = map[string]interface{}{"mint": mint}
+	}
+	response, err := c.sendRequest(ctx, "getTokenAccountsByOwner", []interface{}{
+		owner,
+		filter,
+		map[string]interface{}{"encoding": "jsonParsed"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value []struct {
+			Pubkey  string `json:"pubkey"`
+			Account struct {
+				Owner string `json:"owner"`
+				Data  struct {
+					Parsed struct {
+						Info struct {
+							Mint        string         `json:"mint"`
+							Owner       string         `json:"owner"`
+							TokenAmount rpcTokenAmount `json:"tokenAmount"`
+						} `json:"info"`
+					} `json:"parsed"`
+				} `json:"data"`
+			} `json:"account"`
+		} `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse token accounts: %w", err)
+	}
+
+	accounts := make([]TokenAccount, len(result.Value))
+	for i, v := range result.Value {
+		info := v.Account.Data.Parsed.Info
+		balance := info.TokenAmount.balance()
+		accounts[i] = TokenAccount{
+			Address:      v.Pubkey,
+			Mint:         info.Mint,
+			Owner:        info.Owner,
+			TokenProgram: v.Account.Owner,
+			Amount:       balance.Amount,
+			Decimals:     balance.Decimals,
+			UIAmount:     balance.UIAmount,
+		}
+	}
+	return accounts, nil
+}
+
+// getTokenAccountBalance gets the balance of a token account
+func (c *rpcClient) getTokenAccountBalance(ctx context.Context, address string) (*TokenBalance, error) {
+	response, err := c.sendRequest(ctx, "getTokenAccountBalance", []interface{}{address})
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Value rpcTokenAmount `json:"value"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse token balance: %w", err)
+	}
+	balance := result.Value.balance()
+	return &balance, nil
+}
+
+// tokensResponse is the response of /tokens
+type tokensResponse struct {
+	Owner    string         `json:"owner"`
+	Accounts []TokenAccount `json:"accounts"`
+}
+
+// handleGetTokens lists the token accounts of ?owner= under both token
+// programs, or only those holding ?mint=
+func handleGetTokens(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner := r.URL.Query().Get("owner")
+		if owner == "" {
+			http.Error(w, "owner parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := decodePubkey(owner); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mint := r.URL.Query().Get("mint")
+		if mint != "" {
+			if _, err := decodePubkey(mint); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// A mint filter finds the accounts whichever program owns the mint
+		programs := []string{tokenProgramID, token2022ProgramID}
+		if mint != "" {
+			programs = []string{""}
+		}
+
+		response := tokensResponse{Owner: owner, Accounts: []TokenAccount{}}
+		for _, program := range programs {
+			accounts, err := client.getTokenAccountsByOwner(r.Context(), owner, program, mint)
+			if err != nil {
+				http.Error(w, err.Error(), upstreamStatus(err))
+				return
+			}
+			response.Accounts = append(response.Accounts, accounts...)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
+
+// tokenBalanceResponse is the response of /token-balance
+type tokenBalanceResponse struct {
+	Account string `json:"account"`
+	TokenBalance
+}
+
+func handleGetTokenBalance(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		account := r.URL.Query().Get("account")
+		if account == "" {
+			http.Error(w, "account parameter is required", http.StatusBadRequest)
+			return
+		}
+		if _, err := decodePubkey(account); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		balance, err := client.getTokenAccountBalance(r.Context(), account)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(tokenBalanceResponse{Account: account, TokenBalance: *balance})
+		w.Write(jsonData)
+	}
+}
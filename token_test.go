@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+// Mainnet USDC, an owner and the owner's associated USDC account
+const (
+	testOwner    = "9WzDXwBbmkg8ZTbNMqUxvQRAyrZzDsGYdLVL9zYtAWWM"
+	testMint     = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	testOwnerATA = "FGETo8T8wMcN2wCjav8VK6eh3dLk63evNDPxzLSJra8B"
+)
+
+func encodeTokenAccount(mint, owner string, amount uint64) []byte {
+	mintKey, _ := decodePubkey(mint)
+	ownerKey, _ := decodePubkey(owner)
+	data := append(append([]byte{}, mintKey...), ownerKey...)
+	data = binary.LittleEndian.AppendUint64(data, amount)
+	return append(data, make([]byte, tokenAccountSize-len(data))...)
+}
+
+func encodeMint(decimals uint8) []byte {
+	data := make([]byte, mintAccountSize)
+	data[mintDecimalsOffset] = decimals
+	return data
+}
+
+func TestHandleGetAssociatedTokenAddresses(t *testing.T) {
+	otherMint := base58Encode(bytes.Repeat([]byte{9}, 32))
+	otherATA, _ := associatedTokenAddress(testOwner, otherMint)
+	token2022Mint := base58Encode(bytes.Repeat([]byte{7}, 32))
+	token2022ATA, _ := associatedTokenAddressFor(testOwner, token2022Mint, token2022ProgramID)
+	brokenMint := base58Encode(bytes.Repeat([]byte{5}, 32))
+	brokenATA, _ := associatedTokenAddress(testOwner, brokenMint)
+	accounts := map[string]*AccountInfo{
+		testOwnerATA:  testAccount(tokenProgramID, encodeTokenAccount(testMint, testOwner, 1234500)),
+		testMint:      testAccount(tokenProgramID, encodeMint(6)),
+		otherMint:     testAccount(tokenProgramID, encodeMint(0)),
+		token2022ATA:  testAccount(token2022ProgramID, encodeTokenAccount(token2022Mint, testOwner, 250)),
+		token2022Mint: testAccount(token2022ProgramID, append(encodeMint(2), make([]byte, 84)...)),
+		brokenATA:     testAccount(tokenProgramID, encodeTokenAccount(brokenMint, testOwner, 9)),
+		brokenMint:    testAccount(tokenProgramID, make([]byte, 10)),
+	}
+
+	tests := []struct {
+		name             string
+		mockClient       mockRPCClient
+		queryParam       string
+		expectedStatus   int
+		expectedAccounts []associatedTokenAccount
+	}{
+		{
+			name:           "Addresses Only",
+			mockClient:     mockRPCClient{accounts: accounts},
+			queryParam:     "?owner=" + testOwner + "&mints=" + testMint + "," + otherMint + "," + token2022Mint,
+			expectedStatus: http.StatusOK,
+			expectedAccounts: []associatedTokenAccount{
+				{Mint: testMint, Address: testOwnerATA, TokenProgram: tokenProgramID},
+				{Mint: otherMint, Address: otherATA, TokenProgram: tokenProgramID},
+				{Mint: token2022Mint, Address: token2022ATA, TokenProgram: token2022ProgramID},
+			},
+		},
+		{
+			name:           "With Balances",
+			mockClient:     mockRPCClient{accounts: accounts},
+			queryParam:     "?owner=" + testOwner + "&mints=" + testMint + "," + otherMint + "&withBalances=true",
+			expectedStatus: http.StatusOK,
+			expectedAccounts: []associatedTokenAccount{
+				{Mint: testMint, Address: testOwnerATA, TokenProgram: tokenProgramID, Amount: "1234500", UIAmount: "1.2345"},
+				{Mint: otherMint, Address: otherATA, TokenProgram: tokenProgramID, Amount: "0", UIAmount: "0"},
+			},
+		},
+		{
+			name:           "Token-2022 And Undecodable Mints",
+			mockClient:     mockRPCClient{accounts: accounts},
+			queryParam:     "?owner=" + testOwner + "&mints=" + token2022Mint + "," + brokenMint + "&withBalances=true",
+			expectedStatus: http.StatusOK,
+			expectedAccounts: []associatedTokenAccount{
+				{Mint: token2022Mint, Address: token2022ATA, TokenProgram: token2022ProgramID, Amount: "250", UIAmount: "2.5"},
+				{Mint: brokenMint, Address: brokenATA, TokenProgram: tokenProgramID, Amount: "9"},
+			},
+		},
+		{name: "Missing Owner", mockClient: mockRPCClient{}, queryParam: "?mints=" + testMint, expectedStatus: http.StatusBadRequest},
+		{name: "Missing Mints", mockClient: mockRPCClient{}, queryParam: "?owner=" + testOwner, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Mint", mockClient: mockRPCClient{}, queryParam: "?owner=" + testOwner + "&mints=abc", expectedStatus: http.StatusBadRequest},
+		{name: "Too Many Mints", mockClient: mockRPCClient{}, queryParam: "?owner=" + testOwner + "&mints=" + string(bytes.Repeat([]byte(testMint+","), maxTokenMints+1)), expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, queryParam: "?owner=" + testOwner + "&mints=" + testMint, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/associated-token-addresses"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetAssociatedTokenAddresses(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response associatedTokenAddressesResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if len(response.Accounts) != len(tt.expectedAccounts) {
+				t.Fatalf("Expected %d accounts, got %d", len(tt.expectedAccounts), len(response.Accounts))
+			}
+			for i, expected := range tt.expectedAccounts {
+				got := response.Accounts[i]
+				if got.Mint != expected.Mint || got.Address != expected.Address || got.TokenProgram != expected.TokenProgram || got.Amount != expected.Amount || got.UIAmount != expected.UIAmount {
+					t.Errorf("Expected %+v, got %+v", expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatTokenAmount(t *testing.T) {
+	tests := []struct {
+		amount   uint64
+		decimals uint8
+		expected string
+	}{
+		{amount: 1234500, decimals: 6, expected: "1.2345"},
+		{amount: 5, decimals: 9, expected: "0.000000005"},
+		{amount: 1000000, decimals: 6, expected: "1"},
+		{amount: 0, decimals: 6, expected: "0"},
+		{amount: 42, decimals: 0, expected: "42"},
+	}
+
+	for _, tt := range tests {
+		if got := formatTokenAmount(tt.amount, tt.decimals); got != tt.expected {
+			t.Errorf("formatTokenAmount(%d, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.expected)
+		}
+	}
+}
+
+func TestHandleGetTokens(t *testing.T) {
+	usdc := TokenAccount{Address: testOwnerATA, Mint: testMint, Owner: testOwner, TokenProgram: tokenProgramID, Amount: "1500000", Decimals: 6, UIAmount: "1.5"}
+	other := TokenAccount{Address: stakeProgramID, Mint: voteProgramID, Owner: testOwner, TokenProgram: token2022ProgramID, Amount: "7", Decimals: 0, UIAmount: "7"}
+	accounts := []TokenAccount{usdc, other}
+
+	tests := []struct {
+		name           string
+		query          string
+		mock           mockRPCClient
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Both Programs",
+			query:          "?owner=" + testOwner,
+			mock:           mockRPCClient{tokenAccts: accounts},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"owner":"` + testOwner + `","accounts":[` +
+				`{"address":"` + testOwnerATA + `","mint":"` + testMint + `","owner":"` + testOwner + `","token_program":"` + tokenProgramID + `","amount":"1500000","decimals":6,"ui_amount":"1.5"},` +
+				`{"address":"` + stakeProgramID + `","mint":"` + voteProgramID + `","owner":"` + testOwner + `","token_program":"` + token2022ProgramID + `","amount":"7","decimals":0,"ui_amount":"7"}]}`,
+		},
+		{
+			name:           "Mint Filter",
+			query:          "?owner=" + testOwner + "&mint=" + voteProgramID,
+			mock:           mockRPCClient{tokenAccts: accounts},
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"owner":"` + testOwner + `","accounts":[` +
+				`{"address":"` + stakeProgramID + `","mint":"` + voteProgramID + `","owner":"` + testOwner + `","token_program":"` + token2022ProgramID + `","amount":"7","decimals":0,"ui_amount":"7"}]}`,
+		},
+		{name: "No Accounts", query: "?owner=" + stakeProgramID, mock: mockRPCClient{tokenAccts: accounts}, expectedStatus: http.StatusOK, expectedBody: `{"owner":"` + stakeProgramID + `","accounts":[]}`},
+		{name: "Missing Owner", query: "", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Owner", query: "?owner=0OIl", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Mint", query: "?owner=" + testOwner + "&mint=abc", expectedStatus: http.StatusBadRequest},
+		{name: "Upstream Error", query: "?owner=" + testOwner, mock: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/tokens"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetTokens(&tt.mock).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestHandleGetTokenBalance(t *testing.T) {
+	balances := map[string]*TokenBalance{testOwnerATA: {Amount: "1500000", Decimals: 6, UIAmount: "1.5"}}
+
+	tests := []struct {
+		name           string
+		query          string
+		mock           mockRPCClient
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Balance",
+			query:          "?account=" + testOwnerATA,
+			mock:           mockRPCClient{tokenBals: balances},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"account":"` + testOwnerATA + `","amount":"1500000","decimals":6,"ui_amount":"1.5"}`,
+		},
+		{name: "Not A Token Account", query: "?account=" + testOwner, mock: mockRPCClient{tokenBals: balances}, expectedStatus: http.StatusInternalServerError},
+		{name: "Missing Account", query: "", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Account", query: "?account=abc", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/token-balance"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetTokenBalance(&tt.mock).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestGetTokenAccountsByOwner(t *testing.T) {
+	var params []json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		params = req.Params
+		w.Write([]byte(`{"jsonrpc":"2.0","id":` + strconv.Itoa(req.ID) + `,"result":{"context":{"slot":1},"value":[{"pubkey":"` + testOwnerATA + `","account":{"owner":"` + tokenProgramID + `","lamports":2039280,` +
+			`"data":{"program":"spl-token","parsed":{"type":"account","info":{"mint":"` + testMint + `","owner":"` + testOwner + `","state":"initialized",` +
+			`"tokenAmount":{"amount":"1500000","decimals":6,"uiAmount":1.5,"uiAmountString":"1.5"}}}}}}]}}`))
+	}))
+	defer server.Close()
+	client := newRPCClient(server.URL)
+
+	accounts, err := client.getTokenAccountsByOwner(context.Background(), testOwner, tokenProgramID, "")
+	if err != nil {
+		t.Fatalf("getTokenAccountsByOwner returned error: %v", err)
+	}
+	expected := TokenAccount{Address: testOwnerATA, Mint: testMint, Owner: testOwner, TokenProgram: tokenProgramID, Amount: "1500000", Decimals: 6, UIAmount: "1.5"}
+	if len(accounts) != 1 || accounts[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, accounts)
+	}
+	if len(params) < 2 || string(params[1]) != `{"programId":"`+tokenProgramID+`"}` {
+		t.Errorf("Expected a program filter, got %s", params)
+	}
+
+	client.getTokenAccountsByOwner(context.Background(), testOwner, "", testMint)
+	if len(params) < 2 || string(params[1]) != `{"mint":"`+testMint+`"}` {
+		t.Errorf("Expected a mint filter, got %s", params)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Total fees settings. Every signature costs a getTransaction call, so the
+// limit is kept well below a full signature page.
+const (
+	defaultTotalFeesLimit = 100
+	maxTotalFeesLimit     = 250
+	lamportsPerSOL        = 1_000_000_000
+)
+
+// totalFeesResponse is the response of /account/total-fees
+type totalFeesResponse struct {
+	Address              string  `json:"address"`
+	TransactionsScanned  int     `json:"transactions_scanned"`
+	FeePayerTransactions int     `json:"fee_payer_transactions"`
+	FailedTransactions   int     `json:"failed_transactions"`
+	Unavailable          int     `json:"unavailable_transactions"`
+	TotalFeesLamports    uint64  `json:"total_fees_lamports"`
+	TotalFeesSOL         float64 `json:"total_fees_sol"`
+}
+
+// transactionFee is the part of a json encoded transaction needed to
+// attribute its fee
+type transactionFee struct {
+	Transaction struct {
+		Message struct {
+			AccountKeys []string `json:"accountKeys"`
+		} `json:"message"`
+	} `json:"transaction"`
+	Meta *struct {
+		Err json.RawMessage `json:"err"`
+		Fee uint64          `json:"fee"`
+	} `json:"meta"`
+}
+
+// feePaidBy returns the fee a transaction charged to address. The whole fee,
+// priority fee included, is charged to the fee payer, which is always the
+// first static account key; other signers and accounts pay nothing even
+// though the transaction shows up in their signature history. Failed
+// transactions are charged too.
+func feePaidBy(transaction json.RawMessage, address string) (fee uint64, paid, failed bool, err error) {
+	var parsed transactionFee
+	if err := json.Unmarshal(transaction, &parsed); err != nil {
+		return 0, false, false, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+
+	keys := parsed.Transaction.Message.AccountKeys
+	if len(keys) == 0 || keys[0] != address || parsed.Meta == nil {
+		return 0, false, false, nil
+	}
+	failed = len(parsed.Meta.Err) > 0 && string(parsed.Meta.Err) != "null"
+	return parsed.Meta.Fee, true, failed, nil
+}
+
+// totalFees sums the fees address paid over its last n transactions,
+// fetching them in JSON-RPC batches
+func totalFees(ctx context.Context, client SolanaRPCClient, pool *workerPool, address string, n int) (*totalFeesResponse, error) {
+	signatures, err := client.getSignaturesForAddress(ctx, address, SignatureOptions{Limit: n})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(signatures))
+	for i, signature := range signatures {
+		ids[i] = signature.Signature
+	}
+
+	// Fetched as JSON-RPC batches from a single pool task, which still
+	// queues at the priority of the whole request
+	var transactions []json.RawMessage
+	var errs []error
+	var fetchErr error
+	if err := pool.runAt(ctx, pool.priorityFor(n), []func(){func() {
+		transactions, errs, fetchErr = client.getTransactions(ctx, ids, TransactionOptions{Encoding: "json", MaxSupportedTransactionVersion: new(int)})
+	}}); err != nil {
+		return nil, err
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	response := &totalFeesResponse{Address: address, TransactionsScanned: len(signatures)}
+	for i, transaction := range transactions {
+		// A partial sum would understate the fees, so any failure fails
+		// the whole request
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if transaction == nil {
+			response.Unavailable++
+			continue
+		}
+
+		fee, paid, failed, err := feePaidBy(transaction, address)
+		if err != nil {
+			return nil, err
+		}
+		if !paid {
+			continue
+		}
+		response.FeePayerTransactions++
+		response.TotalFeesLamports += fee
+		if failed {
+			response.FailedTransactions++
+		}
+	}
+	response.TotalFeesSOL = float64(response.TotalFeesLamports) / lamportsPerSOL
+	return response, nil
+}
+
+func handleGetTotalFees(client SolanaRPCClient, pool *workerPool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address := r.URL.Query().Get("address")
+		if address == "" {
+			http.Error(w, "address parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		limit := defaultTotalFeesLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxTotalFeesLimit {
+				http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxTotalFeesLimit), http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		pool.setPriorityHeader(w, limit)
+
+		response, err := totalFees(r.Context(), client, pool, address, limit)
+		if errors.Is(err, errOverloaded) {
+			shed(w, priorityBulk)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
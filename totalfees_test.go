@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Transactions where "payer" pays, fails while paying, and only signs
+var feeTransactions = map[string]json.RawMessage{
+	"paid":    json.RawMessage(`{"transaction":{"message":{"accountKeys":["payer","dest"]}},"meta":{"err":null,"fee":5000}}`),
+	"failed":  json.RawMessage(`{"transaction":{"message":{"accountKeys":["payer","dest"]}},"meta":{"err":{"InstructionError":[0,"Custom"]},"fee":15000}}`),
+	"cosign":  json.RawMessage(`{"transaction":{"message":{"accountKeys":["other","payer"]}},"meta":{"err":null,"fee":10000}}`),
+	"no-meta": json.RawMessage(`{"transaction":{"message":{"accountKeys":["payer"]}},"meta":null}`),
+}
+
+func TestHandleGetTotalFees(t *testing.T) {
+	pool := newWorkerPool(4, 16)
+	defer pool.stop()
+
+	signatures := []SignatureInfo{{Signature: "paid"}, {Signature: "failed"}, {Signature: "cosign"}, {Signature: "no-meta"}, {Signature: "pruned"}}
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+		expected       totalFeesResponse
+	}{
+		{
+			name:           "Fee Payer Only",
+			mockClient:     mockRPCClient{signatures: signatures, transactions: feeTransactions},
+			queryParam:     "?address=payer",
+			expectedStatus: http.StatusOK,
+			expected:       totalFeesResponse{Address: "payer", TransactionsScanned: 5, FeePayerTransactions: 2, FailedTransactions: 1, Unavailable: 1, TotalFeesLamports: 20000, TotalFeesSOL: 0.00002},
+		},
+		{
+			name:           "Limited",
+			mockClient:     mockRPCClient{signatures: signatures, transactions: feeTransactions},
+			queryParam:     "?address=payer&limit=1",
+			expectedStatus: http.StatusOK,
+			expected:       totalFeesResponse{Address: "payer", TransactionsScanned: 1, FeePayerTransactions: 1, TotalFeesLamports: 5000, TotalFeesSOL: 0.000005},
+		},
+		{
+			name:           "Invalid Transaction",
+			mockClient:     mockRPCClient{signatures: []SignatureInfo{{Signature: "bad"}}, transactions: map[string]json.RawMessage{"bad": json.RawMessage(`[]`)}},
+			queryParam:     "?address=payer",
+			expectedStatus: http.StatusInternalServerError,
+		},
+		{name: "Missing Address", queryParam: "", expectedStatus: http.StatusBadRequest},
+		{name: "Limit Too Large", queryParam: "?address=payer&limit=251", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, queryParam: "?address=payer", expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/account/total-fees"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetTotalFees(&tt.mockClient, pool).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response totalFeesResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, response)
+			}
+		})
+	}
+}
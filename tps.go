@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Nodes take a performance sample about every minute and keep the last 720
+const (
+	defaultTPSSamples = 30
+	maxTPSSamples     = 720
+)
+
+// tpsSample is one sample of the /tps time series
+type tpsSample struct {
+	Slot       uint64   `json:"slot"`
+	Seconds    uint64   `json:"seconds"`
+	Slots      uint64   `json:"slots"`
+	TPS        float64  `json:"tps"`
+	NonVoteTPS *float64 `json:"non_vote_tps"`
+	SlotTimeMs float64  `json:"slot_time_ms"`
+}
+
+// tpsResponse is the response of /tps. Current figures are those of the
+// latest sample, averages are weighted by sample length, and the samples
+// run from oldest to newest. Non-vote figures are null when the node does
+// not report them.
+type tpsResponse struct {
+	CurrentTPS        float64     `json:"current_tps"`
+	CurrentNonVoteTPS *float64    `json:"current_non_vote_tps"`
+	AverageTPS        float64     `json:"average_tps"`
+	AverageNonVoteTPS *float64    `json:"average_non_vote_tps"`
+	PeakTPS           float64     `json:"peak_tps"`
+	AverageSlotTimeMs float64     `json:"average_slot_time_ms"`
+	WindowSeconds     uint64      `json:"window_seconds"`
+	Samples           []tpsSample `json:"samples"`
+}
+
+// perSecond divides a count over a number of seconds
+func perSecond(count, seconds uint64) float64 {
+	if seconds == 0 {
+		return 0
+	}
+	return float64(count) / float64(seconds)
+}
+
+// summarizeTPS computes throughput over samples given newest first, as
+// getRecentPerformanceSamples returns them
+func summarizeTPS(samples []PerformanceSample) tpsResponse {
+	response := tpsResponse{Samples: make([]tpsSample, 0, len(samples))}
+	var transactions, nonVote, slots uint64
+	allNonVote := len(samples) > 0
+	for i := len(samples) - 1; i >= 0; i-- {
+		sample := samples[i]
+		entry := tpsSample{
+			Slot:    sample.Slot,
+			Seconds: sample.SamplePeriodSecs,
+			Slots:   sample.NumSlots,
+			TPS:     perSecond(sample.NumTransactions, sample.SamplePeriodSecs),
+		}
+		if sample.NumSlots > 0 {
+			entry.SlotTimeMs = float64(sample.SamplePeriodSecs) * 1000 / float64(sample.NumSlots)
+		}
+		if sample.NumNonVoteTransactions != nil {
+			tps := perSecond(*sample.NumNonVoteTransactions, sample.SamplePeriodSecs)
+			entry.NonVoteTPS = &tps
+			nonVote += *sample.NumNonVoteTransactions
+		} else {
+			allNonVote = false
+		}
+		if entry.TPS > response.PeakTPS {
+			response.PeakTPS = entry.TPS
+		}
+		transactions += sample.NumTransactions
+		slots += sample.NumSlots
+		response.WindowSeconds += sample.SamplePeriodSecs
+		response.Samples = append(response.Samples, entry)
+	}
+	if len(samples) == 0 {
+		return response
+	}
+
+	latest := response.Samples[len(response.Samples)-1]
+	response.CurrentTPS, response.CurrentNonVoteTPS = latest.TPS, latest.NonVoteTPS
+	response.AverageTPS = perSecond(transactions, response.WindowSeconds)
+	if allNonVote {
+		average := perSecond(nonVote, response.WindowSeconds)
+		response.AverageNonVoteTPS = &average
+	}
+	if slots > 0 {
+		response.AverageSlotTimeMs = float64(response.WindowSeconds) * 1000 / float64(slots)
+	}
+	return response
+}
+
+func handleGetTPS(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := defaultTPSSamples
+		if value := r.URL.Query().Get("samples"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 || parsed > maxTPSSamples {
+				http.Error(w, fmt.Sprintf("samples must be between 1 and %d", maxTPSSamples), http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+
+		samples, err := client.getRecentPerformanceSamples(r.Context(), limit)
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(summarizeTPS(samples))
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSummarizeTPS(t *testing.T) {
+	nonVote := func(n uint64) *uint64 { return &n }
+	samples := []PerformanceSample{
+		{Slot: 300, NumSlots: 150, NumTransactions: 240000, NumNonVoteTransactions: nonVote(60000), SamplePeriodSecs: 60},
+		{Slot: 150, NumSlots: 120, NumTransactions: 120000, NumNonVoteTransactions: nonVote(30000), SamplePeriodSecs: 60},
+	}
+
+	summary := summarizeTPS(samples)
+	if summary.CurrentTPS != 4000 || summary.AverageTPS != 3000 || summary.PeakTPS != 4000 {
+		t.Errorf("Expected current 4000, average 3000 and peak 4000 TPS, got %v, %v and %v", summary.CurrentTPS, summary.AverageTPS, summary.PeakTPS)
+	}
+	if summary.CurrentNonVoteTPS == nil || *summary.CurrentNonVoteTPS != 1000 || summary.AverageNonVoteTPS == nil || *summary.AverageNonVoteTPS != 750 {
+		t.Errorf("Expected current 1000 and average 750 non-vote TPS, got %v and %v", summary.CurrentNonVoteTPS, summary.AverageNonVoteTPS)
+	}
+	if summary.AverageSlotTimeMs != 120000.0/270 || summary.WindowSeconds != 120 {
+		t.Errorf("Expected a 120s window of %vms slots, got %vs of %vms", 120000.0/270, summary.WindowSeconds, summary.AverageSlotTimeMs)
+	}
+	if len(summary.Samples) != 2 || summary.Samples[0].Slot != 150 || summary.Samples[0].SlotTimeMs != 500 {
+		t.Errorf("Expected the oldest sample first with 500ms slots, got %+v", summary.Samples)
+	}
+
+	// A sample from an older node leaves the non-vote average unknown
+	samples[1].NumNonVoteTransactions = nil
+	if summary := summarizeTPS(samples); summary.AverageNonVoteTPS != nil || summary.CurrentNonVoteTPS == nil {
+		t.Errorf("Expected only the current non-vote TPS, got %v and %v", summary.CurrentNonVoteTPS, summary.AverageNonVoteTPS)
+	}
+
+	if summary := summarizeTPS(nil); summary.CurrentTPS != 0 || summary.Samples == nil {
+		t.Errorf("Expected an empty summary without samples, got %+v", summary)
+	}
+}
+
+func TestHandleGetTPS(t *testing.T) {
+	samples := []PerformanceSample{{Slot: 150, NumSlots: 150, NumTransactions: 180000, SamplePeriodSecs: 60}}
+
+	tests := []struct {
+		name           string
+		query          string
+		mockClient     mockRPCClient
+		expectedStatus int
+		expectedTPS    float64
+	}{
+		{name: "Default Window", mockClient: mockRPCClient{perfSamples: samples}, expectedStatus: http.StatusOK, expectedTPS: 3000},
+		{name: "Custom Window", query: "?samples=720", mockClient: mockRPCClient{perfSamples: samples}, expectedStatus: http.StatusOK, expectedTPS: 3000},
+		{name: "Invalid Window", query: "?samples=0", expectedStatus: http.StatusBadRequest},
+		{name: "Window Too Large", query: "?samples=721", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC connection failed"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/tps"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handleGetTPS(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var response tpsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.CurrentTPS != tt.expectedTPS || response.CurrentNonVoteTPS != nil {
+				t.Errorf("Expected %v TPS without a non-vote figure, got %v and %v", tt.expectedTPS, response.CurrentTPS, response.CurrentNonVoteTPS)
+			}
+		})
+	}
+}
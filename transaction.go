@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// transactionEncodings are the encodings getTransaction and getBlock accept
+// for transactions. With jsonParsed, instructions of well-known programs are
+// decoded by the node, which makes it noticeably more expensive to serve than
+// the binary encodings, especially for full blocks.
+var transactionEncodings = map[string]bool{
+	"json":       true,
+	"jsonParsed": true,
+	"base64":     true,
+	"base58":     true,
+}
+
+// TransactionOptions configures getTransaction. An empty Encoding leaves
+// the node's default, json.
+type TransactionOptions struct {
+	Encoding                       string
+	MaxSupportedTransactionVersion *int
+}
+
+// requestEncoding returns the ?encoding= of a request, or fallback when the
+// request does not name one
+func requestEncoding(r *http.Request, fallback string) (string, error) {
+	encoding := r.URL.Query().Get("encoding")
+	if encoding == "" {
+		return fallback, nil
+	}
+	if !transactionEncodings[encoding] {
+		return "", fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	return encoding, nil
+}
+
+// transactionParams builds the getTransaction parameters for signature
+func transactionParams(signature string, opts TransactionOptions) []interface{} {
+	config := map[string]interface{}{}
+	if opts.Encoding != "" {
+		config["encoding"] = opts.Encoding
+	}
+	if opts.MaxSupportedTransactionVersion != nil {
+		config["maxSupportedTransactionVersion"] = *opts.MaxSupportedTransactionVersion
+	}
+	return []interface{}{signature, config}
+}
+
+// getTransaction gets a confirmed transaction, or nil if the node does not
+// know the signature
+func (c *rpcClient) getTransaction(ctx context.Context, signature string, opts TransactionOptions) (json.RawMessage, error) {
+	response, err := c.sendRequest(ctx, "getTransaction", transactionParams(signature, opts))
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(response.Result, []byte("null")) {
+		return nil, nil
+	}
+
+	return response.Result, nil
+}
+
+// TransactionDetails is a transaction in the stable shape served by
+// /transaction?format=parsed
+type TransactionDetails struct {
+	Signature    string               `json:"signature"`
+	Slot         uint64               `json:"slot"`
+	BlockTime    *int64               `json:"block_time"`
+	Fee          uint64               `json:"fee"`
+	Status       string               `json:"status"`
+	Err          json.RawMessage      `json:"err"`
+	AccountKeys  []string             `json:"account_keys"`
+	Instructions []InstructionDetails `json:"instructions"`
+}
+
+// InstructionDetails is a top-level instruction with its program and
+// accounts resolved from the transaction's account keys
+type InstructionDetails struct {
+	ProgramID string   `json:"program_id"`
+	Accounts  []string `json:"accounts"`
+	Data      string   `json:"data"`
+}
+
+// Transaction statuses
+const (
+	transactionSucceeded = "success"
+	transactionFailed    = "failed"
+)
+
+// parseTransactionDetails reads a json encoded getTransaction result
+func parseTransactionDetails(raw json.RawMessage) (*TransactionDetails, error) {
+	var tx struct {
+		Slot        uint64 `json:"slot"`
+		BlockTime   *int64 `json:"blockTime"`
+		Transaction struct {
+			Signatures []string `json:"signatures"`
+			Message    struct {
+				AccountKeys  []string `json:"accountKeys"`
+				Instructions []struct {
+					ProgramIDIndex int    `json:"programIdIndex"`
+					Accounts       []int  `json:"accounts"`
+					Data           string `json:"data"`
+				} `json:"instructions"`
+			} `json:"message"`
+		} `json:"transaction"`
+		Meta *struct {
+			Fee             uint64          `json:"fee"`
+			Err             json.RawMessage `json:"err"`
+			LoadedAddresses *struct {
+				Writable []string `json:"writable"`
+				Readonly []string `json:"readonly"`
+			} `json:"loadedAddresses"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(raw, &tx); err != nil {
+		return nil, fmt.Errorf("failed to parse transaction: %w", err)
+	}
+
+	details := &TransactionDetails{
+		Slot:         tx.Slot,
+		BlockTime:    tx.BlockTime,
+		Status:       transactionSucceeded,
+		Err:          json.RawMessage("null"),
+		AccountKeys:  tx.Transaction.Message.AccountKeys,
+		Instructions: make([]InstructionDetails, 0, len(tx.Transaction.Message.Instructions)),
+	}
+	if len(tx.Transaction.Signatures) > 0 {
+		details.Signature = tx.Transaction.Signatures[0]
+	}
+	if tx.Meta != nil {
+		details.Fee = tx.Meta.Fee
+		if len(tx.Meta.Err) > 0 && !bytes.Equal(tx.Meta.Err, []byte("null")) {
+			details.Status, details.Err = transactionFailed, tx.Meta.Err
+		}
+		// Versioned transactions index into the static keys followed by the
+		// writable and then read-only addresses loaded from tables
+		if loaded := tx.Meta.LoadedAddresses; loaded != nil {
+			keys := details.AccountKeys
+			details.AccountKeys = append(append(keys[:len(keys):len(keys)], loaded.Writable...), loaded.Readonly...)
+		}
+	}
+
+	key := func(index int) (string, error) {
+		if index < 0 || index >= len(details.AccountKeys) {
+			return "", fmt.Errorf("instruction references account %d of %d", index, len(details.AccountKeys))
+		}
+		return details.AccountKeys[index], nil
+	}
+	for _, ix := range tx.Transaction.Message.Instructions {
+		programID, err := key(ix.ProgramIDIndex)
+		if err != nil {
+			return nil, err
+		}
+		instruction := InstructionDetails{ProgramID: programID, Accounts: make([]string, len(ix.Accounts)), Data: ix.Data}
+		for i, index := range ix.Accounts {
+			if instruction.Accounts[i], err = key(index); err != nil {
+				return nil, err
+			}
+		}
+		details.Instructions = append(details.Instructions, instruction)
+	}
+	return details, nil
+}
+
+// handleGetTransaction serves transactions by signature, using
+// defaultEncoding when the request does not name an encoding. With
+// ?format=parsed the transaction is fetched json encoded and served as
+// TransactionDetails.
+func handleGetTransaction(client SolanaRPCClient, defaultEncoding string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		signature := r.URL.Query().Get("signature")
+		if signature == "" {
+			http.Error(w, "signature parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		encoding, err := requestEncoding(r, defaultEncoding)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		parsed := false
+		switch r.URL.Query().Get("format") {
+		case "", "raw":
+		case "parsed":
+			if r.URL.Query().Get("encoding") != "" && encoding != "json" {
+				http.Error(w, "format=parsed requires json encoding", http.StatusBadRequest)
+				return
+			}
+			parsed, encoding = true, "json"
+		default:
+			http.Error(w, "format must be raw or parsed", http.StatusBadRequest)
+			return
+		}
+
+		maxTxVersion, err := parseMaxTxVersion(r.URL.Query().Get("maxTxVersion"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		transaction, err := client.getTransaction(r.Context(), signature, TransactionOptions{Encoding: encoding, MaxSupportedTransactionVersion: maxTxVersion})
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		if transaction == nil {
+			http.Error(w, "transaction not found", http.StatusNotFound)
+			return
+		}
+
+		if parsed {
+			details, err := parseTransactionDetails(transaction)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			jsonData, _ := json.Marshal(details)
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(jsonData)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(transaction)
+	}
+}
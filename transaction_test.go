@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleGetTransactionJSONParsed(t *testing.T) {
+	rawTx := `{"slot":100,"transaction":{"message":{"instructions":[{"programIdIndex":3,"accounts":[0,1,2],"data":"3Bxs4h24hBtQy9rw"}]}}}`
+	parsedTx := `{"slot":100,"transaction":{"message":{"instructions":[{"parsed":{"info":{"amount":"1000","authority":"owner","destination":"dst","source":"src"},"type":"transfer"},"program":"spl-token","programId":"` + tokenProgramID + `"}]}}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int               `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var signature string
+		var config map[string]interface{}
+		json.Unmarshal(req.Params[0], &signature)
+		json.Unmarshal(req.Params[1], &config)
+
+		// Mimic a node that only decodes instructions when asked to
+		switch {
+		case signature == "unknown":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","result":null,"id":%d}`, req.ID)
+		case config["encoding"] == "jsonParsed":
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%s,"id":%d}`, parsedTx, req.ID)
+		default:
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","result":%s,"id":%d}`, rawTx, req.ID)
+		}
+	}))
+	defer server.Close()
+
+	client := newRPCClient(server.URL)
+
+	tests := []struct {
+		name           string
+		queryParam     string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{name: "Default Encoding", queryParam: "?signature=abc", expectedStatus: http.StatusOK, expectedBody: rawTx},
+		{name: "JSON Parsed", queryParam: "?signature=abc&encoding=jsonParsed", expectedStatus: http.StatusOK, expectedBody: parsedTx},
+		{name: "Not Found", queryParam: "?signature=unknown", expectedStatus: http.StatusNotFound},
+		{name: "Missing Signature", queryParam: "", expectedStatus: http.StatusBadRequest},
+		{name: "Unsupported Encoding", queryParam: "?signature=abc&encoding=xml", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Version", queryParam: "?signature=abc&maxTxVersion=-1", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/transaction"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetTransaction(client, "").ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestHandleGetTransactionOptions(t *testing.T) {
+	mock := &mockRPCClient{transactions: map[string]json.RawMessage{"abc": json.RawMessage(`{"slot":1}`)}}
+	req := httptest.NewRequest("GET", "/transaction?signature=abc&encoding=jsonParsed&maxTxVersion=legacy", nil)
+	rr := httptest.NewRecorder()
+	handleGetTransaction(mock, "").ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if mock.txOptions.Encoding != "jsonParsed" || mock.txOptions.MaxSupportedTransactionVersion != nil {
+		t.Errorf("unexpected transaction options: %+v", mock.txOptions)
+	}
+}
+
+func TestDefaultEncoding(t *testing.T) {
+	tests := []struct {
+		name             string
+		query            string
+		defaultEncoding  string
+		expectedEncoding string
+		expectedStatus   int
+	}{
+		{name: "Node Default", query: "", defaultEncoding: "", expectedEncoding: "", expectedStatus: http.StatusOK},
+		{name: "Deployment Default", query: "", defaultEncoding: "jsonParsed", expectedEncoding: "jsonParsed", expectedStatus: http.StatusOK},
+		{name: "Request Overrides", query: "&encoding=base64", defaultEncoding: "jsonParsed", expectedEncoding: "base64", expectedStatus: http.StatusOK},
+		{name: "Unsupported Override", query: "&encoding=xml", defaultEncoding: "jsonParsed", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &mockRPCClient{
+				transactions: map[string]json.RawMessage{"abc": json.RawMessage(`{"slot":1}`)},
+				blockDetails: json.RawMessage(`{"blockhash":"abc"}`),
+			}
+
+			rr := httptest.NewRecorder()
+			handleGetTransaction(mock, tt.defaultEncoding).ServeHTTP(rr, httptest.NewRequest("GET", "/transaction?signature=abc"+tt.query, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("/transaction returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if mock.txOptions.Encoding != tt.expectedEncoding {
+				t.Errorf("Expected transaction encoding %q, got %q", tt.expectedEncoding, mock.txOptions.Encoding)
+			}
+
+			rr = httptest.NewRecorder()
+			handleGetBlockDetails(mock, 0, tt.defaultEncoding).ServeHTTP(rr, httptest.NewRequest("GET", "/block-details?block=42"+tt.query, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("/block-details returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if mock.blockOptions.Encoding != tt.expectedEncoding {
+				t.Errorf("Expected block encoding %q, got %q", tt.expectedEncoding, mock.blockOptions.Encoding)
+			}
+		})
+	}
+}
+
+func TestHandleGetTransactionParsed(t *testing.T) {
+	tx := `{"slot":100,"blockTime":1700000000,"transaction":{"signatures":["sig1"],"message":{"accountKeys":["payer","dest","` + stakeProgramID + `"],` +
+		`"instructions":[{"programIdIndex":2,"accounts":[0,1],"data":"3Bxs4h24hBtQy9rw"},{"programIdIndex":3,"accounts":[],"data":""}]}},` +
+		`"meta":{"fee":5000,"err":{"InstructionError":[1,"InvalidArgument"]},"loadedAddresses":{"writable":[],"readonly":["lookup"]}}}`
+	mock := &mockRPCClient{transactions: map[string]json.RawMessage{
+		"sig1": json.RawMessage(tx),
+		"bad":  json.RawMessage(`{"slot":1,"transaction":{"signatures":["bad"],"message":{"accountKeys":["payer"],"instructions":[{"programIdIndex":4,"accounts":[]}]}}}`),
+	}}
+
+	tests := []struct {
+		name             string
+		queryParam       string
+		expectedStatus   int
+		expectedBody     string
+		expectedEncoding string
+	}{
+		{
+			name:           "Parsed",
+			queryParam:     "?signature=sig1&format=parsed",
+			expectedStatus: http.StatusOK,
+			expectedBody: `{"signature":"sig1","slot":100,"block_time":1700000000,"fee":5000,"status":"failed","err":{"InstructionError":[1,"InvalidArgument"]},` +
+				`"account_keys":["payer","dest","` + stakeProgramID + `","lookup"],"instructions":[{"program_id":"` + stakeProgramID + `","accounts":["payer","dest"],"data":"3Bxs4h24hBtQy9rw"},{"program_id":"lookup","accounts":[],"data":""}]}`,
+			expectedEncoding: "json",
+		},
+		{name: "Parsed Needs JSON", queryParam: "?signature=sig1&format=parsed&encoding=base64", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown Format", queryParam: "?signature=sig1&format=typed", expectedStatus: http.StatusBadRequest},
+		{name: "Index Out Of Range", queryParam: "?signature=bad&format=parsed", expectedStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/transaction"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetTransaction(mock, "base64").ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if tt.expectedBody != "" && rr.Body.String() != tt.expectedBody {
+				t.Errorf("handler returned unexpected body: got %v want %v", rr.Body.String(), tt.expectedBody)
+			}
+			if tt.expectedEncoding != "" && mock.txOptions.Encoding != tt.expectedEncoding {
+				t.Errorf("Expected encoding %s, got %s", tt.expectedEncoding, mock.txOptions.Encoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Upstream transport defaults, those of http.DefaultTransport except for
+// idle connections per host: every upstream request goes to the few hosts of
+// the RPC endpoints, so the default of two would close most connections
+// after a burst
+const (
+	defaultUpstreamDialTimeout     = 30 * time.Second
+	defaultUpstreamKeepAlive       = 30 * time.Second
+	defaultUpstreamMaxIdleConns    = 100
+	defaultUpstreamMaxIdlePerHost  = 32
+	defaultUpstreamIdleConnTimeout = 90 * time.Second
+)
+
+// transportOptions configure the HTTP transport of the upstream clients
+type transportOptions struct {
+	// Proxy is an http, https or socks5 proxy URL. Empty takes the proxy
+	// from HTTPS_PROXY, HTTP_PROXY and NO_PROXY.
+	Proxy               string
+	DialTimeout         time.Duration
+	KeepAlive           time.Duration
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+	DisableKeepAlives   bool
+	// RootCAsFile is a PEM bundle trusted in addition to the system roots,
+	// for nodes with certificates from a private CA
+	RootCAsFile string
+}
+
+// newUpstreamTransport builds the transport upstream requests are sent with
+func newUpstreamTransport(opts transportOptions) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid upstream proxy %q", opts.Proxy)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("unsupported upstream proxy scheme %q: want http, https or socks5", proxy.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	transport.DialContext = (&net.Dialer{Timeout: opts.DialTimeout, KeepAlive: opts.KeepAlive}).DialContext
+	transport.MaxIdleConns = opts.MaxIdleConns
+	transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = opts.IdleConnTimeout
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+	if opts.RootCAsFile != "" {
+		pem, err := os.ReadFile(opts.RootCAsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upstream CAs: %w", err)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in upstream CA file %s", opts.RootCAsFile)
+		}
+		transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: roots}
+	}
+	return transport, nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// VoteAccount is a single validator returned by getVoteAccounts
+type VoteAccount struct {
+	VotePubkey       string `json:"votePubkey"`
+	NodePubkey       string `json:"nodePubkey"`
+	ActivatedStake   uint64 `json:"activatedStake"`
+	EpochVoteAccount bool   `json:"epochVoteAccount"`
+	Commission       uint8  `json:"commission"`
+	LastVote         uint64 `json:"lastVote"`
+	RootSlot         uint64 `json:"rootSlot"`
+}
+
+// VoteAccounts is the result of getVoteAccounts
+type VoteAccounts struct {
+	Current    []VoteAccount `json:"current"`
+	Delinquent []VoteAccount `json:"delinquent"`
+}
+
+// ClusterNode is a single node returned by getClusterNodes. Addresses and
+// versions are null for nodes that do not publish them.
+type ClusterNode struct {
+	Pubkey       string  `json:"pubkey"`
+	Gossip       *string `json:"gossip"`
+	TPU          *string `json:"tpu"`
+	RPC          *string `json:"rpc"`
+	Version      *string `json:"version"`
+	FeatureSet   *uint32 `json:"featureSet"`
+	ShredVersion *uint16 `json:"shredVersion"`
+}
+
+// validatorEntry is a validator listed by /validators, its vote account
+// joined with the gossip entry of its identity
+type validatorEntry struct {
+	VotePubkey       string  `json:"vote_pubkey"`
+	Identity         string  `json:"identity"`
+	ActivatedStake   uint64  `json:"activated_stake"`
+	StakePercent     float64 `json:"stake_percent"`
+	Commission       uint8   `json:"commission"`
+	LastVote         uint64  `json:"last_vote"`
+	RootSlot         uint64  `json:"root_slot"`
+	EpochVoteAccount bool    `json:"epoch_vote_account"`
+	Delinquent       bool    `json:"delinquent"`
+	Version          *string `json:"version"`
+	Gossip           *string `json:"gossip"`
+	RPC              *string `json:"rpc"`
+}
+
+// validatorsResponse is the response of /validators. The totals cover every
+// vote account, before filtering.
+type validatorsResponse struct {
+	TotalStake      uint64           `json:"total_stake"`
+	CurrentStake    uint64           `json:"current_stake"`
+	DelinquentStake uint64           `json:"delinquent_stake"`
+	CurrentCount    int              `json:"current_count"`
+	DelinquentCount int              `json:"delinquent_count"`
+	Validators      []validatorEntry `json:"validators"`
+}
+
+// validatorOrders are the ?sort= orders of /validators, each with its
+// default direction
+var validatorOrders = map[string]struct {
+	less       func(a, b *validatorEntry) bool
+	descending bool
+}{
+	"stake":      {less: func(a, b *validatorEntry) bool { return a.ActivatedStake < b.ActivatedStake }, descending: true},
+	"commission": {less: func(a, b *validatorEntry) bool { return a.Commission < b.Commission }},
+	"lastVote":   {less: func(a, b *validatorEntry) bool { return a.LastVote < b.LastVote }, descending: true},
+	"identity":   {less: func(a, b *validatorEntry) bool { return a.Identity < b.Identity }},
+}
+
+// validatorStakeShareResponse is the response of /validator-stake-share
+type validatorStakeShareResponse struct {
+	VotePubkey     string  `json:"vote_pubkey"`
+	NodePubkey     string  `json:"node_pubkey"`
+	ActivatedStake uint64  `json:"activated_stake"`
+	TotalStake     uint64  `json:"total_stake"`
+	SharePercent   float64 `json:"share_percent"`
+	Rank           int     `json:"rank"`
+	ValidatorCount int     `json:"validator_count"`
+	Delinquent     bool    `json:"delinquent"`
+	Commission     uint8   `json:"commission"`
+}
+
+// getVoteAccounts gets the current and delinquent vote accounts
+func (c *rpcClient) getVoteAccounts(ctx context.Context) (*VoteAccounts, error) {
+	response, err := c.sendRequest(ctx, "getVoteAccounts", []interface{}{})
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts VoteAccounts
+	if err := json.Unmarshal(response.Result, &accounts); err != nil {
+		return nil, fmt.Errorf("failed to parse vote accounts: %w", err)
+	}
+
+	return &accounts, nil
+}
+
+// getClusterNodes gets the nodes participating in the cluster's gossip
+func (c *rpcClient) getClusterNodes(ctx context.Context) ([]ClusterNode, error) {
+	response, err := c.sendRequest(ctx, "getClusterNodes", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []ClusterNode
+	if err := json.Unmarshal(response.Result, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to parse cluster nodes: %w", err)
+	}
+
+	return nodes, nil
+}
+
+// validatorStakeShare computes a validator's share of the stake held by all
+// vote accounts, current and delinquent, and its rank by activated stake.
+// Validators with equal stake share a rank. Returns nil if votePubkey is not
+// among the vote accounts.
+func validatorStakeShare(accounts *VoteAccounts, votePubkey string) *validatorStakeShareResponse {
+	var response *validatorStakeShareResponse
+	var total uint64
+	stakes := make([]uint64, 0, len(accounts.Current)+len(accounts.Delinquent))
+
+	collect := func(list []VoteAccount, delinquent bool) {
+		for _, account := range list {
+			total += account.ActivatedStake
+			stakes = append(stakes, account.ActivatedStake)
+			if account.VotePubkey == votePubkey {
+				response = &validatorStakeShareResponse{
+					VotePubkey:     account.VotePubkey,
+					NodePubkey:     account.NodePubkey,
+					ActivatedStake: account.ActivatedStake,
+					Delinquent:     delinquent,
+					Commission:     account.Commission,
+				}
+			}
+		}
+	}
+	collect(accounts.Current, false)
+	collect(accounts.Delinquent, true)
+
+	if response == nil {
+		return nil
+	}
+
+	sort.Slice(stakes, func(i, j int) bool { return stakes[i] > stakes[j] })
+	response.Rank = sort.Search(len(stakes), func(i int) bool { return stakes[i] <= response.ActivatedStake }) + 1
+	response.TotalStake = total
+	response.ValidatorCount = len(stakes)
+	if total > 0 {
+		response.SharePercent = float64(response.ActivatedStake) / float64(total) * 100
+	}
+	return response
+}
+
+func handleGetValidatorStakeShare(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		votePubkey := r.URL.Query().Get("votePubkey")
+		if votePubkey == "" {
+			http.Error(w, "votePubkey parameter is required", http.StatusBadRequest)
+			return
+		}
+
+		accounts, err := client.getVoteAccounts(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		share := validatorStakeShare(accounts, votePubkey)
+		if share == nil {
+			http.Error(w, "vote account not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(share)
+		w.Write(jsonData)
+	}
+}
+
+// listValidators joins the vote accounts with the cluster nodes, keeping
+// those that pass keep
+func listValidators(accounts *VoteAccounts, nodes []ClusterNode, keep func(*validatorEntry) bool) *validatorsResponse {
+	byIdentity := make(map[string]*ClusterNode, len(nodes))
+	for i := range nodes {
+		byIdentity[nodes[i].Pubkey] = &nodes[i]
+	}
+
+	response := &validatorsResponse{CurrentCount: len(accounts.Current), DelinquentCount: len(accounts.Delinquent), Validators: []validatorEntry{}}
+	for _, account := range accounts.Current {
+		response.CurrentStake += account.ActivatedStake
+	}
+	for _, account := range accounts.Delinquent {
+		response.DelinquentStake += account.ActivatedStake
+	}
+	response.TotalStake = response.CurrentStake + response.DelinquentStake
+
+	collect := func(list []VoteAccount, delinquent bool) {
+		for _, account := range list {
+			entry := validatorEntry{
+				VotePubkey:       account.VotePubkey,
+				Identity:         account.NodePubkey,
+				ActivatedStake:   account.ActivatedStake,
+				Commission:       account.Commission,
+				LastVote:         account.LastVote,
+				RootSlot:         account.RootSlot,
+				EpochVoteAccount: account.EpochVoteAccount,
+				Delinquent:       delinquent,
+			}
+			if response.TotalStake > 0 {
+				entry.StakePercent = float64(account.ActivatedStake) / float64(response.TotalStake) * 100
+			}
+			if node := byIdentity[account.NodePubkey]; node != nil {
+				entry.Version, entry.Gossip, entry.RPC = node.Version, node.Gossip, node.RPC
+			}
+			if keep(&entry) {
+				response.Validators = append(response.Validators, entry)
+			}
+		}
+	}
+	collect(accounts.Current, false)
+	collect(accounts.Delinquent, true)
+	return response
+}
+
+func handleGetValidators(client SolanaRPCClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		status := query.Get("status")
+		if status != "" && status != "current" && status != "delinquent" {
+			http.Error(w, "status must be current or delinquent", http.StatusBadRequest)
+			return
+		}
+		sortBy := query.Get("sort")
+		if sortBy == "" {
+			sortBy = "stake"
+		}
+		order, ok := validatorOrders[sortBy]
+		if !ok {
+			http.Error(w, "sort must be stake, commission, lastVote or identity", http.StatusBadRequest)
+			return
+		}
+		descending := order.descending
+		switch query.Get("order") {
+		case "":
+		case "asc":
+			descending = false
+		case "desc":
+			descending = true
+		default:
+			http.Error(w, "order must be asc or desc", http.StatusBadRequest)
+			return
+		}
+		limit := 0
+		if value := query.Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				http.Error(w, "limit must be a positive number", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		identity, votePubkey := query.Get("identity"), query.Get("votePubkey")
+
+		accounts, err := client.getVoteAccounts(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+		nodes, err := client.getClusterNodes(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), upstreamStatus(err))
+			return
+		}
+
+		response := listValidators(accounts, nodes, func(entry *validatorEntry) bool {
+			return (status == "" || entry.Delinquent == (status == "delinquent")) &&
+				(identity == "" || entry.Identity == identity) &&
+				(votePubkey == "" || entry.VotePubkey == votePubkey)
+		})
+		sort.SliceStable(response.Validators, func(i, j int) bool {
+			a, b := &response.Validators[i], &response.Validators[j]
+			if descending {
+				a, b = b, a
+			}
+			return order.less(a, b)
+		})
+		if limit > 0 && len(response.Validators) > limit {
+			response.Validators = response.Validators[:limit]
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		jsonData, _ := json.Marshal(response)
+		w.Write(jsonData)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testVoteAccounts() *VoteAccounts {
+	return &VoteAccounts{
+		Current: []VoteAccount{
+			{VotePubkey: "voteA", NodePubkey: "nodeA", ActivatedStake: 500},
+			{VotePubkey: "voteB", NodePubkey: "nodeB", ActivatedStake: 300, Commission: 5},
+			{VotePubkey: "voteC", NodePubkey: "nodeC", ActivatedStake: 100},
+		},
+		Delinquent: []VoteAccount{
+			{VotePubkey: "voteD", NodePubkey: "nodeD", ActivatedStake: 300},
+		},
+	}
+}
+
+func TestHandleGetValidatorStakeShare(t *testing.T) {
+	tests := []struct {
+		name               string
+		mockClient         mockRPCClient
+		queryParam         string
+		expectedStatus     int
+		expectedShare      float64
+		expectedRank       int
+		expectedDelinquent bool
+	}{
+		{
+			name:           "Largest Validator",
+			mockClient:     mockRPCClient{voteAccounts: testVoteAccounts()},
+			queryParam:     "?votePubkey=voteA",
+			expectedStatus: http.StatusOK,
+			expectedShare:  500.0 / 12,
+			expectedRank:   1,
+		},
+		{
+			name:           "Tied Stake",
+			mockClient:     mockRPCClient{voteAccounts: testVoteAccounts()},
+			queryParam:     "?votePubkey=voteB",
+			expectedStatus: http.StatusOK,
+			expectedShare:  25,
+			expectedRank:   2,
+		},
+		{
+			name:               "Delinquent Validator",
+			mockClient:         mockRPCClient{voteAccounts: testVoteAccounts()},
+			queryParam:         "?votePubkey=voteD",
+			expectedStatus:     http.StatusOK,
+			expectedShare:      25,
+			expectedRank:       2,
+			expectedDelinquent: true,
+		},
+		{
+			name:           "Smallest Validator",
+			mockClient:     mockRPCClient{voteAccounts: testVoteAccounts()},
+			queryParam:     "?votePubkey=voteC",
+			expectedStatus: http.StatusOK,
+			expectedShare:  100.0 / 12,
+			expectedRank:   4,
+		},
+		{name: "Unknown Validator", mockClient: mockRPCClient{voteAccounts: testVoteAccounts()}, queryParam: "?votePubkey=voteZ", expectedStatus: http.StatusNotFound},
+		{name: "Missing Pubkey", mockClient: mockRPCClient{}, queryParam: "", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC error"}, queryParam: "?votePubkey=voteA", expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/validator-stake-share"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetValidatorStakeShare(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response validatorStakeShareResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to parse response: %v", err)
+			}
+			if response.TotalStake != 1200 || response.ValidatorCount != 4 {
+				t.Errorf("Expected total stake 1200 over 4 validators, got %d over %d", response.TotalStake, response.ValidatorCount)
+			}
+			if math.Abs(response.SharePercent-tt.expectedShare) > 1e-9 {
+				t.Errorf("Expected share %v, got %v", tt.expectedShare, response.SharePercent)
+			}
+			if response.Rank != tt.expectedRank {
+				t.Errorf("Expected rank %d, got %d", tt.expectedRank, response.Rank)
+			}
+			if response.Delinquent != tt.expectedDelinquent {
+				t.Errorf("Expected delinquent %v, got %v", tt.expectedDelinquent, response.Delinquent)
+			}
+		})
+	}
+}
+
+func TestHandleGetValidators(t *testing.T) {
+	version := "2.0.14"
+	nodes := []ClusterNode{{Pubkey: "nodeA", Version: &version}}
+
+	tests := []struct {
+		name           string
+		mockClient     mockRPCClient
+		queryParam     string
+		expectedStatus int
+		expectedVotes  []string
+	}{
+		{name: "By Stake", queryParam: "", expectedStatus: http.StatusOK, expectedVotes: []string{"voteA", "voteB", "voteD", "voteC"}},
+		{name: "By Commission", queryParam: "?sort=commission&order=desc", expectedStatus: http.StatusOK, expectedVotes: []string{"voteB", "voteA", "voteC", "voteD"}},
+		{name: "Smallest First", queryParam: "?order=asc&limit=2", expectedStatus: http.StatusOK, expectedVotes: []string{"voteC", "voteB"}},
+		{name: "Delinquent", queryParam: "?status=delinquent", expectedStatus: http.StatusOK, expectedVotes: []string{"voteD"}},
+		{name: "Current", queryParam: "?status=current&sort=identity", expectedStatus: http.StatusOK, expectedVotes: []string{"voteA", "voteB", "voteC"}},
+		{name: "By Identity", queryParam: "?identity=nodeC", expectedStatus: http.StatusOK, expectedVotes: []string{"voteC"}},
+		{name: "By Vote Pubkey", queryParam: "?votePubkey=missing", expectedStatus: http.StatusOK, expectedVotes: []string{}},
+		{name: "Invalid Status", queryParam: "?status=active", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Sort", queryParam: "?sort=name", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Order", queryParam: "?order=up", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Limit", queryParam: "?limit=0", expectedStatus: http.StatusBadRequest},
+		{name: "RPC Error", mockClient: mockRPCClient{shouldFail: true, errorMessage: "RPC connection failed"}, expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.mockClient.shouldFail {
+				tt.mockClient = mockRPCClient{voteAccounts: testVoteAccounts(), clusterNodes: nodes}
+			}
+			req := httptest.NewRequest("GET", "/validators"+tt.queryParam, nil)
+			rr := httptest.NewRecorder()
+			handleGetValidators(&tt.mockClient).ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response validatorsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.TotalStake != 1200 || response.DelinquentStake != 300 || response.CurrentCount != 3 || response.DelinquentCount != 1 {
+				t.Errorf("Expected totals over every vote account, got %+v", response)
+			}
+			votes := []string{}
+			for _, validator := range response.Validators {
+				votes = append(votes, validator.VotePubkey)
+				if validator.VotePubkey == "voteA" && (validator.Version == nil || *validator.Version != version || math.Abs(validator.StakePercent-500.0/12) > 1e-9) {
+					t.Errorf("Expected voteA joined with its cluster node, got %+v", validator)
+				}
+			}
+			if len(votes) != len(tt.expectedVotes) {
+				t.Fatalf("Expected validators %v, got %v", tt.expectedVotes, votes)
+			}
+			for i := range votes {
+				if votes[i] != tt.expectedVotes[i] {
+					t.Fatalf("Expected validators %v, got %v", tt.expectedVotes, votes)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxVerifyBodyBytes bounds /verify-signature bodies. Off-chain messages are
+// not limited to a transaction's size, but anything larger is not a message
+// a wallet would sign.
+const maxVerifyBodyBytes = 64 << 10
+
+// verifySignatureRequest is the body accepted by /verify-signature
+type verifySignatureRequest struct {
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+	Pubkey    string `json:"pubkey"`
+}
+
+// verifySignatureResponse is the response of /verify-signature
+type verifySignatureResponse struct {
+	Valid bool `json:"valid"`
+}
+
+// verifySignature checks an ed25519 signature over a base64 message, with
+// the signature and public key in base58
+func verifySignature(req verifySignatureRequest) (bool, error) {
+	if req.Message == "" || req.Signature == "" || req.Pubkey == "" {
+		return false, fmt.Errorf("message, signature and pubkey are required")
+	}
+
+	message, err := base64.StdEncoding.DecodeString(req.Message)
+	if err != nil {
+		return false, fmt.Errorf("message must be base64 encoded")
+	}
+	signature, err := base58Decode(req.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return false, fmt.Errorf("signature must be %d base58 encoded bytes", ed25519.SignatureSize)
+	}
+	pubkey, err := decodePubkey(req.Pubkey)
+	if err != nil {
+		return false, err
+	}
+
+	return ed25519.Verify(ed25519.PublicKey(pubkey), message, signature), nil
+}
+
+// handleVerifySignature verifies a signed message offline, without calling
+// the RPC node
+func handleVerifySignature(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxVerifyBodyBytes))
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusBadRequest)
+		return
+	}
+
+	var req verifySignatureRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	valid, err := verifySignature(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	jsonData, _ := json.Marshal(verifySignatureResponse{Valid: valid})
+	w.Write(jsonData)
+}
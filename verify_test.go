@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleVerifySignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(bytes.NewReader(bytes.Repeat([]byte{7}, ed25519.SeedSize)))
+	if err != nil {
+		t.Fatalf("GenerateKey returned error: %v", err)
+	}
+	message := []byte("Sign in to example.com")
+	signature := base58Encode(ed25519.Sign(priv, message))
+	encoded := base64.StdEncoding.EncodeToString(message)
+	pubkey := base58Encode(pub)
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedValid  bool
+	}{
+		{name: "Valid", body: `{"message":"` + encoded + `","signature":"` + signature + `","pubkey":"` + pubkey + `"}`, expectedStatus: http.StatusOK, expectedValid: true},
+		{name: "Tampered Message", body: `{"message":"` + base64.StdEncoding.EncodeToString([]byte("Sign in to evil.com")) + `","signature":"` + signature + `","pubkey":"` + pubkey + `"}`, expectedStatus: http.StatusOK},
+		{name: "Wrong Key", body: `{"message":"` + encoded + `","signature":"` + signature + `","pubkey":"11111111111111111111111111111111"}`, expectedStatus: http.StatusOK},
+		{name: "Missing Field", body: `{"message":"` + encoded + `","pubkey":"` + pubkey + `"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Message Not Base64", body: `{"message":"***","signature":"` + signature + `","pubkey":"` + pubkey + `"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Short Signature", body: `{"message":"` + encoded + `","signature":"abc","pubkey":"` + pubkey + `"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Pubkey", body: `{"message":"` + encoded + `","signature":"` + signature + `","pubkey":"0OIl"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Invalid JSON", body: `{`, expectedStatus: http.StatusBadRequest},
+		{name: "Wrong Method", method: "GET", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "POST"
+			}
+			req := httptest.NewRequest(method, "/verify-signature", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			handleVerifySignature(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, tt.expectedStatus, rr.Body.String())
+			}
+			if rr.Code != http.StatusOK {
+				return
+			}
+
+			var response verifySignatureResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Valid != tt.expectedValid {
+				t.Errorf("Expected valid %v, got %v", tt.expectedValid, response.Valid)
+			}
+		})
+	}
+}
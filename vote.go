@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+)
+
+// voteProgramID owns every vote account
+const voteProgramID = "Vote111111111111111111111111111111111111111"
+
+// VoteStateVersions discriminants of the vote program's account layout
+const (
+	voteStateV0_23_5 = iota
+	voteStateV1_14_11
+	voteStateCurrent
+)
+
+// Sizes of the fixed-size records in a vote account
+const (
+	lockoutSize         = 8 + 4
+	landedVoteSize      = 1 + lockoutSize
+	authorizedVoterSize = 8 + 32
+	priorVotersSize     = 32*(32+8+8) + 8 + 1
+	epochCreditsSize    = 8 + 8 + 8
+)
+
+// VoteLockout is a vote on a slot and the number of times it has been
+// confirmed since. Latency is only recorded by the current layout.
+type VoteLockout struct {
+	Slot              uint64 `json:"slot"`
+	ConfirmationCount uint32 `json:"confirmationCount"`
+	Latency           *uint8 `json:"latency,omitempty"`
+}
+
+// AuthorizedVoter is the key allowed to vote from an epoch onwards
+type AuthorizedVoter struct {
+	Epoch uint64 `json:"epoch"`
+	Voter string `json:"authorizedVoter"`
+}
+
+// EpochCredits are the vote credits earned by the end of an epoch
+type EpochCredits struct {
+	Epoch           uint64 `json:"epoch"`
+	Credits         uint64 `json:"credits"`
+	PreviousCredits uint64 `json:"previousCredits"`
+}
+
+// VoteTimestamp is the most recent timestamp submitted with a vote
+type VoteTimestamp struct {
+	Slot      uint64 `json:"slot"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// VoteAccountState is a decoded vote program account
+type VoteAccountState struct {
+	Version              string            `json:"version"`
+	NodePubkey           string            `json:"nodePubkey"`
+	AuthorizedWithdrawer string            `json:"authorizedWithdrawer"`
+	Commission           uint8             `json:"commission"`
+	Votes                []VoteLockout     `json:"votes"`
+	RootSlot             *uint64           `json:"rootSlot"`
+	AuthorizedVoters     []AuthorizedVoter `json:"authorizedVoters"`
+	EpochCredits         []EpochCredits    `json:"epochCredits"`
+	LastTimestamp        VoteTimestamp     `json:"lastTimestamp"`
+}
+
+// readVecLen reads a u64 vector length and checks that the account holds
+// enough data for that many elements of elemSize bytes
+func readVecLen(r *borshReader, elemSize int) (int, error) {
+	n, err := readU64(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(r.remaining()/elemSize) {
+		return 0, errBorshEOF
+	}
+	return int(n), nil
+}
+
+func readVoteLockouts(r *borshReader, landed bool) ([]VoteLockout, error) {
+	size := lockoutSize
+	if landed {
+		size = landedVoteSize
+	}
+	n, err := readVecLen(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	votes := make([]VoteLockout, n)
+	for i := range votes {
+		if landed {
+			b, _ := r.read(1)
+			latency := b[0]
+			votes[i].Latency = &latency
+		}
+		votes[i].Slot, _ = readU64(r)
+		votes[i].ConfirmationCount, _ = readU32(r)
+	}
+	return votes, nil
+}
+
+func readVoteState(r *borshReader, version uint32) (*VoteAccountState, error) {
+	vote := VoteAccountState{Version: "current"}
+	if version == voteStateV1_14_11 {
+		vote.Version = "1.14.11"
+	}
+
+	var err error
+	if vote.NodePubkey, err = readPubkey(r); err != nil {
+		return nil, err
+	}
+	if vote.AuthorizedWithdrawer, err = readPubkey(r); err != nil {
+		return nil, err
+	}
+	commission, err := r.read(1)
+	if err != nil {
+		return nil, err
+	}
+	vote.Commission = commission[0]
+	if vote.Votes, err = readVoteLockouts(r, version == voteStateCurrent); err != nil {
+		return nil, err
+	}
+
+	hasRoot, err := r.read(1)
+	if err != nil {
+		return nil, err
+	}
+	if hasRoot[0] == 1 {
+		root, err := readU64(r)
+		if err != nil {
+			return nil, err
+		}
+		vote.RootSlot = &root
+	}
+
+	n, err := readVecLen(r, authorizedVoterSize)
+	if err != nil {
+		return nil, err
+	}
+	vote.AuthorizedVoters = make([]AuthorizedVoter, n)
+	for i := range vote.AuthorizedVoters {
+		vote.AuthorizedVoters[i].Epoch, _ = readU64(r)
+		vote.AuthorizedVoters[i].Voter, _ = readPubkey(r)
+	}
+
+	// Prior voters are a fixed-size ring buffer the response leaves out
+	if _, err := r.read(priorVotersSize); err != nil {
+		return nil, err
+	}
+
+	if n, err = readVecLen(r, epochCreditsSize); err != nil {
+		return nil, err
+	}
+	vote.EpochCredits = make([]EpochCredits, n)
+	for i := range vote.EpochCredits {
+		vote.EpochCredits[i].Epoch, _ = readU64(r)
+		vote.EpochCredits[i].Credits, _ = readU64(r)
+		vote.EpochCredits[i].PreviousCredits, _ = readU64(r)
+	}
+
+	if vote.LastTimestamp.Slot, err = readU64(r); err != nil {
+		return nil, err
+	}
+	timestamp, err := readU64(r)
+	if err != nil {
+		return nil, err
+	}
+	vote.LastTimestamp.Timestamp = int64(timestamp)
+	return &vote, nil
+}
+
+// decodeVoteAccount decodes the vote program's VoteStateVersions layout.
+// The current and 1.14.11 versions are supported; older and newer layouts
+// are rejected rather than misread.
+func decodeVoteAccount(account *AccountInfo) (string, interface{}, error) {
+	if account.Owner != voteProgramID {
+		return "", nil, fmt.Errorf("account is not owned by the vote program")
+	}
+
+	data, err := account.rawData()
+	if err != nil {
+		return "", nil, err
+	}
+
+	r := &borshReader{data: data}
+	version, err := readU32(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode vote account: %w", err)
+	}
+	switch version {
+	case voteStateV1_14_11, voteStateCurrent:
+	case voteStateV0_23_5:
+		return "", nil, fmt.Errorf("unsupported vote account version 0.23.5")
+	default:
+		return "", nil, fmt.Errorf("unrecognized vote account version %d", version)
+	}
+
+	vote, err := readVoteState(r, version)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode vote account: %w", err)
+	}
+	return "vote", *vote, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// voteAccountSize is the space allocated to every vote account on chain
+const voteAccountSize = 3762
+
+// encodeVoteAccount builds a vote account laid out like a mainnet one: a
+// full lockout tower, a root, one authorized voter, an empty prior voters
+// buffer and 64 epochs of credits, padded to the on-chain account size
+func encodeVoteAccount(version uint32) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, version)
+	data = append(data, bytes.Repeat([]byte{1}, 32)...)
+	data = append(data, bytes.Repeat([]byte{2}, 32)...)
+	data = append(data, 7)
+
+	data = binary.LittleEndian.AppendUint64(data, 31)
+	for i := 0; i < 31; i++ {
+		if version == voteStateCurrent {
+			data = append(data, 1)
+		}
+		data = binary.LittleEndian.AppendUint64(data, uint64(250000000+i))
+		data = binary.LittleEndian.AppendUint32(data, uint32(31-i))
+	}
+	data = append(data, 1)
+	data = binary.LittleEndian.AppendUint64(data, 249999999)
+
+	data = binary.LittleEndian.AppendUint64(data, 1)
+	data = binary.LittleEndian.AppendUint64(data, 580)
+	data = append(data, bytes.Repeat([]byte{3}, 32)...)
+
+	data = append(data, make([]byte, priorVotersSize)...)
+
+	data = binary.LittleEndian.AppendUint64(data, 64)
+	for i := uint64(0); i < 64; i++ {
+		data = binary.LittleEndian.AppendUint64(data, 517+i)
+		data = binary.LittleEndian.AppendUint64(data, (i+1)*6000000)
+		data = binary.LittleEndian.AppendUint64(data, i*6000000)
+	}
+
+	data = binary.LittleEndian.AppendUint64(data, 250000030)
+	data = binary.LittleEndian.AppendUint64(data, 1700000000)
+	return append(data, make([]byte, voteAccountSize-len(data))...)
+}
+
+func TestDecodeVoteAccount(t *testing.T) {
+	_, decoded, err := decodeVoteAccount(testAccount(voteProgramID, encodeVoteAccount(voteStateCurrent)))
+	if err != nil {
+		t.Fatalf("decodeVoteAccount returned error: %v", err)
+	}
+
+	vote := decoded.(VoteAccountState)
+	if vote.NodePubkey != base58Encode(bytes.Repeat([]byte{1}, 32)) || vote.AuthorizedWithdrawer != base58Encode(bytes.Repeat([]byte{2}, 32)) {
+		t.Errorf("unexpected keys: %s, %s", vote.NodePubkey, vote.AuthorizedWithdrawer)
+	}
+	if vote.Commission != 7 || vote.RootSlot == nil || *vote.RootSlot != 249999999 {
+		t.Errorf("unexpected commission or root: %d, %v", vote.Commission, vote.RootSlot)
+	}
+	if len(vote.Votes) != 31 || vote.Votes[30].Slot != 250000030 || vote.Votes[30].ConfirmationCount != 1 || vote.Votes[0].Latency == nil {
+		t.Errorf("unexpected votes: %+v", vote.Votes)
+	}
+	if len(vote.AuthorizedVoters) != 1 || vote.AuthorizedVoters[0].Epoch != 580 {
+		t.Errorf("unexpected authorized voters: %+v", vote.AuthorizedVoters)
+	}
+	if len(vote.EpochCredits) != 64 || vote.EpochCredits[63] != (EpochCredits{Epoch: 580, Credits: 384000000, PreviousCredits: 378000000}) {
+		t.Errorf("unexpected epoch credits: %+v", vote.EpochCredits[63])
+	}
+	if vote.LastTimestamp != (VoteTimestamp{Slot: 250000030, Timestamp: 1700000000}) {
+		t.Errorf("unexpected last timestamp: %+v", vote.LastTimestamp)
+	}
+
+	oversized := encodeVoteAccount(voteStateCurrent)
+	binary.LittleEndian.PutUint64(oversized[69:], 1<<40)
+
+	tests := []struct {
+		name            string
+		account         *AccountInfo
+		expectedVersion string
+		wantErr         string
+	}{
+		{name: "Version 1.14.11", account: testAccount(voteProgramID, encodeVoteAccount(voteStateV1_14_11)), expectedVersion: "1.14.11"},
+		{name: "Version 0.23.5", account: testAccount(voteProgramID, encodeVoteAccount(voteStateV0_23_5)), wantErr: "unsupported vote account version"},
+		{name: "Unrecognized Version", account: testAccount(voteProgramID, encodeVoteAccount(9)), wantErr: "unrecognized vote account version 9"},
+		{name: "Truncated", account: testAccount(voteProgramID, encodeVoteAccount(voteStateCurrent)[:2000]), wantErr: "unexpected end of account data"},
+		{name: "Oversized Votes", account: testAccount(voteProgramID, oversized), wantErr: "unexpected end of account data"},
+		{name: "Wrong Owner", account: testAccount(stakeProgramID, encodeVoteAccount(voteStateCurrent)), wantErr: "not owned by the vote program"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, decoded, err := decodeVoteAccount(tt.account)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decodeVoteAccount returned error: %v", err)
+			}
+			vote := decoded.(VoteAccountState)
+			if vote.Version != tt.expectedVersion || len(vote.Votes) != 31 || vote.Votes[0].Latency != nil {
+				t.Errorf("unexpected vote account: %s with %d votes", vote.Version, len(vote.Votes))
+			}
+		})
+	}
+}
+
+func TestHandleGetAccountVote(t *testing.T) {
+	mock := &mockRPCClient{accountInfo: testAccount(voteProgramID, encodeVoteAccount(voteStateCurrent))}
+	req := httptest.NewRequest("GET", "/account?address=abc&decode=vote", nil)
+	rr := httptest.NewRecorder()
+	handleGetAccount(mock, nil).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v (%s)", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var response struct {
+		Decoder string           `json:"decoder"`
+		Data    VoteAccountState `json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if response.Decoder != "vote" || response.Data.Commission != 7 || len(response.Data.EpochCredits) != 64 {
+		t.Errorf("unexpected response: %s", rr.Body.String())
+	}
+}
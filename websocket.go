@@ -0,0 +1,537 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebSocket settings
+const (
+	wsDialTimeout    = 10 * time.Second
+	wsPingInterval   = 20 * time.Second
+	wsReadTimeout    = 60 * time.Second
+	wsWriteTimeout   = 10 * time.Second
+	wsMaxMessageSize = 16 << 20
+	wsAcceptGUID     = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// WebSocket frame opcodes
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xa
+)
+
+var errWSMessageTooLarge = errors.New("websocket message too large")
+
+// wsConn is a minimal RFC 6455 connection, enough to hold JSON-RPC
+// subscriptions open against the upstream. Clients mask the frames they
+// send; servers, used only by tests, do not.
+type wsConn struct {
+	conn   net.Conn
+	br     *bufio.Reader
+	client bool
+
+	// readTimeout, when set, fails reads once the peer has sent nothing,
+	// not even a pong, for that long
+	readTimeout time.Duration
+	// writeTimeout, when set, fails writes the peer does not take in time
+	writeTimeout time.Duration
+
+	mu sync.Mutex // serializes writes
+}
+
+// wsEndpoint derives the WebSocket endpoint served alongside an HTTP RPC
+// endpoint
+func wsEndpoint(endpoint string) string {
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		return "wss://" + strings.TrimPrefix(endpoint, "https://")
+	case strings.HasPrefix(endpoint, "http://"):
+		return "ws://" + strings.TrimPrefix(endpoint, "http://")
+	}
+	return endpoint
+}
+
+// wsAccept computes the Sec-WebSocket-Accept value for a handshake key
+func wsAccept(key string) string {
+	sum := sha1.Sum([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// wsDialer opens upstream WebSocket connections through the proxy, dialer
+// and TLS settings of the upstream HTTP transport
+type wsDialer struct {
+	proxy       func(*http.Request) (*url.URL, error)
+	dialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+	tlsConfig   *tls.Config
+}
+
+// newWSDialer takes the WebSocket dial settings from an upstream transport
+func newWSDialer(transport *http.Transport) *wsDialer {
+	d := &wsDialer{proxy: transport.Proxy, dialContext: transport.DialContext, tlsConfig: transport.TLSClientConfig}
+	if d.dialContext == nil {
+		d.dialContext = (&net.Dialer{Timeout: wsDialTimeout}).DialContext
+	}
+	return d
+}
+
+// dialWebSocket opens a WebSocket connection to a ws:// or wss:// URL with
+// the settings of the default transport
+func dialWebSocket(rawURL string) (*wsConn, error) {
+	return newWSDialer(http.DefaultTransport.(*http.Transport)).dial(rawURL)
+}
+
+// hostPort returns the host and port of u, with the default port of scheme
+// when it has none
+func hostPort(u *url.URL, defaultPort string) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// dial opens a WebSocket connection to a ws:// or wss:// URL
+func (d *wsDialer) dial(rawURL string) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid websocket url: %w", err)
+	}
+
+	// Proxies are chosen as for the HTTP request the connection upgrades
+	target := *u
+	switch u.Scheme {
+	case "ws":
+		target.Scheme = "http"
+	case "wss":
+		target.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("unsupported websocket scheme %q", u.Scheme)
+	}
+	addr := hostPort(u, map[string]string{"http": "80", "https": "443"}[target.Scheme])
+
+	ctx, cancel := context.WithTimeout(context.Background(), wsDialTimeout)
+	defer cancel()
+	var proxy *url.URL
+	if d.proxy != nil {
+		if proxy, err = d.proxy(&http.Request{URL: &target, Header: http.Header{}}); err != nil {
+			return nil, fmt.Errorf("failed to choose websocket proxy: %w", err)
+		}
+	}
+	var conn net.Conn
+	if proxy != nil {
+		conn, err = d.dialProxy(ctx, proxy, addr)
+	} else {
+		conn, err = d.dialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to websocket: %w", err)
+	}
+	if u.Scheme == "wss" {
+		conn, err = d.handshakeTLS(ctx, conn, u.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to websocket: %w", err)
+		}
+	}
+
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Host:       u.Host,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+	}
+	conn.SetDeadline(time.Now().Add(wsDialTimeout))
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send websocket handshake: %w", err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to read websocket handshake: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: HTTP %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {
+		conn.Close()
+		return nil, fmt.Errorf("websocket handshake failed: invalid accept key")
+	}
+	conn.SetDeadline(time.Time{})
+
+	return &wsConn{conn: conn, br: br, client: true}, nil
+}
+
+// handshakeTLS secures conn for serverName with the transport's TLS settings
+func (d *wsDialer) handshakeTLS(ctx context.Context, conn net.Conn, serverName string) (net.Conn, error) {
+	config := &tls.Config{}
+	if d.tlsConfig != nil {
+		config = d.tlsConfig.Clone()
+	}
+	config.ServerName = serverName
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// dialProxy opens a tunnel to addr through an http, https or socks5 proxy
+func (d *wsDialer) dialProxy(ctx context.Context, proxy *url.URL, addr string) (net.Conn, error) {
+	defaultPort := map[string]string{"http": "80", "https": "443", "socks5": "1080"}[proxy.Scheme]
+	if defaultPort == "" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxy.Scheme)
+	}
+	conn, err := d.dialContext(ctx, "tcp", hostPort(proxy, defaultPort))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if proxy.Scheme == "https" {
+		if conn, err = d.handshakeTLS(ctx, conn, proxy.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	if proxy.Scheme == "socks5" {
+		err = socks5Connect(conn, proxy.User, addr)
+	} else {
+		err = httpConnect(conn, proxy.User, addr)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// httpConnect asks an HTTP proxy for a tunnel to addr with CONNECT
+func httpConnect(conn net.Conn, user *url.Userinfo, addr string) error {
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: addr}, Host: addr, Header: http.Header{}}
+	if user != nil {
+		password, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("failed to send proxy CONNECT: %w", err)
+	}
+	// The reader must not buffer past the response, which ends where the
+	// tunnel starts. The body is left alone, as a 200 answer to CONNECT has
+	// none and reading one would block on the tunnel.
+	resp, err := http.ReadResponse(bufio.NewReaderSize(&oneByteReader{conn}, 1), req)
+	if err != nil {
+		return fmt.Errorf("failed to read proxy CONNECT response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused CONNECT: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// oneByteReader reads a byte at a time, so that nothing past the proxy's
+// response is consumed
+type oneByteReader struct{ r io.Reader }
+
+func (o *oneByteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return o.r.Read(p)
+}
+
+// socks5Connect asks a SOCKS5 proxy (RFC 1928) for a connection to addr,
+// authenticating with a username and password (RFC 1929) when given
+func socks5Connect(conn net.Conn, user *url.Userinfo, addr string) error {
+	host, portString, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil || len(host) > 255 {
+		return fmt.Errorf("invalid address %q", addr)
+	}
+
+	method := byte(0x00)
+	if user != nil {
+		method = 0x02
+	}
+	if _, err := conn.Write([]byte{5, 1, method}); err != nil {
+		return fmt.Errorf("socks5 greeting failed: %w", err)
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil || reply[0] != 5 || reply[1] != method {
+		return fmt.Errorf("socks5 proxy refused authentication method %d", method)
+	}
+	if user != nil {
+		password, _ := user.Password()
+		if len(user.Username()) > 255 || len(password) > 255 {
+			return fmt.Errorf("socks5 credentials too long")
+		}
+		auth := append([]byte{1, byte(len(user.Username()))}, user.Username()...)
+		auth = append(append(auth, byte(len(password))), password...)
+		if _, err := conn.Write(auth); err != nil {
+			return fmt.Errorf("socks5 authentication failed: %w", err)
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil || reply[1] != 0 {
+			return fmt.Errorf("socks5 proxy rejected the credentials")
+		}
+	}
+
+	request := append([]byte{5, 1, 0, 3, byte(len(host))}, host...)
+	request = binary.BigEndian.AppendUint16(request, uint16(port))
+	if _, err := conn.Write(request); err != nil {
+		return fmt.Errorf("socks5 connect failed: %w", err)
+	}
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("socks5 connect failed: %w", err)
+	}
+	if header[1] != 0 {
+		return fmt.Errorf("socks5 proxy refused the connection: reply %d", header[1])
+	}
+	// Skip the bound address and port
+	var skip int
+	switch header[3] {
+	case 1:
+		skip = net.IPv4len + 2
+	case 4:
+		skip = net.IPv6len + 2
+	case 3:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return fmt.Errorf("socks5 connect failed: %w", err)
+		}
+		skip = int(length[0]) + 2
+	default:
+		return fmt.Errorf("socks5 proxy sent address type %d", header[3])
+	}
+	if _, err := io.ReadFull(conn, make([]byte, skip)); err != nil {
+		return fmt.Errorf("socks5 connect failed: %w", err)
+	}
+	return nil
+}
+
+// upgradeWebSocket answers a client's WebSocket handshake and takes over
+// its connection. Failed handshakes are answered with 400.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || r.Header.Get("Sec-WebSocket-Key") == "" {
+		http.Error(w, "expected websocket upgrade", http.StatusBadRequest)
+		return nil, fmt.Errorf("request is not a websocket upgrade")
+	}
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + wsAccept(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send websocket handshake: %w", err)
+	}
+	return &wsConn{conn: conn, br: rw.Reader}, nil
+}
+
+// writeFrame sends a single unfragmented frame
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	if c.client {
+		header[1] |= 0x80
+		mask := make([]byte, 4)
+		rand.Read(mask)
+		header = append(header, mask...)
+		masked := make([]byte, len(payload))
+		for i, b := range payload {
+			masked[i] = b ^ mask[i%4]
+		}
+		payload = masked
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.writeTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return fmt.Errorf("failed to write websocket frame: %w", err)
+	}
+	return nil
+}
+
+// writeText sends a text message
+func (c *wsConn) writeText(payload []byte) error {
+	return c.writeFrame(wsOpText, payload)
+}
+
+// readFrame reads one frame, unmasking its payload if needed
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	if c.readTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+
+	var header [2]byte
+	if _, err = io.ReadFull(c.br, header[:]); err != nil {
+		return
+	}
+	fin, opcode = header[0]&0x80 != 0, header[0]&0x0f
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > wsMaxMessageSize {
+		err = errWSMessageTooLarge
+		return
+	}
+
+	var mask [4]byte
+	masked := header[1]&0x80 != 0
+	if masked {
+		if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// readMessage reads the next data message, answering pings and joining
+// fragments along the way. A close frame from the peer ends the connection
+// with io.EOF.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch opcode {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			c.writeFrame(wsOpClose, payload)
+			return nil, io.EOF
+		case wsOpText, wsOpBinary, wsOpContinuation:
+		default:
+			return nil, fmt.Errorf("unexpected websocket opcode %d", opcode)
+		}
+
+		message = append(message, payload...)
+		if len(message) > wsMaxMessageSize {
+			return nil, errWSMessageTooLarge
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// keepAlive pings the peer every interval, so that its pongs keep a quiet
+// connection within readTimeout, until stop is called or a ping fails
+func (c *wsConn) keepAlive(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := c.writeFrame(wsOpPing, nil); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// isTimeout reports whether err is a read that hit its deadline
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// close sends a normal closure frame and closes the connection
+func (c *wsConn) close() error {
+	c.writeFrame(wsOpClose, []byte{0x03, 0xe8})
+	return c.conn.Close()
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newWSTestServer serves WebSocket connections with serve, acting as the
+// upstream side of a subscription
+func newWSTestServer(t *testing.T, serve func(*wsConn)) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgradeWebSocket(w, r)
+		if err != nil {
+			t.Errorf("failed to upgrade connection: %v", err)
+			return
+		}
+		defer conn.conn.Close()
+
+		serve(conn)
+	}))
+}
+
+func TestWebSocketRoundTrip(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), 70000)
+	received := make(chan []byte, 2)
+	server := newWSTestServer(t, func(c *wsConn) {
+		message, err := c.readMessage()
+		if err != nil {
+			t.Errorf("server failed to read message: %v", err)
+			return
+		}
+		received <- message
+
+		// A ping, then a fragmented message, then one with a 64 bit length
+		c.writeFrame(wsOpPing, []byte("p"))
+		c.conn.Write([]byte{wsOpText, 3, 'a', 'b', 'c'})
+		c.conn.Write([]byte{0x80 | wsOpContinuation, 3, 'd', 'e', 'f'})
+		c.writeText(large)
+
+		// The client answers the ping before reading on
+		_, opcode, payload, err := c.readFrame()
+		if err != nil || opcode != wsOpPong || string(payload) != "p" {
+			t.Errorf("Expected pong, got opcode %d %q (%v)", opcode, payload, err)
+		}
+		c.close()
+	})
+	defer server.Close()
+
+	conn, err := dialWebSocket(wsEndpoint(server.URL))
+	if err != nil {
+		t.Fatalf("dialWebSocket returned error: %v", err)
+	}
+	defer conn.close()
+
+	if err := conn.writeText([]byte("hello")); err != nil {
+		t.Fatalf("writeText returned error: %v", err)
+	}
+	if message := <-received; string(message) != "hello" {
+		t.Errorf("Expected server to receive hello, got %q", message)
+	}
+
+	tests := []struct {
+		name     string
+		expected []byte
+		err      error
+	}{
+		{name: "Fragmented", expected: []byte("abcdef")},
+		{name: "Large", expected: large},
+		{name: "Closed", err: io.EOF},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := conn.readMessage()
+			if err != tt.err {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if !bytes.Equal(message, tt.expected) {
+				t.Errorf("Expected %d byte message, got %d bytes", len(tt.expected), len(message))
+			}
+		})
+	}
+}
+
+func TestDialWebSocketRejectsPlainHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	if _, err := dialWebSocket(wsEndpoint(server.URL)); err == nil {
+		t.Error("Expected handshake with a plain HTTP server to fail")
+	}
+}
+
+// newConnectProxy serves HTTP CONNECT tunnels, recording the addresses
+func newConnectProxy(t *testing.T, tunnels chan<- string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		tunnels <- r.Host
+		conn, rw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			t.Errorf("failed to hijack proxy connection: %v", err)
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		pipe(conn, rw, upstream)
+	}))
+}
+
+// newSOCKS5Proxy serves SOCKS5 connections with username and password
+// authentication, recording the addresses
+func newSOCKS5Proxy(t *testing.T, user, password string, tunnels chan<- string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				greeting := make([]byte, 3)
+				io.ReadFull(br, greeting)
+				conn.Write([]byte{5, 2})
+				auth := make([]byte, 2)
+				io.ReadFull(br, auth)
+				name := make([]byte, auth[1])
+				io.ReadFull(br, name)
+				length, _ := br.ReadByte()
+				secret := make([]byte, length)
+				io.ReadFull(br, secret)
+				if string(name) != user || string(secret) != password {
+					conn.Write([]byte{1, 1})
+					return
+				}
+				conn.Write([]byte{1, 0})
+
+				request := make([]byte, 5)
+				io.ReadFull(br, request)
+				host := make([]byte, request[4]+2)
+				io.ReadFull(br, host)
+				addr := net.JoinHostPort(string(host[:request[4]]), strconv.Itoa(int(binary.BigEndian.Uint16(host[request[4]:]))))
+				upstream, err := net.Dial("tcp", addr)
+				if err != nil {
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					return
+				}
+				tunnels <- addr
+				conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0})
+				pipe(conn, bufio.NewReadWriter(br, bufio.NewWriter(conn)), upstream)
+			}()
+		}
+	}()
+	return listener
+}
+
+// pipe copies between a client connection, read through rw, and upstream
+// until either side closes
+func pipe(conn net.Conn, rw *bufio.ReadWriter, upstream net.Conn) {
+	defer conn.Close()
+	defer upstream.Close()
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(upstream, rw); done <- struct{}{} }()
+	go func() { io.Copy(conn, upstream); done <- struct{}{} }()
+	<-done
+}
+
+func TestWSDialerThroughProxies(t *testing.T) {
+	echo := func(c *wsConn) {
+		if message, err := c.readMessage(); err == nil {
+			c.writeText(message)
+		}
+	}
+	server := newWSTestServer(t, echo)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgradeWebSocket(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.conn.Close()
+		echo(conn)
+	}))
+	defer tlsServer.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(tlsServer.Certificate())
+
+	tunnels := make(chan string, 4)
+	connect := newConnectProxy(t, tunnels)
+	defer connect.Close()
+	socks := newSOCKS5Proxy(t, "user", "secret", tunnels)
+	defer socks.Close()
+
+	tests := []struct {
+		name        string
+		proxy       string
+		url         string
+		tlsConfig   *tls.Config
+		expectedErr bool
+	}{
+		{name: "Direct", url: wsEndpoint(server.URL)},
+		{name: "HTTP Proxy", proxy: connect.URL, url: wsEndpoint(server.URL)},
+		{name: "SOCKS5 Proxy", proxy: "socks5://user:secret@" + socks.Addr().String(), url: wsEndpoint(server.URL)},
+		{name: "SOCKS5 Wrong Password", proxy: "socks5://user:wrong@" + socks.Addr().String(), url: wsEndpoint(server.URL), expectedErr: true},
+		{name: "Private CA", url: wsEndpoint(tlsServer.URL), tlsConfig: &tls.Config{RootCAs: roots}},
+		{name: "Private CA Through Proxy", proxy: connect.URL, url: wsEndpoint(tlsServer.URL), tlsConfig: &tls.Config{RootCAs: roots}},
+		{name: "Untrusted CA", url: wsEndpoint(tlsServer.URL), expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &http.Transport{TLSClientConfig: tt.tlsConfig}
+			if tt.proxy != "" {
+				proxy, _ := url.Parse(tt.proxy)
+				transport.Proxy = http.ProxyURL(proxy)
+			}
+			conn, err := newWSDialer(transport).dial(tt.url)
+			if tt.expectedErr {
+				if err == nil {
+					conn.close()
+					t.Fatal("Expected the dial to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("dial returned error: %v", err)
+			}
+			defer conn.close()
+
+			conn.writeText([]byte("hello"))
+			if message, err := conn.readMessage(); err != nil || string(message) != "hello" {
+				t.Errorf("Expected the echo of hello, got %q, %v", message, err)
+			}
+			if tt.proxy != "" {
+				select {
+				case addr := <-tunnels:
+					if !strings.HasSuffix(tt.url, addr) && !strings.Contains(tt.url, addr) {
+						t.Errorf("Expected a tunnel to the node, got one to %s", addr)
+					}
+				default:
+					t.Error("Expected the connection to go through the proxy")
+				}
+			}
+		})
+	}
+}